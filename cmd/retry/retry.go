@@ -0,0 +1,92 @@
+package retry
+
+import (
+	"os"
+
+	"vault-sync/internal/config"
+	"vault-sync/internal/core"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var dueFlag bool
+
+var RetryCmd = &cobra.Command{
+	Use:   "retry [mount/path...]",
+	Short: "Retry failed and quarantined secrets",
+	Long: `Retry the failed replicas of the given secrets right away, regardless of their backoff
+and quarantine. Without arguments, every secret with a quarantined replica is retried.
+A replica that fails again is scheduled or quarantined as usual.
+
+With --due, only the failed replicas whose retry is due are retried, like the daemon
+does between full syncs; quarantined replicas are left alone.`,
+	Example: `  # Retry every quarantined secret
+  vault-sync retry
+
+  # Retry two secrets now
+  vault-sync retry production/app/database production/app/cache
+
+  # Retry the failed secrets whose backoff has passed
+  vault-sync retry --due`,
+	Run: runRetry,
+}
+
+func init() {
+	RetryCmd.Flags().BoolVar(&dueFlag, "due", false, "only retry the failed secrets whose retry is due")
+}
+
+func runRetry(cmd *cobra.Command, args []string) {
+	logger := log.Logger.With().Str("component", "retry").Logger()
+
+	if dueFlag && len(args) > 0 {
+		logger.Error().Msg("Secret paths cannot be combined with --due")
+		os.Exit(-1)
+	}
+	paths, err := pathmatching.ParseSecretPaths(args)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid secret path")
+		os.Exit(-1)
+	}
+
+	appConfig, err := config.Load()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating config")
+		os.Exit(-1)
+	}
+
+	ctx := cmd.Context()
+	wiring := core.NewWiring(appConfig)
+	stopTracing, err := wiring.InitTracing(ctx)
+	if err != nil {
+		os.Exit(-1)
+	}
+	syncOrchestrator, err := wiring.InitOrchestrator(ctx)
+	if err != nil {
+		stopTracing()
+		os.Exit(-1)
+	}
+
+	var result *orchestrator.SyncResult
+	if dueFlag {
+		result, err = syncOrchestrator.RetryFailed(ctx)
+	} else {
+		result, err = syncOrchestrator.RetryQuarantined(ctx, paths)
+	}
+	stopTracing()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error during retry")
+		os.Exit(-1)
+	}
+	if result.TotalSecrets == 0 {
+		logger.Info().Msg("Nothing to retry")
+		return
+	}
+	if result.FailedSyncs > 0 {
+		logger.Warn().Int("failed", result.FailedSyncs).Msg("Retry completed, but some secrets failed again")
+		return
+	}
+	logger.Info().Int("retried", result.TotalSecrets).Msg("Retry completed successfully")
+}
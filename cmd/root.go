@@ -5,8 +5,11 @@ import (
 	"strings"
 	"vault-sync/cmd/configprint"
 	"vault-sync/cmd/pathmatcher"
+	"vault-sync/cmd/retry"
 	"vault-sync/cmd/sync"
+	"vault-sync/cmd/syncstate"
 	"vault-sync/cmd/version"
+	"vault-sync/internal/service/report"
 	"vault-sync/pkg/log"
 
 	"github.com/spf13/cobra"
@@ -27,10 +30,12 @@ var RootCmd = &cobra.Command{
 	as main site and disaster recovery site.`,
 }
 
+// Execute runs the command line. The commands exit with their own codes, so an error returned here is an invalid
+// command line and exits with report.ExitUsage.
 func Execute() {
 	err := RootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(report.ExitUsage)
 	}
 }
 
@@ -48,6 +53,9 @@ func init() {
 	RootCmd.AddCommand(sync.SyncCmd)
 	RootCmd.AddCommand(pathmatcher.PathMatcherCmd)
 	RootCmd.AddCommand(configprint.ConfigPrintCmd)
+	RootCmd.AddCommand(syncstate.StatusCmd)
+	RootCmd.AddCommand(syncstate.HistoryCmd)
+	RootCmd.AddCommand(retry.RetryCmd)
 }
 
 func initConfig() {
@@ -69,9 +77,8 @@ func initConfig() {
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 
+	// The commands that need the config report this error when they load it and exit with their own codes.
 	if err := viper.ReadInConfig(); err != nil {
 		logger.Error().Err(err).Msg("Error loading config")
-		os.Exit(-1)
-		return
 	}
 }
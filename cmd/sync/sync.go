@@ -1,8 +1,30 @@
 package sync
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	gosync "sync"
+	"syscall"
+
 	"vault-sync/internal/config"
 	"vault-sync/internal/core"
+	"vault-sync/internal/metrics"
+	psqlRepo "vault-sync/internal/repository/postgres"
+	"vault-sync/internal/service/api"
+	"vault-sync/internal/service/daemon"
+	"vault-sync/internal/service/events"
+	"vault-sync/internal/service/health"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/service/report"
+	"vault-sync/internal/service/syncrequests"
+	"vault-sync/internal/service/syncstate"
+	"vault-sync/internal/service/webhook"
+	"vault-sync/internal/service/workqueue"
 	"vault-sync/pkg/log"
 
 	"github.com/spf13/cobra"
@@ -15,84 +37,348 @@ var SyncCmd = &cobra.Command{
 }
 
 var onceCmd = &cobra.Command{
-	Use:     "once",
-	Short:   "Run sync operation once and exit",
-	Long:    `Perform a one-time synchronization of secrets and exit.`,
-	Example: `vault-sync sync once --config /path/to/config.yaml`,
-	Run:     runOnce,
+	Use:   "once",
+	Short: "Run sync operation once and exit",
+	Long: `Perform a one-time synchronization of secrets and exit. When metrics.textfile is set, the
+metrics of the run are written to it for the node_exporter textfile collector, also when the run fails.
+On SIGINT or SIGTERM no new secret is started, the secrets in progress get up to 20s to finish and the
+processed paths are checkpointed; --resume continues the interrupted run instead of starting over.
+
+The exit code tells the outcome of the run:
+  0  every secret was synced or unchanged
+  1  partial failure: some secrets failed or some mounts could not be listed
+  2  failure: the run failed, was interrupted or no secret could be synced, also when the
+     config cannot be loaded or the clusters, the database or tracing cannot be set up
+  3  aborted by a safeguard: the run budget ran out or strict discovery failed
+ 64  invalid command line, e.g. an unknown flag or report format; nothing ran
+
+--report writes the result with the outcome of every secret as JSON, JUnit XML (one test case
+per secret) or a Markdown summary, also when the run fails.`,
+	Example: `  vault-sync sync once --config /path/to/config.yaml
+
+  # Continue a run that was interrupted
+  vault-sync sync once --resume --config /path/to/config.yaml
+
+  # Publish the outcome of every secret as test results in CI
+  vault-sync sync once --report sync-report.xml --report-format junit --config /path/to/config.yaml`,
+	Run: runOnce,
 }
 
-// var daemonCmd = &cobra.Command{
-// 	Use:     "daemon",
-// 	Short:   "Run sync as a scheduled daemon",
-// 	Long:    `Run sync operations continuously based on configured schedule.`,
-// 	Example: `vault-sync sync daemon --config /path/to/config.yaml`,
-// 	Run:     runDaemon,
-// }
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run sync as a scheduled daemon",
+	Long: `Run sync operations continuously based on configured schedule. A full sync runs every
+sync_rule.interval and, in between, the failed secrets whose retry is due are retried
+every retry.interval. Prometheus metrics are served on metrics.listen_address under /metrics,
+the liveness and readiness checks under /healthz and /readyz. When api.enabled is set, the
+control API on api.listen_address triggers, inspects and cancels runs and reports the sync state.
+When webhook.enabled is set, signed POST requests to /webhook sync the paths they name right away.
+When events.enabled is set, the secrets changed on the main cluster are synced as Vault reports them.
+An interrupted full or incremental run is resumed by the next one.
+When sync_requests.enabled is set, the rows inserted into the sync_requests table are synced and updated.
+With work_queue.role set to coordinator, the jobs of the runs are enqueued for the workers instead of
+being run; with worker, the daemon runs no scheduled syncs and runs the jobs it leases from the queue.
+The daemon stops on SIGINT or SIGTERM once the current run ends.`,
+	Example: `vault-sync sync daemon --config /path/to/config.yaml`,
+	Run:     runDaemon,
+}
 
 var dryRunCmd = &cobra.Command{
-	Use:     "dry-run",
-	Short:   "Show what would be synced without actually syncing",
-	Long:    `Discover and display all secrets that would be synchronized without performing actual sync.`,
+	Use:   "dry-run",
+	Short: "Show what would be synced without actually syncing",
+	Long: `Discover and display all secrets that would be synchronized without performing actual sync.
+Exits with 2 when the config cannot be loaded, the path matcher cannot be set up or discovery fails.`,
 	Example: `vault-sync sync dry-run --config /path/to/config.yaml`,
 	Run:     runDryRun,
 }
 
+var (
+	resumeFlag       bool
+	reportFlag       string
+	reportFormatFlag string
+)
+
 func init() {
+	onceCmd.Flags().BoolVar(&resumeFlag, "resume", false, "continue the last run if it was interrupted")
+	onceCmd.Flags().StringVar(&reportFlag, "report", "", "write a report of the run to this file")
+	onceCmd.Flags().StringVar(&reportFormatFlag, "report-format", string(report.FormatJSON),
+		"format of the report (json|junit|markdown)")
 	SyncCmd.AddCommand(onceCmd)
+	SyncCmd.AddCommand(daemonCmd)
 	SyncCmd.AddCommand(dryRunCmd)
 	// SyncCmd.Run = runOnce
 }
 
 func runOnce(cmd *cobra.Command, _ []string) {
+	if code := syncOnce(cmd); code != report.ExitSuccess {
+		os.Exit(code)
+	}
+}
+
+// syncOnce runs the one-time sync and returns the exit code of its outcome. It returns rather than exits so the
+// deferred cleanups run first.
+func syncOnce(cmd *cobra.Command) int {
 	logger := log.Logger.With().Str("component", "sync-once").Logger()
 	logger.Info().Msg("Starting one-time vault-sync")
 
+	reportFormat, err := report.ParseFormat(reportFormatFlag)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error parsing report format")
+		return report.ExitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := startSync(ctx)
+	if reportFlag != "" {
+		if writeErr := writeReport(reportFlag, reportFormat, report.New(result, err)); writeErr != nil {
+			logger.Error().Err(writeErr).Str("report", reportFlag).Msg("Error writing report")
+		}
+	}
+
+	status := report.StatusOf(result, err)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		logger.Warn().Err(err).Msg("One-time sync interrupted, run it with --resume to continue")
+	case err != nil:
+		logger.Error().Err(err).Str("status", string(status)).Msg("Error during sync")
+	case status != report.StatusSuccess:
+		logger.Warn().
+			Str("status", string(status)).
+			Int("failed_syncs", result.FailedSyncs).
+			Strs("failed_mounts", result.FailedMounts).
+			Msg("One-time sync completed, but some secrets were not synced")
+	default:
+		logger.Info().Msg("One-time sync completed successfully")
+	}
+	return status.ExitCode()
+}
+
+// startSync loads the config, builds the orchestrator and runs the sync. An error setting up the sync, e.g. an
+// unreadable config or an unreachable cluster or database, is returned as the error of a run that did not start.
+func startSync(ctx context.Context) (*orchestrator.SyncResult, error) {
 	appConfig, err := config.Load()
 	if err != nil {
-		logger.Error().Err(err).Msg("Error creating config")
-		return
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	wiring := core.NewWiring(appConfig)
-	ctx := cmd.Context()
+	stopTracing, err := wiring.InitTracing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stopTracing()
 
-	orchestrator := wiring.InitOrchestrator(ctx)
-	_, err = orchestrator.StartSync(ctx)
+	var orchestratorOpts []orchestrator.Option
+	if resumeFlag {
+		orchestratorOpts = append(orchestratorOpts, orchestrator.WithResume())
+	}
+	if reportFlag != "" {
+		orchestratorOpts = append(orchestratorOpts, orchestrator.WithUnchangedJobResults())
+	}
+	syncOrchestrator, err := wiring.InitOrchestrator(ctx, orchestratorOpts...)
 	if err != nil {
-		logger.Error().Err(err).Msg("Error during sync")
-		return
+		return nil, err
+	}
+	result, err := syncOrchestrator.StartSync(ctx)
+	if appConfig.Metrics.Textfile != "" {
+		if writeErr := metrics.WriteTextfile(appConfig.Metrics.Textfile); writeErr != nil {
+			log.Logger.Error().Err(writeErr).Str("textfile", appConfig.Metrics.Textfile).Msg("Error writing metrics")
+		}
 	}
-	logger.Info().Msg("One-time sync completed successfully")
+	return result, err
 }
 
-// func runDaemon(cmd *cobra.Command, args []string) {
-// 	logger := log.Logger.With().Str("component", "sync-daemon").Logger()
-// 	logger.Info().Msg("Starting vault-sync daemon")
+// writeReport writes the report of the run to path.
+func writeReport(path string, format report.Format, runReport *report.Report) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+	return report.Write(file, format, runReport)
+}
+
+func runDaemon(cmd *cobra.Command, _ []string) {
+	if err := serveDaemon(cmd); err != nil {
+		os.Exit(-1)
+	}
+}
+
+// serveDaemon runs the daemon until it is stopped. It returns rather than exits so the deferred cleanups run
+// first; the returned error is logged already.
+func serveDaemon(cmd *cobra.Command) error {
+	logger := log.Logger.With().Str("component", "sync-daemon").Logger()
+	logger.Info().Msg("Starting vault-sync daemon")
+
+	appConfig, err := config.Load()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating config")
+		return err
+	}
+
+	wiring := core.NewWiring(appConfig)
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopTracing, err := wiring.InitTracing(ctx)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
 
-// 	appConfig, err := config.NewConfig()
-// 	if err != nil {
-// 		logger.Error().Err(err).Msg("Error creating config")
-// 		return
-// 	}
+	syncOrchestrator, err := wiring.InitOrchestrator(ctx, orchestrator.WithResume())
+	if err != nil {
+		return err
+	}
+	vaultClient, err := wiring.InitVaultClient(ctx)
+	if err != nil {
+		return err
+	}
+	repository, err := wiring.InitSyncedSecretRepository()
+	if err != nil {
+		return err
+	}
+	pathMatcher, err := wiring.InitPathMatcher()
+	if err != nil {
+		return err
+	}
+	daemonOpts := []daemon.Option{daemon.WithDebounce(appConfig.Webhook.Debounce)}
+	if appConfig.WorkQueue.Role == config.WorkQueueWorker {
+		daemonOpts = append(daemonOpts, daemon.WithoutSchedule())
+	}
+	syncDaemon := daemon.NewDaemon(
+		syncOrchestrator,
+		appConfig.SyncRule.GetInterval(),
+		appConfig.Retry.GetInterval(),
+		daemonOpts...,
+	)
+	checker := health.NewChecker(
+		syncDaemon,
+		repository,
+		vaultClient,
+		appConfig.Health.GetStallTimeout(appConfig.Budget.MaxRunDuration),
+	)
 
-// 	wiring := core.NewWiring(appConfig)
-// 	ctx := cmd.Context()
+	var webhookHandler http.Handler
+	if appConfig.Webhook.Enabled {
+		webhookHandler = webhook.NewHandler(syncDaemon, pathMatcher, appConfig.Webhook.Secret)
+	}
+	metricsServer, err := daemon.Listen(appConfig.Metrics.GetListenAddress(), newDaemonHandler(checker, webhookHandler))
+	if err != nil {
+		logger.Error().Err(err).Msg("Error starting HTTP server")
+		return err
+	}
+	servers := []*daemon.Server{metricsServer}
+	if appConfig.API.Enabled {
+		apiServer, apiErr := listenAPI(appConfig, syncDaemon, repository)
+		if apiErr != nil {
+			logger.Error().Err(apiErr).Msg("Error starting control API")
+			return apiErr
+		}
+		servers = append(servers, apiServer)
+	}
+	var background gosync.WaitGroup
+	for _, server := range servers {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			if serveErr := server.Serve(ctx); serveErr != nil {
+				logger.Error().Err(serveErr).Msg("HTTP server failed, stopping daemon")
+				cancel()
+			}
+		}()
+	}
+	if appConfig.Events.Enabled {
+		listener := events.NewListener(
+			vaultClient,
+			pathMatcher,
+			syncDaemon,
+			appConfig.Events.GetEventType(),
+		)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			listener.Run(ctx)
+		}()
+	}
 
-// 	// TODO: Implement scheduler/daemon mode
-// 	// This will use the interval from config.SyncRule.Interval
-// 	// and run sync operations continuously
+	if appConfig.SyncRequests.Enabled {
+		processor := syncrequests.NewProcessor(
+			repository,
+			syncDaemon,
+			pathMatcher,
+			appConfig.ID,
+			appConfig.SyncRequests.GetPollInterval(),
+			appConfig.SyncRequests.GetClaimTimeout(),
+		)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			processor.Run(ctx)
+		}()
+	}
 
-// 	logger.Info().Msg("Daemon mode - TODO: Implement scheduler")
+	if appConfig.WorkQueue.Role == config.WorkQueueWorker {
+		worker := workqueue.NewWorker(
+			repository,
+			syncDaemon,
+			appConfig.ID,
+			appConfig.WorkQueue.GetBatchSize(),
+			appConfig.WorkQueue.GetMaxAttempts(),
+			appConfig.WorkQueue.GetLeaseDuration(),
+			appConfig.WorkQueue.GetPollInterval(),
+		)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			worker.Run(ctx)
+		}()
+	}
+
+	err = syncDaemon.Run(ctx)
+	cancel()
+	background.Wait()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error running daemon")
+		return err
+	}
+	logger.Info().Msg("Daemon stopped")
+	return nil
+}
 
-// 	// For now, run once
-// 	orchestrator := wiring.InitOrchestrator(ctx)
-// 	_, err = orchestrator.StartSync(ctx)
-// 	if err != nil {
-// 		logger.Error().Err(err).Msg("Error during sync")
-// 		return
-// 	}
-// }
+// newDaemonHandler returns the routes served by the daemon. The webhook is only served when webhookHandler is set.
+func newDaemonHandler(checker *health.Checker, webhookHandler http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.Handle("GET /healthz", checker.LivenessHandler())
+	mux.Handle("GET /readyz", checker.ReadinessHandler())
+	if webhookHandler != nil {
+		mux.Handle("POST /webhook", webhookHandler)
+	}
+	return mux
+}
+
+// listenAPI binds the control API, serving HTTPS when api.tls.cert_file is set.
+func listenAPI(
+	appConfig *config.Config,
+	syncDaemon *daemon.Daemon,
+	repository *psqlRepo.SyncedSecretRepository,
+) (*daemon.Server, error) {
+	tlsConfig, err := api.TLSConfig(&appConfig.API.TLS)
+	if err != nil {
+		return nil, err
+	}
+	inspector := syncstate.NewInspector(repository)
+	handler := api.NewHandler(syncDaemon, inspector, appConfig.API.Token)
+	if tlsConfig != nil {
+		return daemon.ListenTLS(appConfig.API.GetListenAddress(), handler, tlsConfig)
+	}
+	return daemon.Listen(appConfig.API.GetListenAddress(), handler)
+}
 
 func runDryRun(cmd *cobra.Command, _ []string) {
 	logger := log.Logger.With().Str("component", "sync-dry-run").Logger()
@@ -101,17 +387,30 @@ func runDryRun(cmd *cobra.Command, _ []string) {
 	appConfig, err := config.Load()
 	if err != nil {
 		logger.Error().Err(err).Msg("Error creating config")
-		return
+		os.Exit(report.ExitFailure)
 	}
 
 	wiring := core.NewWiring(appConfig)
 	ctx := cmd.Context()
 
-	pathMatcher := wiring.InitPathMatcher()
-	secrets, err := pathMatcher.DiscoverSecretsForSync(ctx)
+	pathMatcher, err := wiring.InitPathMatcher()
 	if err != nil {
+		logger.Error().Err(err).Msg("Error setting up the path matcher")
+		os.Exit(report.ExitFailure)
+	}
+	secrets, err := pathMatcher.DiscoverSecretsForSync(ctx)
+	var discoveryErr *pathmatching.DiscoveryError
+	if err != nil && !errors.As(err, &discoveryErr) {
 		logger.Error().Err(err).Msg("Error discovering secrets for dry-run")
-		return
+		os.Exit(report.ExitFailure)
+	}
+	if discoveryErr != nil {
+		for _, mount := range discoveryErr.FailedMounts() {
+			logger.Error().
+				Str("mount", mount).
+				Err(discoveryErr.MountErrors[mount]).
+				Msg("Mount could not be listed; its secrets are missing from the dry run")
+		}
 	}
 
 	logger.Info().Msg("=== DRY RUN: Secrets that would be synced ===")
@@ -0,0 +1,174 @@
+package syncstate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"vault-sync/internal/config"
+	"vault-sync/internal/core"
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/syncstate"
+	"vault-sync/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultHistoryLimit = 20
+
+var (
+	formatFlag     string
+	outputFileFlag string
+
+	mountFlag                string
+	pathFlag                 string
+	clusterFlag              string
+	statusFlags              []string
+	errorCategoryFlags       []string
+	lastSuccessOlderThanFlag time.Duration
+
+	limitFlag int
+)
+
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the sync state of every secret per replica cluster",
+	Long: `Show the stored sync state of every secret on every replica cluster.
+
+Filters can be combined; a record is shown when it matches all of them. Records of
+failed deletes are reported with the status error_deleting.`,
+	Example: `  # Show every failed secret in the production mount
+  vault-sync status --mount production --status failed,error_deleting
+
+  # Secrets the replica tokens are not allowed to write
+  vault-sync status --error-category permission_denied
+
+  # Secrets under app/ on dr-site that did not sync successfully in the last day
+  vault-sync status --path 'app/**' --cluster dr-site --last-success-older-than 24h
+
+  # Export the state for an audit
+  vault-sync status --format csv --output-file sync-status.csv`,
+	Args: cobra.NoArgs,
+	Run:  runStatus,
+}
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history <mount/path>",
+	Short: "Show the recent sync attempts of a secret",
+	Long: `Show the recorded updates, deletes and failures of a secret on every replica cluster,
+newest first. Replicas that were already in sync are not recorded.`,
+	Example: `  vault-sync history production/app/database
+  vault-sync history production/app/database --limit 50 --format json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runHistory,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{StatusCmd, HistoryCmd} {
+		cmd.Flags().StringVarP(&formatFlag, "format", "f", string(syncstate.FormatTable),
+			"output format (table|json|csv)")
+		cmd.Flags().StringVarP(&outputFileFlag, "output-file", "o", "",
+			"write the output to this file instead of stdout")
+	}
+
+	StatusCmd.Flags().StringVar(&mountFlag, "mount", "", "only show secrets of this mount")
+	StatusCmd.Flags().StringVar(&pathFlag, "path", "", "only show secrets whose path matches this glob")
+	StatusCmd.Flags().StringVar(&clusterFlag, "cluster", "", "only show this replica cluster")
+	StatusCmd.Flags().StringSliceVar(&statusFlags, "status", nil,
+		"only show these statuses (success, failed, error_deleting, pending)")
+	StatusCmd.Flags().StringSliceVar(&errorCategoryFlags, "error-category", nil,
+		"only show failed secrets whose last error is of these categories (e.g. permission_denied, unavailable)")
+	StatusCmd.Flags().DurationVar(&lastSuccessOlderThanFlag, "last-success-older-than", 0,
+		"only show secrets that did not sync successfully within this duration")
+
+	HistoryCmd.Flags().IntVarP(&limitFlag, "limit", "n", defaultHistoryLimit, "number of attempts to show")
+}
+
+func runStatus(cmd *cobra.Command, _ []string) {
+	logger := log.Logger.With().Str("component", "status").Logger()
+
+	filter := syncstate.StatusFilter{
+		Mount:                mountFlag,
+		PathGlob:             pathFlag,
+		Cluster:              clusterFlag,
+		LastSuccessOlderThan: lastSuccessOlderThanFlag,
+	}
+	for _, status := range statusFlags {
+		filter.Statuses = append(filter.Statuses, models.SyncStatus(status))
+	}
+	for _, category := range errorCategoryFlags {
+		filter.ErrorCategories = append(filter.ErrorCategories, models.ErrorCategory(category))
+	}
+
+	if err := filter.Validate(); err != nil {
+		logger.Error().Err(err).Msg("Invalid filter")
+		os.Exit(-1)
+	}
+
+	err := run(cmd, func(inspector *syncstate.Inspector, format syncstate.Format, w io.Writer) error {
+		entries, err := inspector.Status(cmd.Context(), filter, time.Now())
+		if err != nil {
+			return err
+		}
+		return syncstate.WriteStatus(w, format, entries)
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to show sync status")
+		os.Exit(-1)
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	logger := log.Logger.With().Str("component", "history").Str("secret", args[0]).Logger()
+
+	err := run(cmd, func(inspector *syncstate.Inspector, format syncstate.Format, w io.Writer) error {
+		entries, err := inspector.History(cmd.Context(), args[0], limitFlag)
+		if err != nil {
+			return err
+		}
+		return syncstate.WriteHistory(w, format, entries)
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to show sync history")
+		os.Exit(-1)
+	}
+}
+
+// run parses the format, connects to the database and hands report the writer selected by --output-file.
+func run(
+	cmd *cobra.Command,
+	report func(inspector *syncstate.Inspector, format syncstate.Format, w io.Writer) error,
+) (err error) {
+	format, err := syncstate.ParseFormat(formatFlag)
+	if err != nil {
+		return err
+	}
+
+	appConfig, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	dbClient, err := core.NewWiring(appConfig).InitSyncedSecretRepository()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, dbClient.Close())
+	}()
+
+	var w io.Writer = cmd.OutOrStdout()
+	if outputFileFlag != "" {
+		file, createErr := os.Create(outputFileFlag)
+		if createErr != nil {
+			return fmt.Errorf("failed to create output file: %w", createErr)
+		}
+		defer func() {
+			err = errors.Join(err, file.Close())
+		}()
+		w = file
+	}
+
+	return report(syncstate.NewInspector(dbClient), format, w)
+}
@@ -22,17 +22,326 @@ type Config struct {
 	LogLevel    string   `mapstructure:"log_level"   validate:"required,oneof=trace debug info warn error fatal panic"`
 	Postgres    Postgres `mapstructure:"postgres"    validate:"required"`
 	Vault       Vault    `mapstructure:"vault"       validate:"required"`
+
+	ReplicaPipeline ReplicaPipeline `mapstructure:"replica_pipeline"`
+	ChangeDetection ChangeDetection `mapstructure:"change_detection"`
+	History         History         `mapstructure:"history"`
+	Retry           Retry           `mapstructure:"retry"`
+	Budget          Budget          `mapstructure:"budget"`
+	Metrics         Metrics         `mapstructure:"metrics"`
+	Health          Health          `mapstructure:"health"`
+	API             API             `mapstructure:"api"`
+	Webhook         Webhook         `mapstructure:"webhook"`
+	Events          Events          `mapstructure:"events"`
+	SyncRequests    SyncRequests    `mapstructure:"sync_requests"`
+	WorkQueue       WorkQueue       `mapstructure:"work_queue"`
+	Tracing         Tracing         `mapstructure:"tracing"`
+}
+
+// Tracing configures the OpenTelemetry traces of runs, sync jobs, Vault operations and database queries.
+// Spans carry the cluster, mount and path of an operation, never secret values.
+//
+//nolint:golines
+type Tracing struct {
+	// Exporter is where spans are sent: none, otlp (OTLP over HTTP) or stdout. Defaults to none.
+	Exporter string `mapstructure:"exporter" validate:"omitempty,oneof=none otlp stdout"`
+	// Endpoint is the URL of the OTLP collector, e.g. http://localhost:4318. Defaults to the
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable, or https://localhost:4318.
+	Endpoint string `mapstructure:"endpoint" validate:"omitempty,url"`
+	// Headers are sent with every export, e.g. for the authentication of a hosted collector.
+	Headers map[string]string `mapstructure:"headers"`
+	// SampleRatio is the share of traces that are recorded, between 0 and 1. Defaults to 1.
+	SampleRatio float64 `mapstructure:"sample_ratio" validate:"omitempty,gte=0,lte=1"`
+}
+
+// GetSampleRatio returns the sample ratio, or the default when unset.
+func (t *Tracing) GetSampleRatio() float64 {
+	if t.SampleRatio == 0 {
+		return 1
+	}
+	return t.SampleRatio
+}
+
+// Metrics configures the Prometheus metrics. The daemon serves them on /metrics, a single run can write them to
+// a textfile for the node_exporter textfile collector.
+//
+//nolint:golines
+type Metrics struct {
+	// ListenAddress is the address the daemon serves /metrics, /healthz, /readyz and /webhook on. Defaults to :9102.
+	ListenAddress string `mapstructure:"listen_address" validate:"omitempty,hostname_port"`
+	// Textfile is the file `sync once` writes the metrics to after the run. Nothing is written when unset.
+	Textfile string `mapstructure:"textfile" validate:"omitempty,filepath"`
+}
+
+// GetListenAddress returns the metrics listen address, or the default when unset.
+func (m *Metrics) GetListenAddress() string {
+	if m.ListenAddress == "" {
+		return ":9102"
+	}
+	return m.ListenAddress
+}
+
+// Health configures the health endpoints of the daemon.
+//
+//nolint:golines
+type Health struct {
+	// StallTimeout is how long a run can take before /healthz reports the daemon as stalled. Defaults to
+	// budget.max_run_duration plus a margin, or to no stall timeout when the run duration is not limited either.
+	StallTimeout time.Duration `mapstructure:"stall_timeout" validate:"omitempty,gte=0"`
+}
+
+// stallTimeoutMargin is how much longer than budget.max_run_duration a run can take by default before it is
+// reported as stalled, which leaves an aborted run the time to record its result.
+const stallTimeoutMargin = 15 * time.Minute
+
+// GetStallTimeout returns the stall timeout. When unset, it is maxRunDuration plus a margin, as a run is aborted
+// once it exceeds maxRunDuration, or 0, which disables it, when maxRunDuration is not set.
+func (h *Health) GetStallTimeout(maxRunDuration time.Duration) time.Duration {
+	if h.StallTimeout > 0 {
+		return h.StallTimeout
+	}
+	if maxRunDuration > 0 {
+		return maxRunDuration + stallTimeoutMargin
+	}
+	return 0
+}
+
+// API configures the control API of the daemon. Callers authenticate with the bearer token, with a client
+// certificate signed by the client CA, or with both when both are set.
+//
+//nolint:golines
+type API struct {
+	// Enabled serves the control API in daemon mode.
+	Enabled bool `mapstructure:"enabled"`
+	// ListenAddress is the address the control API is served on. Defaults to :9103.
+	ListenAddress string `mapstructure:"listen_address" validate:"omitempty,hostname_port"`
+	// Token is the bearer token callers have to send.
+	Token string `mapstructure:"token"`
+	// TLS serves the API over HTTPS, and requires client certificates when a client CA is set.
+	TLS APITLS `mapstructure:"tls"`
+}
+
+// APITLS configures HTTPS and mutual TLS for the control API.
+//
+//nolint:golines
+type APITLS struct {
+	CertFile string `mapstructure:"cert_file" validate:"required_with=KeyFile ClientCAFile,omitempty,filepath"`
+	KeyFile  string `mapstructure:"key_file" validate:"required_with=CertFile,omitempty,filepath"`
+	// ClientCAFile is the CA bundle client certificates are verified against.
+	ClientCAFile string `mapstructure:"client_ca_file" validate:"omitempty,filepath"`
+}
+
+// GetListenAddress returns the API listen address, or the default when unset.
+func (a *API) GetListenAddress() string {
+	if a.ListenAddress == "" {
+		return ":9103"
+	}
+	return a.ListenAddress
+}
+
+// Webhook configures the webhook that syncs the paths named in a request right away in daemon mode. Requests
+// have to be signed with the secret.
+//
+//nolint:golines
+type Webhook struct {
+	// Enabled serves POST /webhook on the metrics listen address.
+	Enabled bool `mapstructure:"enabled"`
+	// Secret is the key of the HMAC-SHA256 signature of every request.
+	Secret string `mapstructure:"secret" validate:"required_if=Enabled true"`
+	// Debounce is how long the paths of requests and of events are collected before they are synced together.
+	// Defaults to 2s.
+	Debounce time.Duration `mapstructure:"debounce" validate:"omitempty,gte=0"`
+}
+
+// Events configures the subscription to the event notifications of the main cluster, available from Vault 1.13.
+// In daemon mode, the secrets named by the events are synced right away, debounced like webhook requests.
+//
+//nolint:golines
+type Events struct {
+	// Enabled subscribes to the events in daemon mode.
+	Enabled bool `mapstructure:"enabled"`
+	// EventType is the event type subscribed to. Defaults to kv-v2/*, every change of a KV v2 secret.
+	EventType string `mapstructure:"event_type"`
+}
+
+// GetEventType returns the event type, or the default when unset.
+func (e *Events) GetEventType() string {
+	if e.EventType == "" {
+		return "kv-v2/*"
+	}
+	return e.EventType
+}
+
+// SyncRequests configures the processing of the sync_requests table in daemon mode. External systems insert a
+// row per secret to sync; the daemon is woken up by a notification, syncs the secrets and writes the outcome back.
+//
+//nolint:golines
+type SyncRequests struct {
+	// Enabled processes the sync requests in daemon mode.
+	Enabled bool `mapstructure:"enabled"`
+	// PollInterval is how often the table is checked besides on notification, to pick up requests inserted while
+	// the daemon was not listening. Defaults to 1m.
+	PollInterval time.Duration `mapstructure:"poll_interval" validate:"omitempty,gte=0"`
+	// ClaimTimeout is how long a request can stay claimed before another daemon claims it again, e.g. after the
+	// daemon that claimed it died. Defaults to 1h.
+	ClaimTimeout time.Duration `mapstructure:"claim_timeout" validate:"omitempty,gte=0"`
+}
+
+// GetPollInterval returns the poll interval, or the default when unset.
+func (s *SyncRequests) GetPollInterval() time.Duration {
+	if s.PollInterval == 0 {
+		return time.Minute
+	}
+	return s.PollInterval
+}
+
+// GetClaimTimeout returns the claim timeout, or the default when unset.
+func (s *SyncRequests) GetClaimTimeout() time.Duration {
+	if s.ClaimTimeout == 0 {
+		return time.Hour
+	}
+	return s.ClaimTimeout
+}
+
+// Work queue roles.
+const (
+	WorkQueueCoordinator = "coordinator"
+	WorkQueueWorker      = "worker"
+)
+
+// WorkQueue configures the scale-out of the sync jobs over several instances sharing the sync_job_queue table.
+// The coordinator discovers the paths and enqueues their jobs; workers lease the jobs, run them and write the
+// outcome back. Without a role, the instance runs its jobs itself.
+//
+//nolint:golines
+type WorkQueue struct {
+	// Role is coordinator or worker. Unset disables the work queue.
+	Role string `mapstructure:"role" validate:"omitempty,oneof=coordinator worker"`
+	// BatchSize is how many jobs a worker leases, and runs in one run, at a time. Defaults to 100.
+	BatchSize int `mapstructure:"batch_size" validate:"omitempty,gte=0"`
+	// LeaseDuration is how long a job stays leased without an extension before another worker leases it again,
+	// e.g. after the worker that leased it died. Defaults to 5m.
+	LeaseDuration time.Duration `mapstructure:"lease_duration" validate:"omitempty,gte=0"`
+	// MaxAttempts is how many times a job is leased before it is failed instead of leased again, so a job that
+	// kills every worker running it does not go round forever. Defaults to 5.
+	MaxAttempts int `mapstructure:"max_attempts" validate:"omitempty,gte=0"`
+	// PollInterval is how often a worker looks for jobs while the queue is empty. Defaults to 5s.
+	PollInterval time.Duration `mapstructure:"poll_interval" validate:"omitempty,gte=0"`
+}
+
+// GetBatchSize returns the batch size, or the default when unset.
+func (w *WorkQueue) GetBatchSize() int {
+	if w.BatchSize == 0 {
+		return 100
+	}
+	return w.BatchSize
+}
+
+// GetLeaseDuration returns the lease duration, or the default when unset.
+func (w *WorkQueue) GetLeaseDuration() time.Duration {
+	if w.LeaseDuration == 0 {
+		return 5 * time.Minute
+	}
+	return w.LeaseDuration
+}
+
+// GetMaxAttempts returns the maximum number of attempts of a job, or the default when unset.
+func (w *WorkQueue) GetMaxAttempts() int {
+	if w.MaxAttempts == 0 {
+		return 5
+	}
+	return w.MaxAttempts
+}
+
+// GetPollInterval returns the poll interval, or the default when unset.
+func (w *WorkQueue) GetPollInterval() time.Duration {
+	if w.PollInterval == 0 {
+		return 5 * time.Second
+	}
+	return w.PollInterval
+}
+
+// Retry configures the retry queue of failed replicas. A failed replica is retried after a backoff that doubles
+// with every failure in a row, and quarantined after too many permanent failures until retried by hand.
+//
+//nolint:golines
+type Retry struct {
+	// InitialBackoff is the wait before the first retry. Defaults to 1m.
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" validate:"omitempty,gte=0"`
+	// MaxBackoff caps the wait between retries. Defaults to 1h.
+	MaxBackoff time.Duration `mapstructure:"max_backoff" validate:"omitempty,gte=0"`
+	// QuarantineAfter is the number of permanent failures in a row that quarantines a replica. Defaults to 10.
+	QuarantineAfter int `mapstructure:"quarantine_after" validate:"omitempty,gte=0"`
+	// Interval is how often the daemon retries the due replicas between full syncs. Defaults to 1m.
+	Interval time.Duration `mapstructure:"interval" validate:"omitempty,gte=0"`
+}
+
+// GetInterval returns the retry interval, or the default when unset.
+func (r *Retry) GetInterval() time.Duration {
+	if r.Interval == 0 {
+		return time.Minute
+	}
+	return r.Interval
+}
+
+// Budget bounds the jobs and runs. A run that exceeds its duration or error budget is aborted: no new job starts
+// and the paths it did not process are counted as skipped. Zero values disable a limit.
+//
+//nolint:golines
+type Budget struct {
+	// JobTimeout bounds each step of a job: reading the main cluster and every replica write or delete. A job that
+	// runs out of time fails.
+	JobTimeout time.Duration `mapstructure:"job_timeout" validate:"omitempty,gte=0"`
+	// MaxRunDuration is how long a run may take before it is aborted.
+	MaxRunDuration time.Duration `mapstructure:"max_run_duration" validate:"omitempty,gte=0"`
+	// MaxFailedJobs is the number of failed jobs that aborts a run.
+	MaxFailedJobs int `mapstructure:"max_failed_jobs" validate:"omitempty,gte=0"`
+	// MaxFailedPercent is the share of failed jobs, in percent of the finished jobs, that aborts a run once 100 jobs
+	// finished.
+	MaxFailedPercent float64 `mapstructure:"max_failed_percent" validate:"omitempty,gt=0,lte=100"`
+}
+
+// History configures the run and event records kept in the sync_runs and sync_events tables.
+//
+//nolint:golines
+type History struct {
+	// Retention is how long runs and their events are kept. Defaults to 720h (30 days).
+	Retention time.Duration `mapstructure:"retention" validate:"omitempty,gte=0"`
+}
+
+// ChangeDetection configures incremental runs. Unchanged secrets are detected from the source version and
+// update time stored per path, and only changed paths get the full replica checks.
+//
+//nolint:golines
+type ChangeDetection struct {
+	// Incremental enables incremental runs between full reconciliations.
+	Incremental bool `mapstructure:"incremental"`
+	// FullReconciliationInterval is how often every replica is checked regardless of changes. Defaults to 24h.
+	FullReconciliationInterval time.Duration `mapstructure:"full_reconciliation_interval" validate:"omitempty,gte=0"`
+}
+
+// ReplicaPipeline configures the per-replica queues that replica writes and deletes go through.
+// Zero values fall back to the job concurrency for workers and four times that for the queue size.
+//
+//nolint:golines
+type ReplicaPipeline struct {
+	// WorkersPerReplica is the number of workers draining the queue of each replica cluster.
+	WorkersPerReplica int `mapstructure:"workers_per_replica" validate:"omitempty,gt=0,lt=101"`
+	// QueueSize is the number of tasks a replica can fall behind before dispatching new jobs blocks.
+	QueueSize int `mapstructure:"queue_size" validate:"omitempty,gt=0"`
 }
 
 type Postgres struct {
-	Address         string `mapstructure:"address"            validate:"required,hostname|ip"`
-	Port            int    `mapstructure:"port"               validate:"required,gt=0,lt=65536"`
-	Username        string `mapstructure:"username"           validate:"required"`
-	Password        string `mapstructure:"password"           validate:"required"`
-	DBName          string `mapstructure:"db_name"            validate:"required"`
-	SSLMode         string `mapstructure:"ssl_mode"           validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
-	SSLRootCertFile string `mapstructure:"ssl_root_cert_file" validate:"omitempty,filepath"`
-	MaxConnections  int    `mapstructure:"max_connections"`
+	Address         string        `mapstructure:"address"            validate:"required,hostname|ip"`
+	Port            int           `mapstructure:"port"               validate:"required,gt=0,lt=65536"`
+	Username        string        `mapstructure:"username"           validate:"required"`
+	Password        string        `mapstructure:"password"           validate:"required"`
+	DBName          string        `mapstructure:"db_name"            validate:"required"`
+	SSLMode         string        `mapstructure:"ssl_mode"           validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	SSLRootCertFile string        `mapstructure:"ssl_root_cert_file" validate:"omitempty,filepath"`
+	MaxConnections  int           `mapstructure:"max_connections"`
+	WriteBatchSize  int           `mapstructure:"write_batch_size"   validate:"omitempty,gt=0,lte=1000"`
+	QueryTimeout    time.Duration `mapstructure:"query_timeout"      validate:"omitempty,gte=0"`
 }
 
 type Vault struct {
@@ -49,11 +358,49 @@ type VaultClusterConfig struct {
 	TLSSkipVerify bool   `mapstructure:"tls_skip_verify" validate:"boolean"`
 	TLSCertFile   string `mapstructure:"tls_cert_file"   validate:"omitempty,filepath"`
 
-	// TODO the following is not used in the application and set to default vaules by vault client
-	// This is added for testing
-	RetryMax     int // TODO `mapstructure:"retry_max"       validate:"omitempty,gt=0"`
-	RetryWaitMin int // TODO `mapstructure:"retry_wait_min"  validate:"omitempty,gt=0"`
-	RetryWaitMax int // TODO `mapstructure:"retry_wait_max"  validate:"omitempty,gt=-0"`
+	// RetryMax is the number of retries for retryable errors (5xx, 429, network errors).
+	// Zero falls back to the default and a negative value disables retries.
+	RetryMax     int           `mapstructure:"retry_max"       validate:"omitempty,gte=-1"`
+	RetryWaitMin time.Duration `mapstructure:"retry_wait_min"  validate:"omitempty,gte=0"`
+	RetryWaitMax time.Duration `mapstructure:"retry_wait_max"  validate:"omitempty,gtefield=RetryWaitMin"`
+
+	CircuitBreaker CircuitBreaker `mapstructure:"circuit_breaker"`
+	RateLimit      RateLimit      `mapstructure:"rate_limit"`
+
+	// ListConcurrency is the number of folders listed in parallel when discovering keys under a mount.
+	ListConcurrency int `mapstructure:"list_concurrency" validate:"omitempty,gt=0,lt=101"`
+}
+
+// CircuitBreaker configures the circuit breaker wrapping all requests to a single Vault cluster.
+// Zero values fall back to the defaults of the vault package.
+//
+//nolint:golines
+type CircuitBreaker struct {
+	// FailureRatio is the ratio of failed requests that trips the breaker.
+	FailureRatio float64 `mapstructure:"failure_ratio" validate:"omitempty,gt=0,lte=1"`
+	// MinRequests is the minimum number of requests in an interval before the breaker can trip.
+	MinRequests uint32 `mapstructure:"min_requests"`
+	// MaxRequests is the number of requests allowed through while the breaker is half-open.
+	MaxRequests uint32 `mapstructure:"max_requests"`
+	// Interval is the period after which failure counts are cleared while the breaker is closed.
+	Interval time.Duration `mapstructure:"interval" validate:"omitempty,gte=0"`
+	// Timeout is how long the breaker stays open before moving to half-open.
+	Timeout time.Duration `mapstructure:"timeout" validate:"omitempty,gte=0"`
+}
+
+// RateLimit throttles the requests sent to a single Vault cluster. Every request counts, including logins,
+// token lookups, listings and the existence checks on replicas, and every attempt of a retried request.
+// Zero values fall back to the defaults of the vault package.
+//
+//nolint:golines
+type RateLimit struct {
+	// RequestsPerSecond is the token bucket refill rate. Zero disables the rate limit.
+	RequestsPerSecond float64 `mapstructure:"requests_per_second" validate:"omitempty,gt=0"`
+	// Burst is the token bucket size. Defaults to one second worth of requests.
+	Burst int `mapstructure:"burst" validate:"omitempty,gt=0"`
+	// MaxConcurrency is the upper bound of in-flight requests. The effective limit backs off
+	// on 429/503 responses and recovers gradually once requests succeed again.
+	MaxConcurrency int `mapstructure:"max_concurrency" validate:"omitempty,gt=0"`
 }
 
 type SyncRule struct {
@@ -61,6 +408,8 @@ type SyncRule struct {
 	KvMounts         []string `mapstructure:"kv_mounts"          validate:"required,min=1,unique"`
 	PathsToReplicate []string `mapstructure:"paths_to_replicate" validate:"omitempty,min=0,unique"`
 	PathsToIgnore    []string `mapstructure:"paths_to_ignore"    validate:"omitempty,unique,min=0"`
+	// Strict aborts a run, before any secret is deleted from the replicas, when a mount cannot be listed.
+	Strict bool `mapstructure:"strict"`
 }
 
 func (syncRule *SyncRule) GetInterval() time.Duration {
@@ -76,6 +425,7 @@ var validate = validator.New()
 //nolint:gochecknoinits
 func init() {
 	validate.RegisterStructValidation(syncRuleValidation, SyncRule{})
+	validate.RegisterStructValidation(apiValidation, API{})
 	if err := validate.RegisterValidation("period_regex", periodRegexValidator); err != nil {
 		panic(fmt.Sprintf("failed to register period_regex validator: %v", err))
 	}
@@ -145,6 +495,18 @@ func syncRuleValidation(sl validator.StructLevel) {
 	}
 }
 
+// apiValidation rejects an enabled API that nobody could be authenticated by.
+func apiValidation(sl validator.StructLevel) {
+	api, ok := sl.Current().Interface().(API)
+	if !ok {
+		sl.ReportError(api, "API", "api", "invalid_type", "")
+		return
+	}
+	if api.Enabled && api.Token == "" && api.TLS.ClientCAFile == "" {
+		sl.ReportError(api.Token, "Token", "token", "api_auth", "")
+	}
+}
+
 func Load() (*Config, error) {
 	logger := log.Logger.With().Str("component", "config").Logger()
 
@@ -225,12 +587,26 @@ func validateConfig(cfg Config) error {
 		switch fieldError.Tag() {
 		case "required":
 			msg = fmt.Sprintf("%s is required", namespace)
+		case "required_with":
+			msg = fmt.Sprintf("%s is required when %s is set", namespace, strings.ReplaceAll(param, " ", " or "))
+		case "required_if":
+			msg = fmt.Sprintf("%s is required when %s is set", namespace, strings.Fields(param)[0])
+		case "api_auth":
+			msg = fmt.Sprintf("%s or Config.API.TLS.ClientCAFile is required when the API is enabled", namespace)
 		case "hostname|ip":
 			msg = fmt.Sprintf("%s must be a valid hostname or IP address", namespace)
 		case "url":
 			msg = fmt.Sprintf("%s must be a valid URL", namespace)
+		case "hostname_port":
+			msg = fmt.Sprintf("%s must be a valid host:port address", namespace)
 		case "gt":
 			msg = fmt.Sprintf("%s must be greater than %s", namespace, param)
+		case "gte":
+			msg = fmt.Sprintf("%s must be greater than or equal to %s", namespace, param)
+		case "lte":
+			msg = fmt.Sprintf("%s must be less than or equal to %s", namespace, param)
+		case "gtefield":
+			msg = fmt.Sprintf("%s must be greater than or equal to %s", namespace, param)
 		case "lt":
 			msg = fmt.Sprintf("%s must be less than %s", namespace, param)
 		case "unique":
@@ -71,11 +71,53 @@ func TestConfigLoadFromYAML(t *testing.T) {
 	require.Equal(t, "test", cfg.ID)
 	require.Equal(t, "info", cfg.LogLevel)
 	require.Equal(t, 5, cfg.Concurrency)
+	require.Equal(t, 3, cfg.ReplicaPipeline.WorkersPerReplica)
+	require.Equal(t, 50, cfg.ReplicaPipeline.QueueSize)
+	require.True(t, cfg.ChangeDetection.Incremental)
+	require.Equal(t, 12*time.Hour, cfg.ChangeDetection.FullReconciliationInterval)
+	require.Equal(t, 168*time.Hour, cfg.History.Retention)
+	require.Equal(t, 30*time.Second, cfg.Retry.InitialBackoff)
+	require.Equal(t, 2*time.Hour, cfg.Retry.MaxBackoff)
+	require.Equal(t, 5, cfg.Retry.QuarantineAfter)
+	require.Equal(t, 2*time.Minute, cfg.Retry.GetInterval())
+	require.Equal(t, Budget{
+		JobTimeout:       2 * time.Minute,
+		MaxRunDuration:   2 * time.Hour,
+		MaxFailedJobs:    500,
+		MaxFailedPercent: 25,
+	}, cfg.Budget)
+	require.Equal(t, "127.0.0.1:9200", cfg.Metrics.GetListenAddress())
+	require.Equal(t, "/var/lib/node_exporter/vault_sync.prom", cfg.Metrics.Textfile)
+	require.Equal(t, 3*time.Hour, cfg.Health.GetStallTimeout(cfg.Budget.MaxRunDuration))
+	require.True(t, cfg.API.Enabled)
+	require.Equal(t, "127.0.0.1:9300", cfg.API.GetListenAddress())
+	require.Equal(t, "api-token", cfg.API.Token)
+	require.Equal(t, APITLS{
+		CertFile:     "/etc/vault-sync/tls/server.crt",
+		KeyFile:      "/etc/vault-sync/tls/server.key",
+		ClientCAFile: "/etc/vault-sync/tls/clients.pem",
+	}, cfg.API.TLS)
+	require.Equal(t, Webhook{Enabled: true, Secret: "webhook-secret", Debounce: 5 * time.Second}, cfg.Webhook)
+	require.True(t, cfg.Events.Enabled)
+	require.Equal(t, "kv-v2/data-*", cfg.Events.GetEventType())
+	require.True(t, cfg.SyncRequests.Enabled)
+	require.Equal(t, 30*time.Second, cfg.SyncRequests.GetPollInterval())
+	require.Equal(t, 2*time.Hour, cfg.SyncRequests.GetClaimTimeout())
+	require.Equal(t, WorkQueueWorker, cfg.WorkQueue.Role)
+	require.Equal(t, 50, cfg.WorkQueue.GetBatchSize())
+	require.Equal(t, 10*time.Minute, cfg.WorkQueue.GetLeaseDuration())
+	require.Equal(t, 3, cfg.WorkQueue.GetMaxAttempts())
+	require.Equal(t, 2*time.Second, cfg.WorkQueue.GetPollInterval())
+	require.Equal(t, "otlp", cfg.Tracing.Exporter)
+	require.Equal(t, "http://otel-collector:4318", cfg.Tracing.Endpoint)
+	require.Equal(t, map[string]string{"x-api-key": "secret"}, cfg.Tracing.Headers)
+	require.InDelta(t, 0.25, cfg.Tracing.GetSampleRatio(), 0)
 
 	require.Equal(t, time.Duration(60*time.Second), cfg.SyncRule.GetInterval())
 	require.Equal(t, []string{"secret", "secret2"}, cfg.SyncRule.KvMounts)
 	require.ElementsMatch(t, []string{"secret/data/test", "secret/data/test2"}, cfg.SyncRule.PathsToReplicate)
 	require.ElementsMatch(t, []string{"secret/data/test3", "secret/data/test4"}, cfg.SyncRule.PathsToIgnore)
+	require.True(t, cfg.SyncRule.Strict)
 
 	// Check Postgres configuration
 	require.Equal(t, "localhost", cfg.Postgres.Address)
@@ -86,6 +128,8 @@ func TestConfigLoadFromYAML(t *testing.T) {
 	require.Equal(t, "/path/to/root.crt", cfg.Postgres.SSLRootCertFile)
 
 	require.Equal(t, 10, cfg.Postgres.MaxConnections)
+	require.Equal(t, 200, cfg.Postgres.WriteBatchSize)
+	require.Equal(t, 5*time.Second, cfg.Postgres.QueryTimeout)
 
 	// Check Vault configuration main cluster
 	require.Equal(t, "main-cluster", cfg.Vault.MainCluster.Name)
@@ -95,6 +139,18 @@ func TestConfigLoadFromYAML(t *testing.T) {
 	require.Equal(t, "my_app_role", cfg.Vault.MainCluster.AppRoleID)
 	require.Equal(t, "my_app_secret", cfg.Vault.MainCluster.AppRoleSecret)
 	require.Equal(t, "approle", cfg.Vault.MainCluster.AppRoleMount)
+	require.Equal(t, 5, cfg.Vault.MainCluster.RetryMax)
+	require.Equal(t, 500*time.Millisecond, cfg.Vault.MainCluster.RetryWaitMin)
+	require.Equal(t, 10*time.Second, cfg.Vault.MainCluster.RetryWaitMax)
+	require.InDelta(t, 0.4, cfg.Vault.MainCluster.CircuitBreaker.FailureRatio, 0.0001)
+	require.Equal(t, uint32(20), cfg.Vault.MainCluster.CircuitBreaker.MinRequests)
+	require.Equal(t, uint32(2), cfg.Vault.MainCluster.CircuitBreaker.MaxRequests)
+	require.Equal(t, 30*time.Second, cfg.Vault.MainCluster.CircuitBreaker.Interval)
+	require.Equal(t, 2*time.Minute, cfg.Vault.MainCluster.CircuitBreaker.Timeout)
+	require.InDelta(t, 50.0, cfg.Vault.MainCluster.RateLimit.RequestsPerSecond, 0.0001)
+	require.Equal(t, 100, cfg.Vault.MainCluster.RateLimit.Burst)
+	require.Equal(t, 16, cfg.Vault.MainCluster.RateLimit.MaxConcurrency)
+	require.Equal(t, 12, cfg.Vault.MainCluster.ListConcurrency)
 
 	// Check Vault configuration replica clusters
 	require.Len(t, cfg.Vault.ReplicaClusters, 2)
@@ -175,6 +231,109 @@ func TestConfigurationValidation(t *testing.T) {
 				errContains: "Config.Concurrency must be greater than 0",
 			},
 
+			{
+				name:        "invalid replica_pipeline.workers_per_replica value",
+				setFields:   updateAndReturnMap(validAppConfig, "replica_pipeline.workers_per_replica", 101),
+				errContains: "Config.ReplicaPipeline.WorkersPerReplica must be less than 101",
+			},
+
+			{
+				name:        "invalid replica_pipeline.queue_size value",
+				setFields:   updateAndReturnMap(validAppConfig, "replica_pipeline.queue_size", -1),
+				errContains: "Config.ReplicaPipeline.QueueSize must be greater than 0",
+			},
+
+			{
+				name:        "invalid change_detection.full_reconciliation_interval value",
+				setFields:   updateAndReturnMap(validAppConfig, "change_detection.full_reconciliation_interval", "-1h"),
+				errContains: "Config.ChangeDetection.FullReconciliationInterval must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid history.retention value",
+				setFields:   updateAndReturnMap(validAppConfig, "history.retention", "-1h"),
+				errContains: "Config.History.Retention must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid retry.max_backoff value",
+				setFields:   updateAndReturnMap(validAppConfig, "retry.max_backoff", "-1m"),
+				errContains: "Config.Retry.MaxBackoff must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid retry.quarantine_after value",
+				setFields:   updateAndReturnMap(validAppConfig, "retry.quarantine_after", -1),
+				errContains: "Config.Retry.QuarantineAfter must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid metrics.listen_address value",
+				setFields:   updateAndReturnMap(validAppConfig, "metrics.listen_address", "localhost"),
+				errContains: "Config.Metrics.ListenAddress must be a valid host:port address",
+			},
+			{
+				name:        "invalid health.stall_timeout value",
+				setFields:   updateAndReturnMap(validAppConfig, "health.stall_timeout", "-1m"),
+				errContains: "Config.Health.StallTimeout must be greater than or equal to 0",
+			},
+			{
+				name:        "api enabled without authentication",
+				setFields:   updateAndReturnMap(validAppConfig, "api.enabled", true),
+				errContains: "Config.API.Token or Config.API.TLS.ClientCAFile is required when the API is enabled",
+			},
+			{
+				name:        "api tls key without certificate",
+				setFields:   updateAndReturnMap(validAppConfig, "api.tls.key_file", "/tmp/server.key"),
+				errContains: "Config.API.TLS.CertFile is required when KeyFile or ClientCAFile is set",
+			},
+			{
+				name:        "webhook enabled without secret",
+				setFields:   updateAndReturnMap(validAppConfig, "webhook.enabled", true),
+				errContains: "Config.Webhook.Secret is required when Enabled is set",
+			},
+			{
+				name:        "invalid webhook.debounce value",
+				setFields:   updateAndReturnMap(validAppConfig, "webhook.debounce", "-1s"),
+				errContains: "Config.Webhook.Debounce must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid sync_requests.poll_interval value",
+				setFields:   updateAndReturnMap(validAppConfig, "sync_requests.poll_interval", "-1s"),
+				errContains: "Config.SyncRequests.PollInterval must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid budget.job_timeout value",
+				setFields:   updateAndReturnMap(validAppConfig, "budget.job_timeout", "-1s"),
+				errContains: "Config.Budget.JobTimeout must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid budget.max_failed_percent value",
+				setFields:   updateAndReturnMap(validAppConfig, "budget.max_failed_percent", 150),
+				errContains: "Config.Budget.MaxFailedPercent must be less than or equal to 100",
+			},
+			{
+				name:        "invalid work_queue.role value",
+				setFields:   updateAndReturnMap(validAppConfig, "work_queue.role", "leader"),
+				errContains: "Config.WorkQueue.Role must be one of [coordinator worker]",
+			},
+			{
+				name:        "invalid work_queue.lease_duration value",
+				setFields:   updateAndReturnMap(validAppConfig, "work_queue.lease_duration", "-1s"),
+				errContains: "Config.WorkQueue.LeaseDuration must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid work_queue.max_attempts value",
+				setFields:   updateAndReturnMap(validAppConfig, "work_queue.max_attempts", -1),
+				errContains: "Config.WorkQueue.MaxAttempts must be greater than or equal to 0",
+			},
+			{
+				name:        "invalid tracing.exporter value",
+				setFields:   updateAndReturnMap(validAppConfig, "tracing.exporter", "jaeger"),
+				errContains: "Config.Tracing.Exporter must be one of [none otlp stdout]",
+			},
+			{
+				name:        "invalid tracing.sample_ratio value",
+				setFields:   updateAndReturnMap(validAppConfig, "tracing.sample_ratio", 1.5),
+				errContains: "Config.Tracing.SampleRatio must be less than or equal to 1",
+			},
+
 			// sync rule level
 			{
 				name:        "missing interval",
@@ -283,6 +442,21 @@ func TestConfigurationValidation(t *testing.T) {
 				setFields:   updateAndReturnMap(validAppConfig, "postgres.port", "a"),
 				errContains: "'postgres.port' cannot parse value as 'int'",
 			},
+			{
+				name:        "invalid postgres.write_batch_size greater than 1000",
+				setFields:   updateAndReturnMap(validAppConfig, "postgres.write_batch_size", 5000),
+				errContains: "Config.Postgres.WriteBatchSize must be less than or equal to 1000",
+			},
+			{
+				name:        "invalid postgres.write_batch_size less than 0",
+				setFields:   updateAndReturnMap(validAppConfig, "postgres.write_batch_size", -1),
+				errContains: "Config.Postgres.WriteBatchSize must be greater than 0",
+			},
+			{
+				name:        "invalid postgres.query_timeout value",
+				setFields:   updateAndReturnMap(validAppConfig, "postgres.query_timeout", "-1s"),
+				errContains: "Config.Postgres.QueryTimeout must be greater than or equal to 0",
+			},
 			{
 				name:        "missing postgres.username",
 				setFields:   deleteFromMap(validAppConfig, "postgres.username"),
@@ -349,6 +523,40 @@ func TestConfigurationValidation(t *testing.T) {
 				),
 				errContains: "Config.Vault.MainCluster.TLSCertFile must be a valid file path",
 			},
+			{
+				name:        "invalid vault.main_cluster.retry_max",
+				setFields:   updateAndReturnMap(validAppConfig, "vault.main_cluster.retry_max", -2),
+				errContains: "Config.Vault.MainCluster.RetryMax must be greater than or equal to -1",
+			},
+			{
+				name: "vault.main_cluster.retry_wait_max less than retry_wait_min",
+				setFields: updateAndReturnMap(
+					updateAndReturnMap(validAppConfig, "vault.main_cluster.retry_wait_min", "2s"),
+					"vault.main_cluster.retry_wait_max",
+					"1s",
+				),
+				errContains: "Config.Vault.MainCluster.RetryWaitMax must be greater than or equal to RetryWaitMin",
+			},
+			{
+				name:        "invalid vault.main_cluster.circuit_breaker.failure_ratio",
+				setFields:   updateAndReturnMap(validAppConfig, "vault.main_cluster.circuit_breaker.failure_ratio", 1.5),
+				errContains: "Config.Vault.MainCluster.CircuitBreaker.FailureRatio must be less than or equal to 1",
+			},
+			{
+				name:        "invalid vault.main_cluster.rate_limit.requests_per_second",
+				setFields:   updateAndReturnMap(validAppConfig, "vault.main_cluster.rate_limit.requests_per_second", -1),
+				errContains: "Config.Vault.MainCluster.RateLimit.RequestsPerSecond must be greater than 0",
+			},
+			{
+				name:        "invalid vault.main_cluster.rate_limit.max_concurrency",
+				setFields:   updateAndReturnMap(validAppConfig, "vault.main_cluster.rate_limit.max_concurrency", -1),
+				errContains: "Config.Vault.MainCluster.RateLimit.MaxConcurrency must be greater than 0",
+			},
+			{
+				name:        "invalid vault.main_cluster.list_concurrency",
+				setFields:   updateAndReturnMap(validAppConfig, "vault.main_cluster.list_concurrency", 101),
+				errContains: "Config.Vault.MainCluster.ListConcurrency must be less than 101",
+			},
 			{
 				name:        "replica_clusters must not be empty",
 				setFields:   updateAndReturnMap(validAppConfig, "vault.replica_clusters", []configFields{}),
@@ -470,7 +678,18 @@ func TestConfigurationValidation(t *testing.T) {
 			cfg.Vault.ReplicaClusters[0].TLSSkipVerify,
 			"Default value for vault.replica_clusters[0].tls_skip_verify should be 'false'",
 		)
+		assert.Zero(
+			t,
+			cfg.Health.GetStallTimeout(cfg.Budget.MaxRunDuration),
+			"health.stall_timeout should be disabled when budget.max_run_duration is not set",
+		)
+
+	})
+
+	t.Run("It derives the stall timeout from the maximum run duration", func(t *testing.T) {
+		health := Health{}
 
+		assert.Equal(t, 2*time.Hour+15*time.Minute, health.GetStallTimeout(2*time.Hour))
 	})
 }
 
@@ -2,13 +2,15 @@ package core
 
 import (
 	"context"
-	"os"
+	"fmt"
 	"sync"
+	"time"
 	"vault-sync/internal/config"
-	repo "vault-sync/internal/repository"
 	psqlRepo "vault-sync/internal/repository/postgres"
+	"vault-sync/internal/service/job"
 	"vault-sync/internal/service/orchestrator"
 	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/tracing"
 	"vault-sync/internal/vault"
 	"vault-sync/pkg/db"
 	"vault-sync/pkg/db/migrations"
@@ -17,9 +19,22 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// tracingShutdownTimeout bounds the export of the last spans when the process exits.
+const tracingShutdownTimeout = 10 * time.Second
+
+// Wiring builds the components of a command from the config. The Vault client and the repository are built
+// once, so every component shares their circuit breakers and the health checks report the breakers in use.
+// The Init functions return the error of a component that cannot be built and leave it to the command to exit.
 type Wiring struct {
 	config *config.Config
 	logger zerolog.Logger
+
+	vaultClientOnce sync.Once
+	vaultClient     *vault.MultiClusterVaultClient
+	vaultClientErr  error
+	repositoryOnce  sync.Once
+	repository      *psqlRepo.SyncedSecretRepository
+	repositoryErr   error
 }
 
 func NewWiring(cfg *config.Config) *Wiring {
@@ -34,62 +49,130 @@ func NewWiring(cfg *config.Config) *Wiring {
 	return instance
 }
 
-func (w *Wiring) InitPostgresDataStore() *db.PostgresDatastore {
+func (w *Wiring) InitPostgresDataStore() (*db.PostgresDatastore, error) {
 	var instance *db.PostgresDatastore
+	var err error
 	var once sync.Once
 	once.Do(func() {
-		var err error
 		instance, err = db.NewPostgresDatastore(&w.config.Postgres, migrations.NewPostgresMigration())
 		if err != nil {
 			w.logger.Error().Err(err).Msg("Failed to create Postgres datastore")
-			os.Exit(-1)
+			err = fmt.Errorf("failed to create Postgres datastore: %w", err)
 		}
 	})
-	return instance
+	return instance, err
 }
 
 func (w *Wiring) GetConfig() *config.Config {
 	return w.config
 }
 
-func (w *Wiring) InitSyncedSecretRepository() repo.SyncedSecretRepository {
-	return psqlRepo.NewSyncedSecretRepository(w.InitPostgresDataStore())
+func (w *Wiring) InitSyncedSecretRepository() (*psqlRepo.SyncedSecretRepository, error) {
+	w.repositoryOnce.Do(func() {
+		datastore, err := w.InitPostgresDataStore()
+		if err != nil {
+			w.repositoryErr = err
+			return
+		}
+		w.repository = psqlRepo.NewSyncedSecretRepository(
+			datastore,
+			psqlRepo.WithQueryTimeout(w.config.Postgres.QueryTimeout),
+		)
+	})
+	return w.repository, w.repositoryErr
 }
 
-func (w *Wiring) InitVaultClient(ctx context.Context) vault.Syncer {
+func (w *Wiring) InitVaultClient(ctx context.Context) (*vault.MultiClusterVaultClient, error) {
 	configAsPointers := make([]*config.VaultClusterConfig, len(w.config.Vault.ReplicaClusters))
 	for i := range w.config.Vault.ReplicaClusters {
 		configAsPointers[i] = &w.config.Vault.ReplicaClusters[i]
 	}
 
-	var instance vault.Syncer
-	var once sync.Once
-	once.Do(func() {
+	w.vaultClientOnce.Do(func() {
 		var err error
-		instance, err = vault.NewMultiClusterVaultClient(ctx, &w.config.Vault.MainCluster, configAsPointers)
+		w.vaultClient, err = vault.NewMultiClusterVaultClient(ctx, &w.config.Vault.MainCluster, configAsPointers)
 		if err != nil {
 			w.logger.Error().Err(err).Msg("Failed to create Vault client")
-			os.Exit(-1)
+			w.vaultClientErr = fmt.Errorf("failed to create Vault client: %w", err)
 		}
 	})
 
-	return instance
+	return w.vaultClient, w.vaultClientErr
 }
 
-func (w *Wiring) InitPathMatcher() *pathmatching.VaultPathMatcher {
-	vaultClient := w.InitVaultClient(context.Background())
-	return pathmatching.NewVaultPathMatcher(vaultClient, &w.config.SyncRule)
+// InitTracing installs the tracer provider of the tracing config. The returned function exports the pending spans
+// and has to be called before the process exits.
+func (w *Wiring) InitTracing(ctx context.Context) (func(), error) {
+	shutdown, err := tracing.Setup(ctx, &w.config.Tracing, w.config.ID)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("Failed to set up tracing")
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tracingShutdownTimeout)
+		defer cancel()
+		if shutdownErr := shutdown(shutdownCtx); shutdownErr != nil {
+			w.logger.Warn().Err(shutdownErr).Msg("Failed to export the last spans")
+		}
+	}, nil
 }
 
-func (w *Wiring) InitOrchestrator(ctx context.Context) *orchestrator.SyncOrchestrator {
-	vaultClient := w.InitVaultClient(ctx)
-	dbClient := w.InitSyncedSecretRepository()
-	pathMatcher := w.InitPathMatcher()
-
-	return orchestrator.NewSyncOrchestrator(
-		vaultClient,
-		dbClient,
-		pathMatcher,
-		w.config.Concurrency,
-	)
+func (w *Wiring) InitPathMatcher() (*pathmatching.VaultPathMatcher, error) {
+	vaultClient, err := w.InitVaultClient(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return pathmatching.NewVaultPathMatcher(vaultClient, &w.config.SyncRule), nil
+}
+
+func (w *Wiring) InitOrchestrator(
+	ctx context.Context,
+	extraOpts ...orchestrator.Option,
+) (*orchestrator.SyncOrchestrator, error) {
+	vaultClient, err := w.InitVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dbClient, err := w.InitSyncedSecretRepository()
+	if err != nil {
+		return nil, err
+	}
+	pathMatcher, err := w.InitPathMatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []orchestrator.Option{
+		orchestrator.WithReplicaPipeline(
+			w.config.ReplicaPipeline.WorkersPerReplica,
+			w.config.ReplicaPipeline.QueueSize,
+		),
+		orchestrator.WithStrictDiscovery(w.config.SyncRule.Strict),
+		orchestrator.WithStatusBatchSize(w.config.Postgres.WriteBatchSize),
+		orchestrator.WithSyncHistory(w.config.ID, w.config.History.Retention),
+		orchestrator.WithCheckpoints(dbClient, w.config.ID),
+		orchestrator.WithRetryPolicy(job.NewRetryPolicy(
+			w.config.Retry.InitialBackoff,
+			w.config.Retry.MaxBackoff,
+			w.config.Retry.QuarantineAfter,
+		)),
+		orchestrator.WithJobTimeout(w.config.Budget.JobTimeout),
+		orchestrator.WithRunBudget(
+			w.config.Budget.MaxRunDuration,
+			w.config.Budget.MaxFailedJobs,
+			w.config.Budget.MaxFailedPercent,
+		),
+	}
+	if w.config.ChangeDetection.Incremental {
+		opts = append(opts, orchestrator.WithIncrementalSync(
+			w.config.ID,
+			w.config.ChangeDetection.FullReconciliationInterval,
+		))
+	}
+	if w.config.WorkQueue.Role == config.WorkQueueCoordinator {
+		opts = append(opts, orchestrator.WithWorkQueue(dbClient, w.config.ID))
+	}
+	opts = append(opts, extraOpts...)
+
+	return orchestrator.NewSyncOrchestrator(vaultClient, dbClient, pathMatcher, w.config.Concurrency, opts...), nil
 }
@@ -0,0 +1,188 @@
+// Package metrics holds the Prometheus metrics of vault-sync. They are served on /metrics in daemon mode and
+// written to a node-exporter textfile after one-time runs.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sony/gobreaker"
+)
+
+const namespace = "vault_sync"
+
+// Registry holds the metrics of vault-sync. It does not include the Go runtime and process metrics, which are only
+// served on /metrics, so a textfile written by a short-lived run does not report them.
+//
+//nolint:gochecknoglobals
+var Registry = prometheus.NewRegistry()
+
+//nolint:gochecknoglobals
+var (
+	runs = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_total",
+		Help:      "Sync runs by kind (full, incremental, retry, paths) and status (completed, interrupted, failed).",
+	}, []string{"kind", "status"})
+
+	runDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of sync runs by kind.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14), //nolint:mnd
+	}, []string{"kind"})
+
+	lastRun = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_run_timestamp_seconds",
+		Help:      "Unix time the last sync run of a kind and status finished.",
+	}, []string{"kind", "status"})
+
+	replicaOutcomes = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "replica_outcomes_total",
+		Help:      "Outcomes of sync jobs per replica cluster, e.g. updated, deleted, unmodified, failed or error_deleting.",
+	}, []string{"cluster", "outcome"})
+
+	jobDuration = promauto.With(Registry).NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of sync jobs, from dispatch until every replica finished.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	vaultRequestDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "vault_request_duration_seconds",
+		Help:      "Latency of Vault requests by cluster and operation; every retry is a request.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"cluster", "operation"})
+
+	vaultRequestErrors = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "vault_request_errors_total",
+		Help:      "Failed Vault requests by cluster, operation and error category.",
+	}, []string{"cluster", "operation", "category"})
+
+	circuitBreakerState = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "State of a circuit breaker: 0 closed, 1 half-open, 2 open.",
+	}, []string{"circuit_breaker"})
+
+	discoveredSecrets = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "discovered_secrets",
+		Help:      "Secrets discovered in the main cluster by the last run, per mount.",
+	}, []string{"mount"})
+
+	eventSubscription = promauto.With(Registry).NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_subscription_connected",
+		Help:      "1 while the daemon is subscribed to the event notifications of the main cluster, 0 otherwise.",
+	})
+
+	secretEvents = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secret_events_total",
+		Help:      "Secret change events received from the main cluster by event type.",
+	}, []string{"event_type"})
+
+	syncRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_requests_total",
+		Help:      "Processed sync requests of the sync_requests table by outcome.",
+	}, []string{"status"})
+
+	queuedJobs = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "work_queue_jobs_total",
+		Help:      "Jobs of the work queue run by this worker by outcome.",
+	}, []string{"status"})
+)
+
+// ObserveRun records a finished run.
+func ObserveRun(kind, status string, duration time.Duration) {
+	runs.WithLabelValues(kind, status).Inc()
+	runDuration.WithLabelValues(kind).Observe(duration.Seconds())
+	lastRun.WithLabelValues(kind, status).SetToCurrentTime()
+}
+
+// ObserveReplicaOutcome records the outcome of a job on a replica.
+func ObserveReplicaOutcome(clusterName, outcome string) {
+	replicaOutcomes.WithLabelValues(clusterName, outcome).Inc()
+}
+
+// ObserveJob records the duration of a sync job.
+func ObserveJob(duration time.Duration) {
+	jobDuration.Observe(duration.Seconds())
+}
+
+// ObserveVaultRequest records a Vault request. category is empty for successful requests.
+func ObserveVaultRequest(clusterName, operation string, duration time.Duration, category string) {
+	vaultRequestDuration.WithLabelValues(clusterName, operation).Observe(duration.Seconds())
+	if category != "" {
+		vaultRequestErrors.WithLabelValues(clusterName, operation, category).Inc()
+	}
+}
+
+// SetCircuitBreakerState records the state of the named circuit breaker.
+func SetCircuitBreakerState(name string, state gobreaker.State) {
+	circuitBreakerState.WithLabelValues(name).Set(float64(state))
+}
+
+// SetDiscoveredSecrets replaces the discovered secret counts with those of the last run.
+func SetDiscoveredSecrets(countsByMount map[string]int) {
+	discoveredSecrets.Reset()
+	for mount, count := range countsByMount {
+		discoveredSecrets.WithLabelValues(mount).Set(float64(count))
+	}
+}
+
+// SetEventSubscriptionConnected records whether the event subscription is connected.
+func SetEventSubscriptionConnected(connected bool) {
+	if connected {
+		eventSubscription.Set(1)
+		return
+	}
+	eventSubscription.Set(0)
+}
+
+// ObserveSecretEvent records a secret change event received from the main cluster.
+func ObserveSecretEvent(eventType string) {
+	secretEvents.WithLabelValues(eventType).Inc()
+}
+
+// ObserveSyncRequest records a sync request written back with its outcome.
+func ObserveSyncRequest(status string) {
+	syncRequests.WithLabelValues(status).Inc()
+}
+
+// ObserveQueuedJob records a job of the work queue written back with its outcome.
+func ObserveQueuedJob(status string) {
+	queuedJobs.WithLabelValues(status).Inc()
+}
+
+// Handler serves the metrics of Registry together with the Go runtime and process metrics.
+func Handler() http.Handler {
+	runtimeRegistry := prometheus.NewRegistry()
+	runtimeRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return promhttp.HandlerFor(prometheus.Gatherers{Registry, runtimeRegistry}, promhttp.HandlerOpts{})
+}
+
+// WriteTextfile writes the metrics of Registry to path in the text format read by the node-exporter textfile
+// collector. The file is replaced atomically, so the collector never reads a partial file.
+func WriteTextfile(path string) error {
+	if err := prometheus.WriteToTextfile(path, Registry); err != nil {
+		return fmt.Errorf("failed to write metrics textfile: %w", err)
+	}
+	return nil
+}
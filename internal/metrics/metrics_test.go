@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveRun(t *testing.T) {
+	ObserveRun("full", "completed", 3*time.Second)
+	ObserveRun("full", "completed", time.Second)
+
+	assert.InDelta(t, 2, testutil.ToFloat64(runs.WithLabelValues("full", "completed")), 0)
+	assert.Positive(t, testutil.ToFloat64(lastRun.WithLabelValues("full", "completed")))
+}
+
+func TestObserveVaultRequest(t *testing.T) {
+	ObserveVaultRequest("replica-a", "read", 10*time.Millisecond, "")
+	ObserveVaultRequest("replica-a", "read", 10*time.Millisecond, "unavailable")
+
+	assert.Equal(t, 1, testutil.CollectAndCount(vaultRequestDuration))
+	assert.InDelta(t, 1, testutil.ToFloat64(vaultRequestErrors.WithLabelValues("replica-a", "read", "unavailable")), 0)
+}
+
+func TestSetCircuitBreakerState(t *testing.T) {
+	SetCircuitBreakerState("vault-replica-a", gobreaker.StateOpen)
+	assert.InDelta(t, 2, testutil.ToFloat64(circuitBreakerState.WithLabelValues("vault-replica-a")), 0)
+
+	SetCircuitBreakerState("vault-replica-a", gobreaker.StateHalfOpen)
+	assert.InDelta(t, 1, testutil.ToFloat64(circuitBreakerState.WithLabelValues("vault-replica-a")), 0)
+}
+
+func TestSetDiscoveredSecrets(t *testing.T) {
+	SetDiscoveredSecrets(map[string]int{"kv": 3, "secret": 5})
+	SetDiscoveredSecrets(map[string]int{"kv": 4})
+
+	expected := `
+# HELP vault_sync_discovered_secrets Secrets discovered in the main cluster by the last run, per mount.
+# TYPE vault_sync_discovered_secrets gauge
+vault_sync_discovered_secrets{mount="kv"} 4
+`
+	require.NoError(t, testutil.CollectAndCompare(discoveredSecrets, strings.NewReader(expected)))
+}
+
+func TestWriteTextfile(t *testing.T) {
+	ObserveReplicaOutcome("replica-b", "updated")
+	path := filepath.Join(t.TempDir(), "vault_sync.prom")
+
+	require.NoError(t, WriteTextfile(path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `vault_sync_replica_outcomes_total{cluster="replica-b",outcome="updated"} 1`)
+	assert.NotContains(t, string(content), "go_goroutines")
+}
+
+func TestWriteTextfile_InvalidPath(t *testing.T) {
+	err := WriteTextfile(filepath.Join(t.TempDir(), "missing", "vault_sync.prom"))
+
+	require.ErrorContains(t, err, "failed to write metrics textfile")
+}
+
+func TestHandler(t *testing.T) {
+	ObserveJob(time.Second)
+	recorder := httptest.NewRecorder()
+
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, string(body), "vault_sync_job_duration_seconds_count")
+	assert.Contains(t, string(body), "go_goroutines")
+}
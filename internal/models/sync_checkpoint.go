@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SyncCheckpoint is the progress of the full or incremental run of an instance that has not completed yet. RunID
+// is the recorded run that started the checkpoint, if it was recorded. Paths holds the paths the run, and the
+// runs that resumed it, processed so far.
+type SyncCheckpoint struct {
+	InstanceID  string           `db:"instance_id"`
+	RunID       *int64           `db:"run_id"`
+	StartedAt   time.Time        `db:"started_at"`
+	Incremental bool             `db:"incremental"`
+	Paths       []CheckpointPath `db:"-"`
+}
+
+// CheckpointPath is a path processed by a checkpointed run.
+type CheckpointPath struct {
+	SecretBackend string `db:"secret_backend"`
+	SecretPath    string `db:"secret_path"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// SyncRunStatus is the state of a sync run.
+type SyncRunStatus string
+
+const (
+	SyncRunRunning     SyncRunStatus = "running"
+	SyncRunCompleted   SyncRunStatus = "completed"
+	SyncRunInterrupted SyncRunStatus = "interrupted"
+	SyncRunFailed      SyncRunStatus = "failed"
+)
+
+func (s SyncRunStatus) String() string {
+	return string(s)
+}
+
+// SyncRun records a single run of an instance with the counters of its result.
+// FinishedAt is nil while the run is in progress, or when the process died before it finished.
+type SyncRun struct {
+	ID                int64         `db:"run_id"`
+	InstanceID        string        `db:"instance_id"`
+	StartedAt         time.Time     `db:"started_at"`
+	FinishedAt        *time.Time    `db:"finished_at"`
+	Status            SyncRunStatus `db:"status"`
+	Incremental       bool          `db:"incremental"`
+	TotalSecrets      int           `db:"total_secrets"`
+	SuccessfulSyncs   int           `db:"successful_syncs"`
+	FailedSyncs       int           `db:"failed_syncs"`
+	SkippedSecrets    int           `db:"skipped_secrets"`
+	NoOpSecrets       int           `db:"no_op_secrets"`
+	DiscoveryFailures int           `db:"discovery_failures"`
+	ErrorMessage      *string       `db:"error_message"`
+}
+
+// SyncAction is what a run did to a secret on a replica.
+type SyncAction string
+
+const (
+	SyncActionUpdated      SyncAction = "updated"
+	SyncActionDeleted      SyncAction = "deleted"
+	SyncActionFailed       SyncAction = "failed"
+	SyncActionDeleteFailed SyncAction = "delete_failed"
+)
+
+func (a SyncAction) String() string {
+	return string(a)
+}
+
+// SyncEvent records a change or failure of one secret on one replica. Unchanged secrets are not recorded.
+// The versions are only set for writes.
+type SyncEvent struct {
+	ID                 int64      `db:"event_id"`
+	RunID              int64      `db:"run_id"`
+	SecretBackend      string     `db:"secret_backend"`
+	SecretPath         string     `db:"secret_path"`
+	DestinationCluster string     `db:"destination_cluster"`
+	Action             SyncAction `db:"action"`
+	SourceVersion      *int64     `db:"source_version"`
+	DestinationVersion *int64     `db:"destination_version"`
+	ErrorMessage       *string    `db:"error_message"`
+	OccurredAt         time.Time  `db:"occurred_at"`
+}
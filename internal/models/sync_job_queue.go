@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// QueuedJobStatus is the state of a queued sync job.
+type QueuedJobStatus string
+
+const (
+	QueuedJobPending   QueuedJobStatus = "pending"
+	QueuedJobLeased    QueuedJobStatus = "leased"
+	QueuedJobCompleted QueuedJobStatus = "completed"
+	QueuedJobFailed    QueuedJobStatus = "failed"
+)
+
+func (s QueuedJobStatus) String() string {
+	return string(s)
+}
+
+// QueuedJob is the sync job of one path, enqueued by a coordinator for the workers. A worker leases the job until
+// LeaseExpiresAt and extends the lease while the job runs; a job whose lease expired is leased again by another
+// worker. Attempts counts the leases. Incremental jobs only check the replicas when the source changed.
+type QueuedJob struct {
+	ID             int64           `db:"job_id"`
+	SecretBackend  string          `db:"secret_backend"`
+	SecretPath     string          `db:"secret_path"`
+	Incremental    bool            `db:"incremental"`
+	Status         QueuedJobStatus `db:"status"`
+	EnqueuedBy     string          `db:"enqueued_by"`
+	EnqueuedAt     time.Time       `db:"enqueued_at"`
+	LeasedBy       *string         `db:"leased_by"`
+	LeaseExpiresAt *time.Time      `db:"lease_expires_at"`
+	Attempts       int             `db:"attempts"`
+	FinishedAt     *time.Time      `db:"finished_at"`
+	ErrorMessage   *string         `db:"error_message"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// SyncRequestStatus is the state of a sync request.
+type SyncRequestStatus string
+
+const (
+	SyncRequestPending   SyncRequestStatus = "pending"
+	SyncRequestRunning   SyncRequestStatus = "running"
+	SyncRequestCompleted SyncRequestStatus = "completed"
+	SyncRequestFailed    SyncRequestStatus = "failed"
+	SyncRequestRejected  SyncRequestStatus = "rejected"
+)
+
+func (s SyncRequestStatus) String() string {
+	return string(s)
+}
+
+// SyncRequest asks for a sync of one secret. External systems insert pending requests; the daemon claims them,
+// syncs the secret and writes the outcome back. ClaimedBy and ClaimedAt are set while a daemon works on the
+// request, FinishedAt and ErrorMessage once it is done.
+type SyncRequest struct {
+	ID            int64             `db:"request_id"`
+	SecretBackend string            `db:"secret_backend"`
+	SecretPath    string            `db:"secret_path"`
+	RequestedBy   *string           `db:"requested_by"`
+	RequestedAt   time.Time         `db:"requested_at"`
+	Status        SyncRequestStatus `db:"status"`
+	ClaimedBy     *string           `db:"claimed_by"`
+	ClaimedAt     *time.Time        `db:"claimed_at"`
+	FinishedAt    *time.Time        `db:"finished_at"`
+	ErrorMessage  *string           `db:"error_message"`
+}
@@ -9,15 +9,60 @@ const (
 	StatusPending      SyncStatus = "pending"
 	StatusDeleted      SyncStatus = "deleted"
 	SyncStatusNotFound SyncStatus = "not_found"
+	// StatusErrorDeleting is never stored; it is reported for failed records whose delete failed.
+	StatusErrorDeleting SyncStatus = "error_deleting"
 )
 
+// DeleteFailedVersion is stored as both versions of a record whose delete from the replica failed.
+const DeleteFailedVersion int64 = -1000
+
 type SyncStatus string
 
 func (s SyncStatus) String() string {
 	return string(s)
 }
 
+// ErrorCategory classifies why an operation on a replica failed.
+type ErrorCategory string
+
+const (
+	// ErrorCategoryNotFound is a secret or path that does not exist.
+	ErrorCategoryNotFound ErrorCategory = "not_found"
+	// ErrorCategoryPermissionDenied is a request the token of the cluster is not allowed to make.
+	ErrorCategoryPermissionDenied ErrorCategory = "permission_denied"
+	// ErrorCategoryAuthExpired is a request rejected because the token expired or was revoked.
+	ErrorCategoryAuthExpired ErrorCategory = "auth_expired"
+	// ErrorCategoryUnavailable is a sealed or failing cluster, or one whose circuit breaker is open.
+	ErrorCategoryUnavailable ErrorCategory = "unavailable"
+	// ErrorCategoryRateLimited is a request rejected by the rate limit of the cluster.
+	ErrorCategoryRateLimited ErrorCategory = "rate_limited"
+	// ErrorCategoryCASConflict is a write rejected because the secret changed since it was read.
+	ErrorCategoryCASConflict ErrorCategory = "cas_conflict"
+	// ErrorCategoryNetwork is a request that did not get a response.
+	ErrorCategoryNetwork ErrorCategory = "network"
+	// ErrorCategoryUnknown is any other failure.
+	ErrorCategoryUnknown ErrorCategory = "unknown"
+)
+
+// IsTransient reports whether failures of the category are expected to go away without intervention.
+func (c ErrorCategory) IsTransient() bool {
+	switch c {
+	case ErrorCategoryAuthExpired, ErrorCategoryUnavailable, ErrorCategoryRateLimited,
+		ErrorCategoryCASConflict, ErrorCategoryNetwork:
+		return true
+	case ErrorCategoryNotFound, ErrorCategoryPermissionDenied, ErrorCategoryUnknown:
+		return false
+	}
+	return false
+}
+
+func (c ErrorCategory) String() string {
+	return string(c)
+}
+
 // SyncedSecret represents a secret that has been synchronized to a replica cluster.
+// AttemptCount, NextRetryAt, ErrorCategory and QuarantinedAt describe the retries of a failed record and are
+// cleared once the replica is synced successfully.
 type SyncedSecret struct {
 	SecretBackend      string     `db:"secret_backend"`
 	SecretPath         string     `db:"secret_path"`
@@ -28,6 +73,14 @@ type SyncedSecret struct {
 	LastSyncSuccess    *time.Time `db:"last_sync_success"`
 	Status             SyncStatus `db:"status"`
 	ErrorMessage       *string    `db:"error_message"`
+	SourceUpdatedTime  *time.Time `db:"source_updated_time"`
+	// AttemptCount is the number of failed attempts in a row.
+	AttemptCount int `db:"attempt_count"`
+	// NextRetryAt is when the failed record is due for a retry; nil means it is due immediately.
+	NextRetryAt   *time.Time     `db:"next_retry_at"`
+	ErrorCategory *ErrorCategory `db:"error_category"`
+	// QuarantinedAt is set once a record failed too often; it is then only retried on request.
+	QuarantinedAt *time.Time `db:"quarantined_at"`
 }
 
 func (s *SyncedSecret) SetErrorMessage(msg *string) {
@@ -42,10 +95,32 @@ func (s *SyncedSecret) SetLastSuccessAttempt(t *time.Time) {
 	s.LastSyncSuccess = t
 }
 
+func (s *SyncedSecret) SetErrorCategory(category ErrorCategory) {
+	s.ErrorCategory = &category
+}
+
+// IsQuarantined reports whether the record is only retried on request.
+func (s *SyncedSecret) IsQuarantined() bool {
+	return s.Status == StatusFailed && s.QuarantinedAt != nil
+}
+
+// IsRetryDue reports whether the failed record may be retried at now. Quarantined records are never due.
+func (s *SyncedSecret) IsRetryDue(now time.Time) bool {
+	return s.Status == StatusFailed && !s.IsQuarantined() && (s.NextRetryAt == nil || !now.Before(*s.NextRetryAt))
+}
+
 func (s *SyncedSecret) GetStatus() SyncStatus {
 	return s.Status
 }
 
+// ReportedStatus returns the stored status, or StatusErrorDeleting when the record marks a failed delete.
+func (s *SyncedSecret) ReportedStatus() SyncStatus {
+	if s.Status == StatusFailed && s.SourceVersion == DeleteFailedVersion {
+		return StatusErrorDeleting
+	}
+	return s.Status
+}
+
 func (s *SyncedSecret) GetDestinationCluster() string {
 	return s.DestinationCluster
 }
@@ -58,12 +133,17 @@ type SyncSecretDeletionResult struct {
 	Status             SyncStatus
 	DeletionAttempt    time.Time
 	ErrorMessage       *string
+	ErrorCategory      *ErrorCategory
 }
 
 func (s *SyncSecretDeletionResult) SetErrorMessage(msg *string) {
 	s.ErrorMessage = msg
 }
 
+func (s *SyncSecretDeletionResult) SetErrorCategory(category ErrorCategory) {
+	s.ErrorCategory = &category
+}
+
 func (s *SyncSecretDeletionResult) SetStatus(status SyncStatus) {
 	s.Status = status
 }
@@ -79,3 +159,13 @@ func (s *SyncSecretDeletionResult) GetStatus() SyncStatus {
 func (s *SyncSecretDeletionResult) GetDestinationCluster() string {
 	return s.DestinationCluster
 }
+
+// FullReconciliation records when an instance last checked every secret on every replica. A coordinator also keeps
+// the full reconciliation whose jobs it enqueued until the workers finished them: its run started at
+// QueuedStartedAt and its last job was enqueued by QueuedEnqueuedAt.
+type FullReconciliation struct {
+	InstanceID       string     `db:"instance_id"`
+	LastFullRun      *time.Time `db:"last_full_run"`
+	QueuedStartedAt  *time.Time `db:"queued_started_at"`
+	QueuedEnqueuedAt *time.Time `db:"queued_enqueued_at"`
+}
@@ -1,11 +1,97 @@
 package repository
 
-import "vault-sync/internal/models"
+import (
+	"context"
+	"time"
 
+	"vault-sync/internal/models"
+)
+
+// SyncedSecretRepository stores the sync state of every secret per replica cluster. Every method stops waiting,
+// including between retries, once its context is done.
 type SyncedSecretRepository interface {
-	GetSyncedSecret(backend, path, destinationCluster string) (*models.SyncedSecret, error)
-	UpdateSyncedSecretStatus(secret *models.SyncedSecret) error
-	GetSyncedSecrets() ([]*models.SyncedSecret, error)
-	DeleteSyncedSecret(backend, path, destinationCluster string) error
+	GetSyncedSecret(ctx context.Context, backend, path, destinationCluster string) (*models.SyncedSecret, error)
+	UpdateSyncedSecretStatus(ctx context.Context, secret *models.SyncedSecret) error
+	// UpdateSyncedSecretStatuses upserts all secrets in a single statement; either all or none are written.
+	UpdateSyncedSecretStatuses(ctx context.Context, secrets []*models.SyncedSecret) error
+	GetSyncedSecrets(ctx context.Context) ([]*models.SyncedSecret, error)
+	// GetFailedSyncedSecrets returns the failed records, ordered by when they are due for a retry.
+	GetFailedSyncedSecrets(ctx context.Context) ([]*models.SyncedSecret, error)
+	DeleteSyncedSecret(ctx context.Context, backend, path, destinationCluster string) error
+	// GetLastFullReconciliation returns when the instance last completed a full run, or the zero time if never.
+	GetLastFullReconciliation(ctx context.Context, instanceID string) (time.Time, error)
+	RecordFullReconciliation(ctx context.Context, instanceID string, completedAt time.Time) error
+	// QueueFullReconciliation keeps the full reconciliation whose run started at startedAt and whose jobs were
+	// enqueued by enqueuedAt, until FinishQueuedFullReconciliation.
+	QueueFullReconciliation(ctx context.Context, instanceID string, startedAt, enqueuedAt time.Time) error
+	// GetQueuedFullReconciliation returns the start and enqueue time of the queued full reconciliation of the
+	// instance, or zero times if none is queued.
+	GetQueuedFullReconciliation(ctx context.Context, instanceID string) (time.Time, time.Time, error)
+	// FinishQueuedFullReconciliation drops the queued full reconciliation, recording it as the last full run when
+	// completed.
+	FinishQueuedFullReconciliation(ctx context.Context, instanceID string, completed bool) error
+	// StartSyncRun inserts the run and sets its ID.
+	StartSyncRun(ctx context.Context, run *models.SyncRun) error
+	// FinishSyncRun stores the end time, status, counters and error of the run.
+	FinishSyncRun(ctx context.Context, run *models.SyncRun) error
+	// InsertSyncEvents appends the events in a single statement.
+	InsertSyncEvents(ctx context.Context, events []*models.SyncEvent) error
+	// PruneSyncHistory deletes the runs started before cutoff together with their events and returns the number
+	// of deleted runs.
+	PruneSyncHistory(ctx context.Context, cutoff time.Time) (int64, error)
+	// GetSyncEvents returns up to limit events of the secret across all replicas, newest first.
+	GetSyncEvents(ctx context.Context, backend, path string, limit int) ([]*models.SyncEvent, error)
 	Close() error
 }
+
+// SyncRequestRepository is the queue of sync requests inserted by external systems. Claimed requests are locked
+// with SKIP LOCKED, so several daemons can share the queue without taking the same request.
+type SyncRequestRepository interface {
+	// ClaimSyncRequests marks up to limit pending requests as running for claimedBy and returns them, oldest
+	// first. Requests claimed more than staleAfter ago and never finished are claimed again.
+	ClaimSyncRequests(
+		ctx context.Context, claimedBy string, limit int, staleAfter time.Duration,
+	) ([]*models.SyncRequest, error)
+	// FinishSyncRequests stores the status, claim, end time and error of the requests still claimed by claimedBy
+	// in one transaction. Requests claimed by another daemon in the meantime are left alone.
+	FinishSyncRequests(ctx context.Context, claimedBy string, requests []*models.SyncRequest) error
+	// ListenForSyncRequests calls notify for every inserted request until ctx is done or the connection is lost.
+	ListenForSyncRequests(ctx context.Context, notify func()) error
+}
+
+// SyncJobQueueRepository is the queue of sync jobs a coordinator shares with its workers. Workers lease jobs with
+// SKIP LOCKED, so no two workers run the same job while its lease lasts.
+type SyncJobQueueRepository interface {
+	// EnqueueSyncJobs adds a pending job per path and returns the number of jobs added or updated. A path that
+	// is already pending is not added twice; its job stays incremental only if both are.
+	EnqueueSyncJobs(ctx context.Context, enqueuedBy string, jobs []*models.QueuedJob) (int64, error)
+	// LeaseSyncJobs leases up to limit pending jobs, or jobs whose lease expired, to workerID for leaseDuration
+	// and returns them, oldest first. Jobs whose lease expired after maxAttempts leases are failed instead, and
+	// only one job of a path is leased at a time.
+	LeaseSyncJobs(
+		ctx context.Context, workerID string, limit int, leaseDuration time.Duration, maxAttempts int,
+	) ([]*models.QueuedJob, error)
+	// ExtendSyncJobLeases moves the lease expiry of the jobs still leased to workerID to leaseDuration from now.
+	ExtendSyncJobLeases(ctx context.Context, workerID string, jobIDs []int64, leaseDuration time.Duration) error
+	// FinishSyncJobs stores the status, lease, end time and error of the jobs still leased to workerID in one
+	// transaction. Jobs leased to another worker in the meantime are left alone.
+	FinishSyncJobs(ctx context.Context, workerID string, jobs []*models.QueuedJob) error
+	// PruneSyncJobs deletes the jobs finished before cutoff and returns their number.
+	PruneSyncJobs(ctx context.Context, cutoff time.Time) (int64, error)
+	// CountSyncJobs returns the number of jobs enqueued up to enqueuedBefore that did not finish yet, and the
+	// number of them that failed since finishedSince.
+	CountSyncJobs(ctx context.Context, finishedSince, enqueuedBefore time.Time) (int64, int64, error)
+}
+
+// SyncCheckpointRepository stores the progress of the full and incremental runs of every instance, so a run that
+// was interrupted can be resumed instead of started over.
+type SyncCheckpointRepository interface {
+	// GetSyncCheckpoint returns the checkpoint of the instance with its processed paths, or nil if there is none.
+	GetSyncCheckpoint(ctx context.Context, instanceID string) (*models.SyncCheckpoint, error)
+	// StartSyncCheckpoint replaces the checkpoint of the instance, and its processed paths, with checkpoint.
+	StartSyncCheckpoint(ctx context.Context, checkpoint *models.SyncCheckpoint) error
+	// AddCheckpointPaths records the paths as processed in the checkpoint of the instance.
+	AddCheckpointPaths(ctx context.Context, instanceID string, paths []models.CheckpointPath) error
+	// DeleteSyncCheckpoint deletes the checkpoint of the instance with its processed paths.
+	DeleteSyncCheckpoint(ctx context.Context, instanceID string) error
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vault-sync/internal/models"
+)
+
+// GetSyncCheckpoint reads the checkpoint and its processed paths in one transaction.
+//
+//nolint:nilnil, unqueryvet
+func (repo *SyncedSecretRepository) GetSyncCheckpoint(
+	ctx context.Context,
+	instanceID string,
+) (*models.SyncCheckpoint, error) {
+	logger := repo.logger.With().
+		Str("event", "get_sync_checkpoint").
+		Str("instance_id", instanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.SyncCheckpoint, error) {
+		tx, err := repo.psql.DB.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting transaction")
+			return nil, fmt.Errorf("error occurred while starting transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		checkpoint := &models.SyncCheckpoint{}
+		err = tx.GetContext(ctx, checkpoint, `SELECT * FROM sync_checkpoints WHERE instance_id = $1`, instanceID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				logger.Debug().Msg("No sync checkpoint recorded")
+				return nil, nil
+			}
+			logger.Error().Err(err).Msg("error occurred while getting sync checkpoint")
+			return nil, fmt.Errorf("error occurred while getting sync checkpoint: %w", err)
+		}
+
+		query := `SELECT secret_backend, secret_path FROM sync_checkpoint_paths WHERE instance_id = $1`
+		if err = tx.SelectContext(ctx, &checkpoint.Paths, query, instanceID); err != nil {
+			logger.Error().Err(err).Msg("error occurred while getting sync checkpoint paths")
+			return nil, fmt.Errorf("error occurred while getting sync checkpoint paths: %w", err)
+		}
+
+		logger.Debug().Int("paths", len(checkpoint.Paths)).Msg("Loaded sync checkpoint")
+		return checkpoint, nil
+	}
+
+	return executeOperationInCircuitBreaker(ctx, repo, "get_sync_checkpoint", true, dbOperation)
+}
+
+// StartSyncCheckpoint deletes the previous checkpoint of the instance, whose paths are removed by the foreign key
+// cascade, and inserts the new one in one transaction.
+func (repo *SyncedSecretRepository) StartSyncCheckpoint(ctx context.Context, checkpoint *models.SyncCheckpoint) error {
+	logger := repo.logger.With().
+		Str("event", "start_sync_checkpoint").
+		Str("instance_id", checkpoint.InstanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.SyncCheckpoint, error) {
+		tx, err := repo.psql.DB.BeginTxx(ctx, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting transaction")
+			return nil, fmt.Errorf("error occurred while starting transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err = tx.ExecContext(ctx, `DELETE FROM sync_checkpoints WHERE instance_id = $1`,
+			checkpoint.InstanceID); err != nil {
+			logger.Error().Err(err).Msg("error occurred while deleting previous sync checkpoint")
+			return nil, fmt.Errorf("error occurred while deleting previous sync checkpoint: %w", err)
+		}
+
+		query := `
+            INSERT INTO sync_checkpoints (instance_id, run_id, started_at, incremental)
+            VALUES (:instance_id, :run_id, :started_at, :incremental)
+        `
+		if _, err = tx.NamedExecContext(ctx, query, *checkpoint); err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting sync checkpoint")
+			return nil, fmt.Errorf("error occurred while starting sync checkpoint: %w", err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			logger.Error().Err(err).Msg("error occurred while committing sync checkpoint")
+			return nil, fmt.Errorf("error occurred while committing sync checkpoint: %w", err)
+		}
+
+		logger.Debug().Msg("Started sync checkpoint")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "start_sync_checkpoint", true, dbOperation)
+	return err
+}
+
+// AddCheckpointPaths inserts the paths with one multi-row statement. Paths that are already recorded are skipped.
+func (repo *SyncedSecretRepository) AddCheckpointPaths(
+	ctx context.Context,
+	instanceID string,
+	paths []models.CheckpointPath,
+) error {
+	logger := repo.logger.With().
+		Str("event", "add_checkpoint_paths").
+		Str("instance_id", instanceID).
+		Int("count", len(paths)).
+		Logger()
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	type checkpointPathRow struct {
+		InstanceID string `db:"instance_id"`
+		models.CheckpointPath
+	}
+	rows := make([]checkpointPathRow, 0, len(paths))
+	for _, path := range paths {
+		rows = append(rows, checkpointPathRow{InstanceID: instanceID, CheckpointPath: path})
+	}
+
+	dbOperation := func(ctx context.Context) (*models.SyncCheckpoint, error) {
+		query := `
+            INSERT INTO sync_checkpoint_paths (instance_id, secret_backend, secret_path)
+            VALUES (:instance_id, :secret_backend, :secret_path)
+            ON CONFLICT DO NOTHING
+        `
+
+		if _, err := repo.psql.DB.NamedExecContext(ctx, query, rows); err != nil {
+			logger.Error().Err(err).Msg("error occurred while adding checkpoint paths")
+			return nil, fmt.Errorf("error occurred while adding checkpoint paths: %w", err)
+		}
+
+		logger.Debug().Msg("Added checkpoint paths")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "add_checkpoint_paths", true, dbOperation)
+	return err
+}
+
+func (repo *SyncedSecretRepository) DeleteSyncCheckpoint(ctx context.Context, instanceID string) error {
+	logger := repo.logger.With().
+		Str("event", "delete_sync_checkpoint").
+		Str("instance_id", instanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.SyncCheckpoint, error) {
+		// The paths of the checkpoint are removed by the foreign key cascade.
+		if _, err := repo.psql.DB.ExecContext(ctx, `DELETE FROM sync_checkpoints WHERE instance_id = $1`,
+			instanceID); err != nil {
+			logger.Error().Err(err).Msg("error occurred while deleting sync checkpoint")
+			return nil, fmt.Errorf("error occurred while deleting sync checkpoint: %w", err)
+		}
+
+		logger.Debug().Msg("Deleted sync checkpoint")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "delete_sync_checkpoint", true, dbOperation)
+	return err
+}
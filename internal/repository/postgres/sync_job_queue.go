@@ -0,0 +1,334 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"vault-sync/internal/models"
+)
+
+// EnqueueSyncJobs inserts the jobs with one multi-row statement. sqlx expands the VALUES clause to one row per
+// job; a path that is already pending keeps its job, which stays incremental only if the new one is as well.
+func (repo *SyncedSecretRepository) EnqueueSyncJobs(
+	ctx context.Context,
+	enqueuedBy string,
+	jobs []*models.QueuedJob,
+) (int64, error) {
+	logger := repo.logger.With().
+		Str("event", "enqueue_sync_jobs").
+		Str("enqueued_by", enqueuedBy).
+		Int("count", len(jobs)).
+		Logger()
+
+	rows := uniqueQueuedJobs(enqueuedBy, jobs)
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	var enqueued int64
+	dbOperation := func(ctx context.Context) ([]*models.QueuedJob, error) {
+		query := `
+            INSERT INTO sync_job_queue (secret_backend, secret_path, incremental, status, enqueued_by)
+            VALUES (:secret_backend, :secret_path, :incremental, :status, :enqueued_by)
+            ON CONFLICT (secret_backend, secret_path) WHERE status = 'pending'
+            DO UPDATE SET incremental = sync_job_queue.incremental AND EXCLUDED.incremental
+        `
+
+		result, err := repo.psql.DB.NamedExecContext(ctx, query, rows)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while enqueuing sync jobs")
+			return nil, fmt.Errorf("error occurred while enqueuing sync jobs: %w", err)
+		}
+
+		enqueued, err = result.RowsAffected()
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while checking rows affected")
+			return nil, fmt.Errorf("error occurred while checking rows affected: %w", err)
+		}
+
+		logger.Debug().Int64("enqueued", enqueued).Msg("Enqueued sync jobs")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "enqueue_sync_jobs", true, dbOperation)
+	return enqueued, err
+}
+
+// uniqueQueuedJobs returns the jobs as pending values for a batch insert, one per path, as a statement cannot
+// update the same row twice.
+func uniqueQueuedJobs(enqueuedBy string, jobs []*models.QueuedJob) []models.QueuedJob {
+	type pathKey struct{ backend, path string }
+
+	indexByPath := make(map[pathKey]int, len(jobs))
+	rows := make([]models.QueuedJob, 0, len(jobs))
+	for _, queuedJob := range jobs {
+		key := pathKey{queuedJob.SecretBackend, queuedJob.SecretPath}
+		if index, exists := indexByPath[key]; exists {
+			rows[index].Incremental = rows[index].Incremental && queuedJob.Incremental
+			continue
+		}
+		indexByPath[key] = len(rows)
+		rows = append(rows, models.QueuedJob{
+			SecretBackend: queuedJob.SecretBackend,
+			SecretPath:    queuedJob.SecretPath,
+			Incremental:   queuedJob.Incremental,
+			Status:        models.QueuedJobPending,
+			EnqueuedBy:    enqueuedBy,
+		})
+	}
+	return rows
+}
+
+// LeaseSyncJobs leases up to limit jobs that are pending or whose lease expired, oldest first. Rows locked by
+// another worker are skipped, so concurrent workers never lease the same job. A job whose lease expired after
+// maxAttempts leases is failed instead. Only one job of a path is leased at a time: a released job stays leased
+// while a pending job may be enqueued for its path, so a path with a job under an unexpired lease, or with an older
+// job under an expired one, is left for later.
+//
+//nolint:unqueryvet
+func (repo *SyncedSecretRepository) LeaseSyncJobs(
+	ctx context.Context,
+	workerID string,
+	limit int,
+	leaseDuration time.Duration,
+	maxAttempts int,
+) ([]*models.QueuedJob, error) {
+	logger := repo.logger.With().
+		Str("event", "lease_sync_jobs").
+		Str("worker_id", workerID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) ([]*models.QueuedJob, error) {
+		jobs := make([]*models.QueuedJob, 0)
+		failQuery := `
+            UPDATE sync_job_queue SET
+                status = $1,
+                lease_expires_at = NULL,
+                finished_at = now(),
+                error_message = format('gave up after %s attempts', attempts)
+            WHERE status = $2 AND lease_expires_at < now() AND attempts >= $3
+        `
+		leaseQuery := `
+            UPDATE sync_job_queue SET
+                status = $1,
+                leased_by = $2,
+                lease_expires_at = now() + make_interval(secs => $3),
+                attempts = attempts + 1
+            WHERE job_id IN (
+                SELECT job_id FROM sync_job_queue q
+                WHERE (q.status = $4 OR (q.status = $1 AND q.lease_expires_at < now()))
+                AND NOT EXISTS (
+                    SELECT 1 FROM sync_job_queue o
+                    WHERE o.secret_backend = q.secret_backend AND o.secret_path = q.secret_path
+                    AND o.job_id <> q.job_id AND o.status = $1
+                    AND (o.lease_expires_at >= now() OR o.job_id < q.job_id)
+                )
+                ORDER BY job_id
+                LIMIT $5
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING *
+        `
+
+		tx, err := repo.psql.DB.BeginTxx(ctx, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting transaction")
+			return jobs, fmt.Errorf("error occurred while starting transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		result, err := tx.ExecContext(ctx, failQuery, models.QueuedJobFailed, models.QueuedJobLeased, maxAttempts)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while failing exhausted sync jobs")
+			return jobs, fmt.Errorf("error occurred while failing exhausted sync jobs: %w", err)
+		}
+		if failed, _ := result.RowsAffected(); failed > 0 {
+			logger.Warn().Int64("count", failed).Int("max_attempts", maxAttempts).
+				Msg("Failed sync jobs that ran out of attempts")
+		}
+
+		err = tx.SelectContext(ctx, &jobs, leaseQuery, models.QueuedJobLeased, workerID,
+			leaseDuration.Seconds(), models.QueuedJobPending, limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while leasing sync jobs")
+			return jobs, fmt.Errorf("error occurred while leasing sync jobs: %w", err)
+		}
+		if err = tx.Commit(); err != nil {
+			logger.Error().Err(err).Msg("error occurred while committing leased sync jobs")
+			return jobs, fmt.Errorf("error occurred while committing leased sync jobs: %w", err)
+		}
+		return jobs, nil
+	}
+
+	jobs, err := executeOperationInCircuitBreaker(ctx, repo, "lease_sync_jobs", false, dbOperation)
+	if err != nil {
+		return []*models.QueuedJob{}, err
+	}
+
+	slices.SortFunc(jobs, func(a, b *models.QueuedJob) int { return cmp.Compare(a.ID, b.ID) })
+	logger.Debug().Int("count", len(jobs)).Msg("Leased sync jobs")
+	return jobs, nil
+}
+
+func (repo *SyncedSecretRepository) ExtendSyncJobLeases(
+	ctx context.Context,
+	workerID string,
+	jobIDs []int64,
+	leaseDuration time.Duration,
+) error {
+	logger := repo.logger.With().
+		Str("event", "extend_sync_job_leases").
+		Str("worker_id", workerID).
+		Int("count", len(jobIDs)).
+		Logger()
+
+	if len(jobIDs) == 0 {
+		return nil
+	}
+
+	dbOperation := func(ctx context.Context) ([]*models.QueuedJob, error) {
+		query := `
+            UPDATE sync_job_queue SET lease_expires_at = now() + make_interval(secs => $1)
+            WHERE job_id = ANY($2) AND leased_by = $3 AND status = $4
+        `
+
+		_, err := repo.psql.DB.ExecContext(ctx, query, leaseDuration.Seconds(), jobIDs, workerID,
+			models.QueuedJobLeased)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while extending sync job leases")
+			return nil, fmt.Errorf("error occurred while extending sync job leases: %w", err)
+		}
+
+		logger.Debug().Msg("Extended sync job leases")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "extend_sync_job_leases", true, dbOperation)
+	return err
+}
+
+// FinishSyncJobs writes the outcome of the jobs back in one transaction. Jobs whose lease expired and that were
+// leased by another worker since are not touched. A job that is still leased is released: its lease expires right
+// away, so it is leased again like the job of a worker that died, and the lease is not counted as an attempt. It
+// is not made pending again, as a pending job may have been enqueued for its path since.
+func (repo *SyncedSecretRepository) FinishSyncJobs(
+	ctx context.Context,
+	workerID string,
+	jobs []*models.QueuedJob,
+) error {
+	logger := repo.logger.With().
+		Str("event", "finish_sync_jobs").
+		Str("worker_id", workerID).
+		Int("count", len(jobs)).
+		Logger()
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	dbOperation := func(ctx context.Context) ([]*models.QueuedJob, error) {
+		query := `
+            UPDATE sync_job_queue SET
+                status = $1,
+                leased_by = $2,
+                lease_expires_at = CASE WHEN $1 = $8 THEN now() ELSE $3 END,
+                attempts = CASE WHEN $1 = $8 THEN attempts - 1 ELSE attempts END,
+                finished_at = $4,
+                error_message = $5
+            WHERE job_id = $6 AND leased_by = $7 AND status = $8
+        `
+
+		tx, err := repo.psql.DB.BeginTxx(ctx, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting transaction")
+			return nil, fmt.Errorf("error occurred while starting transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		for _, queuedJob := range jobs {
+			_, err = tx.ExecContext(ctx, query, queuedJob.Status, queuedJob.LeasedBy, queuedJob.LeaseExpiresAt,
+				queuedJob.FinishedAt, queuedJob.ErrorMessage, queuedJob.ID, workerID, models.QueuedJobLeased)
+			if err != nil {
+				logger.Error().Err(err).Int64("job_id", queuedJob.ID).Msg("error occurred while finishing sync job")
+				return nil, fmt.Errorf("error occurred while finishing sync job %d: %w", queuedJob.ID, err)
+			}
+		}
+		if err = tx.Commit(); err != nil {
+			logger.Error().Err(err).Msg("error occurred while committing finished sync jobs")
+			return nil, fmt.Errorf("error occurred while committing finished sync jobs: %w", err)
+		}
+
+		logger.Debug().Msg("Finished sync jobs")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "finish_sync_jobs", true, dbOperation)
+	return err
+}
+
+func (repo *SyncedSecretRepository) PruneSyncJobs(ctx context.Context, cutoff time.Time) (int64, error) {
+	logger := repo.logger.With().
+		Str("event", "prune_sync_jobs").
+		Time("cutoff", cutoff).
+		Logger()
+
+	var deletedJobs int64
+	dbOperation := func(ctx context.Context) ([]*models.QueuedJob, error) {
+		query := `DELETE FROM sync_job_queue WHERE finished_at < $1`
+
+		result, err := repo.psql.DB.ExecContext(ctx, query, cutoff)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while pruning sync jobs")
+			return nil, fmt.Errorf("error occurred while pruning sync jobs: %w", err)
+		}
+
+		deletedJobs, err = result.RowsAffected()
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while checking rows affected")
+			return nil, fmt.Errorf("error occurred while checking rows affected: %w", err)
+		}
+
+		logger.Debug().Int64("deleted_jobs", deletedJobs).Msg("Pruned sync jobs")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "prune_sync_jobs", true, dbOperation)
+	return deletedJobs, err
+}
+
+// CountSyncJobs counts the jobs enqueued up to enqueuedBefore that are still pending or leased, and those of them
+// that failed since finishedSince. A path that was already pending keeps the job it had, so its job is counted as
+// well.
+func (repo *SyncedSecretRepository) CountSyncJobs(
+	ctx context.Context,
+	finishedSince, enqueuedBefore time.Time,
+) (int64, int64, error) {
+	logger := repo.logger.With().
+		Str("event", "count_sync_jobs").
+		Time("enqueued_before", enqueuedBefore).
+		Logger()
+
+	var openJobs, failedJobs int64
+	dbOperation := func(ctx context.Context) ([]*models.QueuedJob, error) {
+		query := `
+            SELECT
+                count(*) FILTER (WHERE status IN ($1, $2)),
+                count(*) FILTER (WHERE status = $3 AND finished_at >= $4)
+            FROM sync_job_queue WHERE enqueued_at <= $5
+        `
+
+		err := repo.psql.DB.QueryRowxContext(ctx, query, models.QueuedJobPending, models.QueuedJobLeased,
+			models.QueuedJobFailed, finishedSince, enqueuedBefore).Scan(&openJobs, &failedJobs)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while counting sync jobs")
+			return nil, fmt.Errorf("error occurred while counting sync jobs: %w", err)
+		}
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "count_sync_jobs", false, dbOperation)
+	return openJobs, failedJobs, err
+}
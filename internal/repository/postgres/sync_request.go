@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"vault-sync/internal/models"
+)
+
+// SyncRequestsChannel is the channel the insert trigger of sync_requests notifies.
+const SyncRequestsChannel = "vault_sync_requests"
+
+// ClaimSyncRequests marks up to limit open requests as running and returns them, oldest first. Rows locked by
+// another daemon are skipped, so concurrent daemons never claim the same request.
+//
+//nolint:unqueryvet
+func (repo *SyncedSecretRepository) ClaimSyncRequests(
+	ctx context.Context,
+	claimedBy string,
+	limit int,
+	staleAfter time.Duration,
+) ([]*models.SyncRequest, error) {
+	logger := repo.logger.With().
+		Str("event", "claim_sync_requests").
+		Str("claimed_by", claimedBy).
+		Logger()
+
+	dbOperation := func(ctx context.Context) ([]*models.SyncRequest, error) {
+		requests := make([]*models.SyncRequest, 0)
+		query := `
+            UPDATE sync_requests SET status = $1, claimed_by = $2, claimed_at = now()
+            WHERE request_id IN (
+                SELECT request_id FROM sync_requests
+                WHERE status = $3 OR (status = $1 AND claimed_at < now() - make_interval(secs => $4))
+                ORDER BY request_id
+                LIMIT $5
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING *
+        `
+		err := repo.psql.DB.SelectContext(ctx, &requests, query, models.SyncRequestRunning, claimedBy,
+			models.SyncRequestPending, staleAfter.Seconds(), limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while claiming sync requests")
+			return requests, fmt.Errorf("error occurred while claiming sync requests: %w", err)
+		}
+		return requests, nil
+	}
+
+	requests, err := executeOperationInCircuitBreaker(ctx, repo, "claim_sync_requests", false, dbOperation)
+	if err != nil {
+		return []*models.SyncRequest{}, err
+	}
+
+	slices.SortFunc(requests, func(a, b *models.SyncRequest) int { return cmp.Compare(a.ID, b.ID) })
+	logger.Debug().Int("count", len(requests)).Msg("Claimed sync requests")
+	return requests, nil
+}
+
+// FinishSyncRequests writes the outcome of the requests still claimed by claimedBy back to their rows in one
+// transaction. Requests claimed by another daemon in the meantime, after the claim went stale, are left alone.
+func (repo *SyncedSecretRepository) FinishSyncRequests(
+	ctx context.Context,
+	claimedBy string,
+	requests []*models.SyncRequest,
+) error {
+	logger := repo.logger.With().
+		Str("event", "finish_sync_requests").
+		Str("claimed_by", claimedBy).
+		Int("count", len(requests)).
+		Logger()
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	dbOperation := func(ctx context.Context) ([]*models.SyncRequest, error) {
+		query := `
+            UPDATE sync_requests SET
+                status = $1,
+                claimed_by = $2,
+                claimed_at = $3,
+                finished_at = $4,
+                error_message = $5
+            WHERE request_id = $6 AND claimed_by = $7 AND status = $8
+        `
+
+		tx, err := repo.psql.DB.BeginTxx(ctx, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting transaction")
+			return nil, fmt.Errorf("error occurred while starting transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		var lost int
+		for _, request := range requests {
+			result, err := tx.ExecContext(ctx, query, request.Status, request.ClaimedBy, request.ClaimedAt,
+				request.FinishedAt, request.ErrorMessage, request.ID, claimedBy, models.SyncRequestRunning)
+			if err != nil {
+				logger.Error().Err(err).Int64("request_id", request.ID).
+					Msg("error occurred while finishing sync request")
+				return nil, fmt.Errorf("error occurred while finishing sync request %d: %w", request.ID, err)
+			}
+			if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+				lost++
+			}
+		}
+		if err = tx.Commit(); err != nil {
+			logger.Error().Err(err).Msg("error occurred while committing finished sync requests")
+			return nil, fmt.Errorf("error occurred while committing finished sync requests: %w", err)
+		}
+
+		if lost > 0 {
+			logger.Warn().Int("lost", lost).
+				Msg("Sync requests were claimed by another daemon in the meantime; their outcome was not written")
+		}
+		logger.Debug().Msg("Finished sync requests")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "finish_sync_requests", true, dbOperation)
+	return err
+}
+
+// ListenForSyncRequests listens for the notifications of the insert trigger of sync_requests on a dedicated
+// connection. It bypasses the circuit breaker and the retries; the caller decides when to listen again.
+func (repo *SyncedSecretRepository) ListenForSyncRequests(ctx context.Context, notify func()) error {
+	return repo.psql.Listen(ctx, SyncRequestsChannel, func(string) { notify() })
+}
@@ -8,8 +8,10 @@ import (
 	"reflect"
 	"time"
 
+	"vault-sync/internal/metrics"
 	"vault-sync/internal/models"
 	"vault-sync/internal/repository"
+	"vault-sync/internal/tracing"
 	postgres "vault-sync/pkg/db"
 	"vault-sync/pkg/log"
 
@@ -17,27 +19,81 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib" // this is required to register the pgx driver with database/sql
 	"github.com/rs/zerolog"
 	"github.com/sony/gobreaker"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
+// defaultQueryTimeout bounds a single query attempt unless configured otherwise.
+const defaultQueryTimeout = 10 * time.Second
+
 type SyncedSecretRepository struct {
 	psql           *postgres.PostgresDatastore
 	circuitBreaker *gobreaker.CircuitBreaker
 	retryOptFunc   func() []backoff.RetryOption
+	queryTimeout   time.Duration
 	logger         zerolog.Logger
 }
 
+// Option configures optional behaviour of the SyncedSecretRepository.
+type Option func(*SyncedSecretRepository)
+
+// WithQueryTimeout bounds every query attempt; a timed out attempt is retried like any other failure.
+// Zero keeps the default of 10s.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(repo *SyncedSecretRepository) {
+		if timeout > 0 {
+			repo.queryTimeout = timeout
+		}
+	}
+}
+
 type SyncedSecretResult interface {
-	*models.SyncedSecret | []*models.SyncedSecret
+	*models.SyncedSecret | []*models.SyncedSecret | *models.FullReconciliation | *models.SyncRun |
+		[]*models.SyncEvent | []*models.SyncRequest | []*models.QueuedJob | *models.SyncCheckpoint
 }
 
+// upsertSyncedSecretQuery inserts or updates a synced secret. sqlx expands the VALUES clause to one row per
+// element when it is executed with a slice.
+const upsertSyncedSecretQuery = `
+    INSERT INTO synced_secrets (
+        secret_backend,
+        secret_path,
+        source_version,
+        destination_cluster,
+        destination_version,
+        last_sync_attempt,
+        last_sync_success,
+        status,
+        error_message,
+        source_updated_time,
+        attempt_count,
+        next_retry_at,
+        error_category,
+        quarantined_at
+    ) VALUES (:secret_backend, :secret_path, :source_version, :destination_cluster, :destination_version, :last_sync_attempt, :last_sync_success, :status, :error_message, :source_updated_time, :attempt_count, :next_retry_at, :error_category, :quarantined_at)
+    ON CONFLICT (secret_backend, secret_path, destination_cluster)
+    DO UPDATE SET
+        source_version = EXCLUDED.source_version,
+        destination_version = EXCLUDED.destination_version,
+        last_sync_attempt = EXCLUDED.last_sync_attempt,
+        last_sync_success = EXCLUDED.last_sync_success,
+        status = EXCLUDED.status,
+        error_message = EXCLUDED.error_message,
+        source_updated_time = EXCLUDED.source_updated_time,
+        attempt_count = EXCLUDED.attempt_count,
+        next_retry_at = EXCLUDED.next_retry_at,
+        error_category = EXCLUDED.error_category,
+        quarantined_at = EXCLUDED.quarantined_at
+`
+
 // NewSyncedSecretRepository creates a new PostgreSQLSyncedSecretRepository instance
 // with a configured circuit breaker and retry options.
 //
 // The circuit breaker is configured to trip after 30% failure rate with a maximum of 5 requests in half-open state.
 // The retry options use an exponential backoff strategy with a maximum of 10 retries and a total elapsed time of 60 seconds.
+// Retries stop as soon as the context of the operation is done; cancelled operations do not count towards the breaker.
 //
 //nolint:mnd
-func NewSyncedSecretRepository(psql *postgres.PostgresDatastore) *SyncedSecretRepository {
+func NewSyncedSecretRepository(psql *postgres.PostgresDatastore, opts ...Option) *SyncedSecretRepository {
 	gobreakerSettings := gobreaker.Settings{
 		Name:        "synced_secret_db",
 		MaxRequests: 5,                // Allow 5 test requests in half-open state
@@ -47,7 +103,11 @@ func NewSyncedSecretRepository(psql *postgres.PostgresDatastore) *SyncedSecretRe
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
 			return counts.Requests >= 5 && failureRatio >= 0.3 // Trip at 30% failure rate
 		},
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, context.Canceled) // A cancelled caller says nothing about the database
+		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			metrics.SetCircuitBreakerState(name, to)
 			log.Logger.Info().
 				Str("component", "postgres_synced_secret_repository").
 				Str("event", "circuit_breaker_state_change").
@@ -58,18 +118,27 @@ func NewSyncedSecretRepository(psql *postgres.PostgresDatastore) *SyncedSecretRe
 		},
 	}
 
-	return &SyncedSecretRepository{
+	metrics.SetCircuitBreakerState(gobreakerSettings.Name, gobreaker.StateClosed)
+	repo := &SyncedSecretRepository{
 		psql:           psql,
 		circuitBreaker: gobreaker.NewCircuitBreaker(gobreakerSettings),
 		retryOptFunc:   newBackoffStrategy,
+		queryTimeout:   defaultQueryTimeout,
 		logger: log.Logger.With().
 			Str("component", "postgres_synced_secret_repository").
 			Logger(),
 	}
+
+	for _, opt := range opts {
+		opt(repo)
+	}
+
+	return repo
 }
 
-//nolint:noctx, nilnil, unqueryvet
+//nolint:nilnil, unqueryvet
 func (repo *SyncedSecretRepository) GetSyncedSecret(
+	ctx context.Context,
 	backend, path, destinationCluster string,
 ) (*models.SyncedSecret, error) {
 	logger := repo.createOperationLogger("get_synced_secret", backend, path, destinationCluster)
@@ -78,11 +147,11 @@ func (repo *SyncedSecretRepository) GetSyncedSecret(
 		return nil, err
 	}
 
-	dbOperation := func() (*models.SyncedSecret, error) {
+	dbOperation := func(ctx context.Context) (*models.SyncedSecret, error) {
 		var secret = &models.SyncedSecret{}
 		query := `SELECT * FROM synced_secrets WHERE secret_backend = $1 AND secret_path = $2 AND destination_cluster = $3`
 
-		err := repo.psql.DB.Get(secret, query, backend, path, destinationCluster)
+		err := repo.psql.DB.GetContext(ctx, secret, query, backend, path, destinationCluster)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				logger.Debug().Msg(repository.ErrSecretNotFound.Error())
@@ -96,7 +165,7 @@ func (repo *SyncedSecretRepository) GetSyncedSecret(
 		return secret, nil
 	}
 
-	secret, err := executeOperationInCircuitBreaker(repo, true, dbOperation)
+	secret, err := executeOperationInCircuitBreaker(ctx, repo, "get_synced_secret", true, dbOperation)
 	if err != nil {
 		return nil, err
 	} else if secret == nil {
@@ -106,12 +175,12 @@ func (repo *SyncedSecretRepository) GetSyncedSecret(
 	return secret, nil
 }
 
-//nolint:noctx, nilnil, unqueryvet
-func (repo *SyncedSecretRepository) GetSyncedSecrets() ([]*models.SyncedSecret, error) {
-	dbOperation := func() ([]*models.SyncedSecret, error) {
+//nolint:unqueryvet
+func (repo *SyncedSecretRepository) GetSyncedSecrets(ctx context.Context) ([]*models.SyncedSecret, error) {
+	dbOperation := func(ctx context.Context) ([]*models.SyncedSecret, error) {
 		var secrets = make([]*models.SyncedSecret, 0)
 		query := `SELECT * FROM synced_secrets ORDER BY secret_backend, secret_path, destination_cluster`
-		err := repo.psql.DB.Select(&secrets, query)
+		err := repo.psql.DB.SelectContext(ctx, &secrets, query)
 		if err != nil {
 			repo.logger.Error().Err(err).
 				Str("event", "get_synced_secrets").
@@ -121,7 +190,7 @@ func (repo *SyncedSecretRepository) GetSyncedSecrets() ([]*models.SyncedSecret,
 		return secrets, nil
 	}
 
-	secrets, err := executeOperationInCircuitBreaker(repo, false, dbOperation)
+	secrets, err := executeOperationInCircuitBreaker(ctx, repo, "get_synced_secrets", false, dbOperation)
 	if err != nil {
 		return []*models.SyncedSecret{}, err
 	}
@@ -132,7 +201,39 @@ func (repo *SyncedSecretRepository) GetSyncedSecrets() ([]*models.SyncedSecret,
 	return secrets, nil
 }
 
-func (repo *SyncedSecretRepository) UpdateSyncedSecretStatus(secret *models.SyncedSecret) error {
+// GetFailedSyncedSecrets returns the failed records, i.e. the retry queue, ordered by their next retry.
+//
+//nolint:unqueryvet
+func (repo *SyncedSecretRepository) GetFailedSyncedSecrets(ctx context.Context) ([]*models.SyncedSecret, error) {
+	dbOperation := func(ctx context.Context) ([]*models.SyncedSecret, error) {
+		secrets := make([]*models.SyncedSecret, 0)
+		query := `
+            SELECT * FROM synced_secrets
+            WHERE status = $1
+            ORDER BY next_retry_at NULLS FIRST, secret_backend, secret_path, destination_cluster
+        `
+		err := repo.psql.DB.SelectContext(ctx, &secrets, query, models.StatusFailed)
+		if err != nil {
+			repo.logger.Error().Err(err).
+				Str("event", "get_failed_synced_secrets").
+				Msg("error occurred while getting failed synced secrets")
+			return secrets, fmt.Errorf("error occurred while getting failed synced secrets: %w", err)
+		}
+		return secrets, nil
+	}
+
+	secrets, err := executeOperationInCircuitBreaker(ctx, repo, "get_failed_synced_secrets", false, dbOperation)
+	if err != nil {
+		return []*models.SyncedSecret{}, err
+	}
+
+	repo.logger.Debug().Int("count", len(secrets)).
+		Str("event", "get_failed_synced_secrets").
+		Msg("Successfully retrieved failed synced secrets")
+	return secrets, nil
+}
+
+func (repo *SyncedSecretRepository) UpdateSyncedSecretStatus(ctx context.Context, secret *models.SyncedSecret) error {
 	logger := repo.createOperationLogger(
 		"update_synced_secret_status",
 		secret.SecretBackend,
@@ -140,30 +241,8 @@ func (repo *SyncedSecretRepository) UpdateSyncedSecretStatus(secret *models.Sync
 		secret.DestinationCluster,
 	)
 
-	dbOperation := func() (*models.SyncedSecret, error) {
-		query := `
-            INSERT INTO synced_secrets (
-                secret_backend,
-                secret_path,
-                source_version,
-                destination_cluster,
-                destination_version,
-                last_sync_attempt,
-                last_sync_success,
-                status,
-                error_message
-            ) VALUES (:secret_backend, :secret_path, :source_version, :destination_cluster, :destination_version, :last_sync_attempt, :last_sync_success, :status, :error_message)
-            ON CONFLICT (secret_backend, secret_path, destination_cluster)
-            DO UPDATE SET
-                source_version = EXCLUDED.source_version,
-                destination_version = EXCLUDED.destination_version,
-                last_sync_attempt = EXCLUDED.last_sync_attempt,
-                last_sync_success = EXCLUDED.last_sync_success,
-                status = EXCLUDED.status,
-                error_message = EXCLUDED.error_message
-        `
-
-		result, err := repo.psql.DB.NamedExec(query, *secret)
+	dbOperation := func(ctx context.Context) (*models.SyncedSecret, error) {
+		result, err := repo.psql.DB.NamedExecContext(ctx, upsertSyncedSecretQuery, *secret)
 		if err != nil {
 			logger.Error().Err(err).Msg("error occurred while updating synced secret status")
 			return nil, fmt.Errorf("error occurred while updating synced secret status: %w", err)
@@ -179,12 +258,67 @@ func (repo *SyncedSecretRepository) UpdateSyncedSecretStatus(secret *models.Sync
 		return secret, nil
 	}
 
-	_, err := executeOperationInCircuitBreaker(repo, true, dbOperation)
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "update_synced_secret_status", true, dbOperation)
+	return err
+}
+
+// UpdateSyncedSecretStatuses upserts the secrets with one multi-row statement. When the same secret is passed
+// more than once, the last one is written, as a statement cannot update a row twice.
+func (repo *SyncedSecretRepository) UpdateSyncedSecretStatuses(
+	ctx context.Context,
+	secrets []*models.SyncedSecret,
+) error {
+	logger := repo.logger.With().
+		Str("event", "update_synced_secret_statuses").
+		Int("count", len(secrets)).
+		Logger()
+
+	rows := uniqueSyncedSecrets(secrets)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	dbOperation := func(ctx context.Context) (*models.SyncedSecret, error) {
+		result, err := repo.psql.DB.NamedExecContext(ctx, upsertSyncedSecretQuery, rows)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while updating synced secret statuses")
+			return nil, fmt.Errorf("error occurred while updating synced secret statuses: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while checking rows affected")
+			return nil, fmt.Errorf("error occurred while checking rows affected: %w", err)
+		}
+
+		logger.Debug().Int64("rows_affected", rowsAffected).Msg("Successfully updated synced secret statuses")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "update_synced_secret_statuses", true, dbOperation)
 	return err
 }
 
-//nolint:noctx, nilnil, unqueryvet
-func (repo *SyncedSecretRepository) DeleteSyncedSecret(backend, path, destinationCluster string) error {
+// uniqueSyncedSecrets returns the secrets as values for a batch insert, keeping the last one of each key.
+func uniqueSyncedSecrets(secrets []*models.SyncedSecret) []models.SyncedSecret {
+	type secretKey struct{ backend, path, destinationCluster string }
+
+	indexByKey := make(map[secretKey]int, len(secrets))
+	rows := make([]models.SyncedSecret, 0, len(secrets))
+	for _, secret := range secrets {
+		key := secretKey{secret.SecretBackend, secret.SecretPath, secret.DestinationCluster}
+		if index, exists := indexByKey[key]; exists {
+			rows[index] = *secret
+			continue
+		}
+		indexByKey[key] = len(rows)
+		rows = append(rows, *secret)
+	}
+	return rows
+}
+
+//nolint:nilnil
+func (repo *SyncedSecretRepository) DeleteSyncedSecret(ctx context.Context, backend, path, destinationCluster string) error {
 	logger := repo.createOperationLogger("delete_synced_secret", backend, path, destinationCluster)
 
 	if err := validateQueryParameters(backend, path, destinationCluster); err != nil {
@@ -192,10 +326,10 @@ func (repo *SyncedSecretRepository) DeleteSyncedSecret(backend, path, destinatio
 		return err
 	}
 
-	dbOperation := func() (*models.SyncedSecret, error) {
+	dbOperation := func(ctx context.Context) (*models.SyncedSecret, error) {
 		query := `DELETE FROM synced_secrets WHERE secret_backend = $1 AND secret_path = $2 AND destination_cluster = $3`
 
-		result, err := repo.psql.DB.Exec(query, backend, path, destinationCluster)
+		result, err := repo.psql.DB.ExecContext(ctx, query, backend, path, destinationCluster)
 		if err != nil {
 			logger.Error().Err(err).Msg("error occurred while deleting synced secret")
 			return nil, fmt.Errorf("error occurred while deleting synced secret: %w", err)
@@ -216,10 +350,361 @@ func (repo *SyncedSecretRepository) DeleteSyncedSecret(backend, path, destinatio
 		return nil, nil
 	}
 
-	_, err := executeOperationInCircuitBreaker(repo, true, dbOperation)
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "delete_synced_secret", true, dbOperation)
+	return err
+}
+
+//nolint:nilnil, unqueryvet
+func (repo *SyncedSecretRepository) GetLastFullReconciliation(ctx context.Context, instanceID string) (time.Time, error) {
+	logger := repo.logger.With().
+		Str("event", "get_last_full_reconciliation").
+		Str("instance_id", instanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.FullReconciliation, error) {
+		var reconciliation = &models.FullReconciliation{}
+		query := `SELECT * FROM full_reconciliations WHERE instance_id = $1`
+
+		err := repo.psql.DB.GetContext(ctx, reconciliation, query, instanceID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				logger.Debug().Msg("No full reconciliation recorded")
+				return nil, nil
+			}
+			logger.Error().Err(err).Msg("error occurred while getting last full reconciliation")
+			return nil, fmt.Errorf("error occurred while getting last full reconciliation: %w", err)
+		}
+		return reconciliation, nil
+	}
+
+	reconciliation, err := executeOperationInCircuitBreaker(ctx, repo, "get_last_full_reconciliation", true, dbOperation)
+	if err != nil || reconciliation == nil || reconciliation.LastFullRun == nil {
+		return time.Time{}, err
+	}
+
+	return *reconciliation.LastFullRun, nil
+}
+
+func (repo *SyncedSecretRepository) RecordFullReconciliation(
+	ctx context.Context,
+	instanceID string,
+	completedAt time.Time,
+) error {
+	logger := repo.logger.With().
+		Str("event", "record_full_reconciliation").
+		Str("instance_id", instanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.FullReconciliation, error) {
+		query := `
+            INSERT INTO full_reconciliations (instance_id, last_full_run) VALUES ($1, $2)
+            ON CONFLICT (instance_id) DO UPDATE SET last_full_run = EXCLUDED.last_full_run
+        `
+
+		if _, err := repo.psql.DB.ExecContext(ctx, query, instanceID, completedAt); err != nil {
+			logger.Error().Err(err).Msg("error occurred while recording full reconciliation")
+			return nil, fmt.Errorf("error occurred while recording full reconciliation: %w", err)
+		}
+
+		logger.Debug().Time("last_full_run", completedAt).Msg("Recorded full reconciliation")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "record_full_reconciliation", true, dbOperation)
+	return err
+}
+
+// QueueFullReconciliation keeps the full reconciliation whose jobs the instance enqueued, replacing the one queued
+// before, if any. The last full run is left as it is.
+func (repo *SyncedSecretRepository) QueueFullReconciliation(
+	ctx context.Context,
+	instanceID string,
+	startedAt, enqueuedAt time.Time,
+) error {
+	logger := repo.logger.With().
+		Str("event", "queue_full_reconciliation").
+		Str("instance_id", instanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.FullReconciliation, error) {
+		query := `
+            INSERT INTO full_reconciliations (instance_id, queued_started_at, queued_enqueued_at) VALUES ($1, $2, $3)
+            ON CONFLICT (instance_id) DO UPDATE SET
+                queued_started_at = EXCLUDED.queued_started_at,
+                queued_enqueued_at = EXCLUDED.queued_enqueued_at
+        `
+
+		if _, err := repo.psql.DB.ExecContext(ctx, query, instanceID, startedAt, enqueuedAt); err != nil {
+			logger.Error().Err(err).Msg("error occurred while queuing full reconciliation")
+			return nil, fmt.Errorf("error occurred while queuing full reconciliation: %w", err)
+		}
+
+		logger.Debug().Time("started_at", startedAt).Msg("Queued full reconciliation")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "queue_full_reconciliation", true, dbOperation)
+	return err
+}
+
+//nolint:nilnil, unqueryvet
+func (repo *SyncedSecretRepository) GetQueuedFullReconciliation(
+	ctx context.Context,
+	instanceID string,
+) (time.Time, time.Time, error) {
+	logger := repo.logger.With().
+		Str("event", "get_queued_full_reconciliation").
+		Str("instance_id", instanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.FullReconciliation, error) {
+		var reconciliation = &models.FullReconciliation{}
+		query := `SELECT * FROM full_reconciliations WHERE instance_id = $1 AND queued_started_at IS NOT NULL`
+
+		err := repo.psql.DB.GetContext(ctx, reconciliation, query, instanceID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				logger.Debug().Msg("No full reconciliation queued")
+				return nil, nil
+			}
+			logger.Error().Err(err).Msg("error occurred while getting queued full reconciliation")
+			return nil, fmt.Errorf("error occurred while getting queued full reconciliation: %w", err)
+		}
+		return reconciliation, nil
+	}
+
+	reconciliation, err := executeOperationInCircuitBreaker(
+		ctx, repo, "get_queued_full_reconciliation", true, dbOperation)
+	if err != nil || reconciliation == nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return *reconciliation.QueuedStartedAt, *reconciliation.QueuedEnqueuedAt, nil
+}
+
+// FinishQueuedFullReconciliation drops the queued full reconciliation of the instance. When completed, the start
+// of its run becomes the last full run in the same statement.
+func (repo *SyncedSecretRepository) FinishQueuedFullReconciliation(
+	ctx context.Context,
+	instanceID string,
+	completed bool,
+) error {
+	logger := repo.logger.With().
+		Str("event", "finish_queued_full_reconciliation").
+		Str("instance_id", instanceID).
+		Bool("completed", completed).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.FullReconciliation, error) {
+		query := `
+            UPDATE full_reconciliations SET
+                last_full_run = CASE WHEN $2 THEN queued_started_at ELSE last_full_run END,
+                queued_started_at = NULL,
+                queued_enqueued_at = NULL
+            WHERE instance_id = $1 AND queued_started_at IS NOT NULL
+        `
+
+		if _, err := repo.psql.DB.ExecContext(ctx, query, instanceID, completed); err != nil {
+			logger.Error().Err(err).Msg("error occurred while finishing queued full reconciliation")
+			return nil, fmt.Errorf("error occurred while finishing queued full reconciliation: %w", err)
+		}
+
+		logger.Debug().Msg("Finished queued full reconciliation")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "finish_queued_full_reconciliation", true, dbOperation)
+	return err
+}
+
+func (repo *SyncedSecretRepository) StartSyncRun(ctx context.Context, run *models.SyncRun) error {
+	logger := repo.logger.With().
+		Str("event", "start_sync_run").
+		Str("instance_id", run.InstanceID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.SyncRun, error) {
+		query := `
+            INSERT INTO sync_runs (instance_id, started_at, status, incremental)
+            VALUES ($1, $2, $3, $4)
+            RETURNING run_id
+        `
+
+		err := repo.psql.DB.QueryRowxContext(ctx, query, run.InstanceID, run.StartedAt, run.Status, run.Incremental).
+			Scan(&run.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while starting sync run")
+			return nil, fmt.Errorf("error occurred while starting sync run: %w", err)
+		}
+
+		logger.Debug().Int64("run_id", run.ID).Msg("Started sync run")
+		return run, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "start_sync_run", true, dbOperation)
+	return err
+}
+
+func (repo *SyncedSecretRepository) FinishSyncRun(ctx context.Context, run *models.SyncRun) error {
+	logger := repo.logger.With().
+		Str("event", "finish_sync_run").
+		Int64("run_id", run.ID).
+		Logger()
+
+	dbOperation := func(ctx context.Context) (*models.SyncRun, error) {
+		query := `
+            UPDATE sync_runs SET
+                finished_at = :finished_at,
+                status = :status,
+                incremental = :incremental,
+                total_secrets = :total_secrets,
+                successful_syncs = :successful_syncs,
+                failed_syncs = :failed_syncs,
+                skipped_secrets = :skipped_secrets,
+                no_op_secrets = :no_op_secrets,
+                discovery_failures = :discovery_failures,
+                error_message = :error_message
+            WHERE run_id = :run_id
+        `
+
+		if _, err := repo.psql.DB.NamedExecContext(ctx, query, *run); err != nil {
+			logger.Error().Err(err).Msg("error occurred while finishing sync run")
+			return nil, fmt.Errorf("error occurred while finishing sync run: %w", err)
+		}
+
+		logger.Debug().Str("status", run.Status.String()).Msg("Finished sync run")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "finish_sync_run", true, dbOperation)
+	return err
+}
+
+// InsertSyncEvents appends the events with one multi-row statement.
+func (repo *SyncedSecretRepository) InsertSyncEvents(ctx context.Context, events []*models.SyncEvent) error {
+	logger := repo.logger.With().
+		Str("event", "insert_sync_events").
+		Int("count", len(events)).
+		Logger()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows := make([]models.SyncEvent, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, *event)
+	}
+
+	dbOperation := func(ctx context.Context) (*models.SyncRun, error) {
+		query := `
+            INSERT INTO sync_events (
+                run_id,
+                secret_backend,
+                secret_path,
+                destination_cluster,
+                action,
+                source_version,
+                destination_version,
+                error_message,
+                occurred_at
+            ) VALUES (:run_id, :secret_backend, :secret_path, :destination_cluster, :action, :source_version, :destination_version, :error_message, :occurred_at)
+        `
+
+		if _, err := repo.psql.DB.NamedExecContext(ctx, query, rows); err != nil {
+			logger.Error().Err(err).Msg("error occurred while inserting sync events")
+			return nil, fmt.Errorf("error occurred while inserting sync events: %w", err)
+		}
+
+		logger.Debug().Msg("Inserted sync events")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "insert_sync_events", true, dbOperation)
 	return err
 }
 
+func (repo *SyncedSecretRepository) PruneSyncHistory(ctx context.Context, cutoff time.Time) (int64, error) {
+	logger := repo.logger.With().
+		Str("event", "prune_sync_history").
+		Time("cutoff", cutoff).
+		Logger()
+
+	var deletedRuns int64
+	dbOperation := func(ctx context.Context) (*models.SyncRun, error) {
+		// The events of the deleted runs are removed by the foreign key cascade.
+		query := `DELETE FROM sync_runs WHERE started_at < $1`
+
+		result, err := repo.psql.DB.ExecContext(ctx, query, cutoff)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while pruning sync history")
+			return nil, fmt.Errorf("error occurred while pruning sync history: %w", err)
+		}
+
+		deletedRuns, err = result.RowsAffected()
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while checking rows affected")
+			return nil, fmt.Errorf("error occurred while checking rows affected: %w", err)
+		}
+
+		logger.Debug().Int64("deleted_runs", deletedRuns).Msg("Pruned sync history")
+		return nil, nil
+	}
+
+	_, err := executeOperationInCircuitBreaker(ctx, repo, "prune_sync_history", true, dbOperation)
+	return deletedRuns, err
+}
+
+// GetSyncEvents returns the latest events of the secret on every replica, newest first.
+//
+//nolint:unqueryvet
+func (repo *SyncedSecretRepository) GetSyncEvents(
+	ctx context.Context, backend, path string, limit int,
+) ([]*models.SyncEvent, error) {
+	logger := repo.logger.With().
+		Str("event", "get_sync_events").
+		Str("secret_backend", backend).
+		Str("secret_path", path).
+		Logger()
+
+	dbOperation := func(ctx context.Context) ([]*models.SyncEvent, error) {
+		events := make([]*models.SyncEvent, 0)
+		query := `
+            SELECT * FROM sync_events
+            WHERE secret_backend = $1 AND secret_path = $2
+            ORDER BY occurred_at DESC, event_id DESC
+            LIMIT $3
+        `
+		err := repo.psql.DB.SelectContext(ctx, &events, query, backend, path, limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("error occurred while getting sync events")
+			return events, fmt.Errorf("error occurred while getting sync events: %w", err)
+		}
+		return events, nil
+	}
+
+	events, err := executeOperationInCircuitBreaker(ctx, repo, "get_sync_events", false, dbOperation)
+	if err != nil {
+		return []*models.SyncEvent{}, err
+	}
+
+	logger.Debug().Int("count", len(events)).Msg("Successfully retrieved sync events")
+	return events, nil
+}
+
+// Ping checks that the database can be reached. It bypasses the circuit breaker and the retries, so it reports
+// the database as it is right now.
+func (repo *SyncedSecretRepository) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, repo.queryTimeout)
+	defer cancel()
+	return repo.psql.Ping(ctx)
+}
+
+// CircuitBreakerState returns the state of the circuit breaker the queries go through.
+func (repo *SyncedSecretRepository) CircuitBreakerState() gobreaker.State {
+	return repo.circuitBreaker.State()
+}
+
 func (repo *SyncedSecretRepository) Close() error {
 	if repo.psql != nil {
 		return repo.psql.Close()
@@ -228,19 +713,45 @@ func (repo *SyncedSecretRepository) Close() error {
 }
 
 // executeOperationInCircuitBreaker executes the provided database operation within a circuit breaker context.
-// It retries the operation using an exponential backoff strategy if it fails.
+// It retries the operation using an exponential backoff strategy if it fails. Every attempt is bounded by the
+// query timeout, and no further attempt is made once ctx is done. The operation, with all its attempts, is traced
+// as a span named after operationName.
 func executeOperationInCircuitBreaker[T SyncedSecretResult](
+	ctx context.Context,
 	repo *SyncedSecretRepository,
+	operationName string,
 	nullableResult bool,
-	operation func() (T, error),
+	operation func(ctx context.Context) (T, error),
 ) (T, error) {
 	var opsResult T
+	ctx, span := tracing.Start(ctx, "postgres."+operationName,
+		semconv.DBSystemPostgreSQL, semconv.DBOperationName(operationName))
+	defer span.End()
+
+	attempts := 0
+	attempt := func() (T, error) {
+		attempts++
+		span.SetAttributes(tracing.AttemptsKey.Int(attempts))
+		queryCtx, cancel := repo.queryContext(ctx)
+		defer cancel()
+
+		result, err := operation(queryCtx)
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up; make sure the error says so even when the driver reports it differently.
+			if !errors.Is(err, ctx.Err()) {
+				err = fmt.Errorf("%w: %w", ctx.Err(), err)
+			}
+			return result, backoff.Permanent(err)
+		}
+		return result, err
+	}
 
 	result, err := repo.circuitBreaker.Execute(func() (any, error) {
-		return backoff.Retry(context.Background(), operation, repo.retryOptFunc()...)
+		return backoff.Retry(ctx, attempt, repo.retryOptFunc()...)
 	})
 
 	if circuitBreakerErr := repo.handleCircuitBreakerError(err); circuitBreakerErr != nil {
+		tracing.RecordError(span, circuitBreakerErr)
 		return opsResult, circuitBreakerErr
 	}
 
@@ -259,6 +770,14 @@ func executeOperationInCircuitBreaker[T SyncedSecretResult](
 	return typedResult, nil
 }
 
+// queryContext returns the context of a single query attempt, bounded by the query timeout if one is set.
+func (repo *SyncedSecretRepository) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if repo.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, repo.queryTimeout)
+}
+
 func (repo *SyncedSecretRepository) handleCircuitBreakerError(err error) error {
 	if err == nil {
 		return nil
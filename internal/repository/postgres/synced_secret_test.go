@@ -143,7 +143,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestGetSyncedSecret() {
 			}
 			repo := NewSyncedSecretRepository(suite.db)
 
-			result, err := repo.GetSyncedSecret(tc.backend, tc.path, tc.destinationCluster)
+			result, err := repo.GetSyncedSecret(suite.ctx, tc.backend, tc.path, tc.destinationCluster)
 
 			if tc.expectedErr != nil {
 				suite.ErrorIs(err, tc.expectedErr, "Expected error does not match")
@@ -171,7 +171,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestGetSyncedSecret() {
 		}
 
 		for i := 0; i < 3; i++ {
-			_, err := repo.GetSyncedSecret("kv", "does/not/exist", "prod")
+			_, err := repo.GetSyncedSecret(suite.ctx, "kv", "does/not/exist", "prod")
 			suite.ErrorIs(err, repository.ErrSecretNotFound, "Expected ErrSecretNotFound error")
 		}
 	})
@@ -224,7 +224,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestGetSyncedSecrets() {
 
 		repo := NewSyncedSecretRepository(suite.db)
 
-		result, err := repo.GetSyncedSecrets()
+		result, err := repo.GetSyncedSecrets(suite.ctx)
 
 		suite.NoError(err)
 		suite.NotNil(result)
@@ -238,7 +238,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestGetSyncedSecrets() {
 	suite.Run("returns empty slice for no secrets without error", func() {
 		repo := NewSyncedSecretRepository(suite.db)
 
-		result, err := repo.GetSyncedSecrets()
+		result, err := repo.GetSyncedSecrets(suite.ctx)
 
 		suite.NoError(err)
 		suite.NotNil(result)
@@ -332,7 +332,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestUpdateSyncedSecretStatus() {
 			}
 			repo := NewSyncedSecretRepository(suite.db)
 
-			err := repo.UpdateSyncedSecretStatus(&tc.secretToUpdate)
+			err := repo.UpdateSyncedSecretStatus(suite.ctx, &tc.secretToUpdate)
 
 			if tc.expectedErr != nil {
 				suite.ErrorIs(err, tc.expectedErr, "Expected error does not match")
@@ -341,6 +341,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestUpdateSyncedSecretStatus() {
 
 				if tc.shouldUpdateFields {
 					result, err := repo.GetSyncedSecret(
+						suite.ctx,
 						tc.secretToUpdate.SecretBackend,
 						tc.secretToUpdate.SecretPath,
 						tc.secretToUpdate.DestinationCluster,
@@ -377,6 +378,144 @@ type syncedSecretDeleteTestCase struct {
 	shouldVerifyDeleted bool
 }
 
+func (suite *SyncedSecretRepositoryTestSuite) TestUpdateSyncedSecretStatuses() {
+	newSecret := func(path, cluster string, version int64, status models.SyncStatus) *models.SyncedSecret {
+		return &models.SyncedSecret{
+			SecretBackend:      "kv",
+			SecretPath:         path,
+			SourceVersion:      version,
+			DestinationCluster: cluster,
+			DestinationVersion: version,
+			LastSyncAttempt:    time.Now().UTC(),
+			Status:             status,
+		}
+	}
+
+	suite.Run("inserts and updates all secrets in one batch", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		suite.insertTestSecret(newSecret("app/existing", "prod", 1, models.StatusFailed))
+
+		err := repo.UpdateSyncedSecretStatuses(suite.ctx, []*models.SyncedSecret{
+			newSecret("app/existing", "prod", 2, models.StatusSuccess),
+			newSecret("app/new", "prod", 1, models.StatusSuccess),
+			newSecret("app/new", "staging", 1, models.StatusSuccess),
+		})
+
+		suite.Require().NoError(err)
+		secrets, err := repo.GetSyncedSecrets(suite.ctx)
+		suite.Require().NoError(err)
+		suite.Len(secrets, 3)
+		existing, err := repo.GetSyncedSecret(suite.ctx, "kv", "app/existing", "prod")
+		suite.Require().NoError(err)
+		suite.Equal(int64(2), existing.SourceVersion)
+		suite.Equal(models.StatusSuccess, existing.Status)
+	})
+
+	suite.Run("writes the last of duplicate secrets", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+
+		err := repo.UpdateSyncedSecretStatuses(suite.ctx, []*models.SyncedSecret{
+			newSecret("app/config", "prod", 1, models.StatusFailed),
+			newSecret("app/config", "prod", 2, models.StatusSuccess),
+		})
+
+		suite.Require().NoError(err)
+		result, err := repo.GetSyncedSecret(suite.ctx, "kv", "app/config", "prod")
+		suite.Require().NoError(err)
+		suite.Equal(int64(2), result.SourceVersion)
+		suite.Equal(models.StatusSuccess, result.Status)
+	})
+
+	suite.Run("does nothing for an empty batch", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+
+		suite.NoError(repo.UpdateSyncedSecretStatuses(suite.ctx, nil))
+	})
+}
+
+func (suite *SyncedSecretRepositoryTestSuite) TestRetryQueue() {
+	newFailed := func(path string, attempts int) *models.SyncedSecret {
+		category := models.ErrorCategoryPermissionDenied
+		return &models.SyncedSecret{
+			SecretBackend:      "kv",
+			SecretPath:         path,
+			SourceVersion:      1,
+			DestinationCluster: "prod",
+			LastSyncAttempt:    time.Now().UTC(),
+			Status:             models.StatusFailed,
+			AttemptCount:       attempts,
+			ErrorCategory:      &category,
+		}
+	}
+
+	suite.Run("stores and clears the retry state", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		nextRetry := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+		failed := newFailed("app/config", 3)
+		failed.NextRetryAt = &nextRetry
+		suite.Require().NoError(repo.UpdateSyncedSecretStatus(suite.ctx, failed))
+
+		result, err := repo.GetSyncedSecret(suite.ctx, "kv", "app/config", "prod")
+
+		suite.Require().NoError(err)
+		suite.Equal(3, result.AttemptCount)
+		suite.True(nextRetry.Equal(*result.NextRetryAt))
+		suite.Equal(models.ErrorCategoryPermissionDenied, *result.ErrorCategory)
+		suite.Nil(result.QuarantinedAt)
+
+		suite.Require().NoError(repo.UpdateSyncedSecretStatus(suite.ctx, &models.SyncedSecret{
+			SecretBackend:      "kv",
+			SecretPath:         "app/config",
+			SourceVersion:      1,
+			DestinationCluster: "prod",
+			DestinationVersion: 1,
+			LastSyncAttempt:    time.Now().UTC(),
+			Status:             models.StatusSuccess,
+		}))
+		result, err = repo.GetSyncedSecret(suite.ctx, "kv", "app/config", "prod")
+		suite.Require().NoError(err)
+		suite.Zero(result.AttemptCount)
+		suite.Nil(result.NextRetryAt)
+		suite.Nil(result.ErrorCategory)
+	})
+
+	suite.Run("returns only failed secrets, those without a retry time first", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		later := time.Now().UTC().Add(time.Hour)
+		sooner := time.Now().UTC().Add(time.Minute)
+		quarantinedAt := time.Now().UTC()
+		laterRetry := newFailed("app/later", 2)
+		laterRetry.NextRetryAt = &later
+		soonerRetry := newFailed("app/sooner", 1)
+		soonerRetry.NextRetryAt = &sooner
+		quarantined := newFailed("app/quarantined", 10)
+		quarantined.QuarantinedAt = &quarantinedAt
+		suite.insertSecret("kv", "app/ok", "prod")
+		suite.Require().NoError(repo.UpdateSyncedSecretStatuses(
+			suite.ctx, []*models.SyncedSecret{laterRetry, soonerRetry, quarantined},
+		))
+
+		results, err := repo.GetFailedSyncedSecrets(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Require().Len(results, 3)
+		suite.Equal("app/quarantined", results[0].SecretPath)
+		suite.True(results[0].IsQuarantined())
+		suite.Equal("app/sooner", results[1].SecretPath)
+		suite.Equal("app/later", results[2].SecretPath)
+	})
+
+	suite.Run("returns empty slice when nothing failed", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		suite.insertSecret("kv", "app/ok", "prod")
+
+		results, err := repo.GetFailedSyncedSecrets(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Empty(results)
+	})
+}
+
 func (suite *SyncedSecretRepositoryTestSuite) TestDeleteSyncedSecret() {
 	now := time.Now().UTC().Truncate(time.Millisecond)
 	successTime := now.Add(-1 * time.Minute)
@@ -448,14 +587,14 @@ func (suite *SyncedSecretRepositoryTestSuite) TestDeleteSyncedSecret() {
 
 			repo := NewSyncedSecretRepository(suite.db)
 
-			err := repo.DeleteSyncedSecret(tc.backend, tc.path, tc.destinationCluster)
+			err := repo.DeleteSyncedSecret(suite.ctx, tc.backend, tc.path, tc.destinationCluster)
 
 			if tc.expectedErr != nil {
 				suite.ErrorIs(err, tc.expectedErr, "Expected error does not match")
 			} else {
 				suite.NoError(err, "Expected no error")
 				if tc.shouldVerifyDeleted {
-					result, err := repo.GetSyncedSecret(tc.backend, tc.path, tc.destinationCluster)
+					result, err := repo.GetSyncedSecret(suite.ctx, tc.backend, tc.path, tc.destinationCluster)
 					suite.Error(err, "synced secret not found")
 					suite.Nil(result, "Expected secret to be deleted")
 				}
@@ -467,6 +606,649 @@ func (suite *SyncedSecretRepositoryTestSuite) TestDeleteSyncedSecret() {
 
 // in this test, no need to check the returned values since this was already tested above individually,
 // this test focuses on the behavior of the circuit breaker and retry mechanism
+func (suite *SyncedSecretRepositoryTestSuite) TestSourceUpdatedTime() {
+	suite.Run("stores and returns the source updated time", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		updatedTime := time.Now().UTC().Truncate(time.Microsecond)
+		secret := &models.SyncedSecret{
+			SecretBackend:      "kv",
+			SecretPath:         "app/config",
+			SourceVersion:      4,
+			SourceUpdatedTime:  &updatedTime,
+			DestinationCluster: "prod",
+			DestinationVersion: 4,
+			LastSyncAttempt:    time.Now().UTC(),
+			Status:             models.StatusSuccess,
+		}
+
+		suite.Require().NoError(repo.UpdateSyncedSecretStatus(suite.ctx, secret))
+		result, err := repo.GetSyncedSecret(suite.ctx, "kv", "app/config", "prod")
+
+		suite.Require().NoError(err)
+		suite.Require().NotNil(result.SourceUpdatedTime)
+		suite.True(updatedTime.Equal(*result.SourceUpdatedTime))
+	})
+}
+
+func (suite *SyncedSecretRepositoryTestSuite) TestFullReconciliation() {
+	suite.Run("returns the zero time when no full reconciliation was recorded", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE full_reconciliations")
+		repo := NewSyncedSecretRepository(suite.db)
+
+		lastFullRun, err := repo.GetLastFullReconciliation(suite.ctx, "instance-a")
+
+		suite.NoError(err)
+		suite.True(lastFullRun.IsZero())
+	})
+
+	suite.Run("records and overwrites the last full reconciliation per instance", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE full_reconciliations")
+		repo := NewSyncedSecretRepository(suite.db)
+		first := time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond)
+		second := first.Add(30 * time.Minute)
+
+		suite.Require().NoError(repo.RecordFullReconciliation(suite.ctx, "instance-a", first))
+		suite.Require().NoError(repo.RecordFullReconciliation(suite.ctx, "instance-a", second))
+		suite.Require().NoError(repo.RecordFullReconciliation(suite.ctx, "instance-b", first))
+
+		lastFullRun, err := repo.GetLastFullReconciliation(suite.ctx, "instance-a")
+		suite.NoError(err)
+		suite.True(second.Equal(lastFullRun))
+
+		lastFullRun, err = repo.GetLastFullReconciliation(suite.ctx, "instance-b")
+		suite.NoError(err)
+		suite.True(first.Equal(lastFullRun))
+	})
+
+	suite.Run("keeps a queued full reconciliation until it finished", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE full_reconciliations")
+		repo := NewSyncedSecretRepository(suite.db)
+		startedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond)
+		enqueuedAt := startedAt.Add(time.Minute)
+
+		suite.Require().NoError(repo.QueueFullReconciliation(suite.ctx, "instance-a", startedAt, enqueuedAt))
+
+		queuedStart, queuedEnqueue, err := repo.GetQueuedFullReconciliation(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.True(startedAt.Equal(queuedStart))
+		suite.True(enqueuedAt.Equal(queuedEnqueue))
+		lastFullRun, err := repo.GetLastFullReconciliation(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.True(lastFullRun.IsZero(), "a queued reconciliation is not the last full run")
+
+		suite.Require().NoError(repo.FinishQueuedFullReconciliation(suite.ctx, "instance-a", true))
+
+		queuedStart, _, err = repo.GetQueuedFullReconciliation(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.True(queuedStart.IsZero())
+		lastFullRun, err = repo.GetLastFullReconciliation(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.True(startedAt.Equal(lastFullRun))
+	})
+
+	suite.Run("drops a queued full reconciliation that did not complete", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE full_reconciliations")
+		repo := NewSyncedSecretRepository(suite.db)
+		lastFullRun := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Microsecond)
+		suite.Require().NoError(repo.RecordFullReconciliation(suite.ctx, "instance-a", lastFullRun))
+		suite.Require().NoError(repo.QueueFullReconciliation(suite.ctx, "instance-a", time.Now(), time.Now()))
+
+		suite.Require().NoError(repo.FinishQueuedFullReconciliation(suite.ctx, "instance-a", false))
+
+		queuedStart, _, err := repo.GetQueuedFullReconciliation(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.True(queuedStart.IsZero())
+		recorded, err := repo.GetLastFullReconciliation(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.True(lastFullRun.Equal(recorded), "the last full run is kept")
+	})
+}
+
+func (suite *SyncedSecretRepositoryTestSuite) TestSyncHistory() {
+	startRun := func(repo *SyncedSecretRepository, startedAt time.Time) *models.SyncRun {
+		run := &models.SyncRun{InstanceID: "instance-a", StartedAt: startedAt, Status: models.SyncRunRunning}
+		suite.Require().NoError(repo.StartSyncRun(suite.ctx, run))
+		suite.Require().NotZero(run.ID)
+		return run
+	}
+	version := func(v int64) *int64 { return &v }
+
+	suite.Run("records a run and its events", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_runs CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		run := startRun(repo, now)
+		errorMsg := "permission denied"
+
+		err := repo.InsertSyncEvents(suite.ctx, []*models.SyncEvent{
+			{
+				RunID: run.ID, SecretBackend: "kv", SecretPath: "app/db", DestinationCluster: "replica-a",
+				Action: models.SyncActionUpdated, SourceVersion: version(3), DestinationVersion: version(3),
+				OccurredAt: now,
+			},
+			{
+				RunID: run.ID, SecretBackend: "kv", SecretPath: "app/db", DestinationCluster: "replica-b",
+				Action: models.SyncActionFailed, SourceVersion: version(3), ErrorMessage: &errorMsg, OccurredAt: now,
+			},
+		})
+		suite.Require().NoError(err)
+
+		finishedAt := now.Add(time.Minute)
+		run.FinishedAt = &finishedAt
+		run.Status = models.SyncRunCompleted
+		run.TotalSecrets = 1
+		run.FailedSyncs = 1
+		suite.Require().NoError(repo.FinishSyncRun(suite.ctx, run))
+
+		var storedRun models.SyncRun
+		suite.Require().NoError(suite.db.DB.GetContext(suite.ctx, &storedRun,
+			"SELECT * FROM sync_runs WHERE run_id = $1", run.ID))
+		suite.Equal("instance-a", storedRun.InstanceID)
+		suite.Equal(models.SyncRunCompleted, storedRun.Status)
+		suite.Equal(1, storedRun.FailedSyncs)
+		suite.Require().NotNil(storedRun.FinishedAt)
+		suite.True(finishedAt.Equal(*storedRun.FinishedAt))
+
+		var events []models.SyncEvent
+		suite.Require().NoError(suite.db.DB.SelectContext(suite.ctx, &events,
+			"SELECT * FROM sync_events WHERE run_id = $1 ORDER BY destination_cluster", run.ID))
+		suite.Require().Len(events, 2)
+		suite.Equal(models.SyncActionUpdated, events[0].Action)
+		suite.Equal(int64(3), *events[0].DestinationVersion)
+		suite.Nil(events[0].ErrorMessage)
+		suite.Equal(models.SyncActionFailed, events[1].Action)
+		suite.Nil(events[1].DestinationVersion)
+		suite.Equal(errorMsg, *events[1].ErrorMessage)
+	})
+
+	suite.Run("returns the latest events of a secret, newest first", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_runs CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		run := startRun(repo, now)
+		var events []*models.SyncEvent
+		for i := range 3 {
+			events = append(events, &models.SyncEvent{
+				RunID: run.ID, SecretBackend: "kv", SecretPath: "app/db", DestinationCluster: "replica-a",
+				Action: models.SyncActionUpdated, SourceVersion: version(int64(i + 1)),
+				OccurredAt: now.Add(time.Duration(i) * time.Minute),
+			})
+		}
+		events = append(events, &models.SyncEvent{
+			RunID: run.ID, SecretBackend: "kv", SecretPath: "app/api", DestinationCluster: "replica-a",
+			Action: models.SyncActionDeleted, OccurredAt: now.Add(time.Hour),
+		})
+		suite.Require().NoError(repo.InsertSyncEvents(suite.ctx, events))
+
+		stored, err := repo.GetSyncEvents(suite.ctx, "kv", "app/db", 2)
+
+		suite.Require().NoError(err)
+		suite.Require().Len(stored, 2)
+		suite.Equal(int64(3), *stored[0].SourceVersion)
+		suite.Equal(int64(2), *stored[1].SourceVersion)
+	})
+
+	suite.Run("does nothing when there are no events", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+
+		suite.NoError(repo.InsertSyncEvents(suite.ctx, nil))
+	})
+
+	suite.Run("prunes old runs together with their events", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_runs CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+		now := time.Now().UTC()
+		oldRun := startRun(repo, now.Add(-48*time.Hour))
+		recentRun := startRun(repo, now)
+		for _, run := range []*models.SyncRun{oldRun, recentRun} {
+			suite.Require().NoError(repo.InsertSyncEvents(suite.ctx, []*models.SyncEvent{{
+				RunID: run.ID, SecretBackend: "kv", SecretPath: "app/db", DestinationCluster: "replica-a",
+				Action: models.SyncActionDeleted, OccurredAt: run.StartedAt,
+			}}))
+		}
+
+		deleted, err := repo.PruneSyncHistory(suite.ctx, now.Add(-24*time.Hour))
+
+		suite.Require().NoError(err)
+		suite.Equal(int64(1), deleted)
+		var runIDs []int64
+		suite.Require().NoError(suite.db.DB.SelectContext(suite.ctx, &runIDs, "SELECT run_id FROM sync_events"))
+		suite.Equal([]int64{recentRun.ID}, runIDs)
+	})
+}
+
+func (suite *SyncedSecretRepositoryTestSuite) TestSyncRequests() {
+	insertRequest := func(backend, path string) {
+		_, err := suite.db.DB.ExecContext(suite.ctx,
+			"INSERT INTO sync_requests (secret_backend, secret_path, requested_by) VALUES ($1, $2, 'rotation')",
+			backend, path)
+		suite.Require().NoError(err)
+	}
+
+	suite.Run("claims every request once", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_requests")
+		repo := NewSyncedSecretRepository(suite.db)
+		insertRequest("kv", "app/db")
+		insertRequest("kv", "app/api")
+
+		first, err := repo.ClaimSyncRequests(suite.ctx, "instance-a", 1, time.Hour)
+		suite.Require().NoError(err)
+		second, err := repo.ClaimSyncRequests(suite.ctx, "instance-b", 10, time.Hour)
+		suite.Require().NoError(err)
+		none, err := repo.ClaimSyncRequests(suite.ctx, "instance-a", 10, time.Hour)
+		suite.Require().NoError(err)
+
+		suite.Require().Len(first, 1)
+		suite.Equal("app/db", first[0].SecretPath)
+		suite.Equal(models.SyncRequestRunning, first[0].Status)
+		suite.Equal("instance-a", *first[0].ClaimedBy)
+		suite.NotNil(first[0].ClaimedAt)
+		suite.Require().Len(second, 1)
+		suite.Equal("app/api", second[0].SecretPath)
+		suite.Empty(none)
+	})
+
+	suite.Run("claims requests of a stale claim again", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_requests")
+		repo := NewSyncedSecretRepository(suite.db)
+		insertRequest("kv", "app/db")
+		_, err := repo.ClaimSyncRequests(suite.ctx, "instance-a", 10, time.Hour)
+		suite.Require().NoError(err)
+
+		reclaimed, err := repo.ClaimSyncRequests(suite.ctx, "instance-b", 10, 0)
+
+		suite.Require().NoError(err)
+		suite.Require().Len(reclaimed, 1)
+		suite.Equal("instance-b", *reclaimed[0].ClaimedBy)
+	})
+
+	suite.Run("writes the outcome back", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_requests")
+		repo := NewSyncedSecretRepository(suite.db)
+		insertRequest("kv", "app/db")
+		insertRequest("kv", "app/api")
+		claimed, err := repo.ClaimSyncRequests(suite.ctx, "instance-a", 10, time.Hour)
+		suite.Require().NoError(err)
+		suite.Require().Len(claimed, 2)
+		finishedAt := time.Now().UTC().Truncate(time.Microsecond)
+		errorMsg := "permission denied"
+		claimed[0].Status = models.SyncRequestFailed
+		claimed[0].FinishedAt = &finishedAt
+		claimed[0].ErrorMessage = &errorMsg
+		claimed[1].Status = models.SyncRequestPending
+		claimed[1].ClaimedBy = nil
+		claimed[1].ClaimedAt = nil
+
+		suite.Require().NoError(repo.FinishSyncRequests(suite.ctx, "instance-a", claimed))
+
+		var stored []models.SyncRequest
+		suite.Require().NoError(suite.db.DB.SelectContext(suite.ctx, &stored,
+			"SELECT * FROM sync_requests ORDER BY request_id"))
+		suite.Require().Len(stored, 2)
+		suite.Equal(models.SyncRequestFailed, stored[0].Status)
+		suite.Equal(errorMsg, *stored[0].ErrorMessage)
+		suite.True(finishedAt.Equal(*stored[0].FinishedAt))
+		suite.Equal(models.SyncRequestPending, stored[1].Status)
+		suite.Nil(stored[1].ClaimedBy)
+	})
+
+	suite.Run("leaves a request alone that was claimed again by another daemon", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_requests")
+		repo := NewSyncedSecretRepository(suite.db)
+		insertRequest("kv", "app/db")
+		stale, err := repo.ClaimSyncRequests(suite.ctx, "instance-a", 10, time.Hour)
+		suite.Require().NoError(err)
+		suite.Require().Len(stale, 1)
+		reclaimed, err := repo.ClaimSyncRequests(suite.ctx, "instance-b", 10, 0)
+		suite.Require().NoError(err)
+		suite.Require().Len(reclaimed, 1)
+		finishedAt := time.Now().UTC()
+		stale[0].Status = models.SyncRequestCompleted
+		stale[0].FinishedAt = &finishedAt
+
+		suite.Require().NoError(repo.FinishSyncRequests(suite.ctx, "instance-a", stale))
+
+		var stored models.SyncRequest
+		suite.Require().NoError(suite.db.DB.GetContext(suite.ctx, &stored, "SELECT * FROM sync_requests"))
+		suite.Equal(models.SyncRequestRunning, stored.Status)
+		suite.Equal("instance-b", *stored.ClaimedBy)
+		suite.Nil(stored.FinishedAt)
+	})
+
+	suite.Run("notifies the listener of inserted requests", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		ctx, cancel := context.WithCancel(suite.ctx)
+		defer cancel()
+		notified := make(chan struct{}, 1)
+		listenDone := make(chan error, 1)
+		go func() {
+			listenDone <- repo.ListenForSyncRequests(ctx, func() {
+				select {
+				case notified <- struct{}{}:
+				default:
+				}
+			})
+		}()
+
+		suite.Eventually(func() bool {
+			insertRequest("kv", "app/db")
+			select {
+			case <-notified:
+				return true
+			case <-time.After(100 * time.Millisecond):
+				return false
+			}
+		}, 10*time.Second, 10*time.Millisecond)
+		cancel()
+		suite.Error(<-listenDone)
+	})
+}
+
+func (suite *SyncedSecretRepositoryTestSuite) TestSyncJobQueue() {
+	queuedJobs := func(paths ...string) []*models.QueuedJob {
+		jobs := make([]*models.QueuedJob, 0, len(paths))
+		for _, path := range paths {
+			jobs = append(jobs, &models.QueuedJob{SecretBackend: "kv", SecretPath: path, Incremental: true})
+		}
+		return jobs
+	}
+
+	suite.Run("keeps one pending job per path", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db", "app/api", "app/db"))
+		suite.Require().NoError(err)
+		fullRun := queuedJobs("app/db")
+		fullRun[0].Incremental = false
+		_, err = repo.EnqueueSyncJobs(suite.ctx, "coordinator", fullRun)
+		suite.Require().NoError(err)
+
+		var stored []models.QueuedJob
+		suite.Require().NoError(suite.db.DB.SelectContext(suite.ctx, &stored,
+			"SELECT * FROM sync_job_queue ORDER BY job_id"))
+		suite.Require().Len(stored, 2)
+		suite.Equal("app/db", stored[0].SecretPath)
+		suite.False(stored[0].Incremental, "a full job is not downgraded to an incremental one")
+		suite.Equal(models.QueuedJobPending, stored[0].Status)
+		suite.Equal("coordinator", stored[0].EnqueuedBy)
+		suite.True(stored[1].Incremental)
+	})
+
+	suite.Run("leases every job once", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db", "app/api"))
+		suite.Require().NoError(err)
+
+		first, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 1, time.Hour, 5)
+		suite.Require().NoError(err)
+		second, err := repo.LeaseSyncJobs(suite.ctx, "worker-b", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+		none, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+
+		suite.Require().Len(first, 1)
+		suite.Equal("app/db", first[0].SecretPath)
+		suite.Equal(models.QueuedJobLeased, first[0].Status)
+		suite.Equal("worker-a", *first[0].LeasedBy)
+		suite.Equal(1, first[0].Attempts)
+		suite.NotNil(first[0].LeaseExpiresAt)
+		suite.Require().Len(second, 1)
+		suite.Equal("app/api", second[0].SecretPath)
+		suite.Empty(none)
+	})
+
+	suite.Run("leases the jobs of an expired lease again", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db"))
+		suite.Require().NoError(err)
+		_, err = repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, 0, 5)
+		suite.Require().NoError(err)
+
+		released, err := repo.LeaseSyncJobs(suite.ctx, "worker-b", 10, time.Hour, 5)
+
+		suite.Require().NoError(err)
+		suite.Require().Len(released, 1)
+		suite.Equal("worker-b", *released[0].LeasedBy)
+		suite.Equal(2, released[0].Attempts)
+	})
+
+	suite.Run("extends only the leases of the worker", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db"))
+		suite.Require().NoError(err)
+		leased, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, 0, 5)
+		suite.Require().NoError(err)
+		suite.Require().Len(leased, 1)
+
+		suite.Require().NoError(repo.ExtendSyncJobLeases(suite.ctx, "worker-b", []int64{leased[0].ID}, time.Hour))
+		suite.Require().NoError(repo.ExtendSyncJobLeases(suite.ctx, "worker-a", []int64{leased[0].ID}, time.Hour))
+
+		none, err := repo.LeaseSyncJobs(suite.ctx, "worker-b", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+		suite.Empty(none)
+	})
+
+	suite.Run("writes the outcome back unless the job was leased again", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db", "app/api"))
+		suite.Require().NoError(err)
+		leased, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+		suite.Require().Len(leased, 2)
+		finishedAt := time.Now().UTC().Truncate(time.Microsecond)
+		errorMsg := "permission denied"
+		leased[0].Status = models.QueuedJobFailed
+		leased[0].LeaseExpiresAt = nil
+		leased[0].FinishedAt = &finishedAt
+		leased[0].ErrorMessage = &errorMsg
+		leased[1].Status = models.QueuedJobCompleted
+		leased[1].FinishedAt = &finishedAt
+
+		suite.Require().NoError(repo.FinishSyncJobs(suite.ctx, "worker-b", leased))
+		suite.Require().NoError(repo.FinishSyncJobs(suite.ctx, "worker-a", leased[:1]))
+
+		var stored []models.QueuedJob
+		suite.Require().NoError(suite.db.DB.SelectContext(suite.ctx, &stored,
+			"SELECT * FROM sync_job_queue ORDER BY job_id"))
+		suite.Require().Len(stored, 2)
+		suite.Equal(models.QueuedJobFailed, stored[0].Status)
+		suite.Equal(errorMsg, *stored[0].ErrorMessage)
+		suite.True(finishedAt.Equal(*stored[0].FinishedAt))
+		suite.Equal(models.QueuedJobLeased, stored[1].Status, "another worker's outcome is not written")
+	})
+
+	suite.Run("releases a job while a pending job exists for its path", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db", "app/api"))
+		suite.Require().NoError(err)
+		leased, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+		suite.Require().Len(leased, 2)
+		_, err = repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db"))
+		suite.Require().NoError(err)
+		finishedAt := time.Now().UTC().Truncate(time.Microsecond)
+		leased[0].LeaseExpiresAt = nil
+		leased[1].Status = models.QueuedJobCompleted
+		leased[1].LeaseExpiresAt = nil
+		leased[1].FinishedAt = &finishedAt
+
+		suite.Require().NoError(repo.FinishSyncJobs(suite.ctx, "worker-a", leased))
+
+		var stored []models.QueuedJob
+		suite.Require().NoError(suite.db.DB.SelectContext(suite.ctx, &stored,
+			"SELECT * FROM sync_job_queue ORDER BY job_id"))
+		suite.Require().Len(stored, 3)
+		suite.Equal(models.QueuedJobLeased, stored[0].Status, "the released job stays leased")
+		suite.Require().NotNil(stored[0].LeaseExpiresAt)
+		suite.Equal(models.QueuedJobCompleted, stored[1].Status, "the outcome of the batch is written")
+		suite.Equal(models.QueuedJobPending, stored[2].Status)
+
+		again, err := repo.LeaseSyncJobs(suite.ctx, "worker-b", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+		suite.Require().Len(again, 1, "the pending job waits for the released one of its path")
+		suite.Equal(stored[0].ID, again[0].ID)
+		suite.Equal(1, again[0].Attempts, "a released lease is not counted as an attempt")
+
+		none, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, time.Hour, 5)
+		suite.Require().NoError(err)
+		suite.Empty(none, "the pending job waits while its path is leased")
+	})
+
+	suite.Run("fails a job whose lease expired after the last attempt", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db"))
+		suite.Require().NoError(err)
+		for range 2 {
+			leased, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 10, 0, 2)
+			suite.Require().NoError(err)
+			suite.Require().Len(leased, 1)
+		}
+
+		none, err := repo.LeaseSyncJobs(suite.ctx, "worker-b", 10, time.Hour, 2)
+
+		suite.Require().NoError(err)
+		suite.Empty(none)
+		var stored models.QueuedJob
+		suite.Require().NoError(suite.db.DB.GetContext(suite.ctx, &stored, "SELECT * FROM sync_job_queue"))
+		suite.Equal(models.QueuedJobFailed, stored.Status)
+		suite.Nil(stored.LeaseExpiresAt)
+		suite.NotNil(stored.FinishedAt)
+		suite.Equal("gave up after 2 attempts", *stored.ErrorMessage)
+	})
+
+	suite.Run("counts the open jobs and the jobs failed since a time", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db", "app/api", "app/web"))
+		suite.Require().NoError(err)
+		enqueuedAt := time.Now()
+		leased, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 2, time.Hour, 5)
+		suite.Require().NoError(err)
+		suite.Require().Len(leased, 2)
+		finishedAt := time.Now()
+		errorMsg := "permission denied"
+		leased[0].Status = models.QueuedJobFailed
+		leased[0].FinishedAt = &finishedAt
+		leased[0].ErrorMessage = &errorMsg
+		suite.Require().NoError(repo.FinishSyncJobs(suite.ctx, "worker-a", leased[:1]))
+		_, err = repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/cache"))
+		suite.Require().NoError(err)
+
+		openJobs, failedJobs, err := repo.CountSyncJobs(suite.ctx, finishedAt.Add(-time.Minute), enqueuedAt)
+		suite.Require().NoError(err)
+		suite.Equal(int64(2), openJobs, "the jobs enqueued later are not counted")
+		suite.Equal(int64(1), failedJobs)
+
+		_, failedJobs, err = repo.CountSyncJobs(suite.ctx, finishedAt.Add(time.Minute), enqueuedAt)
+		suite.Require().NoError(err)
+		suite.Zero(failedJobs, "the jobs failed before are not counted")
+	})
+
+	suite.Run("prunes the jobs finished before the cutoff", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_job_queue")
+		repo := NewSyncedSecretRepository(suite.db)
+		_, err := repo.EnqueueSyncJobs(suite.ctx, "coordinator", queuedJobs("app/db", "app/api"))
+		suite.Require().NoError(err)
+		leased, err := repo.LeaseSyncJobs(suite.ctx, "worker-a", 1, time.Hour, 5)
+		suite.Require().NoError(err)
+		finishedAt := time.Now().Add(-2 * time.Hour)
+		leased[0].Status = models.QueuedJobCompleted
+		leased[0].FinishedAt = &finishedAt
+		suite.Require().NoError(repo.FinishSyncJobs(suite.ctx, "worker-a", leased))
+
+		deleted, err := repo.PruneSyncJobs(suite.ctx, time.Now().Add(-time.Hour))
+
+		suite.Require().NoError(err)
+		suite.Equal(int64(1), deleted)
+	})
+}
+
+func (suite *SyncedSecretRepositoryTestSuite) TestSyncCheckpoints() {
+	runID := int64(42)
+	startedAt := time.Now().UTC().Truncate(time.Microsecond)
+	newCheckpoint := func(instanceID string) *models.SyncCheckpoint {
+		return &models.SyncCheckpoint{InstanceID: instanceID, RunID: &runID, StartedAt: startedAt, Incremental: true}
+	}
+
+	suite.Run("returns nil without a checkpoint", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_checkpoints CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+
+		checkpoint, err := repo.GetSyncCheckpoint(suite.ctx, "instance-a")
+
+		suite.Require().NoError(err)
+		suite.Nil(checkpoint)
+	})
+
+	suite.Run("keeps the processed paths of the checkpoint", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_checkpoints CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+		suite.Require().NoError(repo.StartSyncCheckpoint(suite.ctx, newCheckpoint("instance-a")))
+		suite.Require().NoError(repo.StartSyncCheckpoint(suite.ctx, newCheckpoint("instance-b")))
+
+		suite.Require().NoError(repo.AddCheckpointPaths(suite.ctx, "instance-a", []models.CheckpointPath{
+			{SecretBackend: "kv", SecretPath: "app/db"},
+			{SecretBackend: "kv", SecretPath: "app/api"},
+		}))
+		suite.Require().NoError(repo.AddCheckpointPaths(suite.ctx, "instance-a", []models.CheckpointPath{
+			{SecretBackend: "kv", SecretPath: "app/db"},
+		}))
+
+		checkpoint, err := repo.GetSyncCheckpoint(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.Require().NotNil(checkpoint)
+		suite.Equal(runID, *checkpoint.RunID)
+		suite.True(startedAt.Equal(checkpoint.StartedAt))
+		suite.True(checkpoint.Incremental)
+		suite.ElementsMatch([]models.CheckpointPath{
+			{SecretBackend: "kv", SecretPath: "app/db"},
+			{SecretBackend: "kv", SecretPath: "app/api"},
+		}, checkpoint.Paths)
+
+		other, err := repo.GetSyncCheckpoint(suite.ctx, "instance-b")
+		suite.Require().NoError(err)
+		suite.Empty(other.Paths)
+	})
+
+	suite.Run("starting a checkpoint drops the previous one", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_checkpoints CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+		suite.Require().NoError(repo.StartSyncCheckpoint(suite.ctx, newCheckpoint("instance-a")))
+		suite.Require().NoError(repo.AddCheckpointPaths(suite.ctx, "instance-a", []models.CheckpointPath{
+			{SecretBackend: "kv", SecretPath: "app/db"},
+		}))
+
+		next := newCheckpoint("instance-a")
+		next.Incremental = false
+		suite.Require().NoError(repo.StartSyncCheckpoint(suite.ctx, next))
+
+		checkpoint, err := repo.GetSyncCheckpoint(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.Require().NotNil(checkpoint)
+		suite.False(checkpoint.Incremental)
+		suite.Empty(checkpoint.Paths)
+	})
+
+	suite.Run("deletes the checkpoint", func() {
+		suite.pgHelper.ExecutePsqlCommand(context.Background(), "TRUNCATE TABLE sync_checkpoints CASCADE")
+		repo := NewSyncedSecretRepository(suite.db)
+		suite.Require().NoError(repo.StartSyncCheckpoint(suite.ctx, newCheckpoint("instance-a")))
+
+		suite.Require().NoError(repo.DeleteSyncCheckpoint(suite.ctx, "instance-a"))
+
+		checkpoint, err := repo.GetSyncCheckpoint(suite.ctx, "instance-a")
+		suite.Require().NoError(err)
+		suite.Nil(checkpoint)
+	})
+}
+
 func (suite *SyncedSecretRepositoryTestSuite) TestFailureWithCircuitBreakerAndRetry() {
 
 	type testCases struct {
@@ -493,14 +1275,14 @@ func (suite *SyncedSecretRepositoryTestSuite) TestFailureWithCircuitBreakerAndRe
 				suite.insertTestSecret(secret)
 			},
 			functionToExecute: func(repo *SyncedSecretRepository) (any, error) {
-				return repo.GetSyncedSecret(secret.SecretBackend, secret.SecretPath, secret.DestinationCluster)
+				return repo.GetSyncedSecret(suite.ctx, secret.SecretBackend, secret.SecretPath, secret.DestinationCluster)
 			},
 		},
 		{
 			name:            "GetSyncedSecrets",
 			prepareTestFunc: func(repo *SyncedSecretRepository) {},
 			functionToExecute: func(repo *SyncedSecretRepository) (any, error) {
-				return repo.GetSyncedSecrets()
+				return repo.GetSyncedSecrets(suite.ctx)
 			},
 		},
 		{
@@ -509,7 +1291,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestFailureWithCircuitBreakerAndRe
 				suite.insertTestSecret(secret)
 			},
 			functionToExecute: func(repo *SyncedSecretRepository) (any, error) {
-				return nil, repo.UpdateSyncedSecretStatus(secret)
+				return nil, repo.UpdateSyncedSecretStatus(suite.ctx, secret)
 			},
 		},
 		{
@@ -518,7 +1300,7 @@ func (suite *SyncedSecretRepositoryTestSuite) TestFailureWithCircuitBreakerAndRe
 				suite.insertTestSecret(secret)
 			},
 			functionToExecute: func(repo *SyncedSecretRepository) (any, error) {
-				return nil, repo.DeleteSyncedSecret(secret.SecretBackend, secret.SecretPath, secret.DestinationCluster)
+				return nil, repo.DeleteSyncedSecret(suite.ctx, secret.SecretBackend, secret.SecretPath, secret.DestinationCluster)
 			},
 		},
 	}
@@ -611,6 +1393,52 @@ func (suite *SyncedSecretRepositoryTestSuite) TestFailureWithCircuitBreakerAndRe
 		}
 	})
 
+	suite.Run("stops retrying once the context is done", func() {
+		repo := &SyncedSecretRepository{
+			psql:           suite.db,
+			circuitBreaker: createFastFailCircuitBreaker(),
+			retryOptFunc:   newBackoffStrategy,
+		}
+		suite.pgHelper.Stop(context.Background(), nil)
+		ctx, cancel := context.WithTimeout(suite.ctx, time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err := repo.GetSyncedSecrets(ctx)
+
+		suite.ErrorIs(err, context.DeadlineExceeded)
+		suite.Less(time.Since(start), 10*time.Second, "Expected retries to stop with the context")
+	})
+
+	suite.Run("bounds every attempt by the query timeout", func() {
+		repo := &SyncedSecretRepository{
+			psql:           suite.db,
+			circuitBreaker: createFastFailCircuitBreaker(),
+			retryOptFunc:   createConstantBackoffRetryFunc(100*time.Millisecond, 1),
+			queryTimeout:   time.Nanosecond,
+		}
+
+		_, err := repo.GetSyncedSecrets(suite.ctx)
+
+		suite.ErrorIs(err, repository.ErrDatabaseGeneric)
+		suite.ErrorIs(err, context.DeadlineExceeded)
+	})
+
+	suite.Run("cancelled operations do not open the circuit breaker", func() {
+		repo := NewSyncedSecretRepository(suite.db)
+		ctx, cancel := context.WithCancel(suite.ctx)
+		cancel()
+
+		for range 10 {
+			_, err := repo.GetSyncedSecrets(ctx)
+			suite.ErrorIs(err, context.Canceled)
+		}
+
+		suite.Equal(gobreaker.StateClosed, repo.circuitBreaker.State())
+		_, err := repo.GetSyncedSecrets(suite.ctx)
+		suite.NoError(err)
+	})
+
 	suite.Run("circuit breaker affects calls to other functions", func() {
 		repo := &SyncedSecretRepository{
 			psql:           suite.db,
@@ -619,18 +1447,18 @@ func (suite *SyncedSecretRepositoryTestSuite) TestFailureWithCircuitBreakerAndRe
 		}
 		suite.pgHelper.Stop(context.Background(), nil)
 
-		_, retryError := repo.GetSyncedSecret("kv", "test", "prod")
-		_, retryError2 := repo.GetSyncedSecret("kv", "test", "prod")
+		_, retryError := repo.GetSyncedSecret(suite.ctx, "kv", "test", "prod")
+		_, retryError2 := repo.GetSyncedSecret(suite.ctx, "kv", "test", "prod")
 		suite.ErrorIs(retryError, repository.ErrDatabaseGeneric, "Expected ErrDatabaseGeneric error")
 		suite.ErrorIs(retryError2, repository.ErrDatabaseGeneric, "Expected ErrDatabaseGeneric error")
 
-		_, circuitError := repo.GetSyncedSecrets()
+		_, circuitError := repo.GetSyncedSecrets(suite.ctx)
 		suite.ErrorIs(circuitError, repository.ErrDatabaseUnavailable, "Expected ErrDatabaseUnavailable error")
 
-		circuitError = repo.UpdateSyncedSecretStatus(secret)
+		circuitError = repo.UpdateSyncedSecretStatus(suite.ctx, secret)
 		suite.ErrorIs(circuitError, repository.ErrDatabaseUnavailable, "Expected ErrDatabaseUnavailable error")
 
-		circuitError = repo.DeleteSyncedSecret("kv", "test", "prod")
+		circuitError = repo.DeleteSyncedSecret(suite.ctx, "kv", "test", "prod")
 		suite.ErrorIs(circuitError, repository.ErrDatabaseUnavailable, "Expected ErrDatabaseUnavailable error")
 	})
 }
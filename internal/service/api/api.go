@@ -0,0 +1,383 @@
+// Package api serves the control API of the daemon. Tooling can trigger a full sync or a sync of given paths,
+// read the current and last run, cancel the current run, and query the stored sync state of secrets.
+//
+// Triggered runs go through the daemon, so they never overlap with scheduled runs, and run the same code as the
+// CLI: a full sync like `vault-sync sync once`, a sync of paths like `vault-sync retry <paths>`. The sync state
+// is read like `vault-sync status` and `vault-sync history`, with the same filters and formats.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/daemon"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/service/syncstate"
+	"vault-sync/pkg/log"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxRequestBodySize  = 1 << 20
+)
+
+// Scheduler starts and cancels runs. It is implemented by the daemon.
+type Scheduler interface {
+	Trigger(paths []pathmatching.SecretPath) error
+	CancelRun() bool
+	CurrentRun() *daemon.RunSummary
+	LastRun() *daemon.RunSummary
+}
+
+// Inspector reads the stored sync state. It is implemented by the syncstate inspector.
+type Inspector interface {
+	Status(ctx context.Context, filter syncstate.StatusFilter, now time.Time) ([]syncstate.StatusEntry, error)
+	History(ctx context.Context, secretPath string, limit int) ([]syncstate.HistoryEntry, error)
+}
+
+// TriggerRequest is the body of POST /api/v1/runs. Without paths, a full sync is run.
+type TriggerRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// TriggerResponse is the answer to POST /api/v1/runs.
+type TriggerResponse struct {
+	Status string   `json:"status"`
+	Kind   string   `json:"kind"`
+	Paths  []string `json:"paths,omitempty"`
+}
+
+// RunResponse describes a run. FinishedAt and Result are left out while the run is in progress.
+type RunResponse struct {
+	Kind            string          `json:"kind"`
+	Paths           []string        `json:"paths,omitempty"`
+	Triggered       bool            `json:"triggered"`
+	Status          string          `json:"status"`
+	StartedAt       time.Time       `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Error           string          `json:"error,omitempty"`
+	Result          *ResultResponse `json:"result,omitempty"`
+}
+
+// ResultResponse is the result of a finished run.
+type ResultResponse struct {
+	TotalSecrets      int              `json:"total_secrets"`
+	SuccessfulSyncs   int              `json:"successful_syncs"`
+	FailedSyncs       int              `json:"failed_syncs"`
+	SkippedSecrets    int              `json:"skipped_secrets"`
+	NoOpSecrets       int              `json:"noop_secrets"`
+	DiscoveryFailures int              `json:"discovery_failures"`
+	FailedMounts      []string         `json:"failed_mounts,omitempty"`
+	Incremental       bool             `json:"incremental"`
+	VaultRequests     map[string]int64 `json:"vault_requests,omitempty"`
+	FailedSecrets     []FailedSecret   `json:"failed_secrets,omitempty"`
+}
+
+// FailedSecret is a secret whose job failed in a run.
+type FailedSecret struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// ErrorResponse is the body of every failed request.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	scheduler Scheduler
+	inspector Inspector
+	now       func() time.Time
+	logger    zerolog.Logger
+}
+
+// NewHandler returns the routes of the control API. When token is set, every request has to carry it as a
+// bearer token; client certificates are checked by the TLS listener.
+func NewHandler(scheduler Scheduler, inspector Inspector, token string) http.Handler {
+	h := &handler{
+		scheduler: scheduler,
+		inspector: inspector,
+		now:       time.Now,
+		logger:    log.Logger.With().Str("component", "api").Logger(),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/runs", h.triggerRun)
+	mux.HandleFunc("GET /api/v1/runs/current", h.currentRun)
+	mux.HandleFunc("DELETE /api/v1/runs/current", h.cancelRun)
+	mux.HandleFunc("GET /api/v1/runs/last", h.lastRun)
+	mux.HandleFunc("GET /api/v1/secrets", h.status)
+	mux.HandleFunc("GET /api/v1/history/{secret...}", h.history)
+	if token == "" {
+		return mux
+	}
+	return requireToken(token, mux)
+}
+
+// requireToken rejects requests without the bearer token. The token is compared in constant time.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "missing or invalid bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *handler) triggerRun(w http.ResponseWriter, r *http.Request) {
+	var request TriggerRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+		return
+	}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+	paths, err := pathmatching.ParseSecretPaths(request.Paths)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = h.scheduler.Trigger(paths); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	response := TriggerResponse{Status: "queued", Kind: daemon.RunKindSync}
+	if len(paths) > 0 {
+		response.Kind = daemon.RunKindPaths
+		response.Paths = pathStrings(paths)
+	}
+	h.logger.Info().Str("remote_addr", r.RemoteAddr).Str("run", response.Kind).Strs("paths", response.Paths).
+		Msg("Run triggered")
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+func (h *handler) currentRun(w http.ResponseWriter, _ *http.Request) {
+	h.writeRun(w, h.scheduler.CurrentRun(), "no run in progress")
+}
+
+func (h *handler) lastRun(w http.ResponseWriter, _ *http.Request) {
+	h.writeRun(w, h.scheduler.LastRun(), "no run finished yet")
+}
+
+func (h *handler) cancelRun(w http.ResponseWriter, r *http.Request) {
+	if !h.scheduler.CancelRun() {
+		writeError(w, http.StatusNotFound, errors.New("no run in progress"))
+		return
+	}
+	h.logger.Info().Str("remote_addr", r.RemoteAddr).Msg("Run cancelled")
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "cancelling"})
+}
+
+// status serves the sync state like `vault-sync status`. The query parameters are the flags of the command;
+// status and error_category take comma-separated lists.
+func (h *handler) status(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	filter := syncstate.StatusFilter{
+		Mount:    query.Get("mount"),
+		PathGlob: query.Get("path"),
+		Cluster:  query.Get("cluster"),
+	}
+	for _, status := range splitList(query.Get("status")) {
+		filter.Statuses = append(filter.Statuses, models.SyncStatus(status))
+	}
+	for _, category := range splitList(query.Get("error_category")) {
+		filter.ErrorCategories = append(filter.ErrorCategories, models.ErrorCategory(category))
+	}
+	if value := query.Get("last_success_older_than"); value != "" {
+		olderThan, err := time.ParseDuration(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid last_success_older_than: %w", err))
+			return
+		}
+		filter.LastSuccessOlderThan = olderThan
+	}
+	if err := filter.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, err := h.inspector.Status(r.Context(), filter, h.now())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to read sync status")
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeReport(w, format, func(out io.Writer) error { return syncstate.WriteStatus(out, format, entries) })
+}
+
+// history serves the recent sync attempts of a secret like `vault-sync history`.
+func (h *handler) history(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseFormat(w, r)
+	if !ok {
+		return
+	}
+	secret := r.PathValue("secret")
+	if _, err := pathmatching.ParseSecretPath(secret); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := defaultHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q (must be a positive number)", value))
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.inspector.History(r.Context(), secret, limit)
+	if err != nil {
+		h.logger.Error().Err(err).Str("secret", secret).Msg("Failed to read sync history")
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeReport(w, format, func(out io.Writer) error { return syncstate.WriteHistory(out, format, entries) })
+}
+
+func (h *handler) writeRun(w http.ResponseWriter, run *daemon.RunSummary, notFound string) {
+	if run == nil {
+		writeError(w, http.StatusNotFound, errors.New(notFound))
+		return
+	}
+	writeJSON(w, http.StatusOK, newRunResponse(run, h.now()))
+}
+
+// newRunResponse returns the response for run. The duration of a run in progress is the time it has been
+// running so far.
+func newRunResponse(run *daemon.RunSummary, now time.Time) RunResponse {
+	response := RunResponse{
+		Kind:      run.Kind,
+		Paths:     pathStrings(run.Paths),
+		Triggered: run.Triggered,
+		Status:    run.Status.String(),
+		StartedAt: run.StartedAt,
+		Result:    newResultResponse(run.Result),
+	}
+	end := now
+	if !run.FinishedAt.IsZero() {
+		finishedAt := run.FinishedAt
+		response.FinishedAt = &finishedAt
+		end = finishedAt
+	}
+	response.DurationSeconds = end.Sub(run.StartedAt).Seconds()
+	if run.Err != nil {
+		response.Error = run.Err.Error()
+	}
+	return response
+}
+
+func newResultResponse(result *orchestrator.SyncResult) *ResultResponse {
+	if result == nil {
+		return nil
+	}
+	response := &ResultResponse{
+		TotalSecrets:      result.TotalSecrets,
+		SuccessfulSyncs:   result.SuccessfulSyncs,
+		FailedSyncs:       result.FailedSyncs,
+		SkippedSecrets:    result.SkippedSecrets,
+		NoOpSecrets:       result.NoOpSecrets,
+		DiscoveryFailures: result.DiscoveryFailures,
+		FailedMounts:      result.FailedMounts,
+		Incremental:       result.Incremental,
+		VaultRequests:     result.VaultRequests,
+	}
+	for _, jobResult := range result.JobResults {
+		if jobResult == nil || jobResult.Error == nil {
+			continue
+		}
+		response.FailedSecrets = append(response.FailedSecrets, FailedSecret{
+			Path:  pathmatching.SecretPath{Mount: jobResult.Mount, KeyPath: jobResult.KeyPath}.String(),
+			Error: jobResult.Error.Error(),
+		})
+	}
+	return response
+}
+
+// parseFormat reads the format query parameter, json by default, and answers with an error when it is invalid.
+func parseFormat(w http.ResponseWriter, r *http.Request) (syncstate.Format, bool) {
+	value := r.URL.Query().Get("format")
+	if value == "" {
+		return syncstate.FormatJSON, true
+	}
+	format, err := syncstate.ParseFormat(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	return format, true
+}
+
+// writeReport writes a report of the syncstate package in format. The report is written to the response as it
+// is rendered; an error is only returned to the client before anything was written.
+func writeReport(w http.ResponseWriter, format syncstate.Format, write func(out io.Writer) error) {
+	switch format {
+	case syncstate.FormatCSV:
+		w.Header().Set("Content-Type", "text/csv")
+	case syncstate.FormatTable:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if err := write(w); err != nil {
+		log.Logger.Warn().Err(err).Str("component", "api").Msg("Failed to write report")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Logger.Warn().Err(err).Str("component", "api").Msg("Failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, ErrorResponse{Error: err.Error()})
+}
+
+func splitList(value string) []string {
+	var values []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func pathStrings(paths []pathmatching.SecretPath) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(paths))
+	for _, path := range paths {
+		values = append(values, path.String())
+	}
+	return values
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/daemon"
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/service/syncstate"
+)
+
+type fakeScheduler struct {
+	triggered  [][]pathmatching.SecretPath
+	triggerErr error
+	cancelled  bool
+	current    *daemon.RunSummary
+	last       *daemon.RunSummary
+}
+
+func (s *fakeScheduler) Trigger(paths []pathmatching.SecretPath) error {
+	if s.triggerErr != nil {
+		return s.triggerErr
+	}
+	s.triggered = append(s.triggered, paths)
+	return nil
+}
+
+func (s *fakeScheduler) CancelRun() bool {
+	if s.current == nil {
+		return false
+	}
+	s.cancelled = true
+	return true
+}
+
+func (s *fakeScheduler) CurrentRun() *daemon.RunSummary { return s.current }
+
+func (s *fakeScheduler) LastRun() *daemon.RunSummary { return s.last }
+
+type fakeInspector struct {
+	filter       syncstate.StatusFilter
+	entries      []syncstate.StatusEntry
+	historyPath  string
+	historyLimit int
+	history      []syncstate.HistoryEntry
+	err          error
+}
+
+func (i *fakeInspector) Status(
+	_ context.Context,
+	filter syncstate.StatusFilter,
+	_ time.Time,
+) ([]syncstate.StatusEntry, error) {
+	i.filter = filter
+	return i.entries, i.err
+}
+
+func (i *fakeInspector) History(_ context.Context, secretPath string, limit int) ([]syncstate.HistoryEntry, error) {
+	i.historyPath = secretPath
+	i.historyLimit = limit
+	return i.history, i.err
+}
+
+type APITestSuite struct {
+	suite.Suite
+	now       time.Time
+	scheduler *fakeScheduler
+	inspector *fakeInspector
+	handler   http.Handler
+}
+
+func TestAPISuite(t *testing.T) {
+	suite.Run(t, new(APITestSuite))
+}
+
+func (suite *APITestSuite) SetupTest() {
+	suite.now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	suite.scheduler = &fakeScheduler{}
+	suite.inspector = &fakeInspector{}
+	suite.handler = NewHandler(suite.scheduler, suite.inspector, "")
+}
+
+func (suite *APITestSuite) request(method, target, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	suite.handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func (suite *APITestSuite) decode(recorder *httptest.ResponseRecorder, target any) {
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), target), recorder.Body.String())
+}
+
+func (suite *APITestSuite) TestToken() {
+	suite.handler = NewHandler(suite.scheduler, suite.inspector, "s3cret")
+
+	for _, header := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/runs/last", nil)
+		request.Header.Set("Authorization", header)
+		recorder := httptest.NewRecorder()
+		suite.handler.ServeHTTP(recorder, request)
+		suite.Equal(http.StatusUnauthorized, recorder.Code, header)
+		suite.Equal("Bearer", recorder.Header().Get("WWW-Authenticate"))
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/runs/last", nil)
+	request.Header.Set("Authorization", "Bearer s3cret")
+	recorder := httptest.NewRecorder()
+	suite.handler.ServeHTTP(recorder, request)
+	suite.Equal(http.StatusNotFound, recorder.Code, "authenticated, but no run finished yet")
+}
+
+func (suite *APITestSuite) TestTriggerRun_FullSync() {
+	recorder := suite.request(http.MethodPost, "/api/v1/runs", "")
+
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	var response TriggerResponse
+	suite.decode(recorder, &response)
+	suite.Equal(TriggerResponse{Status: "queued", Kind: daemon.RunKindSync}, response)
+	suite.Equal([][]pathmatching.SecretPath{{}}, suite.scheduler.triggered)
+}
+
+func (suite *APITestSuite) TestTriggerRun_Paths() {
+	recorder := suite.request(http.MethodPost, "/api/v1/runs", `{"paths": ["production/app/database", "/team-a/cache/"]}`)
+
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	var response TriggerResponse
+	suite.decode(recorder, &response)
+	suite.Equal(daemon.RunKindPaths, response.Kind)
+	suite.Equal([]string{"production/app/database", "team-a/cache"}, response.Paths)
+	suite.Equal([][]pathmatching.SecretPath{{
+		{Mount: "production", KeyPath: "app/database"},
+		{Mount: "team-a", KeyPath: "cache"},
+	}}, suite.scheduler.triggered)
+}
+
+func (suite *APITestSuite) TestTriggerRun_Rejected() {
+	testCases := []struct {
+		name       string
+		body       string
+		triggerErr error
+		code       int
+		err        string
+	}{
+		{name: "invalid json", body: `{"paths":`, code: http.StatusBadRequest, err: "invalid request body"},
+		{name: "invalid path", body: `{"paths": ["production"]}`, code: http.StatusBadRequest, err: "invalid secret path"},
+		{name: "already queued", triggerErr: daemon.ErrRunQueued, code: http.StatusConflict, err: "already queued"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			suite.SetupTest()
+			suite.scheduler.triggerErr = tc.triggerErr
+
+			recorder := suite.request(http.MethodPost, "/api/v1/runs", tc.body)
+
+			suite.Equal(tc.code, recorder.Code)
+			var response ErrorResponse
+			suite.decode(recorder, &response)
+			suite.Contains(response.Error, tc.err)
+			suite.Empty(suite.scheduler.triggered)
+		})
+	}
+}
+
+func (suite *APITestSuite) TestRuns() {
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodGet, "/api/v1/runs/current", "").Code)
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodGet, "/api/v1/runs/last", "").Code)
+
+	suite.scheduler.current = &daemon.RunSummary{
+		Kind:      daemon.RunKindPaths,
+		Paths:     []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}},
+		Triggered: true,
+		Status:    models.SyncRunRunning,
+		StartedAt: time.Now().Add(-time.Minute),
+	}
+	suite.scheduler.last = &daemon.RunSummary{
+		Kind:       daemon.RunKindSync,
+		Status:     models.SyncRunFailed,
+		StartedAt:  suite.now.Add(-2 * time.Minute),
+		FinishedAt: suite.now.Add(-time.Minute),
+		Result: &orchestrator.SyncResult{
+			TotalSecrets:    3,
+			SuccessfulSyncs: 2,
+			FailedSyncs:     1,
+			VaultRequests:   map[string]int64{"main": 7},
+			JobResults: []*job.SyncJobResult{
+				{Mount: "production", KeyPath: "app/database"},
+				{Mount: "production", KeyPath: "app/cache", Error: errors.New("permission denied")},
+			},
+		},
+		Err: errors.New("1 secret failed"),
+	}
+
+	recorder := suite.request(http.MethodGet, "/api/v1/runs/current", "")
+	suite.Equal(http.StatusOK, recorder.Code)
+	var current RunResponse
+	suite.decode(recorder, &current)
+	suite.Equal(daemon.RunKindPaths, current.Kind)
+	suite.Equal([]string{"production/app/database"}, current.Paths)
+	suite.True(current.Triggered)
+	suite.Equal("running", current.Status)
+	suite.Nil(current.FinishedAt)
+	suite.Nil(current.Result)
+	suite.Positive(current.DurationSeconds)
+
+	recorder = suite.request(http.MethodGet, "/api/v1/runs/last", "")
+	suite.Equal(http.StatusOK, recorder.Code)
+	var last RunResponse
+	suite.decode(recorder, &last)
+	suite.Equal("failed", last.Status)
+	suite.Equal("1 secret failed", last.Error)
+	suite.InDelta(60, last.DurationSeconds, 0)
+	suite.Equal(&ResultResponse{
+		TotalSecrets:    3,
+		SuccessfulSyncs: 2,
+		FailedSyncs:     1,
+		VaultRequests:   map[string]int64{"main": 7},
+		FailedSecrets:   []FailedSecret{{Path: "production/app/cache", Error: "permission denied"}},
+	}, last.Result)
+}
+
+func (suite *APITestSuite) TestCancelRun() {
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodDelete, "/api/v1/runs/current", "").Code)
+	suite.False(suite.scheduler.cancelled)
+
+	suite.scheduler.current = &daemon.RunSummary{Kind: daemon.RunKindSync, Status: models.SyncRunRunning}
+	recorder := suite.request(http.MethodDelete, "/api/v1/runs/current", "")
+
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	suite.True(suite.scheduler.cancelled)
+}
+
+func (suite *APITestSuite) TestStatus() {
+	suite.inspector.entries = []syncstate.StatusEntry{
+		{Mount: "production", Path: "app/database", Cluster: "dr-site", Status: "failed"},
+	}
+
+	recorder := suite.request(http.MethodGet,
+		"/api/v1/secrets?mount=production&path=app/**&cluster=dr-site&status=failed,error_deleting"+
+			"&error_category=permission_denied&last_success_older_than=24h", "")
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("application/json", recorder.Header().Get("Content-Type"))
+	suite.Equal(syncstate.StatusFilter{
+		Mount:                "production",
+		PathGlob:             "app/**",
+		Cluster:              "dr-site",
+		Statuses:             []models.SyncStatus{models.StatusFailed, models.StatusErrorDeleting},
+		ErrorCategories:      []models.ErrorCategory{models.ErrorCategoryPermissionDenied},
+		LastSuccessOlderThan: 24 * time.Hour,
+	}, suite.inspector.filter)
+	var entries []syncstate.StatusEntry
+	suite.decode(recorder, &entries)
+	suite.Equal(suite.inspector.entries, entries)
+}
+
+func (suite *APITestSuite) TestStatus_CSV() {
+	suite.inspector.entries = []syncstate.StatusEntry{{Mount: "production", Path: "app/database", Status: "success"}}
+
+	recorder := suite.request(http.MethodGet, "/api/v1/secrets?format=csv", "")
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("text/csv", recorder.Header().Get("Content-Type"))
+	suite.True(strings.HasPrefix(recorder.Body.String(), "MOUNT,PATH,CLUSTER,STATUS"))
+}
+
+func (suite *APITestSuite) TestStatus_Rejected() {
+	for _, query := range []string{
+		"format=xml",
+		"status=broken",
+		"error_category=unknown_category",
+		"last_success_older_than=yesterday",
+		"path=[",
+	} {
+		recorder := suite.request(http.MethodGet, "/api/v1/secrets?"+query, "")
+		suite.Equal(http.StatusBadRequest, recorder.Code, query)
+	}
+
+	suite.inspector.err = errors.New("database is unavailable")
+	suite.Equal(http.StatusInternalServerError, suite.request(http.MethodGet, "/api/v1/secrets", "").Code)
+}
+
+func (suite *APITestSuite) TestHistory() {
+	suite.inspector.history = []syncstate.HistoryEntry{{RunID: 4, Cluster: "dr-site", Action: "updated"}}
+
+	recorder := suite.request(http.MethodGet, "/api/v1/history/production/app/database?limit=5", "")
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("production/app/database", suite.inspector.historyPath)
+	suite.Equal(5, suite.inspector.historyLimit)
+	var entries []syncstate.HistoryEntry
+	suite.decode(recorder, &entries)
+	suite.Equal(suite.inspector.history, entries)
+
+	suite.request(http.MethodGet, "/api/v1/history/production/app/database", "")
+	suite.Equal(defaultHistoryLimit, suite.inspector.historyLimit)
+
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/api/v1/history/production", "").Code)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/api/v1/history/a/b?limit=0", "").Code)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"vault-sync/internal/config"
+)
+
+// TLSConfig returns the TLS config of the API, or nil to serve plain HTTP when no certificate is set. With a
+// client CA, every client has to present a certificate signed by it.
+//
+//nolint:nilnil
+func TLSConfig(cfg *config.APITLS) (*tls.Config, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caBundle, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caBundle) {
+		return nil, errors.New("failed to read API client CA: no PEM certificates found")
+	}
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vault-sync/internal/config"
+	"vault-sync/internal/service/daemon"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPEM     []byte
+	keyPEM      []byte
+}
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCertificate, parentKey := template, key
+	if parent != nil {
+		parentCertificate, parentKey = parent.certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCertificate, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestTLSConfig_WithoutCertificate(t *testing.T) {
+	tlsConfig, err := TLSConfig(&config.APITLS{})
+
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestTLSConfig_InvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "vault-sync test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	certFile := writeFile(t, dir, "server.crt", ca.certPEM)
+	keyFile := writeFile(t, dir, "server.key", ca.keyPEM)
+
+	_, err := TLSConfig(&config.APITLS{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")})
+	require.ErrorContains(t, err, "failed to load API certificate")
+
+	_, err = TLSConfig(&config.APITLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile})
+	require.ErrorContains(t, err, "no PEM certificates found")
+}
+
+func TestTLSConfig_RequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "vault-sync test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	server := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "vault-sync"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	client := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "ci"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	tlsConfig, err := TLSConfig(&config.APITLS{
+		CertFile:     writeFile(t, dir, "server.crt", server.certPEM),
+		KeyFile:      writeFile(t, dir, "server.key", server.keyPEM),
+		ClientCAFile: writeFile(t, dir, "ca.crt", ca.certPEM),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	listener, err := daemon.ListenTLS("127.0.0.1:0", NewHandler(&fakeScheduler{}, &fakeInspector{}, ""), tlsConfig)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- listener.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-serveDone)
+	})
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.certificate)
+	get := func(certificates []tls.Certificate) (*http.Response, error) {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      rootCAs,
+			Certificates: certificates,
+			MinVersion:   tls.VersionTLS12,
+		}}}
+		defer httpClient.CloseIdleConnections()
+		request, requestErr := http.NewRequestWithContext(
+			context.Background(), http.MethodGet, "https://"+listener.Addr()+"/api/v1/runs/last", nil)
+		require.NoError(t, requestErr)
+		return httpClient.Do(request)
+	}
+
+	_, err = get(nil)
+	require.Error(t, err, "a client without a certificate is rejected")
+
+	clientCertificate, err := tls.X509KeyPair(client.certPEM, client.keyPEM)
+	require.NoError(t, err)
+	response, err := get([]tls.Certificate{clientCertificate})
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
@@ -0,0 +1,575 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/pkg/log"
+)
+
+// Runner runs full syncs, retries of the due failed replicas, syncs of given paths and of the paths leased from the
+// work queue. It is implemented by the orchestrator.
+type Runner interface {
+	StartSync(ctx context.Context) (*orchestrator.SyncResult, error)
+	RetryFailed(ctx context.Context) (*orchestrator.SyncResult, error)
+	RetryQuarantined(ctx context.Context, paths []pathmatching.SecretPath) (*orchestrator.SyncResult, error)
+	SyncPaths(ctx context.Context, paths []pathmatching.SecretPath) (*orchestrator.SyncResult, error)
+	SyncLeased(ctx context.Context, paths []pathmatching.SecretPath, incremental bool) (*orchestrator.SyncResult, error)
+}
+
+// Kinds of daemon runs.
+const (
+	RunKindSync    = "sync"
+	RunKindRetry   = "retry"
+	RunKindPaths   = "paths"
+	RunKindEvent   = "event"
+	RunKindRequest = "request"
+	RunKindWorker  = "worker"
+)
+
+const (
+	// defaultDebounce is how long the daemon collects enqueued paths before syncing them.
+	defaultDebounce = 2 * time.Second
+	// pathRunConcurrency is how many syncs of enqueued or requested paths run at once next to the scheduled runs.
+	pathRunConcurrency = 2
+)
+
+// ErrRunQueued is returned by Trigger while an earlier triggered run is still waiting to start.
+var ErrRunQueued = errors.New("a triggered run is already queued")
+
+// RunSummary describes a run of the daemon. FinishedAt is zero and Result nil while the run is in progress.
+// Paths are set for runs of given paths, Triggered for runs started through Trigger.
+type RunSummary struct {
+	Kind       string
+	Paths      []pathmatching.SecretPath
+	Triggered  bool
+	Status     models.SyncRunStatus
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Result     *orchestrator.SyncResult
+	Err        error
+}
+
+// runRequest is a run asked for through Trigger; a full sync when paths is empty.
+type runRequest struct {
+	paths []pathmatching.SecretPath
+}
+
+// requestedRun is a sync of paths asked for through SyncLeased, which waits for its outcome on done.
+type requestedRun struct {
+	kind  string
+	paths []pathmatching.SecretPath
+	sync  func(ctx context.Context) (*orchestrator.SyncResult, error)
+	done  chan runOutcome
+}
+
+type runOutcome struct {
+	result *orchestrator.SyncResult
+	err    error
+}
+
+// Daemon runs a full sync every sync interval and, between full syncs, retries the failed replicas that are
+// due every retry interval. These runs never overlap: a tick, a triggered run or leased paths that arrive during a
+// run are handled after it.
+//
+// Enqueued and requested paths are synced in a lane of their own, next to the scheduled runs, so a notification
+// is not held up by a long full sync. At most pathRunConcurrency of these runs go at once, and a path is never
+// synced by two of them at the same time. The runner keeps the runs of the lane and the other runs from syncing
+// the same path at once: the job of a path waits while another run syncs it.
+type Daemon struct {
+	runner        Runner
+	syncInterval  time.Duration
+	retryInterval time.Duration
+	debounce      time.Duration
+	scheduled     bool
+	requests      chan runRequest
+	requestedRuns chan requestedRun
+	logger        zerolog.Logger
+
+	// started is closed once Run started; runCtx is the context of Run.
+	started chan struct{}
+	runCtx  context.Context
+
+	pendingMu     sync.Mutex
+	pending       map[string]pathmatching.SecretPath
+	pendingSignal chan struct{}
+	inFlight      map[string]chan struct{}
+	pathSlots     chan struct{}
+	pathRuns      sync.WaitGroup
+
+	runsMu     sync.Mutex
+	currentRun *RunSummary
+	cancelRun  context.CancelFunc
+	lastRun    *RunSummary
+}
+
+// Option configures optional behaviour of the Daemon.
+type Option func(*Daemon)
+
+// WithDebounce sets how long enqueued paths are collected before they are synced, so a burst of notifications
+// for the same paths results in a single run. Zero keeps the default of 2s.
+func WithDebounce(debounce time.Duration) Option {
+	return func(d *Daemon) {
+		if debounce > 0 {
+			d.debounce = debounce
+		}
+	}
+}
+
+// WithoutSchedule keeps the daemon from running full syncs and retries on its own, e.g. on a worker that only runs
+// the jobs it leases from the work queue. Triggered runs and syncs of given paths still run.
+func WithoutSchedule() Option {
+	return func(d *Daemon) {
+		d.scheduled = false
+	}
+}
+
+// NewDaemon returns a daemon that runs the runner on the given intervals.
+func NewDaemon(runner Runner, syncInterval, retryInterval time.Duration, opts ...Option) *Daemon {
+	d := &Daemon{
+		runner:        runner,
+		syncInterval:  syncInterval,
+		retryInterval: retryInterval,
+		debounce:      defaultDebounce,
+		scheduled:     true,
+		requests:      make(chan runRequest, 1),
+		requestedRuns: make(chan requestedRun),
+		started:       make(chan struct{}),
+		pending:       make(map[string]pathmatching.SecretPath),
+		pendingSignal: make(chan struct{}, 1),
+		inFlight:      make(map[string]chan struct{}),
+		pathSlots:     make(chan struct{}, pathRunConcurrency),
+		logger:        log.Logger.With().Str("component", "daemon").Logger(),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run starts with a full sync, unless the daemon runs without schedule, and keeps running until ctx is done. A
+// failed run is logged and does not stop the daemon. Run returns once the syncs of enqueued paths ended as well.
+func (d *Daemon) Run(ctx context.Context) error {
+	if d.syncInterval <= 0 || d.retryInterval <= 0 {
+		return errors.New("daemon intervals must be greater than zero")
+	}
+
+	d.logger.Info().
+		Dur("sync_interval", d.syncInterval).
+		Dur("retry_interval", d.retryInterval).
+		Bool("scheduled", d.scheduled).
+		Msg("Starting daemon")
+
+	d.runCtx = ctx
+	close(d.started)
+	d.pathRuns.Add(1)
+	go func() {
+		defer d.pathRuns.Done()
+		d.syncEnqueued(ctx)
+	}()
+	defer d.pathRuns.Wait()
+
+	if d.scheduled {
+		d.run(ctx, &RunSummary{Kind: RunKindSync}, d.runner.StartSync)
+	}
+	syncTicker := time.NewTicker(d.syncInterval)
+	defer syncTicker.Stop()
+	retryTicker := time.NewTicker(d.retryInterval)
+	defer retryTicker.Stop()
+	if !d.scheduled {
+		syncTicker.Stop()
+		retryTicker.Stop()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info().Msg("Stopping daemon")
+			return nil
+		case <-syncTicker.C:
+			d.run(ctx, &RunSummary{Kind: RunKindSync}, d.runner.StartSync)
+			retryTicker.Reset(d.retryInterval)
+		case <-retryTicker.C:
+			d.run(ctx, &RunSummary{Kind: RunKindRetry}, d.runner.RetryFailed)
+		case request := <-d.requests:
+			if len(request.paths) == 0 {
+				d.run(ctx, &RunSummary{Kind: RunKindSync, Triggered: true}, d.runner.StartSync)
+				if d.scheduled {
+					syncTicker.Reset(d.syncInterval)
+					retryTicker.Reset(d.retryInterval)
+				}
+				continue
+			}
+			d.run(ctx, &RunSummary{Kind: RunKindPaths, Paths: request.paths, Triggered: true},
+				func(ctx context.Context) (*orchestrator.SyncResult, error) {
+					return d.runner.RetryQuarantined(ctx, request.paths)
+				})
+		case requested := <-d.requestedRuns:
+			outcome := runOutcome{err: ctx.Err()}
+			d.run(ctx, &RunSummary{Kind: requested.kind, Paths: requested.paths},
+				func(ctx context.Context) (*orchestrator.SyncResult, error) {
+					outcome.result, outcome.err = requested.sync(ctx)
+					return outcome.result, outcome.err
+				})
+			requested.done <- outcome
+		}
+	}
+}
+
+// syncEnqueued syncs the pending paths one debounce period after the first of them was enqueued, until ctx is
+// done. While every slot of the lane is taken, the pending paths keep collecting for the next run.
+func (d *Daemon) syncEnqueued(ctx context.Context) {
+	debounceTimer := time.NewTimer(d.debounce)
+	debounceTimer.Stop()
+	defer debounceTimer.Stop()
+	debouncing := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.pendingSignal:
+			if !debouncing {
+				debounceTimer.Reset(d.debounce)
+				debouncing = true
+			}
+		case <-debounceTimer.C:
+			debouncing = false
+			if !d.acquirePathSlot(ctx) {
+				return
+			}
+			paths := d.takePending()
+			if len(paths) == 0 {
+				d.releasePathSlot()
+				continue
+			}
+			d.pathRuns.Add(1)
+			go func() {
+				defer d.pathRuns.Done()
+				defer d.releasePathSlot()
+				defer d.releasePaths(paths)
+				d.runPaths(ctx, &RunSummary{Kind: RunKindEvent, Paths: paths},
+					func(ctx context.Context) (*orchestrator.SyncResult, error) {
+						return d.runner.SyncPaths(ctx, paths)
+					})
+			}()
+		}
+	}
+}
+
+// Enqueue adds paths to the paths to sync and returns how many of them were not pending yet. The pending paths
+// are synced together one debounce period after the first of them was enqueued, so duplicates of a pending path
+// are dropped. A path that is being synced stays pending until that sync is done.
+func (d *Daemon) Enqueue(paths []pathmatching.SecretPath) int {
+	d.pendingMu.Lock()
+	added := 0
+	for _, path := range paths {
+		if _, ok := d.pending[path.String()]; ok {
+			continue
+		}
+		d.pending[path.String()] = path
+		added++
+	}
+	d.pendingMu.Unlock()
+
+	select {
+	case d.pendingSignal <- struct{}{}:
+	default:
+	}
+	return added
+}
+
+// takePending returns the pending paths that are not being synced, sorted, and marks them in flight.
+func (d *Daemon) takePending() []pathmatching.SecretPath {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	paths := make([]pathmatching.SecretPath, 0, len(d.pending))
+	for key, path := range d.pending {
+		if _, inFlight := d.inFlight[key]; inFlight {
+			continue
+		}
+		paths = append(paths, path)
+		d.inFlight[key] = make(chan struct{})
+		delete(d.pending, key)
+	}
+	slices.SortFunc(paths, func(a, b pathmatching.SecretPath) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return paths
+}
+
+// claimPaths marks paths in flight once none of them is being synced any more, or gives up when ctx is done.
+func (d *Daemon) claimPaths(ctx context.Context, paths []pathmatching.SecretPath) error {
+	for {
+		d.pendingMu.Lock()
+		var busy chan struct{}
+		for _, path := range paths {
+			if done, inFlight := d.inFlight[path.String()]; inFlight {
+				busy = done
+				break
+			}
+		}
+		if busy == nil {
+			for _, path := range paths {
+				d.inFlight[path.String()] = make(chan struct{})
+			}
+			d.pendingMu.Unlock()
+			return nil
+		}
+		d.pendingMu.Unlock()
+
+		select {
+		case <-busy:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// releasePaths ends the sync of paths. Paths that were enqueued again in the meantime are synced after another
+// debounce period.
+func (d *Daemon) releasePaths(paths []pathmatching.SecretPath) {
+	d.pendingMu.Lock()
+	for _, path := range paths {
+		if done, inFlight := d.inFlight[path.String()]; inFlight {
+			close(done)
+			delete(d.inFlight, path.String())
+		}
+	}
+	pending := len(d.pending) > 0
+	d.pendingMu.Unlock()
+
+	if pending {
+		select {
+		case d.pendingSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *Daemon) acquirePathSlot(ctx context.Context) bool {
+	select {
+	case d.pathSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (d *Daemon) releasePathSlot() {
+	<-d.pathSlots
+}
+
+// SyncRequested syncs paths like enqueued paths, without debouncing, and returns the result of the run. The run
+// starts once Run started, a slot of the lane is free and no other run of the lane syncs one of the paths, so it
+// does not wait for a scheduled run. SyncRequested gives up waiting, and cancels the run, when ctx is done.
+func (d *Daemon) SyncRequested(
+	ctx context.Context,
+	paths []pathmatching.SecretPath,
+) (*orchestrator.SyncResult, error) {
+	select {
+	case <-d.started:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := d.claimPaths(ctx, paths); err != nil {
+		return nil, err
+	}
+	defer d.releasePaths(paths)
+	if !d.acquirePathSlot(ctx) {
+		return nil, ctx.Err()
+	}
+	defer d.releasePathSlot()
+
+	runCtx, cancel := context.WithCancel(d.runCtx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	outcome := runOutcome{err: runCtx.Err()}
+	d.runPaths(runCtx, &RunSummary{Kind: RunKindRequest, Paths: paths},
+		func(ctx context.Context) (*orchestrator.SyncResult, error) {
+			outcome.result, outcome.err = d.runner.SyncPaths(ctx, paths)
+			return outcome.result, outcome.err
+		})
+	return outcome.result, outcome.err
+}
+
+// SyncLeased hands the jobs of paths leased from the work queue to the run loop and returns the result of the run.
+// The run starts once the scheduled or triggered run in progress, if any, is done. SyncLeased gives up waiting when
+// ctx is done.
+func (d *Daemon) SyncLeased(
+	ctx context.Context,
+	paths []pathmatching.SecretPath,
+	incremental bool,
+) (*orchestrator.SyncResult, error) {
+	return d.runRequested(ctx, RunKindWorker, paths, func(ctx context.Context) (*orchestrator.SyncResult, error) {
+		return d.runner.SyncLeased(ctx, paths, incremental)
+	})
+}
+
+// runRequested hands syncFunc to the run loop and waits for the outcome of the run.
+func (d *Daemon) runRequested(
+	ctx context.Context,
+	kind string,
+	paths []pathmatching.SecretPath,
+	syncFunc func(ctx context.Context) (*orchestrator.SyncResult, error),
+) (*orchestrator.SyncResult, error) {
+	requested := requestedRun{kind: kind, paths: paths, sync: syncFunc, done: make(chan runOutcome, 1)}
+	select {
+	case d.requestedRuns <- requested:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case outcome := <-requested.done:
+		return outcome.result, outcome.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Trigger asks for a full sync, or for a sync of paths regardless of their backoff and quarantine like
+// `vault-sync retry`. The run starts once the current run, if any, is done. A triggered full sync restarts the
+// sync interval.
+func (d *Daemon) Trigger(paths []pathmatching.SecretPath) error {
+	select {
+	case d.requests <- runRequest{paths: paths}:
+		return nil
+	default:
+		return ErrRunQueued
+	}
+}
+
+// CancelRun cancels the context of the run in progress and reports whether there was one. The run stops at the
+// next point that checks its context and is recorded as interrupted. Syncs of enqueued or requested paths are
+// not cancelled.
+func (d *Daemon) CancelRun() bool {
+	d.runsMu.Lock()
+	defer d.runsMu.Unlock()
+	if d.currentRun == nil {
+		return false
+	}
+	d.logger.Info().Str("run", d.currentRun.Kind).Msg("Cancelling run")
+	d.cancelRun()
+	return true
+}
+
+// CurrentRun returns the scheduled, triggered or leased run in progress, or nil between runs. Syncs of enqueued or
+// requested paths are not reported while they run.
+func (d *Daemon) CurrentRun() *RunSummary {
+	d.runsMu.Lock()
+	defer d.runsMu.Unlock()
+	return copyRun(d.currentRun)
+}
+
+// LastRun returns the last finished run of any kind, or nil before the first one finished.
+func (d *Daemon) LastRun() *RunSummary {
+	d.runsMu.Lock()
+	defer d.runsMu.Unlock()
+	return copyRun(d.lastRun)
+}
+
+func (d *Daemon) run(
+	ctx context.Context,
+	run *RunSummary,
+	runFunc func(ctx context.Context) (*orchestrator.SyncResult, error),
+) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	d.startRun(run, cancel)
+	result, err := runFunc(runCtx)
+	d.finishRun(run, result, err, runCtx.Err() != nil)
+	d.logRun(ctx, runCtx, run, result, err)
+}
+
+// runPaths runs runFunc in the lane of enqueued and requested paths. The run is only recorded once it finished.
+func (d *Daemon) runPaths(
+	ctx context.Context,
+	run *RunSummary,
+	runFunc func(ctx context.Context) (*orchestrator.SyncResult, error),
+) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	run.Status = models.SyncRunRunning
+	run.StartedAt = time.Now()
+	result, err := runFunc(ctx)
+	d.finishRun(run, result, err, ctx.Err() != nil)
+	d.logRun(d.runCtx, ctx, run, result, err)
+}
+
+func (d *Daemon) logRun(
+	ctx, runCtx context.Context,
+	run *RunSummary,
+	result *orchestrator.SyncResult,
+	err error,
+) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		d.logger.Info().Str("run", run.Kind).Msg("Run interrupted by shutdown")
+	case err != nil && runCtx.Err() != nil:
+		d.logger.Info().Str("run", run.Kind).Msg("Run cancelled")
+	case err != nil:
+		d.logger.Error().Err(err).Str("run", run.Kind).Msg("Run failed")
+	default:
+		d.logger.Debug().
+			Str("run", run.Kind).
+			Int("total_secrets", result.TotalSecrets).
+			Int("failed_syncs", result.FailedSyncs).
+			Msg("Run completed")
+	}
+}
+
+func (d *Daemon) startRun(run *RunSummary, cancel context.CancelFunc) {
+	d.runsMu.Lock()
+	defer d.runsMu.Unlock()
+	run.Status = models.SyncRunRunning
+	run.StartedAt = time.Now()
+	d.currentRun = run
+	d.cancelRun = cancel
+}
+
+// finishRun records run as the last run and clears it if it is the current run. A run failing because it was
+// cancelled, or because the daemon is stopping, is interrupted.
+func (d *Daemon) finishRun(run *RunSummary, result *orchestrator.SyncResult, err error, cancelled bool) {
+	d.runsMu.Lock()
+	defer d.runsMu.Unlock()
+	run.FinishedAt = time.Now()
+	run.Result = result
+	run.Err = err
+	switch {
+	case err == nil:
+		run.Status = models.SyncRunCompleted
+	case cancelled:
+		run.Status = models.SyncRunInterrupted
+	default:
+		run.Status = models.SyncRunFailed
+	}
+	if d.currentRun == run {
+		d.currentRun = nil
+		d.cancelRun = nil
+	}
+	d.lastRun = run
+}
+
+func copyRun(run *RunSummary) *RunSummary {
+	if run == nil {
+		return nil
+	}
+	runCopy := *run
+	return &runCopy
+}
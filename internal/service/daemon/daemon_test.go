@@ -0,0 +1,455 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeRunner counts the runs and reports whether two of them ever overlapped. Full syncs and syncs of paths wait
+// for blockSyncs and blockPaths to be closed, when set.
+type fakeRunner struct {
+	blockSyncs chan struct{}
+	blockPaths chan struct{}
+
+	mu         sync.Mutex
+	syncs      int
+	retries    int
+	pathRuns   [][]pathmatching.SecretPath
+	eventRuns  [][]pathmatching.SecretPath
+	leasedRuns [][]pathmatching.SecretPath
+	running    bool
+	overlapped bool
+	runTime    time.Duration
+	result     *orchestrator.SyncResult
+	err        error
+}
+
+func (r *fakeRunner) StartSync(ctx context.Context) (*orchestrator.SyncResult, error) {
+	if err := wait(ctx, r.blockSyncs); err != nil {
+		return nil, err
+	}
+	return r.run(ctx, &r.syncs)
+}
+
+func (r *fakeRunner) RetryFailed(ctx context.Context) (*orchestrator.SyncResult, error) {
+	return r.run(ctx, &r.retries)
+}
+
+func (r *fakeRunner) RetryQuarantined(
+	ctx context.Context,
+	paths []pathmatching.SecretPath,
+) (*orchestrator.SyncResult, error) {
+	r.mu.Lock()
+	r.pathRuns = append(r.pathRuns, paths)
+	r.mu.Unlock()
+	var ignored int
+	return r.run(ctx, &ignored)
+}
+
+func (r *fakeRunner) SyncPaths(ctx context.Context, paths []pathmatching.SecretPath) (*orchestrator.SyncResult, error) {
+	r.mu.Lock()
+	r.eventRuns = append(r.eventRuns, paths)
+	r.mu.Unlock()
+	if err := wait(ctx, r.blockPaths); err != nil {
+		return nil, err
+	}
+	var ignored int
+	return r.run(ctx, &ignored)
+}
+
+func (r *fakeRunner) SyncLeased(
+	ctx context.Context,
+	paths []pathmatching.SecretPath,
+	_ bool,
+) (*orchestrator.SyncResult, error) {
+	r.mu.Lock()
+	r.leasedRuns = append(r.leasedRuns, paths)
+	r.mu.Unlock()
+	var ignored int
+	return r.run(ctx, &ignored)
+}
+
+func (r *fakeRunner) run(ctx context.Context, counter *int) (*orchestrator.SyncResult, error) {
+	r.mu.Lock()
+	if r.running {
+		r.overlapped = true
+	}
+	r.running = true
+	*counter++
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+	select {
+	case <-time.After(r.runTime):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if r.result != nil {
+		return r.result, r.err
+	}
+	return &orchestrator.SyncResult{}, r.err
+}
+
+func (r *fakeRunner) eventRunCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.eventRuns)
+}
+
+func wait(ctx context.Context, block chan struct{}) error {
+	if block == nil {
+		return nil
+	}
+	select {
+	case <-block:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *fakeRunner) counts() (int, int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.syncs, r.retries, r.overlapped
+}
+
+type DaemonTestSuite struct {
+	suite.Suite
+}
+
+func TestDaemonSuite(t *testing.T) {
+	suite.Run(t, new(DaemonTestSuite))
+}
+
+func (suite *DaemonTestSuite) runFor(daemon *Daemon, duration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	return daemon.Run(ctx)
+}
+
+func (suite *DaemonTestSuite) TestRun_SyncsImmediately() {
+	runner := &fakeRunner{}
+
+	err := suite.runFor(NewDaemon(runner, time.Hour, time.Hour), 20*time.Millisecond)
+
+	suite.NoError(err)
+	syncs, retries, _ := runner.counts()
+	suite.Equal(1, syncs)
+	suite.Zero(retries)
+}
+
+func (suite *DaemonTestSuite) TestRun_WithoutScheduleOnlyRunsWhatItIsAskedFor() {
+	runner := &fakeRunner{}
+
+	err := suite.runFor(NewDaemon(runner, 10*time.Millisecond, 10*time.Millisecond, WithoutSchedule()),
+		35*time.Millisecond)
+
+	suite.NoError(err)
+	syncs, retries, _ := runner.counts()
+	suite.Zero(syncs)
+	suite.Zero(retries)
+}
+
+func (suite *DaemonTestSuite) TestRun_RetriesBetweenSyncs() {
+	runner := &fakeRunner{}
+
+	err := suite.runFor(NewDaemon(runner, time.Hour, 10*time.Millisecond), 55*time.Millisecond)
+
+	suite.NoError(err)
+	syncs, retries, _ := runner.counts()
+	suite.Equal(1, syncs)
+	suite.GreaterOrEqual(retries, 3)
+}
+
+func (suite *DaemonTestSuite) TestRun_SyncsEveryInterval() {
+	runner := &fakeRunner{}
+
+	err := suite.runFor(NewDaemon(runner, 10*time.Millisecond, time.Hour), 55*time.Millisecond)
+
+	suite.NoError(err)
+	syncs, _, _ := runner.counts()
+	suite.GreaterOrEqual(syncs, 4)
+}
+
+func (suite *DaemonTestSuite) TestRun_KeepsRunningAfterFailures() {
+	runner := &fakeRunner{err: errors.New("vault unavailable")}
+
+	err := suite.runFor(NewDaemon(runner, 10*time.Millisecond, time.Hour), 35*time.Millisecond)
+
+	suite.NoError(err)
+	syncs, _, _ := runner.counts()
+	suite.GreaterOrEqual(syncs, 2)
+}
+
+func (suite *DaemonTestSuite) TestRun_RunsNeverOverlap() {
+	runner := &fakeRunner{runTime: 5 * time.Millisecond}
+
+	err := suite.runFor(NewDaemon(runner, 3*time.Millisecond, 2*time.Millisecond), 60*time.Millisecond)
+
+	suite.NoError(err)
+	_, _, overlapped := runner.counts()
+	suite.False(overlapped)
+}
+
+func (suite *DaemonTestSuite) TestRun_RecordsTheRuns() {
+	runner := &fakeRunner{
+		runTime: 30 * time.Millisecond,
+		result:  &orchestrator.SyncResult{TotalSecrets: 7, FailedSyncs: 2},
+		err:     errors.New("replica unavailable"),
+	}
+	daemon := NewDaemon(runner, time.Hour, time.Hour)
+	suite.Nil(daemon.CurrentRun())
+	suite.Nil(daemon.LastRun())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	suite.Eventually(func() bool { return daemon.CurrentRun() != nil }, time.Second, time.Millisecond)
+	current := daemon.CurrentRun()
+	suite.Equal("sync", current.Kind)
+	suite.Equal(models.SyncRunRunning, current.Status)
+	suite.True(current.FinishedAt.IsZero())
+
+	suite.Eventually(func() bool { return daemon.LastRun() != nil }, time.Second, time.Millisecond)
+	cancel()
+	suite.NoError(<-done)
+
+	last := daemon.LastRun()
+	suite.Nil(daemon.CurrentRun())
+	suite.Equal("sync", last.Kind)
+	suite.Equal(models.SyncRunFailed, last.Status)
+	suite.Equal(7, last.Result.TotalSecrets)
+	suite.Equal(2, last.Result.FailedSyncs)
+	suite.EqualError(last.Err, "replica unavailable")
+	suite.False(last.FinishedAt.Before(last.StartedAt))
+}
+
+// start runs daemon until the test ends and waits for its first run to finish.
+func (suite *DaemonTestSuite) start(daemon *Daemon) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	suite.T().Cleanup(func() {
+		cancel()
+		suite.NoError(<-done)
+	})
+	suite.Eventually(func() bool { return daemon.LastRun() != nil }, time.Second, time.Millisecond)
+}
+
+func (suite *DaemonTestSuite) TestTrigger_RunsTheGivenPaths() {
+	runner := &fakeRunner{}
+	daemon := NewDaemon(runner, time.Hour, time.Hour)
+	suite.start(daemon)
+	paths := []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}}
+
+	suite.Require().NoError(daemon.Trigger(paths))
+
+	suite.Eventually(func() bool { return daemon.LastRun().Kind == RunKindPaths }, time.Second, time.Millisecond)
+	last := daemon.LastRun()
+	suite.True(last.Triggered)
+	suite.Equal(paths, last.Paths)
+	suite.Equal(models.SyncRunCompleted, last.Status)
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	suite.Equal([][]pathmatching.SecretPath{paths}, runner.pathRuns)
+}
+
+func (suite *DaemonTestSuite) TestTrigger_RunsAFullSync() {
+	runner := &fakeRunner{}
+	daemon := NewDaemon(runner, time.Hour, time.Hour)
+	suite.start(daemon)
+
+	suite.Require().NoError(daemon.Trigger(nil))
+
+	suite.Eventually(func() bool { return daemon.LastRun().Triggered }, time.Second, time.Millisecond)
+	suite.Equal(RunKindSync, daemon.LastRun().Kind)
+	syncs, _, _ := runner.counts()
+	suite.Equal(2, syncs)
+}
+
+func (suite *DaemonTestSuite) TestTrigger_QueuesOneRunAtATime() {
+	daemon := NewDaemon(&fakeRunner{}, time.Hour, time.Hour)
+
+	suite.Require().NoError(daemon.Trigger(nil))
+	suite.Require().ErrorIs(daemon.Trigger(nil), ErrRunQueued)
+}
+
+func (suite *DaemonTestSuite) TestEnqueue_SyncsThePathsOnceDebounced() {
+	runner := &fakeRunner{}
+	daemon := NewDaemon(runner, time.Hour, time.Hour, WithDebounce(20*time.Millisecond))
+	suite.start(daemon)
+	database := pathmatching.SecretPath{Mount: "production", KeyPath: "app/database"}
+	cache := pathmatching.SecretPath{Mount: "production", KeyPath: "app/cache"}
+
+	suite.Equal(2, daemon.Enqueue([]pathmatching.SecretPath{database, cache}))
+	suite.Equal(0, daemon.Enqueue([]pathmatching.SecretPath{database}), "a pending path is not queued twice")
+
+	suite.Eventually(func() bool { return daemon.LastRun().Kind == RunKindEvent }, time.Second, time.Millisecond)
+	last := daemon.LastRun()
+	suite.False(last.Triggered)
+	suite.Equal([]pathmatching.SecretPath{cache, database}, last.Paths)
+	runner.mu.Lock()
+	suite.Equal([][]pathmatching.SecretPath{{cache, database}}, runner.eventRuns)
+	runner.mu.Unlock()
+
+	suite.Equal(1, daemon.Enqueue([]pathmatching.SecretPath{database}), "a synced path can be queued again")
+	suite.Eventually(func() bool {
+		runner.mu.Lock()
+		defer runner.mu.Unlock()
+		return len(runner.eventRuns) == 2
+	}, time.Second, time.Millisecond)
+}
+
+func (suite *DaemonTestSuite) TestEnqueue_SyncsWhileAFullRunIsBlocked() {
+	runner := &fakeRunner{blockSyncs: make(chan struct{})}
+	defer close(runner.blockSyncs)
+	daemon := NewDaemon(runner, time.Hour, time.Hour, WithDebounce(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	defer func() {
+		cancel()
+		suite.NoError(<-done)
+	}()
+	suite.Eventually(func() bool { return daemon.CurrentRun() != nil }, time.Second, time.Millisecond)
+
+	daemon.Enqueue([]pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}})
+
+	suite.Eventually(func() bool {
+		last := daemon.LastRun()
+		return last != nil && last.Kind == RunKindEvent && last.Status == models.SyncRunCompleted
+	}, time.Second, time.Millisecond)
+	suite.Equal(RunKindSync, daemon.CurrentRun().Kind, "the full run is still in progress")
+}
+
+func (suite *DaemonTestSuite) TestEnqueue_SyncsAPathInFlightAgainOnceItsSyncIsDone() {
+	runner := &fakeRunner{blockPaths: make(chan struct{})}
+	daemon := NewDaemon(runner, time.Hour, time.Hour, WithDebounce(time.Millisecond))
+	suite.start(daemon)
+	database := []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}}
+
+	daemon.Enqueue(database)
+	suite.Eventually(func() bool { return runner.eventRunCount() == 1 }, time.Second, time.Millisecond)
+	suite.Equal(1, daemon.Enqueue(database), "a path in flight is pending again")
+
+	suite.Never(func() bool { return runner.eventRunCount() > 1 }, 50*time.Millisecond, time.Millisecond,
+		"a path is not synced twice at once")
+	close(runner.blockPaths)
+	suite.Eventually(func() bool { return runner.eventRunCount() == 2 }, time.Second, time.Millisecond)
+}
+
+func (suite *DaemonTestSuite) TestSyncRequested_ReturnsTheResultOfTheRun() {
+	result := &orchestrator.SyncResult{TotalSecrets: 1, FailedSyncs: 1}
+	runner := &fakeRunner{result: result}
+	daemon := NewDaemon(runner, time.Hour, time.Hour)
+	suite.start(daemon)
+	paths := []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}}
+
+	got, err := daemon.SyncRequested(context.Background(), paths)
+
+	suite.Require().NoError(err)
+	suite.Same(result, got)
+	last := daemon.LastRun()
+	suite.Equal(RunKindRequest, last.Kind)
+	suite.Equal(paths, last.Paths)
+	runner.mu.Lock()
+	suite.Equal([][]pathmatching.SecretPath{paths}, runner.eventRuns)
+	runner.mu.Unlock()
+}
+
+func (suite *DaemonTestSuite) TestSyncRequested_CompletesWhileAFullRunIsBlocked() {
+	runner := &fakeRunner{blockSyncs: make(chan struct{})}
+	defer close(runner.blockSyncs)
+	daemon := NewDaemon(runner, time.Hour, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	defer func() {
+		cancel()
+		suite.NoError(<-done)
+	}()
+	suite.Eventually(func() bool { return daemon.CurrentRun() != nil }, time.Second, time.Millisecond)
+	paths := []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}}
+
+	requestCtx, cancelRequest := context.WithTimeout(context.Background(), time.Second)
+	defer cancelRequest()
+	_, err := daemon.SyncRequested(requestCtx, paths)
+
+	suite.Require().NoError(err)
+	suite.Equal(RunKindRequest, daemon.LastRun().Kind)
+	suite.Equal(RunKindSync, daemon.CurrentRun().Kind)
+}
+
+func (suite *DaemonTestSuite) TestSyncRequested_GivesUpWhenTheContextIsDone() {
+	daemon := NewDaemon(&fakeRunner{}, time.Hour, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := daemon.SyncRequested(ctx, []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}})
+
+	suite.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (suite *DaemonTestSuite) TestSyncLeased_RunsTheLeasedPaths() {
+	runner := &fakeRunner{}
+	daemon := NewDaemon(runner, time.Hour, time.Hour, WithoutSchedule())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	defer func() {
+		cancel()
+		suite.NoError(<-done)
+	}()
+	paths := []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/database"}}
+
+	_, err := daemon.SyncLeased(context.Background(), paths, true)
+
+	suite.Require().NoError(err)
+	suite.Equal(RunKindWorker, daemon.LastRun().Kind)
+	runner.mu.Lock()
+	suite.Equal([][]pathmatching.SecretPath{paths}, runner.leasedRuns)
+	runner.mu.Unlock()
+	syncs, _, _ := runner.counts()
+	suite.Zero(syncs)
+}
+
+func (suite *DaemonTestSuite) TestCancelRun() {
+	runner := &fakeRunner{runTime: time.Hour}
+	daemon := NewDaemon(runner, time.Hour, time.Hour)
+	suite.False(daemon.CancelRun())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	suite.Eventually(func() bool { return daemon.CurrentRun() != nil }, time.Second, time.Millisecond)
+
+	suite.True(daemon.CancelRun())
+
+	suite.Eventually(func() bool { return daemon.LastRun() != nil }, time.Second, time.Millisecond)
+	suite.Equal(models.SyncRunInterrupted, daemon.LastRun().Status)
+	suite.Require().ErrorIs(daemon.LastRun().Err, context.Canceled)
+	suite.Nil(daemon.CurrentRun())
+	cancel()
+	suite.NoError(<-done)
+}
+
+func (suite *DaemonTestSuite) TestRun_RejectsInvalidIntervals() {
+	err := NewDaemon(&fakeRunner{}, 0, time.Minute).Run(context.Background())
+
+	suite.Error(err)
+}
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/pkg/log"
+)
+
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverShutdownTimeout   = 10 * time.Second
+)
+
+// Server is an HTTP server of the daemon, e.g. for /metrics or the control API.
+type Server struct {
+	httpServer *http.Server
+	listener   net.Listener
+	logger     zerolog.Logger
+}
+
+// Listen binds address, so a port that is already in use is reported before the daemon starts.
+func Listen(address string, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	return &Server{
+		httpServer: &http.Server{Handler: handler, ReadHeaderTimeout: serverReadHeaderTimeout},
+		listener:   listener,
+		logger:     log.Logger.With().Str("component", "daemon_server").Str("address", listener.Addr().String()).Logger(),
+	}, nil
+}
+
+// ListenTLS binds address like Listen and serves HTTPS with tlsConfig.
+func ListenTLS(address string, handler http.Handler, tlsConfig *tls.Config) (*Server, error) {
+	server, err := Listen(address, handler)
+	if err != nil {
+		return nil, err
+	}
+	server.httpServer.TLSConfig = tlsConfig
+	server.listener = tls.NewListener(server.listener, tlsConfig)
+	return server, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Serve handles requests until ctx is done and then shuts the server down, waiting for open requests up to
+// serverShutdownTimeout.
+func (s *Server) Serve(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.httpServer.Serve(s.listener)
+	}()
+	s.logger.Info().Msg("HTTP server started")
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("http server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serverShutdownTimeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down http server: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	s.logger.Info().Msg("HTTP server stopped")
+	return nil
+}
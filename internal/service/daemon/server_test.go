@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+}
+
+func TestServerSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+func (suite *ServerTestSuite) TestServe() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "pong")
+	})
+	server, err := Listen("127.0.0.1:0", mux)
+	suite.Require().NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx) }()
+
+	resp, err := http.Get("http://" + server.Addr() + "/ping") //nolint:noctx
+	suite.Require().NoError(err)
+	body, err := io.ReadAll(resp.Body)
+	suite.Require().NoError(resp.Body.Close())
+	suite.Require().NoError(err)
+	suite.Equal("pong", string(body))
+
+	cancel()
+	select {
+	case err := <-served:
+		suite.NoError(err)
+	case <-time.After(5 * time.Second):
+		suite.Fail("server did not stop")
+	}
+}
+
+func (suite *ServerTestSuite) TestListen_AddressInUse() {
+	server, err := Listen("127.0.0.1:0", http.NewServeMux())
+	suite.Require().NoError(err)
+	defer server.listener.Close()
+
+	_, err = Listen(server.Addr(), http.NewServeMux())
+
+	suite.ErrorContains(err, "failed to listen on")
+}
@@ -0,0 +1,70 @@
+// Package events syncs the secrets changed on the main cluster right away, from the event notifications of Vault.
+// The interval sync keeps running as the backstop for changes made while the subscription was lost.
+package events
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/vault"
+	"vault-sync/pkg/log"
+)
+
+// Source delivers the secret changes of the main cluster. It is implemented by the multi-cluster Vault client.
+type Source interface {
+	SubscribeSecretEvents(ctx context.Context, eventType string, handle func(vault.SecretEvent))
+}
+
+// Queue collects the paths to sync. It is implemented by the daemon, which debounces them.
+type Queue interface {
+	Enqueue(paths []pathmatching.SecretPath) int
+}
+
+// Matcher decides whether a path is synced. It is implemented by the path matcher.
+type Matcher interface {
+	ShouldSync(mount, keyPath string) bool
+}
+
+// Listener enqueues the paths of the secret events that the sync rule covers.
+type Listener struct {
+	source    Source
+	matcher   Matcher
+	queue     Queue
+	eventType string
+	logger    zerolog.Logger
+}
+
+// NewListener returns a listener for the events of eventType, e.g. kv-v2/*.
+func NewListener(source Source, matcher Matcher, queue Queue, eventType string) *Listener {
+	return &Listener{
+		source:    source,
+		matcher:   matcher,
+		queue:     queue,
+		eventType: eventType,
+		logger:    log.Logger.With().Str("component", "events").Str("event_type", eventType).Logger(),
+	}
+}
+
+// Run subscribes to the events until ctx is done.
+func (l *Listener) Run(ctx context.Context) {
+	l.logger.Info().Msg("Subscribing to Vault event notifications")
+	l.source.SubscribeSecretEvents(ctx, l.eventType, l.handle)
+	l.logger.Info().Msg("Stopped Vault event subscription")
+}
+
+func (l *Listener) handle(event vault.SecretEvent) {
+	logger := l.logger.With().
+		Str("received_event_type", event.EventType).
+		Str("mount", event.Mount).
+		Str("key_path", event.KeyPath).
+		Logger()
+	if !l.matcher.ShouldSync(event.Mount, event.KeyPath) {
+		logger.Debug().Msg("Ignoring event of a path the sync rule does not cover")
+		return
+	}
+	if l.queue.Enqueue([]pathmatching.SecretPath{{Mount: event.Mount, KeyPath: event.KeyPath}}) > 0 {
+		logger.Debug().Msg("Queued path of event")
+	}
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/vault"
+)
+
+type fakeSource struct {
+	eventType string
+	events    []vault.SecretEvent
+}
+
+func (s *fakeSource) SubscribeSecretEvents(_ context.Context, eventType string, handle func(vault.SecretEvent)) {
+	s.eventType = eventType
+	for _, event := range s.events {
+		handle(event)
+	}
+}
+
+type fakeQueue struct {
+	enqueued []pathmatching.SecretPath
+}
+
+func (q *fakeQueue) Enqueue(paths []pathmatching.SecretPath) int {
+	q.enqueued = append(q.enqueued, paths...)
+	return len(paths)
+}
+
+// fakeMatcher syncs every path of the secret mount except those under legacy/.
+type fakeMatcher struct{}
+
+func (fakeMatcher) ShouldSync(mount, keyPath string) bool {
+	return mount == "secret" && !strings.HasPrefix(keyPath, "legacy/")
+}
+
+func TestListener_EnqueuesThePathsCoveredByTheSyncRule(t *testing.T) {
+	source := &fakeSource{events: []vault.SecretEvent{
+		{EventType: "kv-v2/data-write", Mount: "secret", KeyPath: "app/database"},
+		{EventType: "kv-v2/data-write", Mount: "secret", KeyPath: "legacy/ftp"},
+		{EventType: "kv-v2/delete", Mount: "other", KeyPath: "app/database"},
+		{EventType: "kv-v2/metadata-delete", Mount: "secret", KeyPath: "app/cache"},
+	}}
+	queue := &fakeQueue{}
+
+	NewListener(source, fakeMatcher{}, queue, "kv-v2/*").Run(context.Background())
+
+	assert.Equal(t, "kv-v2/*", source.eventType)
+	assert.Equal(t, []pathmatching.SecretPath{
+		{Mount: "secret", KeyPath: "app/database"},
+		{Mount: "secret", KeyPath: "app/cache"},
+	}, queue.enqueued)
+}
@@ -0,0 +1,255 @@
+// Package health serves the liveness and readiness endpoints of the daemon.
+//
+// /healthz reports whether the daemon is alive: it fails once a run takes longer than the stall timeout, as the
+// scheduler starts no new run until the current one returns. /readyz reports the database, the circuit breakers,
+// the token and seal status of every Vault cluster and the last run. The daemon is ready when the database and
+// the main cluster can be used; unavailable replicas and failed runs are reported but do not make it unready,
+// since the failed replicas are retried and the other replicas keep syncing.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+
+	"vault-sync/internal/service/daemon"
+	"vault-sync/internal/vault"
+	"vault-sync/pkg/log"
+)
+
+// defaultCheckTimeout bounds the database ping and the Vault checks of a readiness check.
+const defaultCheckTimeout = 5 * time.Second
+
+const (
+	StatusOK       = "ok"
+	StatusStalled  = "stalled"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// Database is the database the sync state is stored in. It is implemented by the postgres repository.
+type Database interface {
+	Ping(ctx context.Context) error
+	CircuitBreakerState() gobreaker.State
+}
+
+// Clusters checks the Vault clusters. It is implemented by the multi-cluster Vault client.
+type Clusters interface {
+	ClusterStatuses(ctx context.Context) []vault.ClusterStatus
+}
+
+// Scheduler is the daemon whose runs are reported.
+type Scheduler interface {
+	CurrentRun() *daemon.RunSummary
+	LastRun() *daemon.RunSummary
+}
+
+// LivenessReport is the body of /healthz.
+type LivenessReport struct {
+	Status     string     `json:"status"`
+	CurrentRun *RunReport `json:"current_run,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ReadinessReport is the body of /readyz.
+type ReadinessReport struct {
+	Status     string          `json:"status"`
+	Database   DatabaseReport  `json:"database"`
+	Clusters   []ClusterReport `json:"clusters"`
+	CurrentRun *RunReport      `json:"current_run,omitempty"`
+	LastRun    *RunReport      `json:"last_run"`
+}
+
+// DatabaseReport is the state of the database.
+type DatabaseReport struct {
+	Reachable      bool   `json:"reachable"`
+	CircuitBreaker string `json:"circuit_breaker"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ClusterReport is the state of a Vault cluster. Role is main or replica.
+type ClusterReport struct {
+	Name           string `json:"name"`
+	Role           string `json:"role"`
+	Authenticated  bool   `json:"authenticated"`
+	Sealed         bool   `json:"sealed"`
+	CircuitBreaker string `json:"circuit_breaker"`
+	Error          string `json:"error,omitempty"`
+}
+
+// RunReport describes a run of the daemon. FinishedAt is left out while the run is in progress.
+type RunReport struct {
+	Kind            string     `json:"kind"`
+	Status          string     `json:"status"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	TotalSecrets    int        `json:"total_secrets"`
+	FailedSyncs     int        `json:"failed_syncs"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Checker runs the liveness and readiness checks.
+type Checker struct {
+	scheduler    Scheduler
+	database     Database
+	clusters     Clusters
+	stallTimeout time.Duration
+	checkTimeout time.Duration
+	now          func() time.Time
+	logger       zerolog.Logger
+}
+
+// NewChecker returns a checker reporting the runs of scheduler, the database and the Vault clusters. A run
+// taking longer than stallTimeout fails the liveness check, unless stallTimeout is 0.
+func NewChecker(scheduler Scheduler, database Database, clusters Clusters, stallTimeout time.Duration) *Checker {
+	return &Checker{
+		scheduler:    scheduler,
+		database:     database,
+		clusters:     clusters,
+		stallTimeout: stallTimeout,
+		checkTimeout: defaultCheckTimeout,
+		now:          time.Now,
+		logger:       log.Logger.With().Str("component", "health").Logger(),
+	}
+}
+
+// Liveness reports whether the current run, if any, is within the stall timeout. Without a stall timeout, a run
+// never fails it.
+func (c *Checker) Liveness() *LivenessReport {
+	now := c.now()
+	report := &LivenessReport{Status: StatusOK}
+	current := c.scheduler.CurrentRun()
+	if current == nil {
+		return report
+	}
+	report.CurrentRun = newRunReport(current, now)
+	if elapsed := now.Sub(current.StartedAt); c.stallTimeout > 0 && elapsed > c.stallTimeout {
+		report.Status = StatusStalled
+		report.Error = fmt.Sprintf("%s run has been running for %s, longer than the stall timeout of %s",
+			current.Kind, elapsed.Round(time.Second), c.stallTimeout)
+	}
+	return report
+}
+
+// Readiness checks the database and the Vault clusters concurrently and reports them with the runs.
+func (c *Checker) Readiness(ctx context.Context) *ReadinessReport {
+	ctx, cancel := context.WithTimeout(ctx, c.checkTimeout)
+	defer cancel()
+
+	var statuses []vault.ClusterStatus
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		statuses = c.clusters.ClusterStatuses(ctx)
+	}()
+	databaseBreaker := c.database.CircuitBreakerState()
+	database := DatabaseReport{CircuitBreaker: databaseBreaker.String()}
+	if err := c.database.Ping(ctx); err != nil {
+		database.Error = err.Error()
+	} else {
+		database.Reachable = true
+	}
+	wg.Wait()
+
+	now := c.now()
+	report := &ReadinessReport{
+		Status:     StatusReady,
+		Database:   database,
+		Clusters:   make([]ClusterReport, 0, len(statuses)),
+		CurrentRun: newRunReport(c.scheduler.CurrentRun(), now),
+		LastRun:    newRunReport(c.scheduler.LastRun(), now),
+	}
+	ready := database.Reachable && databaseBreaker != gobreaker.StateOpen
+	for _, status := range statuses {
+		report.Clusters = append(report.Clusters, newClusterReport(status))
+		if status.Main {
+			ready = ready && status.Authenticated && !status.Sealed && status.CircuitBreaker != gobreaker.StateOpen
+		}
+	}
+	if !ready {
+		report.Status = StatusNotReady
+	}
+	return report
+}
+
+// LivenessHandler serves the liveness report, with status 503 when the daemon is stalled.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		report := c.Liveness()
+		c.writeReport(w, report.Status == StatusOK, report)
+	})
+}
+
+// ReadinessHandler serves the readiness report, with status 503 when the daemon is not ready.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Readiness(r.Context())
+		c.writeReport(w, report.Status == StatusReady, report)
+	})
+}
+
+func (c *Checker) writeReport(w http.ResponseWriter, healthy bool, report any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to write health report")
+	}
+}
+
+func newClusterReport(status vault.ClusterStatus) ClusterReport {
+	report := ClusterReport{
+		Name:           status.Name,
+		Role:           "replica",
+		Authenticated:  status.Authenticated,
+		Sealed:         status.Sealed,
+		CircuitBreaker: status.CircuitBreaker.String(),
+	}
+	if status.Main {
+		report.Role = "main"
+	}
+	if status.Err != nil {
+		report.Error = status.Err.Error()
+	}
+	return report
+}
+
+// newRunReport returns the report of run, or nil when there is no run. The duration of a run in progress is the
+// time it has been running so far.
+func newRunReport(run *daemon.RunSummary, now time.Time) *RunReport {
+	if run == nil {
+		return nil
+	}
+	report := &RunReport{
+		Kind:      run.Kind,
+		Status:    run.Status.String(),
+		StartedAt: run.StartedAt,
+	}
+	if run.Result != nil {
+		report.TotalSecrets = run.Result.TotalSecrets
+		report.FailedSyncs = run.Result.FailedSyncs
+	}
+	end := now
+	if !run.FinishedAt.IsZero() {
+		finishedAt := run.FinishedAt
+		report.FinishedAt = &finishedAt
+		end = finishedAt
+	}
+	report.DurationSeconds = end.Sub(run.StartedAt).Seconds()
+	if run.Err != nil {
+		report.Error = run.Err.Error()
+	}
+	return report
+}
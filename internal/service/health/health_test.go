@@ -0,0 +1,221 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/daemon"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/vault"
+)
+
+type fakeScheduler struct {
+	current *daemon.RunSummary
+	last    *daemon.RunSummary
+}
+
+func (s *fakeScheduler) CurrentRun() *daemon.RunSummary { return s.current }
+
+func (s *fakeScheduler) LastRun() *daemon.RunSummary { return s.last }
+
+type fakeDatabase struct {
+	pingErr error
+	state   gobreaker.State
+}
+
+func (d *fakeDatabase) Ping(context.Context) error { return d.pingErr }
+
+func (d *fakeDatabase) CircuitBreakerState() gobreaker.State { return d.state }
+
+type fakeClusters struct {
+	statuses []vault.ClusterStatus
+}
+
+func (c *fakeClusters) ClusterStatuses(context.Context) []vault.ClusterStatus { return c.statuses }
+
+type HealthTestSuite struct {
+	suite.Suite
+	now       time.Time
+	scheduler *fakeScheduler
+	database  *fakeDatabase
+	clusters  *fakeClusters
+	checker   *Checker
+}
+
+func TestHealthSuite(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+func (suite *HealthTestSuite) SetupTest() {
+	suite.now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	suite.scheduler = &fakeScheduler{}
+	suite.database = &fakeDatabase{state: gobreaker.StateClosed}
+	suite.clusters = &fakeClusters{statuses: []vault.ClusterStatus{
+		{Name: "main", Main: true, Authenticated: true, CircuitBreaker: gobreaker.StateClosed},
+		{Name: "dr-site", Authenticated: true, CircuitBreaker: gobreaker.StateClosed},
+	}}
+	suite.checker = NewChecker(suite.scheduler, suite.database, suite.clusters, time.Hour)
+	suite.checker.now = func() time.Time { return suite.now }
+}
+
+func (suite *HealthTestSuite) serve(handler http.Handler, target any) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	suite.Equal("application/json", recorder.Header().Get("Content-Type"))
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), target))
+	return recorder.Code
+}
+
+func (suite *HealthTestSuite) TestLiveness_OKBetweenRuns() {
+	var report LivenessReport
+
+	code := suite.serve(suite.checker.LivenessHandler(), &report)
+
+	suite.Equal(http.StatusOK, code)
+	suite.Equal(StatusOK, report.Status)
+	suite.Nil(report.CurrentRun)
+}
+
+func (suite *HealthTestSuite) TestLiveness_OKDuringARunWithinTheStallTimeout() {
+	suite.scheduler.current = &daemon.RunSummary{
+		Kind:      "sync",
+		Status:    models.SyncRunRunning,
+		StartedAt: suite.now.Add(-30 * time.Minute),
+	}
+	var report LivenessReport
+
+	code := suite.serve(suite.checker.LivenessHandler(), &report)
+
+	suite.Equal(http.StatusOK, code)
+	suite.Equal(StatusOK, report.Status)
+	suite.Require().NotNil(report.CurrentRun)
+	suite.Equal("running", report.CurrentRun.Status)
+	suite.Nil(report.CurrentRun.FinishedAt)
+	suite.InDelta(1800, report.CurrentRun.DurationSeconds, 0)
+}
+
+func (suite *HealthTestSuite) TestLiveness_StalledRun() {
+	suite.scheduler.current = &daemon.RunSummary{
+		Kind:      "retry",
+		Status:    models.SyncRunRunning,
+		StartedAt: suite.now.Add(-90 * time.Minute),
+	}
+	var report LivenessReport
+
+	code := suite.serve(suite.checker.LivenessHandler(), &report)
+
+	suite.Equal(http.StatusServiceUnavailable, code)
+	suite.Equal(StatusStalled, report.Status)
+	suite.Equal("retry run has been running for 1h30m0s, longer than the stall timeout of 1h0m0s", report.Error)
+}
+
+func (suite *HealthTestSuite) TestLiveness_OKWithoutAStallTimeout() {
+	suite.checker.stallTimeout = 0
+	suite.scheduler.current = &daemon.RunSummary{
+		Kind:      "sync",
+		Status:    models.SyncRunRunning,
+		StartedAt: suite.now.Add(-12 * time.Hour),
+	}
+	var report LivenessReport
+
+	code := suite.serve(suite.checker.LivenessHandler(), &report)
+
+	suite.Equal(http.StatusOK, code)
+	suite.Equal(StatusOK, report.Status)
+	suite.Empty(report.Error)
+}
+
+func (suite *HealthTestSuite) TestReadiness_Ready() {
+	suite.scheduler.last = &daemon.RunSummary{
+		Kind:       "sync",
+		Status:     models.SyncRunFailed,
+		StartedAt:  suite.now.Add(-2 * time.Minute),
+		FinishedAt: suite.now.Add(-time.Minute),
+		Result:     &orchestrator.SyncResult{TotalSecrets: 10, FailedSyncs: 3},
+		Err:        errors.New("replica unavailable"),
+	}
+	suite.clusters.statuses[1].Sealed = true
+	var report ReadinessReport
+
+	code := suite.serve(suite.checker.ReadinessHandler(), &report)
+
+	suite.Equal(http.StatusOK, code, "a sealed replica or a failed run does not make the daemon unready")
+	suite.Equal(StatusReady, report.Status)
+	suite.Equal(DatabaseReport{Reachable: true, CircuitBreaker: "closed"}, report.Database)
+	suite.Equal([]ClusterReport{
+		{Name: "main", Role: "main", Authenticated: true, CircuitBreaker: "closed"},
+		{Name: "dr-site", Role: "replica", Authenticated: true, Sealed: true, CircuitBreaker: "closed"},
+	}, report.Clusters)
+	suite.Nil(report.CurrentRun)
+	suite.Require().NotNil(report.LastRun)
+	suite.Equal("failed", report.LastRun.Status)
+	suite.Equal(suite.now.Add(-time.Minute), *report.LastRun.FinishedAt)
+	suite.InDelta(60, report.LastRun.DurationSeconds, 0)
+	suite.Equal(10, report.LastRun.TotalSecrets)
+	suite.Equal(3, report.LastRun.FailedSyncs)
+	suite.Equal("replica unavailable", report.LastRun.Error)
+}
+
+func (suite *HealthTestSuite) TestReadiness_NotReady() {
+	testCases := []struct {
+		name  string
+		setup func()
+	}{
+		{
+			name:  "database unreachable",
+			setup: func() { suite.database.pingErr = errors.New("connection refused") },
+		},
+		{
+			name:  "database circuit breaker open",
+			setup: func() { suite.database.state = gobreaker.StateOpen },
+		},
+		{
+			name:  "main cluster sealed",
+			setup: func() { suite.clusters.statuses[0].Sealed = true },
+		},
+		{
+			name: "main cluster not authenticated",
+			setup: func() {
+				suite.clusters.statuses[0].Authenticated = false
+				suite.clusters.statuses[0].Err = errors.New("token check failed")
+			},
+		},
+		{
+			name:  "main cluster circuit breaker open",
+			setup: func() { suite.clusters.statuses[0].CircuitBreaker = gobreaker.StateOpen },
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			suite.SetupTest()
+			tc.setup()
+			var report ReadinessReport
+
+			code := suite.serve(suite.checker.ReadinessHandler(), &report)
+
+			suite.Equal(http.StatusServiceUnavailable, code)
+			suite.Equal(StatusNotReady, report.Status)
+		})
+	}
+}
+
+func (suite *HealthTestSuite) TestReadiness_ReportsErrors() {
+	suite.database.pingErr = errors.New("connection refused")
+	suite.clusters.statuses[1].Err = errors.New("seal status check failed")
+
+	report := suite.checker.Readiness(context.Background())
+
+	suite.False(report.Database.Reachable)
+	suite.Equal("connection refused", report.Database.Error)
+	suite.Equal("seal status check failed", report.Clusters[1].Error)
+}
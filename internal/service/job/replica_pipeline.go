@@ -0,0 +1,409 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/tracing"
+	"vault-sync/internal/vault"
+	"vault-sync/pkg/log"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReplicaProgress reports how much work a replica cluster received and processed during a run.
+// Backlog is the number of tasks that were queued but not yet processed when the snapshot was taken,
+// and MaxBacklog is the highest backlog seen, which points at replicas that could not keep up.
+type ReplicaProgress struct {
+	ClusterName string
+	Enqueued    int
+	Completed   int
+	Failed      int
+	Skipped     int
+	Backlog     int
+	MaxBacklog  int
+}
+
+// ReplicaPipeline fans out replica writes and deletes to independent per-replica queues,
+// each drained by its own pool of workers.
+//
+// A SyncJob reads the source secret once, queues one task per replica and returns, so a slow
+// replica only grows its own backlog instead of holding up the jobs of every other cluster.
+// Queues are bounded: once a replica falls queueSize tasks behind, dispatching blocks until it catches up.
+type ReplicaPipeline struct {
+	ctx      context.Context
+	queues   map[string]chan *replicaTask
+	progress map[string]*replicaProgressCounter
+	wg       sync.WaitGroup
+	logger   zerolog.Logger
+}
+
+// replicaTask is a replica write or delete. execute calls done exactly once, possibly from another goroutine
+// after the worker moved on, e.g. once the record of the write is stored by a StatusWriter. jobSpan is the span
+// of the job that queued the task, so the replica operations are traced as part of the job.
+type replicaTask struct {
+	clusterName string
+	execute     func(ctx context.Context, done func(status *ClusterSyncStatus, err error))
+	complete    func(status *ClusterSyncStatus, err error)
+	jobSpan     trace.Span
+}
+
+type replicaProgressCounter struct {
+	mu       sync.Mutex
+	progress ReplicaProgress
+}
+
+// NewReplicaPipeline creates a queue and starts workersPerReplica workers for every replica cluster.
+// Workers stop when Close is called; tasks still queued when ctx is cancelled are skipped.
+func NewReplicaPipeline(
+	ctx context.Context,
+	replicaNames []string,
+	workersPerReplica, queueSize int,
+) *ReplicaPipeline {
+	workersPerReplica = max(1, workersPerReplica)
+	queueSize = max(1, queueSize)
+
+	pipeline := &ReplicaPipeline{
+		ctx:      ctx,
+		queues:   make(map[string]chan *replicaTask, len(replicaNames)),
+		progress: make(map[string]*replicaProgressCounter, len(replicaNames)),
+		logger:   log.Logger.With().Str("component", "replica_pipeline").Logger(),
+	}
+
+	for _, clusterName := range replicaNames {
+		queue := make(chan *replicaTask, queueSize)
+		counter := &replicaProgressCounter{progress: ReplicaProgress{ClusterName: clusterName}}
+		pipeline.queues[clusterName] = queue
+		pipeline.progress[clusterName] = counter
+
+		for range workersPerReplica {
+			pipeline.wg.Add(1)
+			go pipeline.worker(queue, counter)
+		}
+	}
+
+	pipeline.logger.Debug().
+		Int("replicas", len(replicaNames)).
+		Int("workers_per_replica", workersPerReplica).
+		Int("queue_size", queueSize).
+		Msg("Started replica pipeline")
+
+	return pipeline
+}
+
+// Close stops accepting tasks and waits for the workers to drain their queues.
+func (p *ReplicaPipeline) Close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// Progress returns a snapshot of the per-replica progress, sorted by cluster name.
+func (p *ReplicaPipeline) Progress() []ReplicaProgress {
+	progress := make([]ReplicaProgress, 0, len(p.progress))
+	for _, counter := range p.progress {
+		counter.mu.Lock()
+		progress = append(progress, counter.progress)
+		counter.mu.Unlock()
+	}
+
+	slices.SortFunc(progress, func(a, b ReplicaProgress) int {
+		return strings.Compare(a.ClusterName, b.ClusterName)
+	})
+	return progress
+}
+
+// enqueue blocks until the task is queued for its replica. If ctx is cancelled first,
+// the task is completed as skipped.
+func (p *ReplicaPipeline) enqueue(ctx context.Context, task *replicaTask) {
+	queue, exists := p.queues[task.clusterName]
+	if !exists {
+		task.complete(
+			&ClusterSyncStatus{ClusterName: task.clusterName, Status: SyncJobStatusFailed},
+			fmt.Errorf("no replica queue for cluster %s", task.clusterName),
+		)
+		return
+	}
+
+	counter := p.progress[task.clusterName]
+	counter.update(func(progress *ReplicaProgress) {
+		progress.Enqueued++
+		progress.Backlog++
+		progress.MaxBacklog = max(progress.MaxBacklog, progress.Backlog)
+	})
+
+	task.jobSpan = trace.SpanFromContext(ctx)
+	select {
+	case queue <- task:
+	case <-ctx.Done():
+		p.skip(task, ctx.Err())
+	}
+}
+
+func (p *ReplicaPipeline) worker(queue chan *replicaTask, counter *replicaProgressCounter) {
+	defer p.wg.Done()
+
+	for task := range queue {
+		if err := p.ctx.Err(); err != nil {
+			p.skip(task, err)
+			continue
+		}
+
+		task.execute(trace.ContextWithSpan(p.ctx, task.jobSpan), func(status *ClusterSyncStatus, err error) {
+			counter.update(func(progress *ReplicaProgress) {
+				progress.Backlog--
+				if err != nil {
+					progress.Failed++
+				} else {
+					progress.Completed++
+				}
+			})
+			task.complete(status, err)
+		})
+	}
+}
+
+func (p *ReplicaPipeline) skip(task *replicaTask, err error) {
+	p.progress[task.clusterName].update(func(progress *ReplicaProgress) {
+		progress.Backlog--
+		progress.Skipped++
+	})
+	task.complete(&ClusterSyncStatus{ClusterName: task.clusterName, Status: SyncJobStatusPending}, err)
+}
+
+func (c *replicaProgressCounter) update(fn func(progress *ReplicaProgress)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&c.progress)
+}
+
+// replicaResultTracker collects the per-replica outcomes of a dispatched job and
+// builds the job result once the last replica finished.
+type replicaResultTracker struct {
+	mu          sync.Mutex
+	job         *SyncJob
+	remaining   int
+	statuses    []*ClusterSyncStatus
+	multiErr    MultiError
+	canceledErr error
+	done        func(*SyncJobResult)
+}
+
+func newReplicaResultTracker(job *SyncJob, replicaCount int, done func(*SyncJobResult)) *replicaResultTracker {
+	return &replicaResultTracker{
+		job:       job,
+		remaining: replicaCount,
+		statuses:  make([]*ClusterSyncStatus, 0, replicaCount),
+		done:      done,
+	}
+}
+
+func (t *replicaResultTracker) complete(status *ClusterSyncStatus, err error) {
+	status.Error = err
+
+	t.mu.Lock()
+	t.statuses = append(t.statuses, status)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		t.canceledErr = err
+	} else {
+		t.multiErr.Add(err)
+	}
+	t.remaining--
+	if t.remaining > 0 {
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(t.statuses, func(a, b *ClusterSyncStatus) int {
+		return strings.Compare(a.ClusterName, b.ClusterName)
+	})
+
+	// Real failures take precedence so that a partially cancelled job is still reported as failed.
+	err = t.multiErr.Err()
+	if err == nil {
+		err = t.canceledErr
+	}
+	t.done(NewSyncJobResult(t.job, t.statuses, err))
+}
+
+// skipHeld completes the held replicas of state with their status and returns the replicas to dispatch.
+func (t *replicaResultTracker) skipHeld(state *SyncState) []string {
+	dispatched := make([]string, 0, len(state.ReplicaNames))
+	for _, clusterName := range state.ReplicaNames {
+		if status, held := state.HeldReplicas[clusterName]; held {
+			t.complete(&ClusterSyncStatus{ClusterName: clusterName, Status: status}, nil)
+			continue
+		}
+		dispatched = append(dispatched, clusterName)
+	}
+	return dispatched
+}
+
+// Dispatch gathers the current state and makes the same decision as Execute, but hands the replica
+// writes and deletes to the pipeline instead of waiting for them. The source secret is read once and
+// shared by every replica task.
+//
+// Dispatch returns as soon as every replica task is queued. done is then called exactly once, after the
+// last replica finished. When Dispatch returns an error, nothing was queued and done is not called.
+func (job *SyncJob) Dispatch(
+	ctx context.Context,
+	pipeline *ReplicaPipeline,
+	done func(*SyncJobResult),
+) error {
+	ctx, span := job.startSpan(ctx)
+	jobDone := done
+	done = func(result *SyncJobResult) {
+		tracing.RecordError(span, result.Error)
+		span.End()
+		jobDone(result)
+	}
+
+	err := job.dispatch(ctx, pipeline, done)
+	if err != nil {
+		tracing.RecordError(span, err)
+		span.End()
+	}
+	return err
+}
+
+// dispatch runs Dispatch within the span of the job.
+func (job *SyncJob) dispatch(ctx context.Context, pipeline *ReplicaPipeline, done func(*SyncJobResult)) error {
+	logger := job.logger.With().Str("action", "dispatch").Logger()
+	logger.Debug().Msg("Starting secret sync job")
+
+	state, err := withTimeout(ctx, job.timeout, job.gatherCurrentState)
+	if err != nil {
+		return fmt.Errorf("failed to gather current state: %w", err)
+	}
+
+	decision := job.makeDecision(state)
+	logger.Debug().
+		Bool("source_exists", state.SourceExists).
+		Int("total_replicas", len(state.ReplicaNames)).
+		Str("decision", decision.String()).
+		Msg("Made sync decision")
+
+	switch decision {
+	case DecisionNoOp:
+		done(job.buildNoOpResult(ctx, state))
+		return nil
+	case DecisionSync:
+		return job.dispatchSync(ctx, pipeline, state, done)
+	case DecisionDelete:
+		job.dispatchDelete(ctx, pipeline, state, done)
+		return nil
+	default:
+		return fmt.Errorf("unknown decision: %v", decision)
+	}
+}
+
+func (job *SyncJob) dispatchSync(
+	ctx context.Context,
+	pipeline *ReplicaPipeline,
+	state *SyncState,
+	done func(*SyncJobResult),
+) error {
+	replicaNames := state.ReplicaNames
+	logger := job.logger.With().Str("action", "sync").Logger()
+	logger.Debug().Msg("Dispatching sync operation to replica queues")
+
+	sourceSecret, err := withTimeout(ctx, job.timeout, func(ctx context.Context) (*vault.SecretResponse, error) {
+		return job.vaultClient.ReadSecret(ctx, job.mount, job.keyPath)
+	})
+	if err != nil {
+		return fmt.Errorf("vault sync failed: %w", err)
+	}
+
+	if len(replicaNames) == 0 {
+		done(NewSyncJobResult(job, []*ClusterSyncStatus{}, nil))
+		return nil
+	}
+
+	tracker := newReplicaResultTracker(job, len(replicaNames), done)
+
+	for _, clusterName := range tracker.skipHeld(state) {
+		pipeline.enqueue(ctx, &replicaTask{
+			clusterName: clusterName,
+			complete:    tracker.complete,
+			execute: func(ctx context.Context, done func(*ClusterSyncStatus, error)) {
+				syncResult, syncErr := withTimeout(ctx, job.timeout,
+					func(ctx context.Context) (*models.SyncedSecret, error) {
+						return job.vaultClient.SyncSecretToReplica(ctx, clusterName, job.mount, job.keyPath, sourceSecret)
+					})
+				if syncErr != nil {
+					done(&ClusterSyncStatus{
+						ClusterName:   clusterName,
+						Status:        SyncJobStatusFailed,
+						SourceVersion: sourceSecret.Metadata.Version,
+					}, fmt.Errorf("cluster %s vault sync failed: %w", clusterName, syncErr))
+					return
+				}
+
+				var multiErr MultiError
+				if job.statusWriter == nil {
+					status := job.recordSyncResult(ctx, logger, state, syncResult, &multiErr)
+					done(status, multiErr.Err())
+					return
+				}
+
+				status := job.syncResultStatus(logger, state, syncResult, &multiErr)
+				job.statusWriter.Write(syncResult, func(dbErr error) {
+					if dbErr != nil {
+						status = job.dbUpdateFailed(logger, syncResult, dbErr, &multiErr)
+					}
+					done(newWriteStatus(syncResult, status), multiErr.Err())
+				})
+			},
+		})
+	}
+
+	return nil
+}
+
+func (job *SyncJob) dispatchDelete(
+	ctx context.Context,
+	pipeline *ReplicaPipeline,
+	state *SyncState,
+	done func(*SyncJobResult),
+) {
+	replicaNames := state.ReplicaNames
+	logger := job.logger.With().Str("action", "delete").Logger()
+	logger.Debug().Msg("Dispatching delete operation to replica queues")
+
+	if len(replicaNames) == 0 {
+		done(NewSyncJobResult(job, []*ClusterSyncStatus{}, nil))
+		return
+	}
+
+	tracker := newReplicaResultTracker(job, len(replicaNames), done)
+
+	for _, clusterName := range tracker.skipHeld(state) {
+		pipeline.enqueue(ctx, &replicaTask{
+			clusterName: clusterName,
+			complete:    tracker.complete,
+			execute: func(ctx context.Context, done func(*ClusterSyncStatus, error)) {
+				deleteResult, deleteErr := withTimeout(ctx, job.timeout,
+					func(ctx context.Context) (*models.SyncSecretDeletionResult, error) {
+						return job.vaultClient.DeleteSecretFromReplica(ctx, clusterName, job.mount, job.keyPath)
+					})
+				if deleteErr != nil {
+					done(&ClusterSyncStatus{ClusterName: clusterName, Status: SyncJobStatusErrorDeleting},
+						fmt.Errorf("cluster %s vault delete failed: %w", clusterName, deleteErr))
+					return
+				}
+
+				var multiErr MultiError
+				status := job.recordDeleteResult(ctx, logger, deleteResult, state.RecordsByCluster[clusterName], &multiErr)
+				done(status, multiErr.Err())
+			},
+		})
+	}
+}
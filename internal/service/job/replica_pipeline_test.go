@@ -0,0 +1,316 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/tracing"
+	"vault-sync/testutil/testbuilder"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ReplicaPipelineTestSuite struct {
+	suite.Suite
+	ctx     context.Context
+	mount   string
+	keyPath string
+	builder testbuilder.MockClustersStage
+}
+
+func (suite *ReplicaPipelineTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.mount = "test-mount"
+	suite.keyPath = "test/key/path"
+}
+
+func (suite *ReplicaPipelineTestSuite) SetupSubTest() {
+	suite.builder = testbuilder.NewSyncJobMockBuilder().WithKeyPath(suite.keyPath).WithMount(suite.mount).WithClusters(clusters...)
+}
+
+func TestReplicaPipelineSuite(t *testing.T) {
+	suite.Run(t, new(ReplicaPipelineTestSuite))
+}
+
+func (suite *ReplicaPipelineTestSuite) dispatch(
+	ctx context.Context, worker *SyncJob, pipeline *ReplicaPipeline,
+) (chan *SyncJobResult, error) {
+	results := make(chan *SyncJobResult, 1)
+	err := worker.Dispatch(ctx, pipeline, func(result *SyncJobResult) {
+		results <- result
+	})
+	return results, err
+}
+
+func (suite *ReplicaPipelineTestSuite) waitForResult(results chan *SyncJobResult) *SyncJobResult {
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(2 * time.Second):
+		suite.FailNow("timed out waiting for job result")
+		return nil
+	}
+}
+
+func (suite *ReplicaPipelineTestSuite) TestDispatch() {
+	sourceVersion := int64(2)
+
+	suite.Run("traces the replica writes as part of the job", func() {
+		recorder := tracetest.NewSpanRecorder()
+		provider := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(provider)
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			WithUpdateSyncedSecretStatus(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToBuildableStage().Build()
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+		suite.NoError(err)
+		suite.waitForResult(results)
+		pipeline.Close()
+
+		spans := recorder.Ended()
+		suite.Require().Len(spans, 1)
+		suite.Equal("sync_job.execute", spans[0].Name())
+		suite.ElementsMatch(tracing.SecretAttributes("", suite.mount, suite.keyPath), spans[0].Attributes())
+		replicaWrites := 0
+		for _, call := range mockVault.Calls {
+			if call.Method == "SyncSecretToReplica" {
+				replicaWrites++
+				ctx, _ := call.Arguments.Get(0).(context.Context)
+				suite.Equal(spans[0].SpanContext().SpanID(), trace.SpanContextFromContext(ctx).SpanID())
+			}
+		}
+		suite.Equal(len(clusters), replicaWrites)
+	})
+
+	suite.Run("reads the source once and syncs every replica through its own queue", func() {
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			WithUpdateSyncedSecretStatus(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToBuildableStage().Build()
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+		suite.NoError(err)
+		jobResult := suite.waitForResult(results)
+		pipeline.Close()
+
+		suite.NoError(jobResult.Error)
+		suite.Len(jobResult.Status, 2)
+		suite.Equal(cluster1, jobResult.Status[0].ClusterName)
+		suite.Equal(cluster2, jobResult.Status[1].ClusterName)
+		for _, status := range jobResult.Status {
+			suite.Equal(SyncJobStatusUpdated, status.Status)
+		}
+		mockVault.AssertNumberOfCalls(suite.T(), "ReadSecret", 1)
+		mockVault.AssertNotCalled(suite.T(), "SyncSecretToReplicas", mock.Anything, mock.Anything, mock.Anything)
+		suite.Equal([]ReplicaProgress{
+			{ClusterName: cluster1, Enqueued: 1, Completed: 1, MaxBacklog: 1},
+			{ClusterName: cluster2, Enqueued: 1, Completed: 1, MaxBacklog: 1},
+		}, pipeline.Progress())
+	})
+
+	suite.Run("deletes the secret from every replica through its own queue", func() {
+		mockRepo, mockVault := suite.builder.
+			WithDatabaseSecretVersion(sourceVersion).
+			WithGetSyncedSecret(clusters...).
+			WithDeleteSyncedSecret(clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(false).
+			WithDeleteSecretFromReplicas(models.StatusDeleted, cluster1).
+			WithDeleteSecretFromReplicas(models.StatusFailed, cluster2).
+			SwitchToBuildableStage().Build()
+		mockRepo.On("UpdateSyncedSecretStatus", mock.Anything, mock.Anything).Return(nil)
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+		suite.NoError(err)
+		jobResult := suite.waitForResult(results)
+		pipeline.Close()
+
+		suite.Error(jobResult.Error)
+		suite.Equal(SyncJobStatusDeleted, jobResult.Status[0].Status)
+		suite.Equal(SyncJobStatusErrorDeleting, jobResult.Status[1].Status)
+		suite.Equal(1, pipeline.Progress()[0].Completed)
+		suite.Equal(1, pipeline.Progress()[1].Failed)
+	})
+
+	suite.Run("a slow replica does not hold up the dispatch or the other replicas", func() {
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			WithUpdateSyncedSecretStatus(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, cluster1).
+			SwitchToBuildableStage().Build()
+		releaseSlowReplica := make(chan time.Time)
+		mockVault.On("SyncSecretToReplica", mock.Anything, cluster2, suite.mount, suite.keyPath, mock.Anything).
+			WaitUntil(releaseSlowReplica).
+			Return(&models.SyncedSecret{
+				SecretBackend:      suite.mount,
+				SecretPath:         suite.keyPath,
+				DestinationCluster: cluster2,
+				Status:             models.StatusSuccess,
+				SourceVersion:      sourceVersion,
+			}, nil)
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+
+		suite.NoError(err)
+		suite.Eventually(func() bool {
+			return pipeline.Progress()[0].Completed == 1
+		}, time.Second, 5*time.Millisecond)
+		suite.Equal(1, pipeline.Progress()[1].Backlog)
+		suite.Empty(results)
+
+		close(releaseSlowReplica)
+		jobResult := suite.waitForResult(results)
+		pipeline.Close()
+
+		suite.NoError(jobResult.Error)
+		suite.Equal(0, pipeline.Progress()[1].Backlog)
+		suite.Equal(1, pipeline.Progress()[1].Completed)
+	})
+
+	suite.Run("fails a replica that does not answer within the job timeout", func() {
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			WithUpdateSyncedSecretStatus(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, cluster1).
+			SwitchToBuildableStage().Build()
+		mockVault.On("SyncSecretToReplica", mock.Anything, cluster2, suite.mount, suite.keyPath, mock.Anything).
+			Run(func(args mock.Arguments) {
+				<-args.Get(0).(context.Context).Done()
+			}).
+			Return(nil, context.DeadlineExceeded)
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo, WithTimeout(50*time.Millisecond))
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+		suite.NoError(err)
+		jobResult := suite.waitForResult(results)
+		pipeline.Close()
+
+		suite.ErrorIs(jobResult.Error, ErrTimeout)
+		suite.NotErrorIs(jobResult.Error, context.DeadlineExceeded, "a timeout is a failure, not a skip")
+		suite.Equal(SyncJobStatusUpdated, jobResult.Status[0].Status)
+		suite.Equal(SyncJobStatusFailed, jobResult.Status[1].Status)
+		suite.Equal(1, pipeline.Progress()[1].Failed)
+	})
+
+	suite.Run("uses preloaded records and writes the replica records through the status writer", func() {
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToBuildableStage().Build()
+		mockRepo.On("UpdateSyncedSecretStatus", mock.Anything, mock.MatchedBy(func(secret *models.SyncedSecret) bool {
+			return secret.DestinationCluster == cluster2
+		})).Return(errors.New("connection refused"))
+		mockRepo.On("UpdateSyncedSecretStatus", mock.Anything, mock.Anything).Return(nil)
+		records := map[string]*models.SyncedSecret{
+			cluster1: {DestinationCluster: cluster1, Status: models.StatusSuccess, SourceVersion: sourceVersion - 1},
+		}
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		writer := newStatusWriter(suite.ctx, mockRepo, 1, time.Hour)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo, WithRecords(records), WithStatusWriter(writer))
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+		suite.NoError(err)
+		jobResult := suite.waitForResult(results)
+		pipeline.Close()
+		writer.Close()
+
+		suite.ErrorContains(jobResult.Error, "cluster "+cluster2+" DB update: connection refused")
+		suite.Equal(SyncJobStatusUpdated, jobResult.Status[0].Status)
+		suite.Equal(SyncJobStatusFailed, jobResult.Status[1].Status)
+		suite.Equal(1, pipeline.Progress()[0].Completed)
+		suite.Equal(1, pipeline.Progress()[1].Failed)
+		mockRepo.AssertNotCalled(suite.T(), "GetSyncedSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		suite.Equal(sourceVersion-1, records[cluster1].SourceVersion, "preloaded records must not be modified")
+	})
+
+	suite.Run("skips queued tasks when the context is cancelled", func() {
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, clusters...).
+			SwitchToBuildableStage().Build()
+		ctx, cancel := context.WithCancel(suite.ctx)
+		cancel()
+		pipeline := NewReplicaPipeline(ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		results, err := suite.dispatch(ctx, worker, pipeline)
+		suite.NoError(err)
+		jobResult := suite.waitForResult(results)
+		pipeline.Close()
+
+		suite.ErrorIs(jobResult.Error, context.Canceled)
+		for _, status := range jobResult.Status {
+			suite.Equal(SyncJobStatusPending, status.Status)
+		}
+		for _, progress := range pipeline.Progress() {
+			suite.Equal(1, progress.Skipped)
+			suite.Equal(0, progress.Backlog)
+		}
+		mockVault.AssertNotCalled(suite.T(), "SyncSecretToReplica", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("returns an error and does not call done when the source cannot be read", func() {
+		mockRepo, mockVault := suite.builder.
+			WithGetSyncedSecretNotFound(clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicasError(errors.New("failed to read secret")).
+			SwitchToBuildableStage().Build()
+		pipeline := NewReplicaPipeline(suite.ctx, clusters, 1, 1)
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		results, err := suite.dispatch(suite.ctx, worker, pipeline)
+		pipeline.Close()
+
+		suite.ErrorContains(err, "failed to read secret")
+		suite.Empty(results)
+		suite.Equal(0, pipeline.Progress()[0].Enqueued)
+	})
+}
@@ -0,0 +1,78 @@
+package job
+
+import (
+	"time"
+
+	"vault-sync/internal/models"
+)
+
+const (
+	// DefaultRetryInitialBackoff is the wait before the first retry of a failed replica.
+	DefaultRetryInitialBackoff = time.Minute
+	// DefaultRetryMaxBackoff caps the wait between retries.
+	DefaultRetryMaxBackoff = time.Hour
+	// DefaultQuarantineAfter is the number of permanent failures in a row after which a replica is quarantined.
+	DefaultQuarantineAfter = 10
+)
+
+// RetryPolicy schedules the retries of failed replicas. The wait doubles with every failure in a row, starting
+// at the initial backoff and capped at the max backoff. A replica that failed quarantineAfter times in a row
+// with a permanent error is quarantined; transient failures only ever back off, so an outage of a replica
+// does not quarantine everything on it.
+type RetryPolicy struct {
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	quarantineAfter int
+}
+
+// NewRetryPolicy returns a policy with the given settings; zero values fall back to the defaults.
+func NewRetryPolicy(initialBackoff, maxBackoff time.Duration, quarantineAfter int) *RetryPolicy {
+	policy := &RetryPolicy{
+		initialBackoff:  DefaultRetryInitialBackoff,
+		maxBackoff:      DefaultRetryMaxBackoff,
+		quarantineAfter: DefaultQuarantineAfter,
+	}
+	if initialBackoff > 0 {
+		policy.initialBackoff = initialBackoff
+	}
+	if maxBackoff > 0 {
+		policy.maxBackoff = maxBackoff
+	}
+	if quarantineAfter > 0 {
+		policy.quarantineAfter = quarantineAfter
+	}
+	policy.maxBackoff = max(policy.maxBackoff, policy.initialBackoff)
+	return policy
+}
+
+// Backoff returns the wait after the given number of failures in a row.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.initialBackoff
+	for i := 1; i < attempt && backoff < p.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.maxBackoff)
+}
+
+// scheduleRetry sets the retry state of a failed record from the record it replaces. The attempt count is
+// always tracked; the next retry and quarantine are only set with a policy.
+func scheduleRetry(policy *RetryPolicy, record, previous *models.SyncedSecret, now time.Time) {
+	record.AttemptCount = 1
+	if previous != nil && previous.Status == models.StatusFailed {
+		record.AttemptCount = previous.AttemptCount + 1
+	}
+	record.NextRetryAt = nil
+	record.QuarantinedAt = nil
+
+	if policy == nil {
+		return
+	}
+
+	permanent := record.ErrorCategory == nil || !record.ErrorCategory.IsTransient()
+	if permanent && record.AttemptCount >= policy.quarantineAfter {
+		record.QuarantinedAt = &now
+		return
+	}
+	nextRetry := now.Add(policy.Backoff(record.AttemptCount))
+	record.NextRetryAt = &nextRetry
+}
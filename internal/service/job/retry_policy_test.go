@@ -0,0 +1,120 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"vault-sync/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RetryPolicyTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func TestRetryPolicySuite(t *testing.T) {
+	suite.Run(t, new(RetryPolicyTestSuite))
+}
+
+func (suite *RetryPolicyTestSuite) SetupTest() {
+	suite.now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+}
+
+func (suite *RetryPolicyTestSuite) failedRecord(category models.ErrorCategory) *models.SyncedSecret {
+	return &models.SyncedSecret{Status: models.StatusFailed, ErrorCategory: &category}
+}
+
+func (suite *RetryPolicyTestSuite) TestNewRetryPolicy_Defaults() {
+	policy := NewRetryPolicy(0, 0, 0)
+
+	suite.Equal(DefaultRetryInitialBackoff, policy.initialBackoff)
+	suite.Equal(DefaultRetryMaxBackoff, policy.maxBackoff)
+	suite.Equal(DefaultQuarantineAfter, policy.quarantineAfter)
+}
+
+func (suite *RetryPolicyTestSuite) TestNewRetryPolicy_MaxBackoffBelowInitial() {
+	policy := NewRetryPolicy(time.Hour, time.Minute, 3)
+
+	suite.Equal(time.Hour, policy.maxBackoff)
+}
+
+func (suite *RetryPolicyTestSuite) TestBackoff() {
+	policy := NewRetryPolicy(time.Minute, 10*time.Minute, 3)
+
+	suite.Equal(time.Minute, policy.Backoff(0))
+	suite.Equal(time.Minute, policy.Backoff(1))
+	suite.Equal(2*time.Minute, policy.Backoff(2))
+	suite.Equal(8*time.Minute, policy.Backoff(4))
+	suite.Equal(10*time.Minute, policy.Backoff(5))
+	suite.Equal(10*time.Minute, policy.Backoff(100))
+}
+
+func (suite *RetryPolicyTestSuite) TestScheduleRetry_FirstFailure() {
+	record := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+
+	scheduleRetry(NewRetryPolicy(time.Minute, time.Hour, 3), record, nil, suite.now)
+
+	suite.Equal(1, record.AttemptCount)
+	suite.Equal(suite.now.Add(time.Minute), *record.NextRetryAt)
+	suite.Nil(record.QuarantinedAt)
+}
+
+func (suite *RetryPolicyTestSuite) TestScheduleRetry_CountsFailuresInARow() {
+	previous := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+	previous.AttemptCount = 1
+	record := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+
+	scheduleRetry(NewRetryPolicy(time.Minute, time.Hour, 3), record, previous, suite.now)
+
+	suite.Equal(2, record.AttemptCount)
+	suite.Equal(suite.now.Add(2*time.Minute), *record.NextRetryAt)
+}
+
+func (suite *RetryPolicyTestSuite) TestScheduleRetry_RestartsAfterSuccess() {
+	previous := &models.SyncedSecret{Status: models.StatusSuccess, AttemptCount: 4}
+	record := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+
+	scheduleRetry(NewRetryPolicy(time.Minute, time.Hour, 3), record, previous, suite.now)
+
+	suite.Equal(1, record.AttemptCount)
+}
+
+func (suite *RetryPolicyTestSuite) TestScheduleRetry_QuarantinesPermanentFailures() {
+	previous := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+	previous.AttemptCount = 2
+	record := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+
+	scheduleRetry(NewRetryPolicy(time.Minute, time.Hour, 3), record, previous, suite.now)
+
+	suite.Equal(3, record.AttemptCount)
+	suite.Equal(suite.now, *record.QuarantinedAt)
+	suite.Nil(record.NextRetryAt)
+	suite.True(record.IsQuarantined())
+}
+
+func (suite *RetryPolicyTestSuite) TestScheduleRetry_NeverQuarantinesTransientFailures() {
+	previous := suite.failedRecord(models.ErrorCategoryUnavailable)
+	previous.AttemptCount = 20
+	record := suite.failedRecord(models.ErrorCategoryUnavailable)
+
+	scheduleRetry(NewRetryPolicy(time.Minute, time.Hour, 3), record, previous, suite.now)
+
+	suite.Equal(21, record.AttemptCount)
+	suite.Nil(record.QuarantinedAt)
+	suite.Equal(suite.now.Add(time.Hour), *record.NextRetryAt)
+}
+
+func (suite *RetryPolicyTestSuite) TestScheduleRetry_WithoutPolicy() {
+	previous := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+	previous.AttemptCount = 50
+	record := suite.failedRecord(models.ErrorCategoryPermissionDenied)
+
+	scheduleRetry(nil, record, previous, suite.now)
+
+	suite.Equal(51, record.AttemptCount)
+	suite.Nil(record.NextRetryAt)
+	suite.Nil(record.QuarantinedAt)
+	suite.True(record.IsRetryDue(suite.now))
+}
@@ -0,0 +1,134 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+	"vault-sync/pkg/log"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultStatusBatchSize is the number of records written per upsert unless configured otherwise.
+	DefaultStatusBatchSize = 100
+	// defaultStatusFlushInterval bounds how long a record waits for its batch to fill up.
+	defaultStatusFlushInterval = 50 * time.Millisecond
+)
+
+// StatusWriter batches the records of dispatched replica writes into multi-row upserts.
+//
+// A batch is written once it holds batchSize records, or flushInterval after its first record was added, so a
+// job never waits long for a quiet run to fill a batch. Every record of a failed batch is completed with the
+// error, which is reported exactly like a failed single-row update.
+type StatusWriter struct {
+	ctx            context.Context
+	databaseClient repository.SyncedSecretRepository
+	batchSize      int
+	flushInterval  time.Duration
+	writes         chan *statusWrite
+	done           chan struct{}
+	logger         zerolog.Logger
+}
+
+type statusWrite struct {
+	secret   *models.SyncedSecret
+	complete func(err error)
+}
+
+// NewStatusWriter starts a writer that upserts up to batchSize records at once.
+// Batches are written with ctx; once it is done, the records still queued fail with its error.
+// Close must be called to write the last batch.
+func NewStatusWriter(ctx context.Context, dbClient repository.SyncedSecretRepository, batchSize int) *StatusWriter {
+	return newStatusWriter(ctx, dbClient, batchSize, defaultStatusFlushInterval)
+}
+
+func newStatusWriter(
+	ctx context.Context,
+	dbClient repository.SyncedSecretRepository,
+	batchSize int,
+	flushInterval time.Duration,
+) *StatusWriter {
+	batchSize = max(1, batchSize)
+	writer := &StatusWriter{
+		ctx:            ctx,
+		databaseClient: dbClient,
+		batchSize:      batchSize,
+		flushInterval:  flushInterval,
+		writes:         make(chan *statusWrite, batchSize),
+		done:           make(chan struct{}),
+		logger:         log.Logger.With().Str("component", "status_writer").Logger(),
+	}
+
+	go writer.run()
+	return writer
+}
+
+// Write queues the secret for the next batch. complete is called from the writer once the batch is written,
+// with the error of the batch if it failed.
+func (w *StatusWriter) Write(secret *models.SyncedSecret, complete func(err error)) {
+	w.writes <- &statusWrite{secret: secret, complete: complete}
+}
+
+// Close writes the queued records and waits for their completions. Write must not be called afterwards.
+func (w *StatusWriter) Close() {
+	close(w.writes)
+	<-w.done
+}
+
+func (w *StatusWriter) run() {
+	defer close(w.done)
+
+	batch := make([]*statusWrite, 0, w.batchSize)
+	timer := time.NewTimer(w.flushInterval)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		w.flush(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case write, ok := <-w.writes:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, write)
+			if len(batch) == 1 {
+				timer.Reset(w.flushInterval)
+			}
+			if len(batch) >= w.batchSize {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+func (w *StatusWriter) flush(batch []*statusWrite) {
+	if len(batch) == 0 {
+		return
+	}
+
+	secrets := make([]*models.SyncedSecret, 0, len(batch))
+	for _, write := range batch {
+		secrets = append(secrets, write.secret)
+	}
+
+	err := w.databaseClient.UpdateSyncedSecretStatuses(w.ctx, secrets)
+	if err != nil {
+		w.logger.Error().Err(err).Int("records", len(batch)).Msg("Failed to write batch of synced secrets")
+	} else {
+		w.logger.Debug().Int("records", len(batch)).Msg("Wrote batch of synced secrets")
+	}
+
+	for _, write := range batch {
+		write.complete(err)
+	}
+}
@@ -0,0 +1,109 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// batchRecorder records the batches written by a StatusWriter. Only UpdateSyncedSecretStatuses is implemented.
+type batchRecorder struct {
+	repository.SyncedSecretRepository
+	mu      sync.Mutex
+	batches [][]*models.SyncedSecret
+	err     error
+}
+
+func (r *batchRecorder) UpdateSyncedSecretStatuses(_ context.Context, secrets []*models.SyncedSecret) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, secrets)
+	return r.err
+}
+
+func (r *batchRecorder) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sizes := make([]int, 0, len(r.batches))
+	for _, batch := range r.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+type StatusWriterTestSuite struct {
+	suite.Suite
+}
+
+func TestStatusWriterSuite(t *testing.T) {
+	suite.Run(t, new(StatusWriterTestSuite))
+}
+
+func (suite *StatusWriterTestSuite) writeRecords(writer *StatusWriter, count int) chan error {
+	completions := make(chan error, count)
+	for i := range count {
+		writer.Write(&models.SyncedSecret{
+			SecretBackend:      "test-mount",
+			SecretPath:         "test/key/path",
+			DestinationCluster: string(rune('a' + i)),
+		}, func(err error) {
+			completions <- err
+		})
+	}
+	return completions
+}
+
+func (suite *StatusWriterTestSuite) TestWrite() {
+	suite.Run("writes full batches without waiting for the flush interval", func() {
+		recorder := &batchRecorder{}
+		writer := newStatusWriter(context.Background(), recorder, 2, time.Hour)
+
+		completions := suite.writeRecords(writer, 4)
+
+		suite.Eventually(func() bool { return len(completions) == 4 }, time.Second, 5*time.Millisecond)
+		suite.Equal([]int{2, 2}, recorder.batchSizes())
+		writer.Close()
+	})
+
+	suite.Run("writes a partial batch once the flush interval elapsed", func() {
+		recorder := &batchRecorder{}
+		writer := newStatusWriter(context.Background(), recorder, 10, 10*time.Millisecond)
+
+		completions := suite.writeRecords(writer, 3)
+
+		suite.Eventually(func() bool { return len(completions) == 3 }, time.Second, 5*time.Millisecond)
+		suite.Equal([]int{3}, recorder.batchSizes())
+		writer.Close()
+	})
+
+	suite.Run("writes the last batch on close", func() {
+		recorder := &batchRecorder{}
+		writer := newStatusWriter(context.Background(), recorder, 10, time.Hour)
+
+		completions := suite.writeRecords(writer, 3)
+		writer.Close()
+
+		suite.Len(completions, 3)
+		suite.Equal([]int{3}, recorder.batchSizes())
+	})
+
+	suite.Run("completes every record of a failed batch with the error", func() {
+		recorder := &batchRecorder{err: errors.New("connection refused")}
+		writer := newStatusWriter(context.Background(), recorder, 10, time.Hour)
+
+		completions := suite.writeRecords(writer, 3)
+		writer.Close()
+
+		suite.Len(completions, 3)
+		for range 3 {
+			suite.ErrorContains(<-completions, "connection refused")
+		}
+	})
+}
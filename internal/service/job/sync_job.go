@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
+
 	"vault-sync/internal/models"
 	"vault-sync/internal/repository"
+	"vault-sync/internal/tracing"
 	"vault-sync/internal/vault"
 	"vault-sync/pkg/log"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SyncJob struct {
@@ -17,9 +21,74 @@ type SyncJob struct {
 	keyPath        string
 	vaultClient    vault.Syncer
 	databaseClient repository.SyncedSecretRepository
+	incremental    bool
+	statusWriter   *StatusWriter
+	records        map[string]*models.SyncedSecret
+	preloaded      bool
+	retryPolicy    *RetryPolicy
+	forceRetry     bool
+	timeout        time.Duration
 	logger         zerolog.Logger
 }
 
+// ErrTimeout is the error of a job, or of a step of a dispatched job, that ran longer than the timeout of the job.
+var ErrTimeout = errors.New("sync job timed out")
+
+// Option configures optional behaviour of a SyncJob.
+type Option func(*SyncJob)
+
+// WithIncrementalCheck skips the replica checks when the version and update time of the source secret still
+// match what was recorded for every replica at the last successful sync. Only the main cluster metadata is read
+// for unchanged secrets, so a secret that was changed or removed directly on a replica is only repaired by the
+// next full run.
+func WithIncrementalCheck(enabled bool) Option {
+	return func(job *SyncJob) {
+		job.incremental = enabled
+	}
+}
+
+// WithRecords provides the DB records of the secret, keyed by cluster name, so the job does not query them.
+// A nil map means the secret has no records at all.
+func WithRecords(records map[string]*models.SyncedSecret) Option {
+	return func(job *SyncJob) {
+		job.records = records
+		job.preloaded = true
+	}
+}
+
+// WithStatusWriter makes dispatched jobs queue their replica records on writer instead of writing each one
+// separately. A replica task only completes once its record is written.
+func WithStatusWriter(writer *StatusWriter) Option {
+	return func(job *SyncJob) {
+		job.statusWriter = writer
+	}
+}
+
+// WithRetryPolicy schedules the next retry of every failed replica with policy and quarantines the replicas
+// that keep failing. Dispatched jobs leave replicas alone while their retry is not due, unless the source changed
+// since they failed, and leave quarantined replicas alone until a forced retry.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(job *SyncJob) {
+		job.retryPolicy = policy
+	}
+}
+
+// WithForcedRetry retries failed replicas regardless of their backoff and quarantine.
+func WithForcedRetry() Option {
+	return func(job *SyncJob) {
+		job.forceRetry = true
+	}
+}
+
+// WithTimeout bounds Execute, and each step of Dispatch: gathering the state, reading the source secret and every
+// replica write or delete, by timeout. A step that runs out of time fails with ErrTimeout, so the job counts as
+// failed rather than as skipped like the jobs of an interrupted run. Zero disables the timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(job *SyncJob) {
+		job.timeout = timeout
+	}
+}
+
 // SyncDecision represents what action to take.
 type SyncDecision int
 
@@ -33,8 +102,9 @@ func NewSyncJob(
 	mount, keyPath string,
 	vaultClient vault.Syncer,
 	dbClient repository.SyncedSecretRepository,
+	opts ...Option,
 ) *SyncJob {
-	return &SyncJob{
+	job := &SyncJob{
 		mount:          mount,
 		keyPath:        keyPath,
 		vaultClient:    vaultClient,
@@ -45,9 +115,35 @@ func NewSyncJob(
 			Str("key_path", keyPath).
 			Logger(),
 	}
+
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	return job
 }
 
 func (job *SyncJob) Execute(ctx context.Context) (*SyncJobResult, error) {
+	ctx, span := job.startSpan(ctx)
+	defer span.End()
+
+	result, err := withTimeout(ctx, job.timeout, job.execute)
+	if err == nil && result != nil {
+		tracing.RecordError(span, result.Error)
+	}
+	tracing.RecordError(span, err)
+	return result, err
+}
+
+// startSpan starts the span of the job and adds its trace ID to the logger of the job.
+func (job *SyncJob) startSpan(ctx context.Context) (context.Context, trace.Span) {
+	ctx, span := tracing.Start(ctx, "sync_job.execute", tracing.SecretAttributes("", job.mount, job.keyPath)...)
+	job.logger = tracing.WithTraceContext(ctx, job.logger)
+	return ctx, span
+}
+
+// execute runs Execute within the span of the job.
+func (job *SyncJob) execute(ctx context.Context) (*SyncJobResult, error) {
 	logger := job.logger.With().Str("action", "execute").Logger()
 	logger.Debug().Msg("Starting secret sync job")
 
@@ -65,60 +161,160 @@ func (job *SyncJob) Execute(ctx context.Context) (*SyncJobResult, error) {
 
 	switch decision {
 	case DecisionNoOp:
-		return job.buildNoOpResult(state), nil
+		return job.buildNoOpResult(ctx, state), nil
 	case DecisionSync:
-		return job.executeSync(ctx)
+		return job.executeSync(ctx, state)
 	case DecisionDelete:
-		return job.executeDelete(ctx)
+		return job.executeDelete(ctx, state)
 	default:
 		return nil, fmt.Errorf("unknown decision: %v", decision)
 	}
 }
 
+// withTimeout runs op with ctx bounded by timeout, unless timeout is zero. When the timeout expires, the error of op
+// is replaced by ErrTimeout; the context error op returned is only kept as text.
+func withTimeout[T any](
+	ctx context.Context,
+	timeout time.Duration,
+	op func(ctx context.Context) (T, error),
+) (T, error) {
+	if timeout <= 0 {
+		return op(ctx)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+	defer cancel()
+	value, err := op(ctx)
+	if err != nil && errors.Is(context.Cause(ctx), ErrTimeout) {
+		return value, fmt.Errorf("%w after %s: %s", ErrTimeout, timeout, err.Error())
+	}
+	return value, err
+}
+
 // SyncState holds all the information needed to make sync decisions.
 type SyncState struct {
-	ReplicaNames     []string
-	SourceExists     bool
-	SourceVersion    int64
-	RecordsByCluster map[string]*models.SyncedSecret
-	ReplicaExistence map[string]bool
+	ReplicaNames  []string
+	SourceExists  bool
+	SourceVersion int64
+	// SourceUpdatedTime is the update time of the source metadata, stored with every synced record.
+	SourceUpdatedTime *time.Time
+	RecordsByCluster  map[string]*models.SyncedSecret
+	ReplicaExistence  map[string]bool
+	// HeldReplicas holds the failed replicas this job leaves alone, with the status they are reported with.
+	HeldReplicas map[string]SyncJobStatus
 }
 
+// gatherCurrentState reads the DB records and the source metadata with a single request to the main cluster.
+// The replicas are only checked when the records say every replica is up to date, since otherwise the
+// secret is written to all replicas anyway. The secret data is only read later, when a sync is needed.
 func (job *SyncJob) gatherCurrentState(ctx context.Context) (*SyncState, error) {
 	state := &SyncState{
 		RecordsByCluster: make(map[string]*models.SyncedSecret),
 		ReplicaExistence: make(map[string]bool),
+		HeldReplicas:     make(map[string]SyncJobStatus),
 	}
 
 	state.ReplicaNames = job.vaultClient.GetReplicaNames()
 
-	recordsByCluster, err := job.getDBRecords(state.ReplicaNames)
+	recordsByCluster, err := job.getDBRecords(ctx, state.ReplicaNames)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get DB records: %w", err)
 	}
 	state.RecordsByCluster = recordsByCluster
 
-	sourceExists, err := job.vaultClient.SecretExists(ctx, job.mount, job.keyPath)
+	source, err := job.vaultClient.GetSourceState(ctx, job.mount, job.keyPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check source existence: %w", err)
+		return nil, fmt.Errorf("failed to get source state: %w", err)
+	}
+	state.SourceExists = source.Exists
+	if source.Exists {
+		state.SourceVersion = source.Metadata.CurrentVersion
+		state.SourceUpdatedTime = &source.Metadata.UpdatedTime
+	}
+	job.holdReplicas(state, time.Now())
+	if !source.Exists {
+		return state, nil
 	}
-	state.SourceExists = sourceExists
 
-	// Get source version if exists
-	if sourceExists {
-		metadata, getMetadataErr := job.vaultClient.GetSecretMetadata(ctx, job.mount, job.keyPath)
-		if getMetadataErr != nil {
-			return nil, fmt.Errorf("failed to get source metadata: %w", getMetadataErr)
-		}
-		state.SourceVersion = metadata.CurrentVersion
+	if job.incremental && job.isSourceUnchanged(state) {
+		return state, nil
+	}
 
-		replicaExistence := job.checkReplicaExistence(ctx)
-		state.ReplicaExistence = replicaExistence
+	if job.recordsUpToDate(state) {
+		state.ReplicaExistence = job.checkReplicaExistence(ctx)
 	}
 
 	return state, nil
 }
 
+// holdReplicas marks the failed replicas that are not retried by this job: quarantined replicas, and replicas
+// whose retry is not due yet while the source still has the version they failed with.
+func (job *SyncJob) holdReplicas(state *SyncState, now time.Time) {
+	if job.forceRetry {
+		return
+	}
+
+	for clusterName, record := range state.RecordsByCluster {
+		switch {
+		case record.IsQuarantined():
+			state.HeldReplicas[clusterName] = SyncJobStatusQuarantined
+		case record.Status == models.StatusFailed && !record.IsRetryDue(now) &&
+			(!state.SourceExists || record.SourceVersion >= state.SourceVersion):
+			state.HeldReplicas[clusterName] = SyncJobStatusBackingOff
+		default:
+			continue
+		}
+		job.logger.Debug().
+			Str("cluster", clusterName).
+			Str("status", string(state.HeldReplicas[clusterName])).
+			Int("attempt_count", record.AttemptCount).
+			Msg("Leaving failed replica for a later retry")
+	}
+}
+
+// isSourceUnchanged reports whether every replica was successfully synced with the current version and
+// update time of the source. In that case the replicas are assumed to exist, so the decision is a no-op
+// without checking them.
+func (job *SyncJob) isSourceUnchanged(state *SyncState) bool {
+	logger := job.logger.With().Str("action", "incremental_check").Logger()
+
+	if len(state.ReplicaNames) == 0 || len(state.RecordsByCluster) != len(state.ReplicaNames) {
+		return false
+	}
+
+	for _, record := range state.RecordsByCluster {
+		if record.Status != models.StatusSuccess || record.SourceUpdatedTime == nil ||
+			record.SourceVersion != state.SourceVersion || !record.SourceUpdatedTime.Equal(*state.SourceUpdatedTime) {
+			logger.Debug().
+				Str("cluster", record.DestinationCluster).
+				Int64("recorded_version", record.SourceVersion).
+				Int64("source_version", state.SourceVersion).
+				Msg("Source changed since last sync")
+			return false
+		}
+	}
+
+	for _, clusterName := range state.ReplicaNames {
+		state.ReplicaExistence[clusterName] = true
+	}
+
+	logger.Debug().Msg("Source unchanged since last sync, skipping replica checks")
+	return true
+}
+
+// recordsUpToDate reports whether every replica has a record of the current source version.
+func (job *SyncJob) recordsUpToDate(state *SyncState) bool {
+	if len(state.RecordsByCluster) != len(state.ReplicaNames) {
+		return false
+	}
+	for _, record := range state.RecordsByCluster {
+		if record.SourceVersion < state.SourceVersion {
+			return false
+		}
+	}
+	return true
+}
+
 func (job *SyncJob) checkReplicaExistence(ctx context.Context) map[string]bool {
 	logger := job.logger.With().Str("action", "check_replica_existence").Logger()
 
@@ -146,12 +342,23 @@ func (job *SyncJob) checkReplicaExistence(ctx context.Context) map[string]bool {
 	return existence
 }
 
-func (job *SyncJob) getDBRecords(replicaNames []string) (map[string]*models.SyncedSecret, error) {
+func (job *SyncJob) getDBRecords(ctx context.Context, replicaNames []string) (map[string]*models.SyncedSecret, error) {
 	logger := job.logger.With().Str("action", "get_db_records").Logger()
 
 	records := make(map[string]*models.SyncedSecret)
+	if job.preloaded {
+		for _, clusterName := range replicaNames {
+			if record, exists := job.records[clusterName]; exists {
+				recordCopy := *record
+				records[clusterName] = &recordCopy
+			}
+		}
+		logger.Debug().Int("records", len(records)).Msg("Using preloaded DB records")
+		return records, nil
+	}
+
 	for _, clusterName := range replicaNames {
-		record, err := job.databaseClient.GetSyncedSecret(job.mount, job.keyPath, clusterName)
+		record, err := job.databaseClient.GetSyncedSecret(ctx, job.mount, job.keyPath, clusterName)
 		if errors.Is(err, repository.ErrSecretNotFound) {
 			logger.Debug().Str("cluster", clusterName).Msg("No DB record found")
 			continue
@@ -167,7 +374,7 @@ func (job *SyncJob) getDBRecords(replicaNames []string) (map[string]*models.Sync
 
 func (job *SyncJob) makeDecision(state *SyncState) SyncDecision {
 	allReplicasHaveRecords := len(state.RecordsByCluster) == len(state.ReplicaNames)
-	someReplicasHaveRecords := len(state.RecordsByCluster) > 0
+	someReplicasHaveRecords := len(state.RecordsByCluster) > len(state.HeldReplicas)
 
 	switch {
 	case !state.SourceExists && !someReplicasHaveRecords:
@@ -186,8 +393,12 @@ func (job *SyncJob) makeDecision(state *SyncState) SyncDecision {
 		// Source exists, all records exist → check versions
 		needsSync := false
 		for clusterName, record := range state.RecordsByCluster {
-			// Check if version is outdated
-			if record.SourceVersion < state.SourceVersion {
+			if _, held := state.HeldReplicas[clusterName]; held {
+				continue
+			}
+
+			// Check if version is outdated or the last attempt failed
+			if record.SourceVersion < state.SourceVersion || record.Status == models.StatusFailed {
 				needsSync = true
 				break
 			}
@@ -210,114 +421,295 @@ func (job *SyncJob) makeDecision(state *SyncState) SyncDecision {
 	}
 }
 
-func (job *SyncJob) executeSync(ctx context.Context) (*SyncJobResult, error) {
+func (job *SyncJob) executeSync(ctx context.Context, state *SyncState) (*SyncJobResult, error) {
 	logger := job.logger.With().Str("action", "sync").Logger()
 	logger.Debug().Msg("Executing sync operation")
 
-	syncResults, err := job.vaultClient.SyncSecretToReplicas(ctx, job.mount, job.keyPath)
+	syncResults, err := job.syncReplicas(ctx, state)
 	if err != nil {
 		return nil, fmt.Errorf("vault sync failed: %w", err)
 	}
 
 	var multiErr MultiError
-	clusterStatuses := make([]*ClusterSyncStatus, 0, len(syncResults))
+	clusterStatuses := make([]*ClusterSyncStatus, 0, len(state.ReplicaNames))
 
 	for _, syncResult := range syncResults {
-		status := mapFromSyncedSecretStatus(syncResult.Status)
-		if status == SyncJobStatusFailed {
-			logger.Error().
-				Str("cluster", syncResult.DestinationCluster).
-				Msg("Failed to write to vault")
-			multiErr.Add(fmt.Errorf("cluster %s vault write error", syncResult.DestinationCluster))
-		}
-
-		if dbErr := job.databaseClient.UpdateSyncedSecretStatus(syncResult); dbErr != nil {
-			logger.Error().
-				Str("cluster", syncResult.DestinationCluster).
-				Err(dbErr).
-				Msg("Failed to update database")
-			status = SyncJobStatusFailed
-			multiErr.Add(fmt.Errorf("cluster %s DB update: %w", syncResult.DestinationCluster, dbErr))
-		}
-
-		clusterStatuses = append(clusterStatuses, &ClusterSyncStatus{
-			ClusterName: syncResult.DestinationCluster,
-			Status:      status,
-		})
+		var clusterErr MultiError
+		status := job.recordSyncResult(ctx, logger, state, syncResult, &clusterErr)
+		status.Error = clusterErr.Err()
+		multiErr.Add(status.Error)
+		clusterStatuses = append(clusterStatuses, status)
 	}
+	clusterStatuses = append(clusterStatuses, heldStatuses(state)...)
 
 	logger.Debug().Int("synced_count", len(syncResults)).Msg("Sync operation completed")
 	return NewSyncJobResult(job, clusterStatuses, multiErr.Err()), nil
 }
 
-func (job *SyncJob) executeDelete(ctx context.Context) (*SyncJobResult, error) {
+// syncReplicas writes the source to every replica that is not held. With held replicas, the source is read
+// once and written to the other replicas one by one, as Dispatch does.
+func (job *SyncJob) syncReplicas(ctx context.Context, state *SyncState) ([]*models.SyncedSecret, error) {
+	if len(state.HeldReplicas) == 0 {
+		return job.vaultClient.SyncSecretToReplicas(ctx, job.mount, job.keyPath)
+	}
+
+	sourceSecret, err := job.vaultClient.ReadSecret(ctx, job.mount, job.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	syncResults := make([]*models.SyncedSecret, 0, len(state.ReplicaNames))
+	for _, clusterName := range unheldReplicas(state) {
+		syncResult, err := job.vaultClient.SyncSecretToReplica(ctx, clusterName, job.mount, job.keyPath, sourceSecret)
+		if err != nil {
+			return nil, err
+		}
+		syncResults = append(syncResults, syncResult)
+	}
+	return syncResults, nil
+}
+
+func (job *SyncJob) executeDelete(ctx context.Context, state *SyncState) (*SyncJobResult, error) {
 	logger := job.logger.With().Str("action", "delete").Logger()
 	logger.Debug().Msg("Executing delete operation")
 
-	deleteResults, err := job.vaultClient.DeleteSecretFromReplicas(ctx, job.mount, job.keyPath)
+	deleteResults, err := job.deleteFromReplicas(ctx, state)
 	if err != nil {
 		return nil, fmt.Errorf("vault delete failed: %w", err)
 	}
 
 	var multiErr MultiError
-	clusterStatuses := make([]*ClusterSyncStatus, 0, len(deleteResults))
+	clusterStatuses := make([]*ClusterSyncStatus, 0, len(state.ReplicaNames))
 
 	for _, deleteResult := range deleteResults {
-		localLogger := logger.With().Str("cluster", deleteResult.DestinationCluster).Logger()
-		status := mapFromSyncedSecretStatus(deleteResult.Status)
-		if status == SyncJobStatusFailed {
-			localLogger.Error().Msg("Failed to delete from vault; trying to update DB with failed status")
-			multiErr.Add(fmt.Errorf("cluster %s vault delete failed", deleteResult.DestinationCluster))
-			status = SyncJobStatusErrorDeleting
-
-			updateResult := &models.SyncedSecret{
-				SecretBackend:      deleteResult.SecretBackend,
-				SecretPath:         deleteResult.SecretPath,
-				DestinationCluster: deleteResult.DestinationCluster,
-				LastSyncAttempt:    deleteResult.DeletionAttempt,
-				ErrorMessage:       deleteResult.ErrorMessage,
-				Status:             deleteResult.Status,
-				SourceVersion:      -1000,
-				DestinationVersion: -1000,
-			}
-			if dbErr := job.databaseClient.UpdateSyncedSecretStatus(updateResult); dbErr != nil {
-				localLogger.Error().Err(dbErr).Msg("Failed to update database with delete failure status")
-				multiErr.Add(
-					fmt.Errorf(
-						"cluster %s DB update after vault delete failure: %w",
-						deleteResult.DestinationCluster,
-						dbErr,
-					),
-				)
-			}
+		var clusterErr MultiError
+		previous := state.RecordsByCluster[deleteResult.DestinationCluster]
+		status := job.recordDeleteResult(ctx, logger, deleteResult, previous, &clusterErr)
+		status.Error = clusterErr.Err()
+		multiErr.Add(status.Error)
+		clusterStatuses = append(clusterStatuses, status)
+	}
+
+	clusterStatuses = append(clusterStatuses, heldStatuses(state)...)
+
+	logger.Debug().Int("deleted_count", len(deleteResults)).Msg("Delete operation completed")
+	return NewSyncJobResult(job, clusterStatuses, multiErr.Err()), nil
+}
+
+// deleteFromReplicas deletes the secret from every replica that is not held.
+func (job *SyncJob) deleteFromReplicas(
+	ctx context.Context, state *SyncState,
+) ([]*models.SyncSecretDeletionResult, error) {
+	if len(state.HeldReplicas) == 0 {
+		return job.vaultClient.DeleteSecretFromReplicas(ctx, job.mount, job.keyPath)
+	}
+
+	deleteResults := make([]*models.SyncSecretDeletionResult, 0, len(state.ReplicaNames))
+	for _, clusterName := range unheldReplicas(state) {
+		deleteResult, err := job.vaultClient.DeleteSecretFromReplica(ctx, clusterName, job.mount, job.keyPath)
+		if err != nil {
+			return nil, err
+		}
+		deleteResults = append(deleteResults, deleteResult)
+	}
+	return deleteResults, nil
+}
+
+// unheldReplicas returns the replicas of state that are not held.
+func unheldReplicas(state *SyncState) []string {
+	replicaNames := make([]string, 0, len(state.ReplicaNames))
+	for _, clusterName := range state.ReplicaNames {
+		if _, held := state.HeldReplicas[clusterName]; !held {
+			replicaNames = append(replicaNames, clusterName)
+		}
+	}
+	return replicaNames
+}
+
+// heldStatuses returns the statuses of the held replicas of state, which the job leaves alone.
+func heldStatuses(state *SyncState) []*ClusterSyncStatus {
+	statuses := make([]*ClusterSyncStatus, 0, len(state.HeldReplicas))
+	for _, clusterName := range state.ReplicaNames {
+		if status, held := state.HeldReplicas[clusterName]; held {
+			statuses = append(statuses, &ClusterSyncStatus{ClusterName: clusterName, Status: status})
+		}
+	}
+	return statuses
+}
+
+// recordSyncResult stores the outcome of a replica write in the database and maps it to a cluster status.
+// Failures are added to multiErr.
+func (job *SyncJob) recordSyncResult(
+	ctx context.Context,
+	logger zerolog.Logger,
+	state *SyncState,
+	syncResult *models.SyncedSecret,
+	multiErr *MultiError,
+) *ClusterSyncStatus {
+	status := job.syncResultStatus(logger, state, syncResult, multiErr)
+
+	if dbErr := job.databaseClient.UpdateSyncedSecretStatus(ctx, syncResult); dbErr != nil {
+		status = job.dbUpdateFailed(logger, syncResult, dbErr, multiErr)
+	}
+
+	return newWriteStatus(syncResult, status)
+}
+
+// newWriteStatus maps the record of a replica write to a cluster status.
+func newWriteStatus(syncResult *models.SyncedSecret, status SyncJobStatus) *ClusterSyncStatus {
+	return &ClusterSyncStatus{
+		ClusterName:        syncResult.DestinationCluster,
+		Status:             status,
+		SourceVersion:      syncResult.SourceVersion,
+		DestinationVersion: syncResult.DestinationVersion,
+	}
+}
+
+// syncResultStatus prepares the record of a replica write for the database and maps it to a cluster status.
+// The source update time is only stored when the written version is the one it was read for.
+// A failed vault write is added to multiErr and its retry is scheduled.
+func (job *SyncJob) syncResultStatus(
+	logger zerolog.Logger,
+	state *SyncState,
+	syncResult *models.SyncedSecret,
+	multiErr *MultiError,
+) SyncJobStatus {
+	if syncResult.SourceVersion == state.SourceVersion {
+		syncResult.SourceUpdatedTime = state.SourceUpdatedTime
+	}
+
+	status := mapFromSyncedSecretStatus(syncResult.Status)
+	if status == SyncJobStatusFailed {
+		scheduleRetry(job.retryPolicy, syncResult, state.RecordsByCluster[syncResult.DestinationCluster], time.Now())
+		logger.Error().
+			Str("cluster", syncResult.DestinationCluster).
+			Int("attempt_count", syncResult.AttemptCount).
+			Msg("Failed to write to vault")
+		if syncResult.ErrorMessage != nil {
+			multiErr.Add(fmt.Errorf("cluster %s vault write error: %s", syncResult.DestinationCluster, *syncResult.ErrorMessage))
 		} else {
-			localLogger.Debug().Msg("Successfully deleted from vault - removing DB record")
-			if dbErr := job.databaseClient.DeleteSyncedSecret(job.mount, job.keyPath, deleteResult.DestinationCluster); dbErr != nil {
-				localLogger.Error().Err(dbErr).Msg("Failed to delete from database")
-				multiErr.Add(fmt.Errorf("cluster %s DB delete: %w", deleteResult.DestinationCluster, dbErr))
-			}
+			multiErr.Add(fmt.Errorf("cluster %s vault write error", syncResult.DestinationCluster))
 		}
+	}
+	return status
+}
 
-		clusterStatuses = append(clusterStatuses, &ClusterSyncStatus{
-			ClusterName: deleteResult.DestinationCluster,
-			Status:      status,
-		})
+// dbUpdateFailed reports a record of a replica write that could not be stored. The replica is then
+// considered failed, so it is synced again by the next run.
+func (job *SyncJob) dbUpdateFailed(
+	logger zerolog.Logger,
+	syncResult *models.SyncedSecret,
+	dbErr error,
+	multiErr *MultiError,
+) SyncJobStatus {
+	logger.Error().
+		Str("cluster", syncResult.DestinationCluster).
+		Err(dbErr).
+		Msg("Failed to update database")
+	multiErr.Add(fmt.Errorf("cluster %s DB update: %w", syncResult.DestinationCluster, dbErr))
+	return SyncJobStatusFailed
+}
+
+// recordDeleteResult removes the database record of a deleted replica secret, or marks it as
+// failed to delete and schedules its retry from the previous record, and maps the outcome to a cluster
+// status. Failures are added to multiErr.
+func (job *SyncJob) recordDeleteResult(
+	ctx context.Context,
+	logger zerolog.Logger,
+	deleteResult *models.SyncSecretDeletionResult,
+	previous *models.SyncedSecret,
+	multiErr *MultiError,
+) *ClusterSyncStatus {
+	localLogger := logger.With().Str("cluster", deleteResult.DestinationCluster).Logger()
+	status := mapFromSyncedSecretStatus(deleteResult.Status)
+	if status == SyncJobStatusFailed {
+		localLogger.Error().Msg("Failed to delete from vault; trying to update DB with failed status")
+		multiErr.Add(fmt.Errorf("cluster %s vault delete failed", deleteResult.DestinationCluster))
+		status = SyncJobStatusErrorDeleting
+
+		updateResult := &models.SyncedSecret{
+			SecretBackend:      deleteResult.SecretBackend,
+			SecretPath:         deleteResult.SecretPath,
+			DestinationCluster: deleteResult.DestinationCluster,
+			LastSyncAttempt:    deleteResult.DeletionAttempt,
+			ErrorMessage:       deleteResult.ErrorMessage,
+			ErrorCategory:      deleteResult.ErrorCategory,
+			Status:             deleteResult.Status,
+			SourceVersion:      models.DeleteFailedVersion,
+			DestinationVersion: models.DeleteFailedVersion,
+		}
+		scheduleRetry(job.retryPolicy, updateResult, previous, time.Now())
+		if dbErr := job.databaseClient.UpdateSyncedSecretStatus(ctx, updateResult); dbErr != nil {
+			localLogger.Error().Err(dbErr).Msg("Failed to update database with delete failure status")
+			multiErr.Add(
+				fmt.Errorf(
+					"cluster %s DB update after vault delete failure: %w",
+					deleteResult.DestinationCluster,
+					dbErr,
+				),
+			)
+		}
+	} else {
+		localLogger.Debug().Msg("Successfully deleted from vault - removing DB record")
+		if dbErr := job.databaseClient.DeleteSyncedSecret(ctx, job.mount, job.keyPath, deleteResult.DestinationCluster); dbErr != nil {
+			localLogger.Error().Err(dbErr).Msg("Failed to delete from database")
+			multiErr.Add(fmt.Errorf("cluster %s DB delete: %w", deleteResult.DestinationCluster, dbErr))
+		}
 	}
 
-	logger.Debug().Int("deleted_count", len(deleteResults)).Msg("Delete operation completed")
-	return NewSyncJobResult(job, clusterStatuses, multiErr.Err()), nil
+	return &ClusterSyncStatus{
+		ClusterName: deleteResult.DestinationCluster,
+		Status:      status,
+	}
 }
 
-func (job *SyncJob) buildNoOpResult(state *SyncState) *SyncJobResult {
+// backfillSourceUpdatedTime stores the source update time on up to date records that do not have it yet, e.g.
+// records synced before it was tracked. Without it the incremental check would never skip those secrets, as
+// unchanged secrets are not written again. A failed update is only logged and retried on the next run.
+func (job *SyncJob) backfillSourceUpdatedTime(ctx context.Context, state *SyncState) {
+	logger := job.logger.With().Str("action", "backfill_source_updated_time").Logger()
+
+	if state.SourceUpdatedTime == nil || state.SourceUpdatedTime.IsZero() {
+		return
+	}
+
+	for clusterName, record := range state.RecordsByCluster {
+		if record.Status != models.StatusSuccess || record.SourceVersion != state.SourceVersion ||
+			(record.SourceUpdatedTime != nil && record.SourceUpdatedTime.Equal(*state.SourceUpdatedTime)) {
+			continue
+		}
+
+		record.SourceUpdatedTime = state.SourceUpdatedTime
+		if err := job.databaseClient.UpdateSyncedSecretStatus(ctx, record); err != nil {
+			logger.Warn().Str("cluster", clusterName).Err(err).Msg("Failed to store source update time")
+			continue
+		}
+		logger.Debug().Str("cluster", clusterName).Msg("Stored source update time")
+	}
+}
+
+func (job *SyncJob) buildNoOpResult(ctx context.Context, state *SyncState) *SyncJobResult {
+	if job.incremental {
+		job.backfillSourceUpdatedTime(ctx, state)
+	}
+
 	clusterStatuses := make([]*ClusterSyncStatus, 0, len(state.ReplicaNames))
 
 	for _, clusterName := range state.ReplicaNames {
+		status := SyncJobStatusUnModified
+		if heldStatus, held := state.HeldReplicas[clusterName]; held {
+			status = heldStatus
+		}
 		clusterStatuses = append(clusterStatuses, &ClusterSyncStatus{
 			ClusterName: clusterName,
-			Status:      SyncJobStatusUnModified,
+			Status:      status,
 		})
 	}
 
+	if len(state.HeldReplicas) > 0 {
+		job.logger.Info().Int("held_replicas", len(state.HeldReplicas)).Msg("No action needed - failed replicas are held")
+		return NewSyncJobResult(job, clusterStatuses, nil)
+	}
 	job.logger.Info().Msg("No action needed - all replicas up to date")
 	return NewSyncJobResult(job, clusterStatuses, nil)
 }
@@ -365,6 +757,10 @@ const (
 	SyncJobStatusFailed        SyncJobStatus = "failed"
 	SyncJobStatusUnknown       SyncJobStatus = "unknown"
 	SyncJobStatusPending       SyncJobStatus = "pending"
+	// SyncJobStatusBackingOff is a failed replica whose next retry is not due yet.
+	SyncJobStatusBackingOff SyncJobStatus = "backing_off"
+	// SyncJobStatusQuarantined is a replica that failed too often and is only retried on request.
+	SyncJobStatusQuarantined SyncJobStatus = "quarantined"
 )
 
 func mapFromSyncedSecretStatus(status models.SyncStatus) SyncJobStatus {
@@ -390,9 +786,14 @@ type SyncJobResult struct {
 	Error   error
 }
 
+// ClusterSyncStatus is the outcome of a job on one replica. The versions are set for replica writes, and Error
+// holds the failure of this replica only, while SyncJobResult.Error combines the failures of every replica.
 type ClusterSyncStatus struct {
-	ClusterName string
-	Status      SyncJobStatus
+	ClusterName        string
+	Status             SyncJobStatus
+	SourceVersion      int64
+	DestinationVersion int64
+	Error              error
 }
 
 func NewSyncJobResult(job *SyncJob, status []*ClusterSyncStatus, err error) *SyncJobResult {
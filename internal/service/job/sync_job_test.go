@@ -10,6 +10,7 @@ import (
 	"vault-sync/internal/repository"
 	"vault-sync/testutil/testbuilder"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -216,6 +217,28 @@ func (suite *SyncJobTestSuite) TestExecute_Success() {
 		}
 	})
 
+	suite.Run("checks the replicas in incremental mode when no source update time was recorded", func() {
+		mockRepo, mockVault := suite.builder.
+			WithDatabaseSecretVersion(sourceVersion).
+			WithGetSyncedSecret(clusters...).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			SwitchToBuildableStage().Build()
+
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo, WithIncrementalCheck(true))
+
+		jobResult, err := worker.Execute(suite.ctx)
+
+		suite.NoError(err)
+		suite.Len(jobResult.Status, 2)
+		for _, status := range jobResult.Status {
+			suite.Equal(SyncJobStatusUnModified, status.Status)
+		}
+		mockVault.AssertNumberOfCalls(suite.T(), "SecretExistsInReplica", 2)
+	})
+
 	suite.Run("deletes secret from all replicas if it is in DB but not in source cluster", func() {
 		mockRepo, mockVault := suite.builder.
 			WithDatabaseSecretVersion(sourceVersion).
@@ -268,6 +291,63 @@ func (suite *SyncJobTestSuite) TestExecute_Success() {
 		}
 	})
 
+	suite.Run("leaves a quarantined replica alone when syncing the others", func() {
+		mockRepo, mockVault := suite.builder.
+			WithDatabaseSecretVersion(secretVersion).
+			WithGetQuarantinedSyncedSecret(cluster1).
+			WithDatabaseSecretVersion(secretVersion).
+			WithGetSyncedSecret(cluster2).
+			WithUpdateSyncedSecretStatus(models.StatusSuccess, sourceVersion, cluster2).
+			SwitchToVaultStage().
+			WithVaultSecretExists(true).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithGetSecretMetadata(sourceVersion).
+			WithSyncSecretToReplicas(models.StatusSuccess, sourceVersion, cluster2).
+			SwitchToBuildableStage().Build()
+
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		jobResult, err := worker.Execute(suite.ctx)
+
+		suite.NoError(err)
+		suite.NoError(jobResult.Error)
+		suite.Equal([]*ClusterSyncStatus{
+			{ClusterName: cluster2, Status: SyncJobStatusUpdated, SourceVersion: sourceVersion},
+			{ClusterName: cluster1, Status: SyncJobStatusQuarantined},
+		}, jobResult.Status)
+		mockVault.AssertNotCalled(suite.T(), "SyncSecretToReplicas", mock.Anything, mock.Anything, mock.Anything)
+		mockVault.AssertNotCalled(suite.T(), "SyncSecretToReplica",
+			mock.Anything, cluster1, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("leaves a quarantined replica alone when deleting from the others", func() {
+		mockRepo, mockVault := suite.builder.
+			WithDatabaseSecretVersion(sourceVersion).
+			WithGetQuarantinedSyncedSecret(cluster1).
+			WithDatabaseSecretVersion(sourceVersion).
+			WithGetSyncedSecret(cluster2).
+			WithDeleteSyncedSecret(cluster2).
+			SwitchToVaultStage().
+			WithVaultSecretExists(false).
+			WithVaultSecretExistsInReplicas(true, clusters...).
+			WithDeleteSecretFromReplicas(models.StatusDeleted, cluster2).
+			SwitchToBuildableStage().Build()
+
+		worker := NewSyncJob(suite.mount, suite.keyPath, mockVault, mockRepo)
+
+		jobResult, err := worker.Execute(suite.ctx)
+
+		suite.NoError(err)
+		suite.NoError(jobResult.Error)
+		suite.Require().Len(jobResult.Status, 2)
+		suite.Equal(cluster2, jobResult.Status[0].ClusterName)
+		suite.Equal(SyncJobStatusDeleted, jobResult.Status[0].Status)
+		suite.Equal(&ClusterSyncStatus{ClusterName: cluster1, Status: SyncJobStatusQuarantined}, jobResult.Status[1])
+		mockVault.AssertNotCalled(suite.T(), "DeleteSecretFromReplicas", mock.Anything, mock.Anything, mock.Anything)
+		mockVault.AssertNotCalled(suite.T(), "DeleteSecretFromReplica",
+			mock.Anything, cluster1, mock.Anything, mock.Anything)
+	})
+
 	suite.Run("no-op when source missing and no DB records exist", func() {
 		mockRepo, mockVault := suite.builder.
 			WithGetSyncedSecretNotFound(clusters...).
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errorBudgetMinJobs is how many jobs have to finish before the share of failed jobs can abort a run, so a
+// couple of early failures do not abort a large run.
+const errorBudgetMinJobs = 100
+
+// ErrRunAborted is the error of a run that was aborted because it ran out of its budget. SyncResult.AbortReason
+// tells which limit was reached.
+var ErrRunAborted = errors.New("run aborted")
+
+// runBudget aborts a run that takes too long or in which too many jobs fail. Aborting cancels the context of the
+// run, so no new job starts and the discovered jobs that did not start are counted as skipped; discovery stops as
+// well, which SyncResult.Incomplete reports. A nil runBudget never aborts.
+type runBudget struct {
+	ctx              context.Context
+	abort            context.CancelCauseFunc
+	stopDeadline     func() bool
+	maxFailedJobs    int
+	maxFailedPercent float64
+}
+
+// startBudget returns the budget of a run started with ctx, or nil when no limit is set. The run has to use the
+// context of the budget and release it once it finished.
+func (o *SyncOrchestrator) startBudget(ctx context.Context) *runBudget {
+	if o.maxRunDuration <= 0 && o.maxFailedJobs <= 0 && o.maxFailedPercent <= 0 {
+		return nil
+	}
+
+	budgetCtx, abort := context.WithCancelCause(ctx)
+	budget := &runBudget{
+		ctx:              budgetCtx,
+		abort:            abort,
+		stopDeadline:     func() bool { return false },
+		maxFailedJobs:    o.maxFailedJobs,
+		maxFailedPercent: o.maxFailedPercent,
+	}
+	if o.maxRunDuration > 0 {
+		maxRunDuration := o.maxRunDuration
+		timer := time.AfterFunc(maxRunDuration, func() {
+			abort(fmt.Errorf("run exceeded its maximum duration of %s", maxRunDuration))
+		})
+		budget.stopDeadline = timer.Stop
+	}
+	return budget
+}
+
+// context returns the context the run has to use, which is cancelled once the run is aborted.
+func (b *runBudget) context(ctx context.Context) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return b.ctx
+}
+
+// check aborts the run once the failed jobs of result exceed the error budget. The share of failed jobs is taken
+// of the jobs that finished so far.
+func (b *runBudget) check(result *SyncResult) {
+	if b == nil || b.ctx.Err() != nil {
+		return
+	}
+
+	finished := result.TotalSecrets - result.SkippedSecrets
+	switch {
+	case b.maxFailedJobs > 0 && result.FailedSyncs >= b.maxFailedJobs:
+		b.abort(fmt.Errorf("%d jobs failed, the error budget allows %d", result.FailedSyncs, b.maxFailedJobs))
+	case b.maxFailedPercent > 0 && finished >= errorBudgetMinJobs &&
+		float64(result.FailedSyncs)*100 >= b.maxFailedPercent*float64(finished):
+		b.abort(fmt.Errorf("%d of %d jobs failed, the error budget allows %g%%",
+			result.FailedSyncs, finished, b.maxFailedPercent))
+	}
+}
+
+// abortReason returns why the run was aborted, or an empty string when it was not. A run whose parent context
+// is done was interrupted rather than aborted.
+func (b *runBudget) abortReason(parent context.Context) string {
+	if b == nil || parent.Err() != nil || b.ctx.Err() == nil {
+		return ""
+	}
+	return context.Cause(b.ctx).Error()
+}
+
+// release stops the deadline of the run and releases the context of the budget.
+func (b *runBudget) release() {
+	if b == nil {
+		return
+	}
+	b.stopDeadline()
+	b.abort(nil)
+}
@@ -0,0 +1,182 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/pathmatching"
+)
+
+// drainTimeout bounds how long the jobs in flight when a run is interrupted may take to finish.
+const drainTimeout = 20 * time.Second
+
+// runCheckpoint records the paths processed by a full or incremental run, so the run can be resumed once it was
+// interrupted. Like history, checkpointing is best effort: a failed write is logged and never fails the run. A
+// nil runCheckpoint records nothing.
+type runCheckpoint struct {
+	store      repository.SyncCheckpointRepository
+	checkpoint *models.SyncCheckpoint
+	processed  map[string]bool
+	paths      []models.CheckpointPath
+	batchSize  int
+	logger     zerolog.Logger
+}
+
+// resumeCheckpoint returns the checkpoint of an interrupted run to resume, or nil when there is none or resuming
+// is disabled. A checkpoint older than the full reconciliation interval is not resumed, as every path it holds is
+// due for a check again.
+func (o *SyncOrchestrator) resumeCheckpoint(ctx context.Context, now time.Time) *runCheckpoint {
+	if o.checkpoints == nil || !o.resume || o.workQueue != nil {
+		return nil
+	}
+
+	checkpoint, err := o.checkpoints.GetSyncCheckpoint(ctx, o.checkpointID)
+	if err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to get the checkpoint of the last run, starting over")
+		return nil
+	}
+	if checkpoint == nil {
+		return nil
+	}
+	if now.Sub(checkpoint.StartedAt) >= o.fullReconciliationInterval {
+		o.logger.Info().Time("started_at", checkpoint.StartedAt).Msg("Checkpoint is too old to resume, starting over")
+		return nil
+	}
+
+	processed := make(map[string]bool, len(checkpoint.Paths))
+	for _, path := range checkpoint.Paths {
+		processed[pathmatching.SecretPath{Mount: path.SecretBackend, KeyPath: path.SecretPath}.String()] = true
+	}
+	logger := o.logger.With().Str("checkpoint", o.checkpointID).Logger()
+	event := logger.Info().
+		Time("started_at", checkpoint.StartedAt).
+		Bool("incremental", checkpoint.Incremental).
+		Int("processed_paths", len(processed))
+	if checkpoint.RunID != nil {
+		event = event.Int64("interrupted_run_id", *checkpoint.RunID)
+	}
+	event.Msg("Resuming interrupted run")
+
+	return &runCheckpoint{
+		store:      o.checkpoints,
+		checkpoint: checkpoint,
+		processed:  processed,
+		batchSize:  o.statusBatchSize,
+		logger:     logger,
+	}
+}
+
+// startCheckpoint replaces the checkpoint of the instance with a new one for the run. It returns nil when
+// checkpoints are disabled or the checkpoint cannot be recorded.
+func (o *SyncOrchestrator) startCheckpoint(
+	ctx context.Context,
+	startTime time.Time,
+	incremental bool,
+	history *syncHistory,
+) *runCheckpoint {
+	if o.checkpoints == nil || o.workQueue != nil {
+		return nil
+	}
+
+	checkpoint := &models.SyncCheckpoint{
+		InstanceID:  o.checkpointID,
+		StartedAt:   startTime,
+		Incremental: incremental,
+	}
+	if history != nil {
+		checkpoint.RunID = &history.run.ID
+	}
+	if err := o.checkpoints.StartSyncCheckpoint(ctx, checkpoint); err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to record the checkpoint of the run, continuing without it")
+		return nil
+	}
+
+	return &runCheckpoint{
+		store:      o.checkpoints,
+		checkpoint: checkpoint,
+		batchSize:  o.statusBatchSize,
+		logger:     o.logger.With().Str("checkpoint", o.checkpointID).Logger(),
+	}
+}
+
+// processedPaths returns the paths processed before the run was resumed, keyed by SecretPath.String.
+func (c *runCheckpoint) processedPaths() map[string]bool {
+	if c == nil {
+		return nil
+	}
+	return c.processed
+}
+
+// add queues the path of the job, unless the job still needs work, and writes the queued paths once a batch is
+// full. A job needs work when it was cut short by the end of the run or failed, on the main cluster or on a
+// replica, so a resumed run syncs it again.
+func (c *runCheckpoint) add(ctx context.Context, jobResult *job.SyncJobResult) {
+	if c == nil || jobResult.Error != nil {
+		return
+	}
+	for _, status := range jobResult.Status {
+		if status.Status == job.SyncJobStatusFailed || status.Status == job.SyncJobStatusErrorDeleting {
+			return
+		}
+	}
+
+	c.paths = append(c.paths, models.CheckpointPath{SecretBackend: jobResult.Mount, SecretPath: jobResult.KeyPath})
+	if len(c.paths) >= c.batchSize {
+		c.flush(ctx)
+	}
+}
+
+func (c *runCheckpoint) flush(ctx context.Context) {
+	if len(c.paths) == 0 {
+		return
+	}
+
+	if err := c.store.AddCheckpointPaths(ctx, c.checkpoint.InstanceID, c.paths); err != nil {
+		c.logger.Warn().Err(err).Int("paths", len(c.paths)).Msg("Failed to checkpoint processed paths")
+	}
+	c.paths = c.paths[:0]
+}
+
+// finish writes the remaining paths of an interrupted run, so the next run can resume it, and deletes the
+// checkpoint of any other run. It still runs when ctx was cancelled, bounded by historyFinishTimeout.
+func (c *runCheckpoint) finish(ctx context.Context, interrupted bool) {
+	if c == nil {
+		return
+	}
+
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), historyFinishTimeout)
+	defer cancel()
+
+	if interrupted {
+		c.flush(finishCtx)
+		c.logger.Info().Msg("Checkpointed interrupted run")
+		return
+	}
+	if err := c.store.DeleteSyncCheckpoint(finishCtx, c.checkpoint.InstanceID); err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to delete the checkpoint of the run")
+	}
+}
+
+// drainContext returns a context that is only cancelled timeout after ctx is done, or when the returned function
+// is called, so the work in flight when ctx is done can finish.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-drainCtx.Done():
+		}
+	})
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,725 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+	"vault-sync/internal/vault"
+)
+
+const mainCluster = "main"
+
+// fakeVault is an in-memory vault.Syncer with one main cluster and any number of replicas.
+// It records how many jobs gathered state at the same time and counts the requests per cluster,
+// one per call like the real client when no retry is needed.
+type fakeVault struct {
+	mu           sync.Mutex
+	main         map[string]map[string]int64
+	updated      map[string]time.Time
+	replicas     map[string]map[string]int64
+	replicaNames []string
+	mountErrors  map[string]error
+	replicaErrs  map[string]error
+	stateDelay   time.Duration
+	hungReplica  string
+
+	inFlight      atomic.Int32
+	maxSeen       atomic.Int32
+	replicaChecks atomic.Int32
+	requests      map[string]*atomic.Int64
+}
+
+func newFakeVault(replicaNames ...string) *fakeVault {
+	replicas := make(map[string]map[string]int64, len(replicaNames))
+	requests := map[string]*atomic.Int64{mainCluster: new(atomic.Int64)}
+	for _, name := range replicaNames {
+		replicas[name] = make(map[string]int64)
+		requests[name] = new(atomic.Int64)
+	}
+	return &fakeVault{
+		main:         make(map[string]map[string]int64),
+		updated:      make(map[string]time.Time),
+		replicas:     replicas,
+		replicaNames: replicaNames,
+		mountErrors:  make(map[string]error),
+		replicaErrs:  make(map[string]error),
+		requests:     requests,
+	}
+}
+
+func (f *fakeVault) writeSecrets(mount string, keyPaths ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.main[mount] == nil {
+		f.main[mount] = make(map[string]int64)
+	}
+	for _, keyPath := range keyPaths {
+		f.main[mount][keyPath]++
+		f.updated[mount+"/"+keyPath] = time.Now()
+	}
+}
+
+func (f *fakeVault) deleteSecret(mount, keyPath string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.main[mount], keyPath)
+	delete(f.updated, mount+"/"+keyPath)
+}
+
+func (f *fakeVault) deleteFromReplica(clusterName, mount, keyPath string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.replicas[clusterName], mount+"/"+keyPath)
+}
+
+// failReplica makes every write to the replica fail with err; a nil err lets writes succeed again. Failures
+// caused by vault.ErrClusterUnavailable are transient, all others permanent.
+func (f *fakeVault) failReplica(clusterName string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replicaErrs[clusterName] = err
+}
+
+func (f *fakeVault) replicaKeys(clusterName string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.replicas[clusterName]))
+	for key := range f.replicas[clusterName] {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (f *fakeVault) sortedKeys(mount string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.main[mount]))
+	for key := range f.main[mount] {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (f *fakeVault) GetSecretMounts(_ context.Context, _ []string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeVault) GetSecretMetadata(_ context.Context, mount, keyPath string) (*vault.SecretMetadataResponse, error) {
+	f.requests[mainCluster].Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	version, exists := f.main[mount][keyPath]
+	if !exists {
+		return nil, fmt.Errorf("secret %s/%s: %w", mount, keyPath, vault.ErrNotFound)
+	}
+	return &vault.SecretMetadataResponse{
+		CurrentVersion: version,
+		UpdatedTime:    f.updated[mount+"/"+keyPath],
+	}, nil
+}
+
+func (f *fakeVault) GetSourceState(ctx context.Context, mount, keyPath string) (*vault.SourceState, error) {
+	f.trackInFlight()
+	defer f.inFlight.Add(-1)
+
+	metadata, err := f.GetSecretMetadata(ctx, mount, keyPath)
+	if err != nil {
+		return &vault.SourceState{Exists: false}, nil
+	}
+	return &vault.SourceState{Exists: true, Metadata: metadata}, nil
+}
+
+func (f *fakeVault) GetKeysUnderMount(
+	ctx context.Context,
+	mount string,
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+) ([]string, error) {
+	var keys []string
+	err := f.StreamKeysUnderMount(ctx, mount, shouldIncludeKeyPath, func(keyPath string) error {
+		keys = append(keys, keyPath)
+		return nil
+	})
+	return keys, err
+}
+
+func (f *fakeVault) StreamKeysUnderMount(
+	ctx context.Context,
+	mount string,
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+	emit func(keyPath string) error,
+) error {
+	f.requests[mainCluster].Add(1)
+	if err := f.mountErrors[mount]; err != nil {
+		return fmt.Errorf("failed to get keys under mount %s: %w", mount, err)
+	}
+	for _, keyPath := range f.sortedKeys(mount) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !shouldIncludeKeyPath(keyPath, true) {
+			continue
+		}
+		if err := emit(keyPath); err != nil {
+			return fmt.Errorf("failed to get keys under mount %s: %w", mount, err)
+		}
+	}
+	return nil
+}
+
+// trackInFlight records a job gathering state, waits stateDelay and updates the maximum seen at once.
+// The caller decrements inFlight when done.
+func (f *fakeVault) trackInFlight() {
+	current := f.inFlight.Add(1)
+	for {
+		seen := f.maxSeen.Load()
+		if current <= seen || f.maxSeen.CompareAndSwap(seen, current) {
+			break
+		}
+	}
+	time.Sleep(f.stateDelay)
+}
+
+func (f *fakeVault) SecretExists(_ context.Context, mount, keyPath string) (bool, error) {
+	f.trackInFlight()
+	defer f.inFlight.Add(-1)
+	f.requests[mainCluster].Add(1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, exists := f.main[mount][keyPath]
+	return exists, nil
+}
+
+func (f *fakeVault) SecretExistsInReplica(_ context.Context, clusterName, mount, keyPath string) (bool, error) {
+	f.replicaChecks.Add(1)
+	f.requests[clusterName].Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, exists := f.replicas[clusterName][mount+"/"+keyPath]
+	return exists, nil
+}
+
+func (f *fakeVault) SyncSecretToReplicas(ctx context.Context, mount, keyPath string) ([]*models.SyncedSecret, error) {
+	source, err := f.ReadSecret(ctx, mount, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*models.SyncedSecret, 0, len(f.replicaNames))
+	for _, clusterName := range f.replicaNames {
+		result, _ := f.SyncSecretToReplica(ctx, clusterName, mount, keyPath, source)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (f *fakeVault) DeleteSecretFromReplicas(
+	ctx context.Context, mount, keyPath string,
+) ([]*models.SyncSecretDeletionResult, error) {
+	results := make([]*models.SyncSecretDeletionResult, 0, len(f.replicaNames))
+	for _, clusterName := range f.replicaNames {
+		result, _ := f.DeleteSecretFromReplica(ctx, clusterName, mount, keyPath)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (f *fakeVault) ReadSecret(_ context.Context, mount, keyPath string) (*vault.SecretResponse, error) {
+	f.requests[mainCluster].Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	version, exists := f.main[mount][keyPath]
+	if !exists {
+		return nil, fmt.Errorf("secret %s/%s: %w", mount, keyPath, vault.ErrNotFound)
+	}
+	return &vault.SecretResponse{Metadata: vault.SecretEmbededMetadata{Version: version}}, nil
+}
+
+// SyncSecretToReplica writes the secret to the replica. A write to hungReplica blocks until ctx is done.
+func (f *fakeVault) SyncSecretToReplica(
+	ctx context.Context, clusterName, mount, keyPath string, sourceSecret *vault.SecretResponse,
+) (*models.SyncedSecret, error) {
+	f.requests[clusterName].Add(1)
+	if clusterName == f.hungReplica {
+		<-ctx.Done()
+		return nil, fmt.Errorf("write to %s: %w", clusterName, ctx.Err())
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if err := f.replicaErrs[clusterName]; err != nil {
+		// Like the vault client, a failed write is reported as a failed record rather than an error.
+		category := vault.ErrorCategoryOf(err)
+		errorMsg := err.Error()
+		return &models.SyncedSecret{
+			SecretBackend:      mount,
+			SecretPath:         keyPath,
+			SourceVersion:      sourceSecret.Metadata.Version,
+			DestinationCluster: clusterName,
+			LastSyncAttempt:    now,
+			Status:             models.StatusFailed,
+			ErrorMessage:       &errorMsg,
+			ErrorCategory:      &category,
+		}, nil
+	}
+	f.replicas[clusterName][mount+"/"+keyPath] = sourceSecret.Metadata.Version
+	return &models.SyncedSecret{
+		SecretBackend:      mount,
+		SecretPath:         keyPath,
+		SourceVersion:      sourceSecret.Metadata.Version,
+		DestinationCluster: clusterName,
+		DestinationVersion: sourceSecret.Metadata.Version,
+		LastSyncAttempt:    now,
+		LastSyncSuccess:    &now,
+		Status:             models.StatusSuccess,
+	}, nil
+}
+
+func (f *fakeVault) DeleteSecretFromReplica(
+	_ context.Context, clusterName, mount, keyPath string,
+) (*models.SyncSecretDeletionResult, error) {
+	f.requests[clusterName].Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.replicas[clusterName], mount+"/"+keyPath)
+	return &models.SyncSecretDeletionResult{
+		DestinationCluster: clusterName,
+		SecretBackend:      mount,
+		SecretPath:         keyPath,
+		Status:             models.StatusDeleted,
+		DeletionAttempt:    time.Now(),
+	}, nil
+}
+
+func (f *fakeVault) GetReplicaNames() []string {
+	return f.replicaNames
+}
+
+func (f *fakeVault) RequestCounts() map[string]int64 {
+	counts := make(map[string]int64, len(f.requests))
+	for clusterName, requests := range f.requests {
+		counts[clusterName] = requests.Load()
+	}
+	return counts
+}
+
+// fakeRepository is an in-memory repository.SyncedSecretRepository.
+type fakeRepository struct {
+	mu              sync.Mutex
+	records         map[string]*models.SyncedSecret
+	reconciliations map[string]time.Time
+	queued          map[string][2]time.Time
+	singleReads     int
+	singleWrites    int
+	batches         int
+	runs            []*models.SyncRun
+	events          []*models.SyncEvent
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		records:         make(map[string]*models.SyncedSecret),
+		reconciliations: make(map[string]time.Time),
+		queued:          make(map[string][2]time.Time),
+	}
+}
+
+func recordKey(backend, path, destinationCluster string) string {
+	return backend + "/" + path + "@" + destinationCluster
+}
+
+func (r *fakeRepository) GetSyncedSecret(
+	_ context.Context, backend, path, destinationCluster string,
+) (*models.SyncedSecret, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.singleReads++
+	record, exists := r.records[recordKey(backend, path, destinationCluster)]
+	if !exists {
+		return nil, repository.ErrSecretNotFound
+	}
+	recordCopy := *record
+	return &recordCopy, nil
+}
+
+func (r *fakeRepository) UpdateSyncedSecretStatus(_ context.Context, secret *models.SyncedSecret) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.singleWrites++
+	recordCopy := *secret
+	r.records[recordKey(secret.SecretBackend, secret.SecretPath, secret.DestinationCluster)] = &recordCopy
+	return nil
+}
+
+func (r *fakeRepository) UpdateSyncedSecretStatuses(_ context.Context, secrets []*models.SyncedSecret) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches++
+	for _, secret := range secrets {
+		recordCopy := *secret
+		r.records[recordKey(secret.SecretBackend, secret.SecretPath, secret.DestinationCluster)] = &recordCopy
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetSyncedSecrets(_ context.Context) ([]*models.SyncedSecret, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := make([]*models.SyncedSecret, 0, len(r.records))
+	for _, record := range r.records {
+		recordCopy := *record
+		records = append(records, &recordCopy)
+	}
+	return records, nil
+}
+
+func (r *fakeRepository) GetFailedSyncedSecrets(_ context.Context) ([]*models.SyncedSecret, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var secrets []*models.SyncedSecret
+	for _, record := range r.records {
+		if record.Status == models.StatusFailed {
+			recordCopy := *record
+			secrets = append(secrets, &recordCopy)
+		}
+	}
+	return secrets, nil
+}
+
+func (r *fakeRepository) DeleteSyncedSecret(_ context.Context, backend, path, destinationCluster string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, recordKey(backend, path, destinationCluster))
+	return nil
+}
+
+func (r *fakeRepository) GetLastFullReconciliation(_ context.Context, instanceID string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reconciliations[instanceID], nil
+}
+
+func (r *fakeRepository) RecordFullReconciliation(_ context.Context, instanceID string, completedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reconciliations[instanceID] = completedAt
+	return nil
+}
+
+func (r *fakeRepository) QueueFullReconciliation(
+	_ context.Context, instanceID string, startedAt, enqueuedAt time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queued[instanceID] = [2]time.Time{startedAt, enqueuedAt}
+	return nil
+}
+
+func (r *fakeRepository) GetQueuedFullReconciliation(
+	_ context.Context, instanceID string,
+) (time.Time, time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queued := r.queued[instanceID]
+	return queued[0], queued[1], nil
+}
+
+func (r *fakeRepository) FinishQueuedFullReconciliation(_ context.Context, instanceID string, completed bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if queued, exists := r.queued[instanceID]; exists && completed {
+		r.reconciliations[instanceID] = queued[0]
+	}
+	delete(r.queued, instanceID)
+	return nil
+}
+
+func (r *fakeRepository) StartSyncRun(_ context.Context, run *models.SyncRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.ID = int64(len(r.runs) + 1)
+	runCopy := *run
+	r.runs = append(r.runs, &runCopy)
+	return nil
+}
+
+func (r *fakeRepository) FinishSyncRun(_ context.Context, run *models.SyncRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.runs {
+		if stored.ID == run.ID {
+			runCopy := *run
+			r.runs[i] = &runCopy
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) InsertSyncEvents(_ context.Context, events []*models.SyncEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range events {
+		eventCopy := *event
+		eventCopy.ID = int64(len(r.events) + 1)
+		r.events = append(r.events, &eventCopy)
+	}
+	return nil
+}
+
+func (r *fakeRepository) PruneSyncHistory(_ context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pruned := make(map[int64]bool)
+	r.runs = slices.DeleteFunc(r.runs, func(run *models.SyncRun) bool {
+		pruned[run.ID] = run.StartedAt.Before(cutoff)
+		return pruned[run.ID]
+	})
+	r.events = slices.DeleteFunc(r.events, func(event *models.SyncEvent) bool {
+		return pruned[event.RunID]
+	})
+	deleted := 0
+	for _, isPruned := range pruned {
+		if isPruned {
+			deleted++
+		}
+	}
+	return int64(deleted), nil
+}
+
+func (r *fakeRepository) GetSyncEvents(
+	_ context.Context, backend, path string, limit int,
+) ([]*models.SyncEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*models.SyncEvent
+	for i := len(r.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if r.events[i].SecretBackend == backend && r.events[i].SecretPath == path {
+			eventCopy := *r.events[i]
+			events = append(events, &eventCopy)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeRepository) Close() error {
+	return nil
+}
+
+// history returns copies of the recorded runs and events.
+func (r *fakeRepository) history() ([]models.SyncRun, []models.SyncEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make([]models.SyncRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		runs = append(runs, *run)
+	}
+	events := make([]models.SyncEvent, 0, len(r.events))
+	for _, event := range r.events {
+		events = append(events, *event)
+	}
+	return runs, events
+}
+
+// clearSourceUpdatedTimes drops the stored source update times, like records synced before they were tracked.
+func (r *fakeRepository) clearSourceUpdatedTimes() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range r.records {
+		record.SourceUpdatedTime = nil
+	}
+}
+
+// calls returns the number of single-record reads and writes and of batch writes.
+func (r *fakeRepository) calls() (singleReads, singleWrites, batches int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.singleReads, r.singleWrites, r.batches
+}
+
+func (r *fakeRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+// record returns a copy of the record of the secret on the replica, or nil.
+func (r *fakeRepository) record(mount, keyPath, clusterName string) *models.SyncedSecret {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, exists := r.records[recordKey(mount, keyPath, clusterName)]
+	if !exists {
+		return nil
+	}
+	recordCopy := *record
+	return &recordCopy
+}
+
+// fakeWorkQueue is an in-memory repository.SyncJobQueueRepository that keeps the enqueued jobs in order.
+type fakeWorkQueue struct {
+	mu         sync.Mutex
+	jobs       []models.QueuedJob
+	enqueuedBy string
+	pruned     int
+}
+
+var _ repository.SyncJobQueueRepository = (*fakeWorkQueue)(nil)
+
+func (q *fakeWorkQueue) EnqueueSyncJobs(_ context.Context, enqueuedBy string, jobs []*models.QueuedJob) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.enqueuedBy = enqueuedBy
+	for _, queuedJob := range jobs {
+		enqueued := *queuedJob
+		enqueued.Status = models.QueuedJobPending
+		enqueued.EnqueuedAt = time.Now()
+		q.jobs = append(q.jobs, enqueued)
+	}
+	return int64(len(jobs)), nil
+}
+
+func (q *fakeWorkQueue) LeaseSyncJobs(
+	context.Context, string, int, time.Duration, int,
+) ([]*models.QueuedJob, error) {
+	return nil, nil
+}
+
+func (q *fakeWorkQueue) ExtendSyncJobLeases(context.Context, string, []int64, time.Duration) error {
+	return nil
+}
+
+func (q *fakeWorkQueue) FinishSyncJobs(context.Context, string, []*models.QueuedJob) error {
+	return nil
+}
+
+func (q *fakeWorkQueue) PruneSyncJobs(context.Context, time.Time) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pruned++
+	return 0, nil
+}
+
+func (q *fakeWorkQueue) CountSyncJobs(
+	_ context.Context, finishedSince, enqueuedBefore time.Time,
+) (int64, int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var openJobs, failedJobs int64
+	for _, queuedJob := range q.jobs {
+		if queuedJob.EnqueuedAt.After(enqueuedBefore) {
+			continue
+		}
+		switch {
+		case queuedJob.Status == models.QueuedJobPending || queuedJob.Status == models.QueuedJobLeased:
+			openJobs++
+		case queuedJob.Status == models.QueuedJobFailed && !queuedJob.FinishedAt.Before(finishedSince):
+			failedJobs++
+		}
+	}
+	return openJobs, failedJobs, nil
+}
+
+// finishAll marks every enqueued job with status, as the workers would.
+func (q *fakeWorkQueue) finishAll(status models.QueuedJobStatus) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	finishedAt := time.Now()
+	for i := range q.jobs {
+		q.jobs[i].Status = status
+		q.jobs[i].FinishedAt = &finishedAt
+	}
+}
+
+// paths returns the mount/key paths of the enqueued jobs, sorted.
+func (q *fakeWorkQueue) paths() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	paths := make([]string, 0, len(q.jobs))
+	for _, queuedJob := range q.jobs {
+		paths = append(paths, queuedJob.SecretBackend+"/"+queuedJob.SecretPath)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// fakeCheckpoints is an in-memory repository.SyncCheckpointRepository. onAdd, when set, is called after paths
+// were added.
+type fakeCheckpoints struct {
+	mu          sync.Mutex
+	checkpoints map[string]*models.SyncCheckpoint
+	onAdd       func()
+}
+
+var _ repository.SyncCheckpointRepository = (*fakeCheckpoints)(nil)
+
+func newFakeCheckpoints() *fakeCheckpoints {
+	return &fakeCheckpoints{checkpoints: make(map[string]*models.SyncCheckpoint)}
+}
+
+//nolint:nilnil
+func (c *fakeCheckpoints) GetSyncCheckpoint(_ context.Context, instanceID string) (*models.SyncCheckpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkpoint, exists := c.checkpoints[instanceID]
+	if !exists {
+		return nil, nil
+	}
+	checkpointCopy := *checkpoint
+	checkpointCopy.Paths = slices.Clone(checkpoint.Paths)
+	return &checkpointCopy, nil
+}
+
+func (c *fakeCheckpoints) StartSyncCheckpoint(_ context.Context, checkpoint *models.SyncCheckpoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkpointCopy := *checkpoint
+	checkpointCopy.Paths = nil
+	c.checkpoints[checkpoint.InstanceID] = &checkpointCopy
+	return nil
+}
+
+func (c *fakeCheckpoints) AddCheckpointPaths(
+	_ context.Context,
+	instanceID string,
+	paths []models.CheckpointPath,
+) error {
+	c.mu.Lock()
+	if checkpoint, exists := c.checkpoints[instanceID]; exists {
+		checkpoint.Paths = append(checkpoint.Paths, paths...)
+	}
+	onAdd := c.onAdd
+	c.mu.Unlock()
+	if onAdd != nil {
+		onAdd()
+	}
+	return nil
+}
+
+func (c *fakeCheckpoints) DeleteSyncCheckpoint(_ context.Context, instanceID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.checkpoints, instanceID)
+	return nil
+}
+
+// paths returns the mount/key paths of the checkpoint of the instance, sorted, or nil without a checkpoint.
+func (c *fakeCheckpoints) paths(instanceID string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkpoint, exists := c.checkpoints[instanceID]
+	if !exists {
+		return nil
+	}
+	paths := make([]string, 0, len(checkpoint.Paths))
+	for _, path := range checkpoint.Paths {
+		paths = append(paths, path.SecretBackend+"/"+path.SecretPath)
+	}
+	slices.Sort(paths)
+	return paths
+}
@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+	"vault-sync/internal/service/job"
+)
+
+const (
+	// defaultHistoryRetention is how long runs and their events are kept unless configured otherwise.
+	defaultHistoryRetention = 30 * 24 * time.Hour
+	// historyFinishTimeout bounds recording the end of a run, which also happens after the run was cancelled.
+	historyFinishTimeout = 10 * time.Second
+)
+
+// syncHistory records a run and the replica changes and failures of its jobs. Recording is best effort: a
+// failed write is logged and never fails the run. A nil syncHistory records nothing.
+type syncHistory struct {
+	dbClient  repository.SyncedSecretRepository
+	run       *models.SyncRun
+	events    []*models.SyncEvent
+	batchSize int
+	logger    zerolog.Logger
+}
+
+// startHistory inserts the run record. It returns nil when history is disabled or the run cannot be recorded.
+func (o *SyncOrchestrator) startHistory(ctx context.Context, startTime time.Time) *syncHistory {
+	if !o.history {
+		return nil
+	}
+
+	run := &models.SyncRun{
+		InstanceID: o.historyInstanceID,
+		StartedAt:  startTime,
+		Status:     models.SyncRunRunning,
+	}
+	if err := o.dbClient.StartSyncRun(ctx, run); err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to record sync run, continuing without history")
+		return nil
+	}
+
+	return &syncHistory{
+		dbClient:  o.dbClient,
+		run:       run,
+		batchSize: o.statusBatchSize,
+		logger:    o.logger.With().Int64("run_id", run.ID).Logger(),
+	}
+}
+
+// add queues an event for every replica the job changed or failed on, and writes the queued events once a
+// batch is full.
+func (h *syncHistory) add(ctx context.Context, jobResult *job.SyncJobResult) {
+	if h == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, clusterStatus := range jobResult.Status {
+		action, recorded := syncActions[clusterStatus.Status]
+		if !recorded {
+			continue
+		}
+
+		event := &models.SyncEvent{
+			RunID:              h.run.ID,
+			SecretBackend:      jobResult.Mount,
+			SecretPath:         jobResult.KeyPath,
+			DestinationCluster: clusterStatus.ClusterName,
+			Action:             action,
+			OccurredAt:         now,
+		}
+		if clusterStatus.SourceVersion > 0 {
+			event.SourceVersion = &clusterStatus.SourceVersion
+		}
+		if clusterStatus.DestinationVersion > 0 {
+			event.DestinationVersion = &clusterStatus.DestinationVersion
+		}
+		if clusterStatus.Error != nil {
+			message := clusterStatus.Error.Error()
+			event.ErrorMessage = &message
+		}
+		h.events = append(h.events, event)
+	}
+
+	if len(h.events) >= h.batchSize {
+		h.flush(ctx)
+	}
+}
+
+// syncActions maps the replica outcomes that are recorded to their action. Unchanged and skipped replicas are not
+// recorded.
+//
+//nolint:gochecknoglobals
+var syncActions = map[job.SyncJobStatus]models.SyncAction{
+	job.SyncJobStatusUpdated:       models.SyncActionUpdated,
+	job.SyncJobStatusDeleted:       models.SyncActionDeleted,
+	job.SyncJobStatusFailed:        models.SyncActionFailed,
+	job.SyncJobStatusErrorDeleting: models.SyncActionDeleteFailed,
+}
+
+func (h *syncHistory) flush(ctx context.Context) {
+	if len(h.events) == 0 {
+		return
+	}
+
+	if err := h.dbClient.InsertSyncEvents(ctx, h.events); err != nil {
+		h.logger.Warn().Err(err).Int("events", len(h.events)).Msg("Failed to record sync events")
+	}
+	h.events = h.events[:0]
+}
+
+// finish writes the remaining events and the outcome of the run. It still runs when ctx was cancelled, bounded by
+// historyFinishTimeout, so interrupted runs are recorded as such.
+func (h *syncHistory) finish(ctx context.Context, result *SyncResult, runErr error) {
+	if h == nil {
+		return
+	}
+
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), historyFinishTimeout)
+	defer cancel()
+
+	h.flush(finishCtx)
+
+	finishedAt := time.Now()
+	h.run.FinishedAt = &finishedAt
+	h.run.Status = runStatus(runErr)
+	if runErr != nil {
+		message := runErr.Error()
+		h.run.ErrorMessage = &message
+	}
+	if result != nil {
+		h.run.Incremental = result.Incremental
+		h.run.TotalSecrets = result.TotalSecrets
+		h.run.SuccessfulSyncs = result.SuccessfulSyncs
+		h.run.FailedSyncs = result.FailedSyncs
+		h.run.SkippedSecrets = result.SkippedSecrets
+		h.run.NoOpSecrets = result.NoOpSecrets
+		h.run.DiscoveryFailures = result.DiscoveryFailures
+	}
+
+	if err := h.dbClient.FinishSyncRun(finishCtx, h.run); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to record the end of the sync run")
+		return
+	}
+	h.logger.Debug().Str("status", h.run.Status.String()).Msg("Recorded sync run")
+}
+
+func runStatus(runErr error) models.SyncRunStatus {
+	switch {
+	case runErr == nil:
+		return models.SyncRunCompleted
+	case errors.Is(runErr, context.Canceled) || errors.Is(runErr, context.DeadlineExceeded):
+		return models.SyncRunInterrupted
+	default:
+		return models.SyncRunFailed
+	}
+}
+
+// pruneHistory deletes the runs, and their events, that started longer than the retention ago.
+func (o *SyncOrchestrator) pruneHistory(ctx context.Context, now time.Time) {
+	if !o.history || ctx.Err() != nil {
+		return
+	}
+
+	cutoff := now.Add(-o.historyRetention)
+	deletedRuns, err := o.dbClient.PruneSyncHistory(ctx, cutoff)
+	if err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to prune sync history")
+		return
+	}
+	if deletedRuns > 0 {
+		o.logger.Info().Int64("deleted_runs", deletedRuns).Time("cutoff", cutoff).Msg("Pruned sync history")
+	}
+}
@@ -4,34 +4,271 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 
+	"vault-sync/internal/metrics"
+	"vault-sync/internal/models"
 	"vault-sync/internal/repository"
 	"vault-sync/internal/service/job"
 	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/tracing"
 	"vault-sync/internal/vault"
 	"vault-sync/pkg/log"
 )
 
+// SyncResult summarises a run. Counters cover every processed path, while JobResults only keeps the jobs
+// that changed, failed or were skipped: unchanged secrets are counted but not retained, so the result of a
+// run over a large estate stays small, unless WithUnchangedJobResults is set.
 type SyncResult struct {
+	// TotalSecrets counts the processed paths. In a run of a coordinator, it counts the enqueued paths.
 	TotalSecrets    int
 	SuccessfulSyncs int
 	FailedSyncs     int
-	SkippedSecrets  int
-	NoOpSecrets     int
+	// SkippedSecrets counts the discovered paths whose job did not run because the run was interrupted or
+	// aborted. Paths that were never discovered are not counted, see Incomplete.
+	SkippedSecrets int
+	NoOpSecrets    int
+	// DiscoveryFailures counts the mounts that could not be listed, which FailedMounts names.
+	DiscoveryFailures int
+	FailedMounts      []string
+	// Incremental is set when unchanged secrets were only compared against the main cluster metadata.
+	Incremental bool
+	// VaultRequests holds the number of requests the run sent to each cluster, keyed by cluster name.
+	VaultRequests   map[string]int64
 	Duration        time.Duration
 	JobResults      []*job.SyncJobResult
+	ReplicaProgress []job.ReplicaProgress
+	// EnqueuedJobs is set by a run of a coordinator, which enqueues the jobs of the paths for the workers instead
+	// of running them.
+	EnqueuedJobs int
+	// ResumedPaths is set by a run that resumed an interrupted one. It counts the paths processed before, which
+	// the other counters leave out.
+	ResumedPaths int
+	// AbortReason is set when the run ran out of its budget. It tells which limit was reached.
+	AbortReason string
+	// Incomplete is set when the run stopped before discovery was done. The paths discovery never got to are in
+	// no counter, so SkippedSecrets understates what was left out.
+	Incomplete bool
 }
 
+// PathFailures returns why each path of the result was not synced to every replica, keyed by
+// SecretPath.String. A replica left alone because it is backing off or quarantined counts as not synced.
+func (r *SyncResult) PathFailures() map[string]string {
+	failures := make(map[string]string)
+	for _, jobResult := range r.JobResults {
+		key := pathmatching.SecretPath{Mount: jobResult.Mount, KeyPath: jobResult.KeyPath}.String()
+		if jobResult.Error != nil {
+			failures[key] = jobResult.Error.Error()
+			continue
+		}
+		var held []string
+		for _, status := range jobResult.Status {
+			if status.Status == job.SyncJobStatusBackingOff || status.Status == job.SyncJobStatusQuarantined {
+				held = append(held, fmt.Sprintf("%s is %s", status.ClusterName, status.Status))
+			}
+		}
+		if len(held) > 0 {
+			failures[key] = "not synced, replica " + strings.Join(held, ", ")
+		}
+	}
+	return failures
+}
+
+// SkippedPaths returns the paths of the result whose job did not run because the run was interrupted or aborted,
+// keyed by SecretPath.String.
+func (r *SyncResult) SkippedPaths() map[string]bool {
+	skipped := make(map[string]bool)
+	for _, jobResult := range r.JobResults {
+		if errors.Is(jobResult.Error, context.Canceled) || errors.Is(jobResult.Error, context.DeadlineExceeded) {
+			skipped[pathmatching.SecretPath{Mount: jobResult.Mount, KeyPath: jobResult.KeyPath}.String()] = true
+		}
+	}
+	return skipped
+}
+
+// Kinds of runs in the run metrics.
+const (
+	runKindFull        = "full"
+	runKindIncremental = "incremental"
+	runKindRetry       = "retry"
+	runKindPaths       = "paths"
+	runKindWorker      = "worker"
+)
+
+const (
+	// defaultReplicaQueueFactor sizes each replica queue relative to the number of workers of that replica.
+	defaultReplicaQueueFactor = 4
+	// defaultFullReconciliationInterval is how often an incremental instance runs a full reconciliation.
+	defaultFullReconciliationInterval = 24 * time.Hour
+)
+
 type SyncOrchestrator struct {
-	logger      zerolog.Logger
-	vaultClient vault.Syncer
-	pathMatcher pathmatching.PathMatcher
-	dbClient    repository.SyncedSecretRepository
-	concurrency int
+	logger           zerolog.Logger
+	vaultClient      vault.Syncer
+	pathMatcher      pathmatching.PathMatcher
+	dbClient         repository.SyncedSecretRepository
+	concurrency      int
+	replicaWorkers   int
+	replicaQueueSize int
+	statusBatchSize  int
+	strictDiscovery  bool
+
+	incremental                bool
+	instanceID                 string
+	fullReconciliationInterval time.Duration
+
+	history           bool
+	historyInstanceID string
+	historyRetention  time.Duration
+
+	retryPolicy *job.RetryPolicy
+
+	workQueue   repository.SyncJobQueueRepository
+	workQueueID string
+	// reconciliationMu serializes checking and finishing the full reconciliation a coordinator queued.
+	reconciliationMu sync.Mutex
+
+	checkpoints  repository.SyncCheckpointRepository
+	checkpointID string
+	resume       bool
+
+	jobTimeout       time.Duration
+	maxRunDuration   time.Duration
+	maxFailedJobs    int
+	maxFailedPercent float64
+
+	keepUnchanged bool
+
+	// pathLocks is shared by the runs of the orchestrator, which may run concurrently in a daemon.
+	pathLocks *pathLocks
+}
+
+// Option configures optional behaviour of the SyncOrchestrator.
+type Option func(*SyncOrchestrator)
+
+// WithReplicaPipeline sets the number of workers and the queue size of every replica cluster.
+// Zero values keep the defaults: as many workers as the job concurrency and a queue four times that size.
+func WithReplicaPipeline(workersPerReplica, queueSize int) Option {
+	return func(o *SyncOrchestrator) {
+		if workersPerReplica > 0 {
+			o.replicaWorkers = workersPerReplica
+		}
+		if queueSize > 0 {
+			o.replicaQueueSize = queueSize
+		}
+	}
+}
+
+// WithStatusBatchSize sets how many replica records are written to the database per upsert.
+// Zero keeps the default of job.DefaultStatusBatchSize.
+func WithStatusBatchSize(batchSize int) Option {
+	return func(o *SyncOrchestrator) {
+		if batchSize > 0 {
+			o.statusBatchSize = batchSize
+		}
+	}
+}
+
+// WithStrictDiscovery makes a run fail when a mount cannot be listed. Paths only known from the database are
+// then not processed at all, so nothing is deleted from the replicas based on an incomplete listing. Jobs that
+// were already dispatched still finish.
+func WithStrictDiscovery(strict bool) Option {
+	return func(o *SyncOrchestrator) {
+		o.strictDiscovery = strict
+	}
+}
+
+// WithIncrementalSync enables incremental runs: unchanged secrets are detected from the main cluster metadata
+// alone and the replica checks only run for changed paths. A full reconciliation that checks every replica still
+// runs whenever the last one recorded for instanceID is older than fullReconciliationInterval (default 24h).
+func WithIncrementalSync(instanceID string, fullReconciliationInterval time.Duration) Option {
+	return func(o *SyncOrchestrator) {
+		o.incremental = true
+		o.instanceID = instanceID
+		if fullReconciliationInterval > 0 {
+			o.fullReconciliationInterval = fullReconciliationInterval
+		}
+	}
+}
+
+// WithSyncHistory records every run of instanceID and the replica changes and failures of its jobs in the
+// database. Runs that started more than retention ago (default 30 days) are pruned after each run.
+func WithSyncHistory(instanceID string, retention time.Duration) Option {
+	return func(o *SyncOrchestrator) {
+		o.history = true
+		o.historyInstanceID = instanceID
+		if retention > 0 {
+			o.historyRetention = retention
+		}
+	}
+}
+
+// WithRetryPolicy schedules the retries of failed replicas with policy. Runs leave failed replicas alone until
+// their retry is due and quarantine the replicas that keep failing; see RetryFailed and RetryQuarantined.
+func WithRetryPolicy(policy *job.RetryPolicy) Option {
+	return func(o *SyncOrchestrator) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithWorkQueue makes the orchestrator the coordinator of a group of workers: full syncs, retries of due failed
+// replicas and syncs of given paths enqueue the jobs of their paths on queue as instanceID instead of running
+// them, and the workers run them with SyncLeased. Retries of quarantined replicas still run on the coordinator.
+func WithWorkQueue(queue repository.SyncJobQueueRepository, instanceID string) Option {
+	return func(o *SyncOrchestrator) {
+		o.workQueue = queue
+		o.workQueueID = instanceID
+	}
+}
+
+// WithCheckpoints checkpoints the paths processed by full and incremental runs on store as instanceID, so a run
+// that is interrupted can be resumed. The jobs in flight when a run is interrupted get up to 20s to finish.
+func WithCheckpoints(store repository.SyncCheckpointRepository, instanceID string) Option {
+	return func(o *SyncOrchestrator) {
+		o.checkpoints = store
+		o.checkpointID = instanceID
+	}
+}
+
+// WithResume makes StartSync resume the interrupted run of the instance, if any, and skip the paths it processed,
+// instead of starting over. It requires WithCheckpoints.
+func WithResume() Option {
+	return func(o *SyncOrchestrator) {
+		o.resume = true
+	}
+}
+
+// WithJobTimeout bounds every step of a job, from reading the main cluster to each replica write, by timeout. A
+// job that runs out of time fails and frees its worker. Zero disables the timeout.
+func WithJobTimeout(timeout time.Duration) Option {
+	return func(o *SyncOrchestrator) {
+		o.jobTimeout = timeout
+	}
+}
+
+// WithRunBudget aborts a run that takes longer than maxDuration, or once maxFailedJobs jobs or maxFailedPercent
+// percent of the finished jobs failed. The share is only checked once 100 jobs finished. An aborted run starts no
+// new job, lets the jobs in flight finish and fails with ErrRunAborted. Zero values disable a limit.
+func WithRunBudget(maxDuration time.Duration, maxFailedJobs int, maxFailedPercent float64) Option {
+	return func(o *SyncOrchestrator) {
+		o.maxRunDuration = maxDuration
+		o.maxFailedJobs = maxFailedJobs
+		o.maxFailedPercent = maxFailedPercent
+	}
+}
+
+// WithUnchangedJobResults keeps the results of unchanged secrets in SyncResult.JobResults, for reports that list
+// every secret of a run.
+func WithUnchangedJobResults() Option {
+	return func(o *SyncOrchestrator) {
+		o.keepUnchanged = true
+	}
 }
 
 func NewSyncOrchestrator(
@@ -39,38 +276,106 @@ func NewSyncOrchestrator(
 	dbClient repository.SyncedSecretRepository,
 	pathMatcher pathmatching.PathMatcher,
 	concurrency int,
+	opts ...Option,
 ) *SyncOrchestrator {
-	return &SyncOrchestrator{
-		logger:      log.Logger.With().Str("component", "orchestrator").Logger(),
-		vaultClient: vaultClient,
-		pathMatcher: pathMatcher,
-		dbClient:    dbClient,
-		concurrency: concurrency,
+	orchestrator := &SyncOrchestrator{
+		logger:           log.Logger.With().Str("component", "orchestrator").Logger(),
+		vaultClient:      vaultClient,
+		pathMatcher:      pathMatcher,
+		dbClient:         dbClient,
+		concurrency:      concurrency,
+		replicaWorkers:   concurrency,
+		replicaQueueSize: concurrency * defaultReplicaQueueFactor,
+		statusBatchSize:  job.DefaultStatusBatchSize,
+		pathLocks:        newPathLocks(),
+
+		fullReconciliationInterval: defaultFullReconciliationInterval,
+		historyRetention:           defaultHistoryRetention,
 	}
+
+	for _, opt := range opts {
+		opt(orchestrator)
+	}
+
+	return orchestrator
 }
 
+// StartSync runs a full synchronization. Discovered paths are streamed into a fixed pool of workers while
+// discovery is still running; paths that are only known from the database are injected once discovery
+// finished, so secrets removed from the main cluster are still deleted from the replicas.
 func (o *SyncOrchestrator) StartSync(ctx context.Context) (*SyncResult, error) {
 	startTime := time.Now()
-	o.logger.Info().Msg("Starting secret synchronization")
+	ctx, span := tracing.Start(ctx, "orchestrator.start_sync")
+	defer span.End()
+	logger := tracing.WithTraceContext(ctx, o.logger)
+	logger.Info().Msg("Starting secret synchronization")
 
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	discoveredPaths := o.discoverSecrets(ctx)
-	syncedPaths, err := o.getAllSyncedPathsFromDB()
+	history := o.startHistory(ctx, startTime)
+	result, err := o.sync(ctx, startTime, history)
+	history.finish(ctx, result, err)
+	o.pruneHistory(ctx, startTime)
+
+	runKind := runKindFull
+	if result != nil && result.Incremental {
+		runKind = runKindIncremental
+	}
+	metrics.ObserveRun(runKind, runStatus(err).String(), time.Since(startTime))
+	span.SetAttributes(tracing.RunKindKey.String(runKind))
+	tracing.RecordError(span, err)
+
+	return result, err
+}
+
+// sync runs the synchronization of StartSync and records its job results in history.
+func (o *SyncOrchestrator) sync(ctx context.Context, startTime time.Time, history *syncHistory) (*SyncResult, error) {
+	records, err := o.loadSyncedRecords(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get synced paths from DB: %w", err)
 	}
 
-	allPathsToProcess := o.mergePathSets(discoveredPaths, syncedPaths)
-
-	if len(allPathsToProcess) == 0 {
-		return o.emptyResult(startTime), nil
+	requestsBefore := o.vaultClient.RequestCounts()
+
+	// A resumed run keeps the kind and, for recording a full reconciliation, the start of the interrupted run.
+	reconciliationStart := startTime
+	checkpoint := o.resumeCheckpoint(ctx, startTime)
+	var incremental bool
+	if checkpoint != nil {
+		incremental = checkpoint.checkpoint.Incremental
+		reconciliationStart = checkpoint.checkpoint.StartedAt
+	} else {
+		incremental = o.isIncrementalRun(ctx, startTime)
+		checkpoint = o.startCheckpoint(ctx, startTime, incremental, history)
 	}
 
-	result := o.executeSyncJobs(ctx, allPathsToProcess)
+	// The synced paths are consumed by streamPaths, the records stay untouched for the jobs.
+	syncedPaths := maps.Clone(records.paths)
+	processed := checkpoint.processedPaths()
+	stream := func(ctx context.Context, secretPaths chan<- pathmatching.SecretPath) error {
+		return o.streamPaths(ctx, syncedPaths, processed, secretPaths)
+	}
+	if o.workQueue != nil {
+		return o.coordinate(ctx, startTime, incremental, stream)
+	}
+	result, discoveryErr := o.executeSyncJobs(ctx, records, incremental, history, checkpoint, stream)
 	result.Duration = time.Since(startTime)
+	result.VaultRequests = requestsSince(requestsBefore, o.vaultClient.RequestCounts())
+	result.ResumedPaths = len(processed)
+	// An aborted run is checkpointed like an interrupted one, so a resumed run picks up the paths it skipped.
+	aborted := result.AbortReason != ""
+	checkpoint.finish(ctx, ctx.Err() != nil || aborted)
+
+	if o.incremental && !incremental && ctx.Err() == nil && !aborted && discoveryErr == nil {
+		o.recordFullReconciliation(ctx, reconciliationStart)
+	}
+
+	if result.TotalSecrets == 0 && result.DiscoveryFailures == 0 && ctx.Err() == nil && !aborted {
+		o.logger.Warn().Msg("No secrets found to sync")
+		return result, nil
+	}
 
 	o.logSummary(result)
 
@@ -78,161 +383,352 @@ func (o *SyncOrchestrator) StartSync(ctx context.Context) (*SyncResult, error) {
 		return result, fmt.Errorf("sync interrupted: %w", ctx.Err())
 	}
 
+	if aborted {
+		return result, fmt.Errorf("sync %w: %s", ErrRunAborted, result.AbortReason)
+	}
+
+	if discoveryErr != nil && o.strictDiscovery {
+		return result, fmt.Errorf("sync aborted: %w", discoveryErr)
+	}
+
 	return result, nil
 }
 
-func (o *SyncOrchestrator) discoverSecrets(ctx context.Context) []pathmatching.SecretPath {
-	o.logger.Info().Msg("Discovering secrets to sync")
-	//FIX: current impleentation of DiscoverSecretsForSync swallows errors
-	secretPaths, _ := o.pathMatcher.DiscoverSecretsForSync(ctx)
-	o.logger.Info().Int("total_secrets", len(secretPaths)).Msg("Discovered secrets")
-	return secretPaths
-}
+// isIncrementalRun reports whether this run can be incremental, i.e. incremental sync is enabled and the last
+// full reconciliation is recent enough, or the jobs of the full reconciliation a coordinator enqueued are still
+// queued. When the last reconciliation cannot be read, a full run is done.
+func (o *SyncOrchestrator) isIncrementalRun(ctx context.Context, now time.Time) bool {
+	if !o.incremental {
+		return false
+	}
 
-func (o *SyncOrchestrator) getAllSyncedPathsFromDB() ([]pathmatching.SecretPath, error) {
-	o.logger.Debug().Msg("Getting all synced paths from database")
+	if o.reconciliationQueued(ctx) {
+		o.logger.Info().Msg("Running incremental sync, the jobs of the last full reconciliation are still queued")
+		return true
+	}
 
-	syncedRecords, err := o.dbClient.GetSyncedSecrets()
+	lastFullRun, err := o.dbClient.GetLastFullReconciliation(ctx, o.instanceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get synced secrets: %w", err)
+		o.logger.Warn().Err(err).Msg("Failed to get last full reconciliation, running a full reconciliation")
+		return false
 	}
 
-	pathSet := make(map[string]pathmatching.SecretPath)
-	for _, record := range syncedRecords {
-		key := fmt.Sprintf("%s/%s", record.SecretBackend, record.SecretPath)
-		pathSet[key] = pathmatching.SecretPath{
-			Mount:   record.SecretBackend,
-			KeyPath: record.SecretPath,
-		}
+	if lastFullRun.IsZero() || now.Sub(lastFullRun) >= o.fullReconciliationInterval {
+		o.logger.Info().
+			Time("last_full_run", lastFullRun).
+			Dur("full_reconciliation_interval", o.fullReconciliationInterval).
+			Msg("Full reconciliation is due")
+		return false
 	}
 
-	var paths []pathmatching.SecretPath
-	for _, path := range pathSet {
-		paths = append(paths, path)
+	o.logger.Info().Time("last_full_run", lastFullRun).Msg("Running incremental sync")
+	return true
+}
+
+// recordFullReconciliation stores the start of a completed full run, so incremental runs are allowed until the
+// interval elapsed. A failure only means the next run is a full one again.
+func (o *SyncOrchestrator) recordFullReconciliation(ctx context.Context, startTime time.Time) {
+	if err := o.dbClient.RecordFullReconciliation(ctx, o.instanceID, startTime); err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to record full reconciliation")
+		return
 	}
+	o.logger.Info().Time("last_full_run", startTime).Msg("Recorded full reconciliation")
+}
 
-	o.logger.Debug().Int("synced_paths_count", len(paths)).Msg("Retrieved synced paths from database")
-	return paths, nil
+// syncedRecords holds the DB records of a run, loaded with a single query. byPath is keyed by
+// SecretPath.String and then by cluster name; paths holds the distinct paths that have at least one record.
+type syncedRecords struct {
+	byPath map[string]map[string]*models.SyncedSecret
+	paths  map[string]pathmatching.SecretPath
 }
 
-func (o *SyncOrchestrator) mergePathSets(
-	discoveredPaths []pathmatching.SecretPath,
-	syncedPaths []pathmatching.SecretPath,
-) []pathmatching.SecretPath {
-	pathSet := make(map[string]pathmatching.SecretPath)
+// loadSyncedRecords reads every synced record once, so the jobs of a run do not query their own records.
+func (o *SyncOrchestrator) loadSyncedRecords(ctx context.Context) (*syncedRecords, error) {
+	o.logger.Debug().Msg("Loading synced records from database")
 
-	for _, path := range discoveredPaths {
-		key := fmt.Sprintf("%s/%s", path.Mount, path.KeyPath)
-		pathSet[key] = path
+	records, err := o.dbClient.GetSyncedSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get synced secrets: %w", err)
 	}
 
-	for _, path := range syncedPaths {
-		key := fmt.Sprintf("%s/%s", path.Mount, path.KeyPath)
-		pathSet[key] = path
+	loaded := &syncedRecords{
+		byPath: make(map[string]map[string]*models.SyncedSecret),
+		paths:  make(map[string]pathmatching.SecretPath),
 	}
-
-	var allPaths []pathmatching.SecretPath
-	for _, path := range pathSet {
-		allPaths = append(allPaths, path)
+	for _, record := range records {
+		path := pathmatching.SecretPath{
+			Mount:   record.SecretBackend,
+			KeyPath: record.SecretPath,
+		}
+		key := path.String()
+		if _, exists := loaded.byPath[key]; !exists {
+			loaded.byPath[key] = make(map[string]*models.SyncedSecret)
+			loaded.paths[key] = path
+		}
+		loaded.byPath[key][record.DestinationCluster] = record
 	}
 
-	o.logger.Info().
-		Int("discovered_paths", len(discoveredPaths)).
-		Int("synced_paths", len(syncedPaths)).
-		Int("total_unique_paths", len(allPaths)).
-		Msg("Merged path sets for processing")
+	o.logger.Debug().
+		Int("synced_records_count", len(records)).
+		Int("synced_paths_count", len(loaded.paths)).
+		Msg("Loaded synced records from database")
+	return loaded, nil
+}
 
-	return allPaths
+// syncRun holds what the jobs of a single run share.
+type syncRun struct {
+	pipeline     *job.ReplicaPipeline
+	statusWriter *job.StatusWriter
+	records      *syncedRecords
+	jobOpts      []job.Option
 }
 
-func (o *SyncOrchestrator) emptyResult(startTime time.Time) *SyncResult {
-	o.logger.Warn().Msg("No secrets found to sync")
-	return &SyncResult{
-		TotalSecrets: 0,
-		Duration:     time.Since(startTime),
+// jobOptions returns the options of the job for secret, including its preloaded records if the run has any.
+func (r *syncRun) jobOptions(secret pathmatching.SecretPath) []job.Option {
+	if r.records == nil {
+		return r.jobOpts
 	}
+	return append(slices.Clone(r.jobOpts), job.WithRecords(r.records.byPath[secret.String()]))
 }
 
+// executeSyncJobs processes every path sent by stream and returns the error of stream, if any, next to the
+// result. Without records, every job reads its own records. extraOpts are added to the options of every job.
+//
+// Once ctx is done, or the run is aborted by its budget, no new job starts while the jobs in flight get up to
+// drainTimeout to finish, so their outcome is stored and checkpointed.
 func (o *SyncOrchestrator) executeSyncJobs(
 	ctx context.Context,
-	secretPaths []pathmatching.SecretPath,
-) *SyncResult {
-	concurrency := o.concurrency
+	records *syncedRecords,
+	incremental bool,
+	history *syncHistory,
+	checkpoint *runCheckpoint,
+	stream func(ctx context.Context, secretPaths chan<- pathmatching.SecretPath) error,
+	extraOpts ...job.Option,
+) (*SyncResult, error) {
 	o.logger.Info().
-		Int("concurrency", concurrency).
-		Int("total_secrets", len(secretPaths)).
+		Int("concurrency", o.concurrency).
+		Int("replica_workers", o.replicaWorkers).
+		Int("replica_queue_size", o.replicaQueueSize).
+		Int("status_batch_size", o.statusBatchSize).
+		Bool("strict_discovery", o.strictDiscovery).
+		Bool("incremental", incremental).
+		Dur("job_timeout", o.jobTimeout).
 		Msg("Starting concurrent sync jobs")
 
-	result := &SyncResult{
-		TotalSecrets: len(secretPaths),
-		JobResults:   make([]*job.SyncJobResult, 0, len(secretPaths)),
+	budget := o.startBudget(ctx)
+	defer budget.release()
+	runCtx := budget.context(ctx)
+	jobCtx, stopJobs := drainContext(runCtx, drainTimeout)
+	defer stopJobs()
+
+	result := &SyncResult{Incremental: incremental}
+	statusWriter := job.NewStatusWriter(jobCtx, o.dbClient, o.statusBatchSize)
+	run := &syncRun{
+		pipeline: job.NewReplicaPipeline(
+			jobCtx, o.vaultClient.GetReplicaNames(), o.replicaWorkers, o.replicaQueueSize,
+		),
+		statusWriter: statusWriter,
+		records:      records,
+		jobOpts: append([]job.Option{
+			job.WithIncrementalCheck(incremental),
+			job.WithStatusWriter(statusWriter),
+			job.WithRetryPolicy(o.retryPolicy),
+			job.WithTimeout(o.jobTimeout),
+		}, extraOpts...),
+	}
+	secretPaths := make(chan pathmatching.SecretPath, o.concurrency)
+
+	// discoveryErr is written before secretPaths is closed, which happens before the results channel is closed.
+	var discoveryErr error
+	go func() {
+		discoveryErr = stream(runCtx, secretPaths)
+		close(secretPaths)
+	}()
+
+	jobResults := o.runJobsInParallel(runCtx, jobCtx, secretPaths, o.concurrency, run)
+	o.collectResults(jobCtx, result, jobResults, history, checkpoint, budget)
+	result.ReplicaProgress = run.pipeline.Progress()
+	// Discovery stops with the run, the paths it did not get to are not known.
+	result.Incomplete = errors.Is(discoveryErr, context.Canceled) || errors.Is(discoveryErr, context.DeadlineExceeded)
+	if result.AbortReason = budget.abortReason(ctx); result.AbortReason != "" {
+		o.logger.Error().
+			Str("reason", result.AbortReason).
+			Int("skipped", result.SkippedSecrets).
+			Bool("incomplete", result.Incomplete).
+			Msg("Run aborted, the remaining paths were skipped")
+	}
+
+	var mountErrs *pathmatching.DiscoveryError
+	if errors.As(discoveryErr, &mountErrs) {
+		result.FailedMounts = mountErrs.FailedMounts()
+		result.DiscoveryFailures = len(result.FailedMounts)
+		return result, discoveryErr
+	}
+
+	return result, nil
+}
+
+// streamPaths sends every discovered path to secretPaths, followed by the synced paths that discovery did not
+// return. Sending blocks while the workers are busy, which keeps discovery from running far ahead of the sync jobs.
+//
+// When mounts could not be listed, their synced paths are left for a later run instead of being treated as
+// deleted; in strict mode no synced-only path is processed at all. Paths in processed, which an interrupted run
+// processed before, are skipped. The discovery error is returned.
+func (o *SyncOrchestrator) streamPaths(
+	ctx context.Context,
+	syncedPaths map[string]pathmatching.SecretPath,
+	processed map[string]bool,
+	secretPaths chan<- pathmatching.SecretPath,
+) error {
+	send := func(path pathmatching.SecretPath) error {
+		if processed[path.String()] {
+			return nil
+		}
+		select {
+		case secretPaths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	o.logger.Info().Msg("Discovering secrets to sync")
+
+	var mu sync.Mutex
+	discovered := 0
+	discoveredByMount := make(map[string]int)
+	err := o.pathMatcher.StreamSecretsForSync(ctx, func(path pathmatching.SecretPath) error {
+		mu.Lock()
+		delete(syncedPaths, path.String())
+		discovered++
+		discoveredByMount[path.Mount]++
+		mu.Unlock()
+		return send(path)
+	})
+
+	var mountErrs *pathmatching.DiscoveryError
+	if err != nil && !errors.As(err, &mountErrs) {
+		o.logger.Warn().Err(err).Int("discovered", discovered).Msg("Secret discovery stopped")
+		return err
 	}
+	metrics.SetDiscoveredSecrets(discoveredByMount)
 
-	jobResults := o.runJobsInParallel(ctx, secretPaths, concurrency)
-	o.collectResults(result, jobResults)
+	if mountErrs != nil {
+		o.logger.Error().
+			Err(err).
+			Strs("failed_mounts", mountErrs.FailedMounts()).
+			Int("discovered", discovered).
+			Bool("strict", o.strictDiscovery).
+			Msg("Secret discovery is incomplete")
+		if o.strictDiscovery {
+			o.logger.Error().
+				Int("synced_only_paths", len(syncedPaths)).
+				Msg("Strict discovery: aborting run, paths only known from the database are not processed")
+			return err
+		}
+	}
 
-	return result
+	o.logger.Info().
+		Int("discovered_paths", discovered).
+		Int("synced_only_paths", len(syncedPaths)).
+		Msg("Discovered secrets, processing paths only known from the database")
+
+	held := 0
+	for _, path := range syncedPaths {
+		if mountErrs != nil {
+			if _, failed := mountErrs.MountErrors[path.Mount]; failed {
+				held++
+				continue
+			}
+		}
+		if sendErr := send(path); sendErr != nil {
+			return sendErr
+		}
+	}
+
+	if held > 0 {
+		o.logger.Warn().
+			Int("held_paths", held).
+			Msg("Paths of mounts that could not be listed are kept until discovery succeeds")
+	}
+
+	return err
 }
 
+// runJobsInParallel starts concurrency workers that dispatch the jobs of the paths received on secretPaths.
+// A worker moves on to the next path once the replica tasks of a job are queued; the results channel is
+// closed after the last replica task finished and the pipeline and status writer are drained. The jobs run with
+// jobCtx; once ctx is done, the remaining paths are skipped.
 func (o *SyncOrchestrator) runJobsInParallel(
 	ctx context.Context,
-	secretPaths []pathmatching.SecretPath,
+	jobCtx context.Context,
+	secretPaths <-chan pathmatching.SecretPath,
 	concurrency int,
+	run *syncRun,
 ) chan *job.SyncJobResult {
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, concurrency)
-	jobResults := make(chan *job.SyncJobResult, len(secretPaths))
-
-	for _, secret := range secretPaths {
-		wg.Add(1)
-		go o.executeJob(ctx, secret, &wg, semaphore, jobResults)
+	var workers sync.WaitGroup
+	var pendingJobs sync.WaitGroup
+	jobResults := make(chan *job.SyncJobResult, concurrency)
+
+	for range max(1, concurrency) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for secret := range secretPaths {
+				pendingJobs.Add(1)
+				o.executeJob(ctx, jobCtx, secret, &pendingJobs, run, jobResults)
+			}
+		}()
 	}
 
 	go func() {
-		wg.Wait()
+		workers.Wait()
+		pendingJobs.Wait()
+		run.pipeline.Close()
+		run.statusWriter.Close()
 		close(jobResults)
 	}()
 
 	return jobResults
 }
 
-// executeJob runs a single sync job.
+// executeJob runs a single sync job with jobCtx, unless ctx is done, once no other run syncs its path. wg is
+// released once the job result is published, which for dispatched jobs happens after every replica finished.
 func (o *SyncOrchestrator) executeJob(
 	ctx context.Context,
+	jobCtx context.Context,
 	secret pathmatching.SecretPath,
 	wg *sync.WaitGroup,
-	semaphore chan struct{},
+	run *syncRun,
 	jobResults chan *job.SyncJobResult,
 ) {
-	defer wg.Done()
-
-	cancelJob := func(secret pathmatching.SecretPath, err error) {
-		jobResults <- &job.SyncJobResult{Mount: secret.Mount, KeyPath: secret.KeyPath, Error: err}
+	startTime := time.Now()
+	publishResult := func(jobResult *job.SyncJobResult) {
+		metrics.ObserveJob(time.Since(startTime))
+		jobResults <- jobResult
+		wg.Done()
 	}
 
-	select {
-	case <-ctx.Done():
-		cancelJob(secret, ctx.Err())
+	if err := ctx.Err(); err != nil {
+		publishResult(&job.SyncJobResult{Mount: secret.Mount, KeyPath: secret.KeyPath, Error: err})
 		return
-	default:
-		// Context is still valid, proceed
 	}
 
-	// Acquire semaphore with context awareness
-	select {
-	case semaphore <- struct{}{}:
-		defer func() { <-semaphore }()
-	case <-ctx.Done():
-		cancelJob(secret, ctx.Err())
+	// A path is synced by one run at a time; the job waits while another run syncs its path.
+	if err := o.pathLocks.lock(ctx, secret.String()); err != nil {
+		publishResult(&job.SyncJobResult{Mount: secret.Mount, KeyPath: secret.KeyPath, Error: err})
 		return
 	}
+	publishUnlocked := publishResult
+	publishResult = func(jobResult *job.SyncJobResult) {
+		o.pathLocks.unlock(secret.String())
+		publishUnlocked(jobResult)
+	}
 
-	// Create and execute sync job
-	syncJob := job.NewSyncJob(secret.Mount, secret.KeyPath, o.vaultClient, o.dbClient)
+	// Create and dispatch sync job; replica writes continue in the pipeline after the worker moves on
+	syncJob := job.NewSyncJob(secret.Mount, secret.KeyPath, o.vaultClient, o.dbClient, run.jobOptions(secret)...)
 
-	// Execute with context (job.Execute should also respect context)
-	jobSyncResult, err := syncJob.Execute(ctx)
-	if err != nil {
+	if err := syncJob.Dispatch(jobCtx, run.pipeline, publishResult); err != nil {
 		o.logger.Error().
 			Err(err).
 			Str("mount", secret.Mount).
@@ -240,20 +736,36 @@ func (o *SyncOrchestrator) executeJob(
 			Msg("Sync job execution failed")
 
 		// Create a failed result
-		jobSyncResult = job.NewSyncJobResult(syncJob, []*job.ClusterSyncStatus{}, err)
+		publishResult(job.NewSyncJobResult(syncJob, []*job.ClusterSyncStatus{}, err))
 	}
-	jobResults <- jobSyncResult
 }
 
-// collectResults aggregates job results and updates counters.
-func (o *SyncOrchestrator) collectResults(result *SyncResult, jobResults chan *job.SyncJobResult) {
+// collectResults aggregates job results as they arrive, updates counters, records them in history and the
+// checkpoint and checks the error budget. Results of unchanged secrets are only counted.
+func (o *SyncOrchestrator) collectResults(
+	ctx context.Context,
+	result *SyncResult,
+	jobResults chan *job.SyncJobResult,
+	history *syncHistory,
+	checkpoint *runCheckpoint,
+	budget *runBudget,
+) {
 	for jobResult := range jobResults {
-		result.JobResults = append(result.JobResults, jobResult)
-		o.categorizeJobResult(jobResult, result)
+		history.add(ctx, jobResult)
+		checkpoint.add(ctx, jobResult)
+		for _, clusterStatus := range jobResult.Status {
+			metrics.ObserveReplicaOutcome(clusterStatus.ClusterName, string(clusterStatus.Status))
+		}
+		result.TotalSecrets++
+		if noOp := o.categorizeJobResult(jobResult, result); !noOp || jobResult.Error != nil || o.keepUnchanged {
+			result.JobResults = append(result.JobResults, jobResult)
+		}
+		budget.check(result)
 	}
 }
 
-func (o *SyncOrchestrator) categorizeJobResult(jobResult *job.SyncJobResult, result *SyncResult) {
+// categorizeJobResult updates the counters for a job result and reports whether the job was a no-op.
+func (o *SyncOrchestrator) categorizeJobResult(jobResult *job.SyncJobResult, result *SyncResult) bool {
 	// Check if job execution failed
 	if jobResult.Error != nil {
 		// Check if the error is due to context cancellation
@@ -264,12 +776,13 @@ func (o *SyncOrchestrator) categorizeJobResult(jobResult *job.SyncJobResult, res
 				Str("mount", jobResult.Mount).
 				Str("path", jobResult.KeyPath).
 				Msg("Job skipped due to context cancellation")
-			return
+			return false
 		}
 	}
 
-	hasFailure := false
-	allNoOp := true
+	// A job that failed before reaching any replica, e.g. reading the main cluster, has no replica statuses.
+	hasFailure := jobResult.Error != nil && len(jobResult.Status) == 0
+	allNoOp := !hasFailure
 
 	for _, clusterStatus := range jobResult.Status {
 		if o.isFailureStatus(clusterStatus.Status) {
@@ -282,6 +795,7 @@ func (o *SyncOrchestrator) categorizeJobResult(jobResult *job.SyncJobResult, res
 	}
 
 	o.updateResultCounters(result, hasFailure, allNoOp, jobResult)
+	return !hasFailure && allNoOp
 }
 
 // isFailureStatus checks if a status indicates failure.
@@ -334,13 +848,45 @@ func (o *SyncOrchestrator) logJobError(clusterName string, jobResult *job.SyncJo
 		Msg("Job failed")
 }
 
+// requestsSince returns the number of requests sent to each cluster between two RequestCounts snapshots.
+func requestsSince(before, after map[string]int64) map[string]int64 {
+	requests := make(map[string]int64, len(after))
+	for clusterName, count := range after {
+		requests[clusterName] = count - before[clusterName]
+	}
+	return requests
+}
+
 func (o *SyncOrchestrator) logSummary(result *SyncResult) {
 	o.logger.Info().
 		Int("total", result.TotalSecrets).
 		Int("successful", result.SuccessfulSyncs).
 		Int("failed", result.FailedSyncs).
 		Int("skipped", result.SkippedSecrets).
+		Bool("incomplete", result.Incomplete).
 		Int("no_op", result.NoOpSecrets).
+		Int("discovery_failures", result.DiscoveryFailures).
+		Bool("incremental", result.Incremental).
+		Str("abort_reason", result.AbortReason).
 		Dur("duration", result.Duration).
 		Msg("Synchronization completed")
+
+	for _, clusterName := range slices.Sorted(maps.Keys(result.VaultRequests)) {
+		o.logger.Info().
+			Str("cluster", clusterName).
+			Int64("requests", result.VaultRequests[clusterName]).
+			Msg("Vault requests")
+	}
+
+	for _, progress := range result.ReplicaProgress {
+		o.logger.Info().
+			Str("cluster", progress.ClusterName).
+			Int("enqueued", progress.Enqueued).
+			Int("completed", progress.Completed).
+			Int("failed", progress.Failed).
+			Int("skipped", progress.Skipped).
+			Int("backlog", progress.Backlog).
+			Int("max_backlog", progress.MaxBacklog).
+			Msg("Replica progress")
+	}
 }
@@ -189,6 +189,7 @@ func (suite *OrchestratorTestSuite) TestStartSync_Discovery() {
 		suite.NoError(err)
 		suite.Equal(1, result2.NoOpSecrets)
 		suite.Equal(0, result2.SuccessfulSyncs)
+		suite.Empty(result2.JobResults, "Unchanged secrets are counted but not kept")
 	})
 
 	suite.Run("stops and categorizes cancelled jobs as skipped", func() {
@@ -383,13 +384,13 @@ func (suite *OrchestratorTestSuite) assertSecretDeletedFromReplicas(mount string
 }
 
 func (suite *OrchestratorTestSuite) assertDBRecordCount(expected int, msgAndArgs ...interface{}) {
-	syncedSecrets, err := suite.repo.GetSyncedSecrets()
+	syncedSecrets, err := suite.repo.GetSyncedSecrets(suite.ctx)
 	suite.NoError(err)
 	suite.Len(syncedSecrets, expected, msgAndArgs...)
 }
 
 func (suite *OrchestratorTestSuite) assertOnlySecretRemains(expectedPath string) {
-	syncedSecrets, err := suite.repo.GetSyncedSecrets()
+	syncedSecrets, err := suite.repo.GetSyncedSecrets(suite.ctx)
 	suite.NoError(err)
 	for _, record := range syncedSecrets {
 		suite.Equal(expectedPath, record.SecretPath, "Only %s should remain in DB", expectedPath)
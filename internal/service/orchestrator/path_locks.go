@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+)
+
+// pathLocks keeps the jobs of concurrent runs from syncing the same path at once, e.g. a full run and the sync of
+// a path named by a webhook, which would write the same replica secret and upsert the same records. A job holds
+// the lock of its path until every replica of the job finished.
+type pathLocks struct {
+	mu   sync.Mutex
+	held map[string]chan struct{}
+}
+
+func newPathLocks() *pathLocks {
+	return &pathLocks{held: make(map[string]chan struct{})}
+}
+
+// lock waits until no other job holds path and holds it, or returns the error of ctx once ctx is done.
+func (l *pathLocks) lock(ctx context.Context, path string) error {
+	for {
+		l.mu.Lock()
+		released, held := l.held[path]
+		if !held {
+			l.held[path] = make(chan struct{})
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// unlock releases path for the next job waiting for it.
+func (l *pathLocks) unlock(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if released, held := l.held[path]; held {
+		close(released)
+		delete(l.held, path)
+	}
+}
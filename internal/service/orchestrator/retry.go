@@ -0,0 +1,193 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"vault-sync/internal/metrics"
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/tracing"
+)
+
+// RetryFailed retries the failed replicas whose retry is due, without discovering the main cluster. The jobs of
+// their paths run like in a full run, so the other replicas of those paths are checked as well. Quarantined
+// replicas are left alone. Nothing is recorded when no retry is due. A coordinator enqueues the jobs instead.
+func (o *SyncOrchestrator) RetryFailed(ctx context.Context) (*SyncResult, error) {
+	return o.retry(ctx, "due", true, func(failed []*models.SyncedSecret, now time.Time) []pathmatching.SecretPath {
+		return pathsOf(failed, func(record *models.SyncedSecret) bool {
+			return record.IsRetryDue(now)
+		})
+	})
+}
+
+// RetryQuarantined retries the failed replicas of paths regardless of their backoff and quarantine, or of every
+// path with a quarantined replica when paths is empty. A replica that fails again is quarantined again.
+func (o *SyncOrchestrator) RetryQuarantined(
+	ctx context.Context,
+	paths []pathmatching.SecretPath,
+) (*SyncResult, error) {
+	selectPaths := func(failed []*models.SyncedSecret, _ time.Time) []pathmatching.SecretPath {
+		if len(paths) > 0 {
+			return paths
+		}
+		return pathsOf(failed, (*models.SyncedSecret).IsQuarantined)
+	}
+	return o.retry(ctx, "quarantined", false, selectPaths, job.WithForcedRetry())
+}
+
+// SyncPaths runs the jobs of paths right away, e.g. for the paths named by a webhook, without discovering the main
+// cluster. The jobs run like in a full run: failed replicas whose retry is not due and quarantined replicas are
+// left alone. A coordinator enqueues the jobs instead.
+func (o *SyncOrchestrator) SyncPaths(ctx context.Context, paths []pathmatching.SecretPath) (*SyncResult, error) {
+	startTime := time.Now()
+	ctx, span := tracing.Start(ctx, "orchestrator.sync_paths", tracing.RunKindKey.String(runKindPaths))
+	defer span.End()
+	logger := tracing.WithTraceContext(ctx, o.logger)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(paths) == 0 {
+		return &SyncResult{}, nil
+	}
+	logger.Info().Int("paths", len(paths)).Msg("Syncing paths")
+
+	if o.workQueue != nil {
+		return o.enqueuePaths(ctx, span, runKindPaths, startTime, paths)
+	}
+	return o.syncPaths(ctx, span, runKindPaths, startTime, false, paths)
+}
+
+// SyncLeased runs the jobs of paths a worker leased from the work queue. The jobs run like in a full run; with
+// incremental, a job whose source is unchanged since its last sync is skipped like in an incremental run.
+func (o *SyncOrchestrator) SyncLeased(
+	ctx context.Context,
+	paths []pathmatching.SecretPath,
+	incremental bool,
+) (*SyncResult, error) {
+	startTime := time.Now()
+	ctx, span := tracing.Start(ctx, "orchestrator.sync_leased", tracing.RunKindKey.String(runKindWorker))
+	defer span.End()
+	logger := tracing.WithTraceContext(ctx, o.logger)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(paths) == 0 {
+		return &SyncResult{}, nil
+	}
+	logger.Info().Int("paths", len(paths)).Bool("incremental", incremental).Msg("Syncing leased paths")
+
+	return o.syncPaths(ctx, span, runKindWorker, startTime, incremental, paths)
+}
+
+// retry runs the jobs of the paths picked from the failed records by selectPaths. The jobs read their own
+// records, as a retry only touches a small part of the synced paths. A coordinator enqueues the jobs when
+// queueable is set.
+func (o *SyncOrchestrator) retry(
+	ctx context.Context,
+	kind string,
+	queueable bool,
+	selectPaths func(failed []*models.SyncedSecret, now time.Time) []pathmatching.SecretPath,
+	jobOpts ...job.Option,
+) (*SyncResult, error) {
+	startTime := time.Now()
+	ctx, span := tracing.Start(ctx, "orchestrator.retry", tracing.RunKindKey.String(runKindRetry))
+	defer span.End()
+	logger := tracing.WithTraceContext(ctx, o.logger).With().Str("retry", kind).Logger()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	failed, err := o.dbClient.GetFailedSyncedSecrets(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to get failed secrets from DB: %w", err)
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	paths := selectPaths(failed, startTime)
+	if len(paths) == 0 {
+		logger.Debug().Int("failed_records", len(failed)).Msg("No retries to run")
+		return &SyncResult{}, nil
+	}
+	logger.Info().Int("paths", len(paths)).Int("failed_records", len(failed)).Msg("Retrying failed secrets")
+
+	if queueable && o.workQueue != nil {
+		return o.enqueuePaths(ctx, span, runKindRetry, startTime, paths)
+	}
+	return o.syncPaths(ctx, span, runKindRetry, startTime, false, paths, jobOpts...)
+}
+
+// syncPaths runs the jobs of paths as a run of runKind and records it in the history and the run metrics. The run
+// only fails when ctx is done or it is aborted by its budget; failed jobs are reported in the result.
+func (o *SyncOrchestrator) syncPaths(
+	ctx context.Context,
+	span trace.Span,
+	runKind string,
+	startTime time.Time,
+	incremental bool,
+	paths []pathmatching.SecretPath,
+	jobOpts ...job.Option,
+) (*SyncResult, error) {
+	history := o.startHistory(ctx, startTime)
+	requestsBefore := o.vaultClient.RequestCounts()
+	// unsent and stopErr are written before the stream ends, which happens before executeSyncJobs returns.
+	var unsent []pathmatching.SecretPath
+	var stopErr error
+	stream := func(ctx context.Context, secretPaths chan<- pathmatching.SecretPath) error {
+		for i, path := range paths {
+			select {
+			case secretPaths <- path:
+			case <-ctx.Done():
+				unsent, stopErr = paths[i:], ctx.Err()
+				return nil
+			}
+		}
+		return nil
+	}
+	result, _ := o.executeSyncJobs(ctx, nil, incremental, history, nil, stream, jobOpts...)
+	// The paths are known, so the ones the run did not get to are skipped like the jobs it did not start.
+	for _, path := range unsent {
+		result.TotalSecrets++
+		result.SkippedSecrets++
+		result.JobResults = append(result.JobResults,
+			&job.SyncJobResult{Mount: path.Mount, KeyPath: path.KeyPath, Error: stopErr})
+	}
+	result.Duration = time.Since(startTime)
+	result.VaultRequests = requestsSince(requestsBefore, o.vaultClient.RequestCounts())
+	o.logSummary(result)
+
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		err = fmt.Errorf("%s run interrupted: %w", runKind, ctx.Err())
+	case result.AbortReason != "":
+		err = fmt.Errorf("%s %w: %s", runKind, ErrRunAborted, result.AbortReason)
+	}
+	history.finish(ctx, result, err)
+	metrics.ObserveRun(runKind, runStatus(err).String(), result.Duration)
+	tracing.RecordError(span, err)
+	return result, err
+}
+
+// pathsOf returns the distinct paths of the records matching include, in the order of the records.
+func pathsOf(records []*models.SyncedSecret, include func(*models.SyncedSecret) bool) []pathmatching.SecretPath {
+	seen := make(map[string]bool)
+	var paths []pathmatching.SecretPath
+	for _, record := range records {
+		path := pathmatching.SecretPath{Mount: record.SecretBackend, KeyPath: record.SecretPath}
+		if !include(record) || seen[path.String()] {
+			continue
+		}
+		seen[path.String()] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
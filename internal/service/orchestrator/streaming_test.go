@@ -0,0 +1,1043 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/config"
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/vault"
+)
+
+const (
+	replicaA = "replica-a"
+	replicaB = "replica-b"
+)
+
+type StreamingSyncTestSuite struct {
+	suite.Suite
+	ctx   context.Context
+	vault *fakeVault
+	repo  *fakeRepository
+}
+
+func TestStreamingSyncSuite(t *testing.T) {
+	suite.Run(t, new(StreamingSyncTestSuite))
+}
+
+func (suite *StreamingSyncTestSuite) SetupSubTest() {
+	suite.ctx = context.Background()
+	suite.vault = newFakeVault(replicaA, replicaB)
+	suite.repo = newFakeRepository()
+}
+
+func (suite *StreamingSyncTestSuite) newOrchestrator(concurrency int, opts ...Option) *SyncOrchestrator {
+	return suite.newOrchestratorWithRule(&config.SyncRule{KvMounts: mounts}, concurrency, opts...)
+}
+
+func (suite *StreamingSyncTestSuite) newOrchestratorWithRule(
+	syncRule *config.SyncRule, concurrency int, opts ...Option,
+) *SyncOrchestrator {
+	pathMatcher := pathmatching.NewVaultPathMatcher(suite.vault, syncRule)
+	return NewSyncOrchestrator(suite.vault, suite.repo, pathMatcher, concurrency, opts...)
+}
+
+func (suite *StreamingSyncTestSuite) writeNumberedSecrets(mount string, count int) {
+	for i := range count {
+		suite.vault.writeSecrets(mount, fmt.Sprintf("app/secret-%04d", i))
+	}
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync() {
+	suite.Run("syncs every discovered secret with a fixed number of workers", func() {
+		suite.writeNumberedSecrets(teamAMount, 150)
+		suite.writeNumberedSecrets(teamBMount, 50)
+		suite.vault.stateDelay = time.Millisecond
+
+		result, err := suite.newOrchestrator(4).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(200, result.TotalSecrets)
+		suite.Equal(200, result.SuccessfulSyncs)
+		suite.Len(result.JobResults, 200)
+		suite.LessOrEqual(suite.vault.maxSeen.Load(), int32(4))
+		suite.Len(suite.vault.replicaKeys(replicaA), 200)
+		suite.Len(suite.vault.replicaKeys(replicaB), 200)
+		suite.Equal(400, suite.repo.count())
+	})
+
+	suite.Run("counts unchanged secrets without keeping their results", func() {
+		suite.writeNumberedSecrets(teamAMount, 100)
+		orchestrator := suite.newOrchestrator(8)
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.writeSecrets(teamAMount, "app/secret-0042")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(100, result.TotalSecrets)
+		suite.Equal(99, result.NoOpSecrets)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Require().Len(result.JobResults, 1)
+		suite.Equal("app/secret-0042", result.JobResults[0].KeyPath)
+	})
+
+	suite.Run("keeps the results of unchanged secrets when asked to", func() {
+		suite.writeNumberedSecrets(teamAMount, 30)
+		orchestrator := suite.newOrchestrator(4, WithUnchangedJobResults())
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.writeSecrets(teamAMount, "app/secret-0007")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(29, result.NoOpSecrets)
+		suite.Len(result.JobResults, 30)
+	})
+
+	suite.Run("processes paths only known from the database after discovery", func() {
+		suite.writeNumberedSecrets(teamAMount, 20)
+		orchestrator := suite.newOrchestrator(3)
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.deleteSecret(teamAMount, "app/secret-0003")
+		suite.vault.deleteSecret(teamAMount, "app/secret-0017")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(20, result.TotalSecrets)
+		suite.Equal(2, result.SuccessfulSyncs)
+		suite.Equal(18, result.NoOpSecrets)
+		suite.NotContains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0003")
+		suite.NotContains(suite.vault.replicaKeys(replicaB), teamAMount+"/app/secret-0017")
+		suite.Equal(36, suite.repo.count())
+	})
+
+	suite.Run("returns an empty result when nothing is discovered or synced", func() {
+		result, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(0, result.TotalSecrets)
+		suite.Empty(result.JobResults)
+	})
+
+	suite.Run("stops streaming and accounts for every processed path when cancelled", func() {
+		suite.writeNumberedSecrets(teamAMount, 500)
+		suite.vault.stateDelay = time.Millisecond
+		ctx, cancel := context.WithTimeout(suite.ctx, 20*time.Millisecond)
+		defer cancel()
+
+		result, err := suite.newOrchestrator(2).StartSync(ctx)
+
+		suite.Require().ErrorContains(err, "sync interrupted")
+		suite.Less(result.TotalSecrets, 500)
+		total := result.SuccessfulSyncs + result.FailedSyncs + result.NoOpSecrets + result.SkippedSecrets
+		suite.Equal(result.TotalSecrets, total)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_IncompleteDiscovery() {
+	setup := func() {
+		suite.writeNumberedSecrets(teamAMount, 5)
+		suite.writeNumberedSecrets(teamBMount, 5)
+		_, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+
+		suite.vault.deleteSecret(teamAMount, "app/secret-0001")
+		suite.vault.deleteSecret(teamBMount, "app/secret-0001")
+		suite.vault.mountErrors[teamAMount] = errors.New("permission denied")
+	}
+
+	suite.Run("counts the failed mount and keeps its synced paths when not strict", func() {
+		setup()
+
+		result, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.DiscoveryFailures)
+		suite.Equal([]string{teamAMount}, result.FailedMounts)
+		suite.Equal(5, result.TotalSecrets, "4 discovered team-b secrets and 1 deleted one")
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Contains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0001")
+		suite.NotContains(suite.vault.replicaKeys(replicaA), teamBMount+"/app/secret-0001")
+	})
+
+	suite.Run("aborts the run before any delete when strict", func() {
+		setup()
+		syncRule := &config.SyncRule{KvMounts: mounts, Strict: true}
+
+		result, err := suite.newOrchestratorWithRule(syncRule, 2, WithStrictDiscovery(true)).StartSync(suite.ctx)
+
+		suite.Require().ErrorIs(err, pathmatching.ErrDiscoveryIncomplete)
+		suite.ErrorContains(err, "sync aborted")
+		suite.ErrorContains(err, "permission denied")
+		suite.Require().NotNil(result)
+		suite.Equal(1, result.DiscoveryFailures)
+		suite.Equal(0, result.TotalSecrets, "discovery stops at the first failed mount")
+		suite.Contains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0001")
+		suite.Contains(suite.vault.replicaKeys(replicaA), teamBMount+"/app/secret-0001")
+		suite.Equal(20, suite.repo.count())
+	})
+
+	suite.Run("syncs the other mounts before failing in strict mode when a later mount fails", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		suite.vault.mountErrors[teamBMount] = errors.New("connection refused")
+
+		result, err := suite.newOrchestrator(2, WithStrictDiscovery(true)).StartSync(suite.ctx)
+
+		suite.Require().ErrorIs(err, pathmatching.ErrDiscoveryIncomplete)
+		suite.Equal(3, result.SuccessfulSyncs)
+		suite.Equal([]string{teamBMount}, result.FailedMounts)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_Incremental() {
+	const instanceID = "vault-sync-test"
+
+	suite.Run("runs and records a full reconciliation when none was recorded", func() {
+		suite.writeNumberedSecrets(teamAMount, 10)
+		_, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.replicaChecks.Store(0)
+
+		result, err := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.False(result.Incremental)
+		suite.Equal(int32(20), suite.vault.replicaChecks.Load())
+		lastFullRun, err := suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.False(lastFullRun.IsZero())
+	})
+
+	suite.Run("only checks the replicas of changed secrets", func() {
+		suite.writeNumberedSecrets(teamAMount, 10)
+		orchestrator := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour))
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.replicaChecks.Store(0)
+		suite.vault.writeSecrets(teamAMount, "app/secret-0004")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		suite.Equal(9, result.NoOpSecrets)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Equal(int32(0), suite.vault.replicaChecks.Load(), "the changed secret is written without checks")
+		suite.Equal(int64(1), result.VaultRequests[replicaA])
+	})
+
+	suite.Run("repairs a secret removed from a replica only on the next full reconciliation", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		_, err := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.deleteFromReplica(replicaA, teamAMount, "app/secret-0001")
+
+		result, err := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		suite.NotContains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0001")
+
+		suite.Require().NoError(suite.repo.RecordFullReconciliation(suite.ctx, instanceID, time.Now().Add(-2*time.Hour)))
+		result, err = suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.False(result.Incremental)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Contains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0001")
+	})
+
+	suite.Run("stores the source update time of records synced before it was tracked", func() {
+		suite.writeNumberedSecrets(teamAMount, 5)
+		_, err := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.repo.clearSourceUpdatedTimes()
+
+		result, err := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		suite.Equal(5, result.NoOpSecrets)
+		suite.vault.replicaChecks.Store(0)
+
+		result, err = suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(5, result.NoOpSecrets)
+		suite.Equal(int32(0), suite.vault.replicaChecks.Load())
+	})
+
+	suite.Run("does not record a full reconciliation when discovery is incomplete", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		suite.vault.mountErrors[teamBMount] = errors.New("permission denied")
+
+		result, err := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.False(result.Incremental)
+		lastFullRun, err := suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.True(lastFullRun.IsZero())
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_VaultRequests() {
+	suite.Run("reads new secrets once from main and writes them without checking the replicas", func() {
+		suite.writeNumberedSecrets(teamAMount, 10)
+
+		result, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(map[string]int64{
+			mainCluster: 2 + 2*10,
+			replicaA:    10,
+			replicaB:    10,
+		}, result.VaultRequests, "one listing per mount, metadata and data per secret, one write per replica")
+	})
+
+	suite.Run("sends one metadata request per unchanged secret and checks the replicas", func() {
+		suite.writeNumberedSecrets(teamAMount, 10)
+		orchestrator := suite.newOrchestrator(2)
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.writeSecrets(teamAMount, "app/secret-0003")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(map[string]int64{
+			mainCluster: 2 + 10 + 1,
+			replicaA:    9 + 1,
+			replicaB:    9 + 1,
+		}, result.VaultRequests, "the changed secret is read once and written without checking the replicas")
+	})
+
+	suite.Run("only reads main metadata for unchanged secrets in incremental runs", func() {
+		suite.writeNumberedSecrets(teamAMount, 10)
+		orchestrator := suite.newOrchestrator(2, WithIncrementalSync("vault-sync-test", time.Hour))
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		suite.Equal(map[string]int64{mainCluster: 2 + 10, replicaA: 0, replicaB: 0}, result.VaultRequests)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_BatchedDatabaseAccess() {
+	suite.Run("loads the records once and writes them in batches", func() {
+		suite.writeNumberedSecrets(teamAMount, 100)
+		orchestrator := suite.newOrchestrator(4, WithStatusBatchSize(50))
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.writeSecrets(teamAMount, "app/secret-0042")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Equal(200, suite.repo.count())
+		singleReads, singleWrites, batches := suite.repo.calls()
+		suite.Zero(singleReads)
+		suite.Zero(singleWrites)
+		suite.GreaterOrEqual(batches, 4)
+		suite.Less(batches, 200)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_History() {
+	eventsByAction := func(events []models.SyncEvent) map[models.SyncAction]int {
+		actions := make(map[models.SyncAction]int)
+		for _, event := range events {
+			actions[event.Action]++
+		}
+		return actions
+	}
+
+	suite.Run("records the run and an event per changed replica", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db", "app/api")
+		orchestrator := suite.newOrchestrator(2, WithSyncHistory("instance-a", 0))
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		_, err = orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+
+		runs, events := suite.repo.history()
+		suite.Require().Len(runs, 2)
+		suite.Equal("instance-a", runs[0].InstanceID)
+		suite.Equal(models.SyncRunCompleted, runs[0].Status)
+		suite.NotNil(runs[0].FinishedAt)
+		suite.Equal(2, runs[0].SuccessfulSyncs)
+		suite.Equal(2, runs[1].NoOpSecrets)
+		suite.Require().Len(events, 4)
+		for _, event := range events {
+			suite.Equal(runs[0].ID, event.RunID)
+			suite.Equal(models.SyncActionUpdated, event.Action)
+			suite.Equal(int64(1), *event.SourceVersion)
+			suite.Equal(int64(1), *event.DestinationVersion)
+			suite.Nil(event.ErrorMessage)
+		}
+	})
+
+	suite.Run("records deletes and failed writes with their error", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db", "app/api")
+		orchestrator := suite.newOrchestrator(2, WithSyncHistory("instance-a", 0))
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.deleteSecret(teamAMount, "app/db")
+		suite.vault.writeSecrets(teamAMount, "app/api")
+		suite.vault.failReplica(replicaB, errors.New("permission denied"))
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.FailedSyncs)
+		runs, events := suite.repo.history()
+		suite.Require().Len(runs, 2)
+		suite.Equal(1, runs[1].FailedSyncs)
+		var runEvents []models.SyncEvent
+		for _, event := range events {
+			if event.RunID == runs[1].ID {
+				runEvents = append(runEvents, event)
+			}
+		}
+		suite.Equal(map[models.SyncAction]int{
+			models.SyncActionDeleted: 2,
+			models.SyncActionUpdated: 1,
+			models.SyncActionFailed:  1,
+		}, eventsByAction(runEvents))
+		for _, event := range runEvents {
+			if event.Action == models.SyncActionFailed {
+				suite.Equal(replicaB, event.DestinationCluster)
+				suite.Equal(int64(2), *event.SourceVersion)
+				suite.Nil(event.DestinationVersion)
+				suite.Require().NotNil(event.ErrorMessage)
+				suite.Contains(*event.ErrorMessage, "permission denied")
+			}
+		}
+	})
+
+	suite.Run("records a cancelled run as interrupted", func() {
+		suite.writeNumberedSecrets(teamAMount, 500)
+		suite.vault.stateDelay = time.Millisecond
+		ctx, cancel := context.WithTimeout(suite.ctx, 20*time.Millisecond)
+		defer cancel()
+
+		_, err := suite.newOrchestrator(2, WithSyncHistory("instance-a", 0)).StartSync(ctx)
+
+		suite.Require().Error(err)
+		runs, _ := suite.repo.history()
+		suite.Require().Len(runs, 1)
+		suite.Equal(models.SyncRunInterrupted, runs[0].Status)
+		suite.Require().NotNil(runs[0].ErrorMessage)
+		suite.Contains(*runs[0].ErrorMessage, "sync interrupted")
+	})
+
+	suite.Run("prunes the runs older than the retention", func() {
+		oldRun := &models.SyncRun{InstanceID: "instance-a", StartedAt: time.Now().Add(-48 * time.Hour)}
+		suite.Require().NoError(suite.repo.StartSyncRun(suite.ctx, oldRun))
+		suite.Require().NoError(suite.repo.InsertSyncEvents(suite.ctx, []*models.SyncEvent{
+			{RunID: oldRun.ID, SecretBackend: teamAMount, SecretPath: "app/old", Action: models.SyncActionUpdated},
+		}))
+		suite.vault.writeSecrets(teamAMount, "app/db")
+
+		_, err := suite.newOrchestrator(2, WithSyncHistory("instance-a", 24*time.Hour)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		runs, events := suite.repo.history()
+		suite.Require().Len(runs, 1)
+		suite.NotEqual(oldRun.ID, runs[0].ID)
+		suite.Len(events, 2)
+	})
+
+	suite.Run("records nothing when history is disabled", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+
+		_, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		runs, events := suite.repo.history()
+		suite.Empty(runs)
+		suite.Empty(events)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_RetryQueue() {
+	permissionDenied := fmt.Errorf("write failed: %w", vault.ErrPermissionDenied)
+
+	suite.Run("retries a failed replica on the next run without a retry policy", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2)
+		suite.vault.failReplica(replicaB, permissionDenied)
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		record := suite.repo.record(teamAMount, "app/db", replicaB)
+		suite.Require().NotNil(record)
+		suite.Equal(1, record.AttemptCount)
+		suite.Nil(record.NextRetryAt)
+		suite.Equal(models.ErrorCategoryPermissionDenied, *record.ErrorCategory)
+		suite.vault.failReplica(replicaB, nil)
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Contains(suite.vault.replicaKeys(replicaB), teamAMount+"/app/db")
+		record = suite.repo.record(teamAMount, "app/db", replicaB)
+		suite.Equal(models.StatusSuccess, record.Status)
+		suite.Zero(record.AttemptCount)
+		suite.Nil(record.ErrorCategory)
+	})
+
+	suite.Run("leaves a failed replica alone until its retry is due", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2, WithRetryPolicy(job.NewRetryPolicy(time.Hour, time.Hour, 10)))
+		suite.vault.failReplica(replicaB, permissionDenied)
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		record := suite.repo.record(teamAMount, "app/db", replicaB)
+		suite.Require().NotNil(record.NextRetryAt)
+		suite.WithinDuration(time.Now().Add(time.Hour), *record.NextRetryAt, time.Minute)
+		suite.vault.failReplica(replicaB, nil)
+
+		result, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		retryResult, retryErr := orchestrator.RetryFailed(suite.ctx)
+
+		suite.Require().NoError(retryErr)
+		suite.Equal(1, result.NoOpSecrets)
+		suite.Zero(retryResult.TotalSecrets)
+		suite.Empty(suite.vault.replicaKeys(replicaB))
+	})
+
+	suite.Run("retries a backing off replica when the source changed", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2, WithRetryPolicy(job.NewRetryPolicy(time.Hour, time.Hour, 10)))
+		suite.vault.failReplica(replicaB, permissionDenied)
+
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.failReplica(replicaB, nil)
+		suite.vault.writeSecrets(teamAMount, "app/db")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Contains(suite.vault.replicaKeys(replicaB), teamAMount+"/app/db")
+	})
+
+	suite.Run("retries only the due paths between runs", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db", "app/api", "app/cache")
+		orchestrator := suite.newOrchestrator(2, WithRetryPolicy(job.NewRetryPolicy(time.Millisecond, time.Millisecond, 10)))
+		suite.vault.failReplica(replicaB, permissionDenied)
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.failReplica(replicaB, nil)
+		time.Sleep(5 * time.Millisecond)
+
+		result, err := orchestrator.RetryFailed(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(3, result.TotalSecrets)
+		suite.Equal(3, result.SuccessfulSyncs)
+		suite.Len(suite.vault.replicaKeys(replicaB), 3)
+		retryResult, err := orchestrator.RetryFailed(suite.ctx)
+		suite.Require().NoError(err)
+		suite.Zero(retryResult.TotalSecrets)
+	})
+
+	suite.Run("quarantines a replica that keeps failing and only retries it on request", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2, WithRetryPolicy(job.NewRetryPolicy(time.Millisecond, time.Millisecond, 2)))
+		suite.vault.failReplica(replicaB, permissionDenied)
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		time.Sleep(5 * time.Millisecond)
+		_, err = orchestrator.RetryFailed(suite.ctx)
+		suite.Require().NoError(err)
+
+		record := suite.repo.record(teamAMount, "app/db", replicaB)
+		suite.Equal(2, record.AttemptCount)
+		suite.NotNil(record.QuarantinedAt)
+		suite.Nil(record.NextRetryAt)
+		suite.vault.failReplica(replicaB, nil)
+		time.Sleep(5 * time.Millisecond)
+
+		retryResult, err := orchestrator.RetryFailed(suite.ctx)
+		suite.Require().NoError(err)
+		suite.Zero(retryResult.TotalSecrets)
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		_, err = orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.Empty(suite.vault.replicaKeys(replicaB))
+
+		result, err := orchestrator.RetryQuarantined(suite.ctx, nil)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Contains(suite.vault.replicaKeys(replicaB), teamAMount+"/app/db")
+		suite.Nil(suite.repo.record(teamAMount, "app/db", replicaB).QuarantinedAt)
+	})
+
+	suite.Run("never quarantines a replica for transient failures", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2, WithRetryPolicy(job.NewRetryPolicy(time.Millisecond, time.Millisecond, 1)))
+		suite.vault.failReplica(replicaB, vault.ErrClusterUnavailable)
+
+		_, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		record := suite.repo.record(teamAMount, "app/db", replicaB)
+		suite.Equal(models.ErrorCategoryUnavailable, *record.ErrorCategory)
+		suite.Nil(record.QuarantinedAt)
+		suite.NotNil(record.NextRetryAt)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestSyncPaths() {
+	suite.Run("syncs only the given paths", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db", "app/api")
+		orchestrator := suite.newOrchestrator(2, WithSyncHistory("instance-a", 0))
+
+		result, err := orchestrator.SyncPaths(suite.ctx, []pathmatching.SecretPath{{Mount: teamAMount, KeyPath: "app/db"}})
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.TotalSecrets)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Equal([]string{teamAMount + "/app/db"}, suite.vault.replicaKeys(replicaA))
+		runs, _ := suite.repo.history()
+		suite.Require().Len(runs, 1)
+		suite.Equal(models.SyncRunCompleted, runs[0].Status)
+	})
+
+	suite.Run("deletes a given path that was removed from the main cluster", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2)
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.deleteSecret(teamAMount, "app/db")
+
+		result, err := orchestrator.SyncPaths(suite.ctx, []pathmatching.SecretPath{{Mount: teamAMount, KeyPath: "app/db"}})
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.TotalSecrets)
+		suite.Empty(suite.vault.replicaKeys(replicaA))
+		suite.Zero(suite.repo.count())
+	})
+
+	suite.Run("leaves a failed replica alone until its retry is due", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2, WithRetryPolicy(job.NewRetryPolicy(time.Hour, time.Hour, 10)))
+		suite.vault.failReplica(replicaB, fmt.Errorf("write failed: %w", vault.ErrPermissionDenied))
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.failReplica(replicaB, nil)
+
+		_, err = orchestrator.SyncPaths(suite.ctx, []pathmatching.SecretPath{{Mount: teamAMount, KeyPath: "app/db"}})
+
+		suite.Require().NoError(err)
+		suite.Empty(suite.vault.replicaKeys(replicaB))
+	})
+
+	suite.Run("waits while another run syncs a path", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2)
+		path := pathmatching.SecretPath{Mount: teamAMount, KeyPath: "app/db"}
+		suite.Require().NoError(orchestrator.pathLocks.lock(suite.ctx, path.String()))
+		results := make(chan *SyncResult, 1)
+		go func() {
+			result, _ := orchestrator.SyncPaths(suite.ctx, []pathmatching.SecretPath{path})
+			results <- result
+		}()
+
+		select {
+		case <-results:
+			suite.FailNow("the path was synced while another run held it")
+		case <-time.After(50 * time.Millisecond):
+		}
+		suite.Empty(suite.vault.replicaKeys(replicaA))
+		orchestrator.pathLocks.unlock(path.String())
+
+		select {
+		case result := <-results:
+			suite.Equal(1, result.SuccessfulSyncs)
+		case <-time.After(2 * time.Second):
+			suite.FailNow("timed out waiting for the path to be synced")
+		}
+		suite.Equal([]string{teamAMount + "/app/db"}, suite.vault.replicaKeys(replicaA))
+	})
+
+	suite.Run("skips a path another run syncs once the run is cancelled", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		orchestrator := suite.newOrchestrator(2)
+		path := pathmatching.SecretPath{Mount: teamAMount, KeyPath: "app/db"}
+		suite.Require().NoError(orchestrator.pathLocks.lock(suite.ctx, path.String()))
+		defer orchestrator.pathLocks.unlock(path.String())
+		ctx, cancel := context.WithTimeout(suite.ctx, 50*time.Millisecond)
+		defer cancel()
+
+		result, err := orchestrator.SyncPaths(ctx, []pathmatching.SecretPath{path})
+
+		suite.Require().Error(err)
+		suite.Equal(1, result.SkippedSecrets)
+		suite.Empty(suite.vault.replicaKeys(replicaA))
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestWorkQueue() {
+	const instanceID = "coordinator"
+
+	suite.Run("a coordinator enqueues the job of every path instead of running it", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db", "app/api")
+		_, err := suite.newOrchestrator(2).StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.deleteSecret(teamAMount, "app/api")
+		suite.vault.writeSecrets(teamAMount, "app/cache")
+		suite.vault.replicaChecks.Store(0)
+		queue := &fakeWorkQueue{}
+
+		result, err := suite.newOrchestrator(2, WithWorkQueue(queue, instanceID)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(3, result.TotalSecrets)
+		suite.Equal(3, result.EnqueuedJobs)
+		suite.Zero(result.SuccessfulSyncs)
+		suite.Equal(instanceID, queue.enqueuedBy)
+		suite.Equal([]string{
+			teamAMount + "/app/api",
+			teamAMount + "/app/cache",
+			teamAMount + "/app/db",
+		}, queue.paths(), "the path only known from the database is enqueued as well")
+		suite.NotContains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/cache")
+		suite.Equal(int32(0), suite.vault.replicaChecks.Load())
+		suite.Equal(1, queue.pruned)
+	})
+
+	suite.Run("a coordinator enqueues incremental jobs during incremental runs", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		queue := &fakeWorkQueue{}
+		orchestrator := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour), WithWorkQueue(queue, instanceID))
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		suite.Require().Len(queue.jobs, 6)
+		suite.False(queue.jobs[0].Incremental, "the first run is a full reconciliation")
+		suite.True(queue.jobs[5].Incremental)
+	})
+
+	suite.Run("a coordinator records a full reconciliation once the workers finished its jobs", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		queue := &fakeWorkQueue{}
+		orchestrator := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour), WithWorkQueue(queue, instanceID))
+		startedAt := time.Now()
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		lastFullRun, err := suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.True(lastFullRun.IsZero(), "the jobs of the full reconciliation are only enqueued")
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.True(result.Incremental, "no second full reconciliation is enqueued while the first is queued")
+		lastFullRun, err = suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.True(lastFullRun.IsZero())
+
+		queue.finishAll(models.QueuedJobCompleted)
+		restarted := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour), WithWorkQueue(queue, instanceID))
+		result, err = restarted.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		lastFullRun, err = suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.False(lastFullRun.Before(startedAt), "the reconciliation is recorded with the start of its run")
+		suite.True(lastFullRun.Before(time.Now()))
+	})
+
+	suite.Run("a coordinator enqueues a full reconciliation again when one of its jobs failed", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		queue := &fakeWorkQueue{}
+		orchestrator := suite.newOrchestrator(2, WithIncrementalSync(instanceID, time.Hour), WithWorkQueue(queue, instanceID))
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		queue.finishAll(models.QueuedJobFailed)
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.False(result.Incremental)
+		lastFullRun, err := suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.True(lastFullRun.IsZero(), "a reconciliation with a failed job is not recorded")
+	})
+
+	suite.Run("a coordinator enqueues given paths and due retries but retries quarantined replicas itself", func() {
+		suite.vault.writeSecrets(teamAMount, "app/db")
+		queue := &fakeWorkQueue{}
+		orchestrator := suite.newOrchestrator(2, WithWorkQueue(queue, instanceID))
+		paths := []pathmatching.SecretPath{{Mount: teamAMount, KeyPath: "app/db"}}
+
+		result, err := orchestrator.SyncPaths(suite.ctx, paths)
+		suite.Require().NoError(err)
+		suite.Equal(1, result.EnqueuedJobs)
+
+		result, err = orchestrator.RetryQuarantined(suite.ctx, paths)
+		suite.Require().NoError(err)
+		suite.Zero(result.EnqueuedJobs)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Equal([]string{teamAMount + "/app/db"}, queue.paths())
+	})
+
+	suite.Run("a worker runs the leased paths", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		orchestrator := suite.newOrchestrator(2)
+		_, err := orchestrator.StartSync(suite.ctx)
+		suite.Require().NoError(err)
+		suite.vault.writeSecrets(teamAMount, "app/secret-0001")
+		suite.vault.replicaChecks.Store(0)
+		paths := []pathmatching.SecretPath{
+			{Mount: teamAMount, KeyPath: "app/secret-0000"},
+			{Mount: teamAMount, KeyPath: "app/secret-0001"},
+		}
+
+		result, err := orchestrator.SyncLeased(suite.ctx, paths, true)
+
+		suite.Require().NoError(err)
+		suite.True(result.Incremental)
+		suite.Equal(2, result.TotalSecrets)
+		suite.Equal(1, result.NoOpSecrets)
+		suite.Equal(1, result.SuccessfulSyncs)
+		suite.Equal(int32(0), suite.vault.replicaChecks.Load())
+		suite.Empty(result.PathFailures())
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_Checkpoints() {
+	const instanceID = "vault-sync-test"
+
+	suite.Run("keeps the checkpoint of an interrupted run and resumes it", func() {
+		suite.writeNumberedSecrets(teamAMount, 20)
+		suite.vault.stateDelay = time.Millisecond
+		checkpoints := newFakeCheckpoints()
+		ctx, cancel := context.WithCancel(suite.ctx)
+		defer cancel()
+		checkpoints.onAdd = cancel
+		orchestrator := suite.newOrchestrator(1, WithStatusBatchSize(1), WithCheckpoints(checkpoints, instanceID))
+
+		_, err := orchestrator.StartSync(ctx)
+
+		suite.Require().ErrorIs(err, context.Canceled)
+		processed := checkpoints.paths(instanceID)
+		suite.Require().NotEmpty(processed)
+		suite.Less(len(processed), 20)
+		for _, path := range processed {
+			suite.Contains(suite.vault.replicaKeys(replicaA), path, "the jobs in flight finish")
+		}
+
+		checkpoints.onAdd = nil
+		resumed := suite.newOrchestrator(2, WithCheckpoints(checkpoints, instanceID), WithResume())
+		result, err := resumed.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(len(processed), result.ResumedPaths)
+		suite.Equal(20-len(processed), result.TotalSecrets)
+		suite.Len(suite.vault.replicaKeys(replicaA), 20)
+		suite.Nil(checkpoints.paths(instanceID), "the checkpoint of a completed run is deleted")
+	})
+
+	suite.Run("does not checkpoint failed jobs, so a resumed run retries them", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		checkpoints := newFakeCheckpoints()
+		checkpoint := suite.newOrchestrator(1, WithCheckpoints(checkpoints, instanceID)).
+			startCheckpoint(suite.ctx, time.Now(), false, nil)
+		synced := []*job.ClusterSyncStatus{{ClusterName: replicaA, Status: job.SyncJobStatusUpdated}}
+		failed := []*job.ClusterSyncStatus{{ClusterName: replicaA, Status: job.SyncJobStatusFailed}}
+		checkpoint.add(suite.ctx, &job.SyncJobResult{Mount: teamAMount, KeyPath: "app/secret-0000", Status: synced})
+		checkpoint.add(suite.ctx, &job.SyncJobResult{Mount: teamAMount, KeyPath: "app/secret-0001", Status: failed})
+		checkpoint.add(suite.ctx, &job.SyncJobResult{Mount: teamAMount, KeyPath: "app/secret-0002", Error: job.ErrTimeout})
+		checkpoint.finish(suite.ctx, true)
+		suite.Equal([]string{teamAMount + "/app/secret-0000"}, checkpoints.paths(instanceID))
+
+		result, err := suite.newOrchestrator(2, WithCheckpoints(checkpoints, instanceID), WithResume()).
+			StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(1, result.ResumedPaths)
+		suite.Equal(2, result.TotalSecrets, "the failed jobs are retried")
+		suite.Contains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0001")
+		suite.Contains(suite.vault.replicaKeys(replicaA), teamAMount+"/app/secret-0002")
+	})
+
+	suite.Run("starts over without resume", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		checkpoints := newFakeCheckpoints()
+		suite.Require().NoError(checkpoints.StartSyncCheckpoint(suite.ctx, &models.SyncCheckpoint{
+			InstanceID: instanceID, StartedAt: time.Now(),
+		}))
+		suite.Require().NoError(checkpoints.AddCheckpointPaths(suite.ctx, instanceID, []models.CheckpointPath{
+			{SecretBackend: teamAMount, SecretPath: "app/secret-0000"},
+		}))
+
+		result, err := suite.newOrchestrator(2, WithCheckpoints(checkpoints, instanceID)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Zero(result.ResumedPaths)
+		suite.Equal(3, result.TotalSecrets)
+		suite.Nil(checkpoints.paths(instanceID))
+	})
+
+	suite.Run("resumes the kind of the interrupted run", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		checkpoints := newFakeCheckpoints()
+		startedAt := time.Now().Add(-time.Minute)
+		suite.Require().NoError(checkpoints.StartSyncCheckpoint(suite.ctx, &models.SyncCheckpoint{
+			InstanceID: instanceID, StartedAt: startedAt,
+		}))
+		orchestrator := suite.newOrchestrator(2,
+			WithIncrementalSync(instanceID, time.Hour), WithCheckpoints(checkpoints, instanceID), WithResume())
+		suite.Require().NoError(suite.repo.RecordFullReconciliation(suite.ctx, instanceID, time.Now()))
+
+		result, err := orchestrator.StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.False(result.Incremental, "the interrupted run was a full reconciliation")
+		lastFullRun, err := suite.repo.GetLastFullReconciliation(suite.ctx, instanceID)
+		suite.Require().NoError(err)
+		suite.True(startedAt.Equal(lastFullRun))
+	})
+
+	suite.Run("does not resume a checkpoint older than the full reconciliation interval", func() {
+		suite.writeNumberedSecrets(teamAMount, 3)
+		checkpoints := newFakeCheckpoints()
+		suite.Require().NoError(checkpoints.StartSyncCheckpoint(suite.ctx, &models.SyncCheckpoint{
+			InstanceID: instanceID, StartedAt: time.Now().Add(-48 * time.Hour),
+		}))
+		suite.Require().NoError(checkpoints.AddCheckpointPaths(suite.ctx, instanceID, []models.CheckpointPath{
+			{SecretBackend: teamAMount, SecretPath: "app/secret-0000"},
+		}))
+
+		result, err := suite.newOrchestrator(2, WithCheckpoints(checkpoints, instanceID), WithResume()).
+			StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Zero(result.ResumedPaths)
+		suite.Equal(3, result.TotalSecrets)
+	})
+}
+
+func (suite *StreamingSyncTestSuite) TestStartSync_RunBudget() {
+	suite.Run("fails the jobs of a replica that does not answer within the job timeout", func() {
+		suite.writeNumberedSecrets(teamAMount, 5)
+		suite.vault.hungReplica = replicaA
+
+		result, err := suite.newOrchestrator(2, WithJobTimeout(20*time.Millisecond)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Equal(5, result.FailedSyncs)
+		suite.Zero(result.SkippedSecrets)
+		for _, jobResult := range result.JobResults {
+			suite.ErrorIs(jobResult.Error, job.ErrTimeout)
+		}
+		suite.Len(suite.vault.replicaKeys(replicaB), 5)
+	})
+
+	suite.Run("aborts the run once the failed jobs exceed the error budget", func() {
+		suite.writeNumberedSecrets(teamAMount, 300)
+		suite.vault.failReplica(replicaA, errors.New("permission denied"))
+
+		result, err := suite.newOrchestrator(2, WithRunBudget(0, 10, 0)).StartSync(suite.ctx)
+
+		suite.Require().ErrorIs(err, ErrRunAborted)
+		suite.Contains(result.AbortReason, "jobs failed, the error budget allows 10")
+		suite.GreaterOrEqual(result.FailedSyncs, 10)
+		suite.Less(result.TotalSecrets, 300)
+		total := result.SuccessfulSyncs + result.FailedSyncs + result.NoOpSecrets + result.SkippedSecrets
+		suite.Equal(result.TotalSecrets, total)
+	})
+
+	suite.Run("aborts the run once the share of failed jobs exceeds the error budget", func() {
+		suite.writeNumberedSecrets(teamAMount, 300)
+		suite.vault.failReplica(replicaA, errors.New("permission denied"))
+
+		result, err := suite.newOrchestrator(2, WithRunBudget(0, 0, 50)).StartSync(suite.ctx)
+
+		suite.Require().ErrorIs(err, ErrRunAborted)
+		suite.Contains(result.AbortReason, "the error budget allows 50%")
+		suite.GreaterOrEqual(result.FailedSyncs, errorBudgetMinJobs)
+		suite.Less(result.TotalSecrets, 300)
+	})
+
+	suite.Run("counts the remaining jobs as skipped once the run exceeds its maximum duration", func() {
+		suite.writeNumberedSecrets(teamAMount, 500)
+		suite.vault.stateDelay = time.Millisecond
+		checkpoints := newFakeCheckpoints()
+
+		result, err := suite.newOrchestrator(2, WithRunBudget(20*time.Millisecond, 0, 0),
+			WithCheckpoints(checkpoints, "vault-sync-test")).StartSync(suite.ctx)
+
+		suite.Require().ErrorIs(err, ErrRunAborted)
+		suite.Equal("run exceeded its maximum duration of 20ms", result.AbortReason)
+		suite.Less(result.TotalSecrets, 500)
+		suite.True(result.Incomplete, "the paths discovery did not get to are not counted")
+		total := result.SuccessfulSyncs + result.FailedSyncs + result.NoOpSecrets + result.SkippedSecrets
+		suite.Equal(result.TotalSecrets, total)
+		suite.Len(checkpoints.paths("vault-sync-test"), result.SuccessfulSyncs, "an aborted run can be resumed")
+	})
+
+	suite.Run("does not abort a run within its budget", func() {
+		suite.writeNumberedSecrets(teamAMount, 20)
+
+		result, err := suite.newOrchestrator(2, WithRunBudget(time.Minute, 1, 1)).StartSync(suite.ctx)
+
+		suite.Require().NoError(err)
+		suite.Empty(result.AbortReason)
+		suite.False(result.Incomplete)
+		suite.Equal(20, result.SuccessfulSyncs)
+	})
+
+	suite.Run("counts every given path the aborted run did not get to as skipped", func() {
+		suite.writeNumberedSecrets(teamAMount, 50)
+		suite.vault.failReplica(replicaA, errors.New("permission denied"))
+		suite.vault.stateDelay = time.Millisecond
+		var paths []pathmatching.SecretPath
+		for i := range 50 {
+			paths = append(paths, pathmatching.SecretPath{Mount: teamAMount, KeyPath: fmt.Sprintf("app/secret-%04d", i)})
+		}
+
+		result, err := suite.newOrchestrator(1, WithRunBudget(0, 2, 0)).SyncLeased(suite.ctx, paths, false)
+
+		suite.Require().ErrorIs(err, ErrRunAborted)
+		suite.False(result.Incomplete)
+		suite.Equal(50, result.TotalSecrets)
+		suite.Equal(50, result.SuccessfulSyncs+result.FailedSyncs+result.NoOpSecrets+result.SkippedSecrets)
+		suite.Len(result.SkippedPaths(), result.SkippedSecrets)
+		suite.Positive(result.SkippedSecrets)
+	})
+}
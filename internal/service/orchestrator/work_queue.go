@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"vault-sync/internal/metrics"
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/internal/tracing"
+)
+
+const (
+	// enqueueBatchSize is how many jobs a coordinator inserts into the work queue with one statement.
+	enqueueBatchSize = 1000
+	// queuedJobRetention is how long finished jobs are kept in the work queue.
+	queuedJobRetention = 7 * 24 * time.Hour
+)
+
+// coordinate enqueues the job of every path sent by stream on the work queue instead of running it. The jobs
+// keep the incremental flag of the run, and the workers read the records of their paths themselves. A full run
+// counts as a full reconciliation once the workers finished its jobs without a failure, which a later run
+// checks.
+func (o *SyncOrchestrator) coordinate(
+	ctx context.Context,
+	startTime time.Time,
+	incremental bool,
+	stream func(ctx context.Context, secretPaths chan<- pathmatching.SecretPath) error,
+) (*SyncResult, error) {
+	o.logger.Info().Bool("incremental", incremental).Msg("Enqueuing sync jobs for the workers")
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	secretPaths := make(chan pathmatching.SecretPath, enqueueBatchSize)
+
+	// discoveryErr is written before secretPaths is closed.
+	var discoveryErr error
+	go func() {
+		discoveryErr = stream(streamCtx, secretPaths)
+		close(secretPaths)
+	}()
+
+	result := &SyncResult{Incremental: incremental}
+	var enqueueErr error
+	batch := make([]*models.QueuedJob, 0, enqueueBatchSize)
+	flush := func() {
+		if len(batch) == 0 || enqueueErr != nil {
+			return
+		}
+		enqueued, err := o.workQueue.EnqueueSyncJobs(ctx, o.workQueueID, batch)
+		if err != nil {
+			enqueueErr = err
+			cancel()
+			return
+		}
+		result.EnqueuedJobs += int(enqueued)
+		batch = batch[:0]
+	}
+	for path := range secretPaths {
+		if enqueueErr != nil {
+			continue
+		}
+		result.TotalSecrets++
+		batch = append(batch, queuedJob(path, incremental))
+		if len(batch) == enqueueBatchSize {
+			flush()
+		}
+	}
+	flush()
+	result.Duration = time.Since(startTime)
+
+	var mountErrs *pathmatching.DiscoveryError
+	if errors.As(discoveryErr, &mountErrs) {
+		result.FailedMounts = mountErrs.FailedMounts()
+		result.DiscoveryFailures = len(result.FailedMounts)
+	}
+
+	if enqueueErr != nil {
+		return result, fmt.Errorf("failed to enqueue sync jobs: %w", enqueueErr)
+	}
+
+	if o.incremental && !incremental && ctx.Err() == nil && discoveryErr == nil {
+		o.queueReconciliation(ctx, startTime, time.Now())
+	}
+	o.pruneQueuedJobs(ctx, startTime)
+
+	o.logger.Info().
+		Int("total_secrets", result.TotalSecrets).
+		Int("enqueued_jobs", result.EnqueuedJobs).
+		Int("discovery_failures", result.DiscoveryFailures).
+		Dur("duration", result.Duration).
+		Msg("Enqueued sync jobs")
+
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("sync interrupted: %w", ctx.Err())
+	}
+
+	if mountErrs != nil && o.strictDiscovery {
+		return result, fmt.Errorf("sync aborted: %w", discoveryErr)
+	}
+
+	return result, nil
+}
+
+// enqueuePaths enqueues the jobs of paths as a run of runKind and records it in the run metrics. The run fails
+// when the jobs cannot be enqueued.
+func (o *SyncOrchestrator) enqueuePaths(
+	ctx context.Context,
+	span trace.Span,
+	runKind string,
+	startTime time.Time,
+	paths []pathmatching.SecretPath,
+) (*SyncResult, error) {
+	jobs := make([]*models.QueuedJob, 0, len(paths))
+	for _, path := range paths {
+		jobs = append(jobs, queuedJob(path, false))
+	}
+
+	result := &SyncResult{TotalSecrets: len(paths)}
+	var err error
+	for batch := range slices.Chunk(jobs, enqueueBatchSize) {
+		var enqueued int64
+		enqueued, err = o.workQueue.EnqueueSyncJobs(ctx, o.workQueueID, batch)
+		if err != nil {
+			err = fmt.Errorf("failed to enqueue the jobs of the %s run: %w", runKind, err)
+			break
+		}
+		result.EnqueuedJobs += int(enqueued)
+	}
+	result.Duration = time.Since(startTime)
+
+	if err == nil {
+		o.logger.Info().
+			Str("run", runKind).
+			Int("enqueued_jobs", result.EnqueuedJobs).
+			Dur("duration", result.Duration).
+			Msg("Enqueued sync jobs")
+	}
+	metrics.ObserveRun(runKind, runStatus(err).String(), result.Duration)
+	tracing.RecordError(span, err)
+	return result, err
+}
+
+// queueReconciliation stores the full reconciliation started at startedAt, whose jobs were enqueued by enqueuedAt,
+// until its jobs finished. It is stored with the instance, so a restarted coordinator still records it. A failure
+// only means the next run is a full one again.
+func (o *SyncOrchestrator) queueReconciliation(ctx context.Context, startedAt, enqueuedAt time.Time) {
+	if err := o.dbClient.QueueFullReconciliation(ctx, o.instanceID, startedAt, enqueuedAt); err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to queue full reconciliation")
+		return
+	}
+	o.logger.Info().Time("started_at", startedAt).
+		Msg("Enqueued full reconciliation, it is recorded once the workers finished its jobs")
+}
+
+// reconciliationQueued reports whether the jobs of the full reconciliation the coordinator enqueued are still
+// queued. Once they finished, the reconciliation is recorded with the start of its run, unless one of its jobs
+// failed: a path the workers could not sync was not reconciled, so the next run is a full one again. When the
+// queue cannot be read, it reports false, so a full run is done as when the last reconciliation cannot be read.
+func (o *SyncOrchestrator) reconciliationQueued(ctx context.Context) bool {
+	if o.workQueue == nil {
+		return false
+	}
+	o.reconciliationMu.Lock()
+	defer o.reconciliationMu.Unlock()
+
+	startedAt, enqueuedAt, err := o.dbClient.GetQueuedFullReconciliation(ctx, o.instanceID)
+	if err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to get the queued full reconciliation")
+		return false
+	}
+	if startedAt.IsZero() {
+		return false
+	}
+
+	openJobs, failedJobs, err := o.workQueue.CountSyncJobs(ctx, startedAt, enqueuedAt)
+	if err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to count the jobs of the queued full reconciliation")
+		return false
+	}
+	if openJobs > 0 {
+		o.logger.Debug().Int64("open_jobs", openJobs).Msg("Full reconciliation still queued")
+		return true
+	}
+
+	completed := failedJobs == 0
+	if err = o.dbClient.FinishQueuedFullReconciliation(ctx, o.instanceID, completed); err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to finish the queued full reconciliation")
+		return false
+	}
+	if completed {
+		o.logger.Info().Time("last_full_run", startedAt).Msg("Recorded full reconciliation")
+	} else {
+		o.logger.Warn().Int64("failed_jobs", failedJobs).
+			Msg("Jobs of the queued full reconciliation failed, running a full reconciliation again")
+	}
+	return false
+}
+
+// pruneQueuedJobs deletes the jobs of the work queue that finished longer than queuedJobRetention ago.
+func (o *SyncOrchestrator) pruneQueuedJobs(ctx context.Context, now time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	cutoff := now.Add(-queuedJobRetention)
+	deletedJobs, err := o.workQueue.PruneSyncJobs(ctx, cutoff)
+	if err != nil {
+		o.logger.Warn().Err(err).Msg("Failed to prune the work queue")
+		return
+	}
+	if deletedJobs > 0 {
+		o.logger.Info().Int64("deleted_jobs", deletedJobs).Time("cutoff", cutoff).Msg("Pruned the work queue")
+	}
+}
+
+func queuedJob(path pathmatching.SecretPath, incremental bool) *models.QueuedJob {
+	return &models.QueuedJob{SecretBackend: path.Mount, SecretPath: path.KeyPath, Incremental: incremental}
+}
@@ -0,0 +1,59 @@
+package pathmatching
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrDiscoveryIncomplete is matched by every DiscoveryError.
+var ErrDiscoveryIncomplete = errors.New("secret discovery is incomplete")
+
+// DiscoveryError reports the mounts that could not be listed. Secrets found in the other mounts are still returned
+// next to it, so callers decide whether a partial listing is good enough.
+type DiscoveryError struct {
+	MountErrors map[string]error
+}
+
+func (e *DiscoveryError) Error() string {
+	failures := make([]string, 0, len(e.MountErrors))
+	for _, mount := range e.FailedMounts() {
+		failures = append(failures, fmt.Sprintf("%s: %v", mount, e.MountErrors[mount]))
+	}
+	return fmt.Sprintf("%s: %d mount(s) failed: %s",
+		ErrDiscoveryIncomplete, len(e.MountErrors), strings.Join(failures, "; "))
+}
+
+func (e *DiscoveryError) Unwrap() []error {
+	errs := []error{ErrDiscoveryIncomplete}
+	for _, mount := range e.FailedMounts() {
+		errs = append(errs, e.MountErrors[mount])
+	}
+	return errs
+}
+
+// FailedMounts returns the mounts that could not be listed, sorted by name.
+func (e *DiscoveryError) FailedMounts() []string {
+	mounts := make([]string, 0, len(e.MountErrors))
+	for mount := range e.MountErrors {
+		mounts = append(mounts, mount)
+	}
+	slices.Sort(mounts)
+	return mounts
+}
+
+func (e *DiscoveryError) add(mount string, err error) {
+	if e.MountErrors == nil {
+		e.MountErrors = make(map[string]error)
+	}
+	e.MountErrors[mount] = err
+}
+
+// errOrNil returns the error if at least one mount failed, and an untyped nil otherwise.
+func (e *DiscoveryError) errOrNil() error {
+	if len(e.MountErrors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,32 @@
+package pathmatching
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoveryError(t *testing.T) {
+	t.Run("reports every failed mount in name order", func(t *testing.T) {
+		permissionErr := errors.New("permission denied")
+		discoveryErr := &DiscoveryError{}
+		discoveryErr.add("team-b", errors.New("connection refused"))
+		discoveryErr.add("team-a", permissionErr)
+
+		err := discoveryErr.errOrNil()
+
+		require.Error(t, err)
+		assert.Equal(t, []string{"team-a", "team-b"}, discoveryErr.FailedMounts())
+		assert.Equal(t,
+			"secret discovery is incomplete: 2 mount(s) failed: team-a: permission denied; team-b: connection refused",
+			err.Error())
+		assert.ErrorIs(t, err, ErrDiscoveryIncomplete)
+		assert.ErrorIs(t, err, permissionErr)
+	})
+
+	t.Run("is nil when every mount was listed", func(t *testing.T) {
+		assert.NoError(t, (&DiscoveryError{}).errOrNil())
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"vault-sync/internal/config"
 	"vault-sync/internal/vault"
@@ -23,11 +25,40 @@ func (sp SecretPath) String() string {
 	return fmt.Sprintf("%s/%s", sp.Mount, sp.KeyPath)
 }
 
+// ParseSecretPath parses a secret given as mount/path. Leading and trailing slashes are ignored.
+func ParseSecretPath(value string) (SecretPath, error) {
+	mount, keyPath, found := strings.Cut(strings.Trim(value, "/"), "/")
+	if !found || keyPath == "" {
+		return SecretPath{}, fmt.Errorf("invalid secret path %q (format should be mount/path)", value)
+	}
+	return SecretPath{Mount: mount, KeyPath: keyPath}, nil
+}
+
+// ParseSecretPaths parses every value with ParseSecretPath and stops at the first invalid one.
+func ParseSecretPaths(values []string) ([]SecretPath, error) {
+	paths := make([]SecretPath, 0, len(values))
+	for _, value := range values {
+		path, err := ParseSecretPath(value)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
 // PathMatcher discovers and filters secrets based on sync rules.
 type PathMatcher interface {
 	// DiscoverSecretsForSync finds all secrets that should be synced based on sync rules.
+	// Mounts that cannot be listed are reported in a *DiscoveryError next to the secrets of the other mounts.
+	// With a strict sync rule, discovery stops at the first mount that cannot be listed.
 	DiscoverSecretsForSync(ctx context.Context) ([]SecretPath, error)
 
+	// StreamSecretsForSync finds the same secrets as DiscoverSecretsForSync, but hands each one to emit
+	// as soon as it is found. emit may be called concurrently; an error returned by emit stops discovery.
+	// Mounts that cannot be listed are reported in a *DiscoveryError once every mount was walked.
+	StreamSecretsForSync(ctx context.Context, emit func(SecretPath) error) error
+
 	// DiscoverFromMounts finds all secrets in specific mount points.
 	DiscoverFromMounts(ctx context.Context, mounts []string) ([]SecretPath, error)
 
@@ -62,21 +93,80 @@ func (pm *VaultPathMatcher) DiscoverSecretsForSync(ctx context.Context) ([]Secre
 		Msg("Starting secret discovery based on sync rules")
 
 	var allSecrets []SecretPath
+	discoveryErr := &DiscoveryError{}
 
 	for _, mount := range pm.syncRule.KvMounts {
 		logger.Debug().Str("mount", mount).Msg("Processing mount")
 
 		secrets, err := pm.discoverSecretsInMount(ctx, mount)
+		if ctx.Err() != nil {
+			return allSecrets, ctx.Err()
+		}
 		if err != nil {
 			logger.Error().Str("mount", mount).Err(err).Msg("Failed to discover secrets in mount")
+			discoveryErr.add(mount, err)
+			if pm.syncRule.Strict {
+				break
+			}
 			continue
 		}
 
 		allSecrets = append(allSecrets, secrets...)
 	}
 
-	logger.Info().Int("discovered_count", len(allSecrets)).Msg("Secret discovery completed")
-	return allSecrets, nil
+	logger.Info().
+		Int("discovered_count", len(allSecrets)).
+		Int("failed_mounts", len(discoveryErr.MountErrors)).
+		Msg("Secret discovery completed")
+	return allSecrets, discoveryErr.errOrNil()
+}
+
+func (pm *VaultPathMatcher) StreamSecretsForSync(ctx context.Context, emit func(SecretPath) error) error {
+	logger := pm.logger.With().Str("action", "stream_secrets_for_sync").Logger()
+	logger.Debug().
+		Strs("kv_mounts", pm.syncRule.KvMounts).
+		Strs("paths_to_replicate", pm.syncRule.PathsToReplicate).
+		Strs("paths_to_ignore", pm.syncRule.PathsToIgnore).
+		Msg("Starting streaming secret discovery based on sync rules")
+
+	var discovered atomic.Int64
+	discoveryErr := &DiscoveryError{}
+
+	for _, mount := range pm.syncRule.KvMounts {
+		logger.Debug().Str("mount", mount).Msg("Processing mount")
+
+		var stopOnce sync.Once
+		var stopErr error
+		err := pm.vaultClient.StreamKeysUnderMount(ctx, mount, pm.mountFilter(mount), func(keyPath string) error {
+			if err := emit(SecretPath{Mount: mount, KeyPath: keyPath}); err != nil {
+				stopOnce.Do(func() { stopErr = err })
+				return err
+			}
+			discovered.Add(1)
+			return nil
+		})
+		// The consumer asked to stop, so the remaining mounts are not walked either.
+		if stopErr != nil {
+			return stopErr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			logger.Error().Str("mount", mount).Err(err).Msg("Failed to discover secrets in mount")
+			discoveryErr.add(mount, err)
+			if pm.syncRule.Strict {
+				break
+			}
+			continue
+		}
+	}
+
+	logger.Info().
+		Int64("discovered_count", discovered.Load()).
+		Int("failed_mounts", len(discoveryErr.MountErrors)).
+		Msg("Secret discovery completed")
+	return discoveryErr.errOrNil()
 }
 
 func (pm *VaultPathMatcher) DiscoverFromMounts(ctx context.Context, mounts []string) ([]SecretPath, error) {
@@ -84,13 +174,18 @@ func (pm *VaultPathMatcher) DiscoverFromMounts(ctx context.Context, mounts []str
 	logger.Debug().Strs("mounts", mounts).Msg("Discovering all secrets from specific mounts")
 
 	var allSecrets []SecretPath
+	discoveryErr := &DiscoveryError{}
 
 	filterFunc := func(_ string, _ bool) bool { return true }
 
 	for _, mount := range mounts {
 		keyPaths, err := pm.vaultClient.GetKeysUnderMount(ctx, mount, filterFunc)
+		if ctx.Err() != nil {
+			return allSecrets, ctx.Err()
+		}
 		if err != nil {
 			logger.Error().Str("mount", mount).Err(err).Msg("Failed to get keys under mount")
+			discoveryErr.add(mount, err)
 			continue
 		}
 
@@ -101,7 +196,7 @@ func (pm *VaultPathMatcher) DiscoverFromMounts(ctx context.Context, mounts []str
 
 	logger.Info().Int("discovered_count", len(allSecrets)).Msg("Mount-based discovery completed")
 
-	return allSecrets, nil
+	return allSecrets, discoveryErr.errOrNil()
 }
 
 func (pm *VaultPathMatcher) ShouldSync(mount, keyPath string) bool {
@@ -115,25 +210,7 @@ func (pm *VaultPathMatcher) discoverSecretsInMount(
 		Str("mount", mount).
 		Logger()
 
-	filterFunc := func(keyPath string, isFinalPath bool) bool {
-		if isFinalPath {
-			shouldInclude := pm.ShouldSync(mount, keyPath)
-			logger.Debug().
-				Str("path", keyPath).
-				Bool("should_include", shouldInclude).
-				Msg("Final path evaluation")
-			return shouldInclude
-		}
-		shouldTraverse := pm.shouldTraverseIntoPath(keyPath)
-		logger.Debug().
-			Str("path", keyPath).
-			Bool("should_traverse", shouldTraverse).
-			Msg("Traversal path evaluation")
-		return shouldTraverse
-	}
-
-	keyPaths, err := pm.vaultClient.GetKeysUnderMount(ctx, mount, filterFunc)
-
+	keyPaths, err := pm.vaultClient.GetKeysUnderMount(ctx, mount, pm.mountFilter(mount))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get keys under mount %s: %w", mount, err)
 	}
@@ -154,6 +231,29 @@ func (pm *VaultPathMatcher) discoverSecretsInMount(
 	return secrets, nil
 }
 
+// mountFilter returns the callback used while walking a mount: folders are only entered when they can lead to
+// a path to replicate, and keys are kept when they should be synced.
+func (pm *VaultPathMatcher) mountFilter(mount string) func(keyPath string, isFinalPath bool) bool {
+	logger := pm.logger.With().Str("mount", mount).Logger()
+
+	return func(keyPath string, isFinalPath bool) bool {
+		if isFinalPath {
+			shouldInclude := pm.ShouldSync(mount, keyPath)
+			logger.Debug().
+				Str("path", keyPath).
+				Bool("should_include", shouldInclude).
+				Msg("Final path evaluation")
+			return shouldInclude
+		}
+		shouldTraverse := pm.shouldTraverseIntoPath(keyPath)
+		logger.Debug().
+			Str("path", keyPath).
+			Bool("should_traverse", shouldTraverse).
+			Msg("Traversal path evaluation")
+		return shouldTraverse
+	}
+}
+
 func (pm *VaultPathMatcher) shouldTraverseIntoPath(keyPath string) bool {
 	// First check if this path branch would be completely ignored
 	if pm.isPathBranchCompletelyIgnored(keyPath) {
@@ -7,6 +7,8 @@ import (
 	"vault-sync/internal/vault"
 	"vault-sync/testutil"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -787,3 +789,20 @@ func (suite *PathMatcherTestSuite) containsSecret(secrets []SecretPath, mount, k
 	expected := SecretPath{Mount: mount, KeyPath: keyPath}
 	suite.Contains(secrets, expected, "should contain secret %s/%s", mount, keyPath)
 }
+
+func TestParseSecretPath(t *testing.T) {
+	path, err := ParseSecretPath("/production/app/database/")
+	require.NoError(t, err)
+	assert.Equal(t, SecretPath{Mount: "production", KeyPath: "app/database"}, path)
+
+	for _, value := range []string{"", "production", "production/"} {
+		_, err = ParseSecretPath(value)
+		require.Error(t, err, value)
+	}
+
+	paths, err := ParseSecretPaths([]string{"a/b", "c/d/e"})
+	require.NoError(t, err)
+	assert.Equal(t, []SecretPath{{Mount: "a", KeyPath: "b"}, {Mount: "c", KeyPath: "d/e"}}, paths)
+	_, err = ParseSecretPaths([]string{"a/b", "c"})
+	require.EqualError(t, err, `invalid secret path "c" (format should be mount/path)`)
+}
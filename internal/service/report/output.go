@@ -0,0 +1,255 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format is the output format of Write.
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatJUnit    Format = "junit"
+	FormatMarkdown Format = "markdown"
+)
+
+// markdownMaxPaths bounds the secrets listed by a Markdown report, which is meant to be read by people.
+const markdownMaxPaths = 100
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case FormatJSON, FormatJUnit, FormatMarkdown:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported report format: %s (use 'json', 'junit' or 'markdown')", value)
+	}
+}
+
+// Write writes the report to w in the given format.
+func Write(w io.Writer, format Format, report *Report) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatJUnit:
+		return writeJUnit(w, report)
+	case FormatMarkdown:
+		return writeMarkdown(w, report)
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+// failure returns why the job of a secret failed.
+func (j Job) failure() string {
+	if j.Error != "" {
+		return j.Error
+	}
+	var failed []string
+	for _, replica := range j.Replicas {
+		if replica.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s: %s", replica.Cluster, replica.Error))
+		}
+	}
+	return strings.Join(failed, "; ")
+}
+
+// details returns one line per replica of the job of a secret.
+func (j Job) details() string {
+	lines := make([]string, 0, len(j.Replicas))
+	for _, replica := range j.Replicas {
+		line := fmt.Sprintf("%s: %s", replica.Cluster, replica.Status)
+		if replica.Error != "" {
+			line += ": " + replica.Error
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// add appends a test case to the suite and counts its outcome.
+func (s *junitTestSuite) add(testCase junitTestCase) {
+	s.Cases = append(s.Cases, testCase)
+	s.Tests++
+	switch {
+	case testCase.Failure != nil:
+		s.Failures++
+	case testCase.Error != nil:
+		s.Errors++
+	case testCase.Skipped != nil:
+		s.Skipped++
+	}
+}
+
+// writeJUnit writes a test suite per mount with a test case per secret, and a "run" suite that fails when the
+// run failed, was aborted or could not list a mount.
+func writeJUnit(w io.Writer, report *Report) error {
+	run := junitTestSuite{Name: "run"}
+	runCase := junitTestCase{Name: "sync", ClassName: "run"}
+	switch {
+	case report.AbortReason != "":
+		runCase.Error = &junitMessage{Message: "run aborted: " + report.AbortReason}
+	case report.Error != "":
+		runCase.Error = &junitMessage{Message: report.Error}
+	}
+	run.add(runCase)
+	for _, mount := range report.FailedMounts {
+		run.add(junitTestCase{
+			Name:      "list " + mount,
+			ClassName: "run",
+			Failure:   &junitMessage{Message: "mount could not be listed, its secrets were not synced"},
+		})
+	}
+
+	suites := []junitTestSuite{run}
+	for _, job := range report.Jobs {
+		if suites[len(suites)-1].Name != job.Mount {
+			suites = append(suites, junitTestSuite{Name: job.Mount})
+		}
+		testCase := junitTestCase{Name: job.Path, ClassName: job.Mount}
+		switch job.Outcome {
+		case OutcomeFailed:
+			testCase.Failure = &junitMessage{Message: job.failure(), Text: job.details()}
+		case OutcomeSkipped:
+			testCase.Skipped = &junitMessage{Message: "not synced, the run was interrupted or aborted"}
+		}
+		suites[len(suites)-1].add(testCase)
+	}
+
+	testSuites := junitTestSuites{
+		Name:   "vault-sync",
+		Time:   fmt.Sprintf("%.3f", report.DurationSeconds),
+		Suites: suites,
+	}
+	for _, suite := range suites {
+		testSuites.Tests += suite.Tests
+		testSuites.Failures += suite.Failures
+		testSuites.Errors += suite.Errors
+		testSuites.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(testSuites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeMarkdown writes a summary of the run: its counters, the failed and skipped secrets and the replicas.
+func writeMarkdown(w io.Writer, report *Report) error {
+	var b strings.Builder
+	duration := time.Duration(report.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+
+	b.WriteString("# Sync report\n\n")
+	fmt.Fprintf(&b, "**Status:** %s (exit code %d)\n\n", report.Status, report.ExitCode)
+	if report.AbortReason != "" {
+		fmt.Fprintf(&b, "**Aborted:** %s\n\n", markdownCell(report.AbortReason))
+	} else if report.Error != "" {
+		fmt.Fprintf(&b, "**Error:** %s\n\n", markdownCell(report.Error))
+	}
+
+	b.WriteString("| Secrets | Synced | Unchanged | Failed | Skipped | Duration |\n")
+	b.WriteString("| ---: | ---: | ---: | ---: | ---: | ---: |\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %s |\n", report.TotalSecrets, report.SuccessfulSyncs,
+		report.NoOpSecrets, report.FailedSyncs, report.SkippedSecrets, duration)
+	if report.Incomplete {
+		b.WriteString("\nThe run stopped before it discovered every secret; the secrets it never got to are not counted.\n")
+	}
+	if report.ResumedPaths > 0 {
+		fmt.Fprintf(&b, "\nResumed an interrupted run, %d secrets were processed before.\n", report.ResumedPaths)
+	}
+
+	if len(report.FailedMounts) > 0 {
+		b.WriteString("\n## Mounts that could not be listed\n\n")
+		for _, mount := range report.FailedMounts {
+			fmt.Fprintf(&b, "- `%s`\n", mount)
+		}
+	}
+
+	writeMarkdownJobs(&b, report.Jobs, OutcomeFailed, "Failed secrets")
+	writeMarkdownJobs(&b, report.Jobs, OutcomeSkipped, "Skipped secrets")
+
+	if len(report.Replicas) > 0 {
+		b.WriteString("\n## Replicas\n\n")
+		b.WriteString("| Cluster | Enqueued | Completed | Failed | Skipped | Max backlog |\n")
+		b.WriteString("| --- | ---: | ---: | ---: | ---: | ---: |\n")
+		for _, replica := range report.Replicas {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d |\n", replica.Cluster, replica.Enqueued,
+				replica.Completed, replica.Failed, replica.Skipped, replica.MaxBacklog)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownJobs writes a table of the secrets with the given outcome, listing at most markdownMaxPaths.
+func writeMarkdownJobs(b *strings.Builder, jobs []Job, outcome, title string) {
+	var listed, total int
+	for _, job := range jobs {
+		if job.Outcome != outcome {
+			continue
+		}
+		total++
+		if listed == markdownMaxPaths {
+			continue
+		}
+		if listed == 0 {
+			fmt.Fprintf(b, "\n## %s\n\n| Secret | Error |\n| --- | --- |\n", title)
+		}
+		fmt.Fprintf(b, "| `%s/%s` | %s |\n", job.Mount, job.Path, markdownCell(job.failure()))
+		listed++
+	}
+	if total > listed {
+		fmt.Fprintf(b, "\nand %d more.\n", total-listed)
+	}
+}
+
+// markdownCell makes text safe to use in a table cell.
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(text)
+}
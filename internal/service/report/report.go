@@ -0,0 +1,219 @@
+// Package report decides the outcome of a one-time sync and writes its result for CI systems and monitoring.
+package report
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+)
+
+// Status is the outcome of a run.
+type Status string
+
+const (
+	// StatusSuccess is a run in which every secret was synced or unchanged.
+	StatusSuccess Status = "success"
+	// StatusPartialFailure is a run that finished with some failed secrets or mounts that could not be listed.
+	StatusPartialFailure Status = "partial_failure"
+	// StatusFailure is a run that failed, was interrupted, or in which no secret was synced.
+	StatusFailure Status = "failure"
+	// StatusAborted is a run stopped by a safeguard: its run budget or strict discovery.
+	StatusAborted Status = "aborted"
+)
+
+// Exit codes of a one-time sync, one per Status. ExitUsage is not the outcome of a run: the command line was
+// invalid and nothing ran. It is EX_USAGE of sysexits.h, outside the range of the outcomes.
+const (
+	ExitSuccess        = 0
+	ExitPartialFailure = 1
+	ExitFailure        = 2
+	ExitAborted        = 3
+	ExitUsage          = 64
+)
+
+// ExitCode returns the exit code of a run with status s.
+func (s Status) ExitCode() int {
+	switch s {
+	case StatusSuccess:
+		return ExitSuccess
+	case StatusPartialFailure:
+		return ExitPartialFailure
+	case StatusAborted:
+		return ExitAborted
+	default:
+		return ExitFailure
+	}
+}
+
+// StatusOf returns the outcome of a run from its result and error. result may be nil when the run failed before
+// it started.
+func StatusOf(result *orchestrator.SyncResult, runErr error) Status {
+	var discoveryErr *pathmatching.DiscoveryError
+	switch {
+	case errors.Is(runErr, orchestrator.ErrRunAborted) || errors.As(runErr, &discoveryErr):
+		return StatusAborted
+	case runErr != nil || result == nil:
+		return StatusFailure
+	case result.FailedSyncs > 0 && result.SuccessfulSyncs+result.NoOpSecrets == 0:
+		return StatusFailure
+	case result.DiscoveryFailures > 0 && result.TotalSecrets == 0:
+		return StatusFailure
+	case result.FailedSyncs > 0 || result.DiscoveryFailures > 0:
+		return StatusPartialFailure
+	default:
+		return StatusSuccess
+	}
+}
+
+// Outcomes of the job of a secret.
+const (
+	OutcomeSynced    = "synced"
+	OutcomeUnchanged = "unchanged"
+	OutcomeFailed    = "failed"
+	OutcomeSkipped   = "skipped"
+)
+
+// Report is the result of a run as written by Write. Jobs lists the secrets of SyncResult.JobResults, sorted by
+// mount and path. SkippedSecrets only counts the discovered secrets; Incomplete tells that the run stopped before
+// it discovered every secret, so the secrets it never got to are in no counter.
+type Report struct {
+	Status            Status           `json:"status"`
+	ExitCode          int              `json:"exit_code"`
+	Error             string           `json:"error,omitempty"`
+	AbortReason       string           `json:"abort_reason,omitempty"`
+	Incomplete        bool             `json:"incomplete,omitempty"`
+	Incremental       bool             `json:"incremental"`
+	DurationSeconds   float64          `json:"duration_seconds"`
+	TotalSecrets      int              `json:"total_secrets"`
+	SuccessfulSyncs   int              `json:"successful_syncs"`
+	FailedSyncs       int              `json:"failed_syncs"`
+	SkippedSecrets    int              `json:"skipped_secrets"`
+	NoOpSecrets       int              `json:"noop_secrets"`
+	ResumedPaths      int              `json:"resumed_paths,omitempty"`
+	EnqueuedJobs      int              `json:"enqueued_jobs,omitempty"`
+	DiscoveryFailures int              `json:"discovery_failures"`
+	FailedMounts      []string         `json:"failed_mounts,omitempty"`
+	VaultRequests     map[string]int64 `json:"vault_requests,omitempty"`
+	Replicas          []Replica        `json:"replicas,omitempty"`
+	Jobs              []Job            `json:"jobs"`
+}
+
+// Replica is the work a replica cluster received and processed during the run.
+type Replica struct {
+	Cluster    string `json:"cluster"`
+	Enqueued   int    `json:"enqueued"`
+	Completed  int    `json:"completed"`
+	Failed     int    `json:"failed"`
+	Skipped    int    `json:"skipped"`
+	MaxBacklog int    `json:"max_backlog"`
+}
+
+// Job is the outcome of the job of one secret.
+type Job struct {
+	Mount    string          `json:"mount"`
+	Path     string          `json:"path"`
+	Outcome  string          `json:"outcome"`
+	Error    string          `json:"error,omitempty"`
+	Replicas []ReplicaStatus `json:"replicas,omitempty"`
+}
+
+// ReplicaStatus is the outcome of the job of a secret on one replica.
+type ReplicaStatus struct {
+	Cluster            string `json:"cluster"`
+	Status             string `json:"status"`
+	SourceVersion      int64  `json:"source_version,omitempty"`
+	DestinationVersion int64  `json:"destination_version,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// New builds the report of a run from its result and error. result may be nil when the run failed before it
+// started.
+func New(result *orchestrator.SyncResult, runErr error) *Report {
+	status := StatusOf(result, runErr)
+	report := &Report{Status: status, ExitCode: status.ExitCode(), Jobs: []Job{}}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	if result == nil {
+		return report
+	}
+
+	report.AbortReason = result.AbortReason
+	report.Incomplete = result.Incomplete
+	report.Incremental = result.Incremental
+	report.DurationSeconds = result.Duration.Seconds()
+	report.TotalSecrets = result.TotalSecrets
+	report.SuccessfulSyncs = result.SuccessfulSyncs
+	report.FailedSyncs = result.FailedSyncs
+	report.SkippedSecrets = result.SkippedSecrets
+	report.NoOpSecrets = result.NoOpSecrets
+	report.ResumedPaths = result.ResumedPaths
+	report.EnqueuedJobs = result.EnqueuedJobs
+	report.DiscoveryFailures = result.DiscoveryFailures
+	report.FailedMounts = result.FailedMounts
+	report.VaultRequests = result.VaultRequests
+
+	for _, progress := range result.ReplicaProgress {
+		report.Replicas = append(report.Replicas, Replica{
+			Cluster:    progress.ClusterName,
+			Enqueued:   progress.Enqueued,
+			Completed:  progress.Completed,
+			Failed:     progress.Failed,
+			Skipped:    progress.Skipped,
+			MaxBacklog: progress.MaxBacklog,
+		})
+	}
+	for _, jobResult := range result.JobResults {
+		report.Jobs = append(report.Jobs, newJob(jobResult))
+	}
+	slices.SortFunc(report.Jobs, func(a, b Job) int {
+		if c := strings.Compare(a.Mount, b.Mount); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return report
+}
+
+func newJob(jobResult *job.SyncJobResult) Job {
+	reported := Job{Mount: jobResult.Mount, Path: jobResult.KeyPath, Outcome: OutcomeUnchanged}
+	if jobResult.Error != nil {
+		reported.Error = jobResult.Error.Error()
+	}
+
+	// A job that failed before reaching any replica has no replica statuses, as counted by the orchestrator.
+	failed := jobResult.Error != nil && len(jobResult.Status) == 0
+	for _, status := range jobResult.Status {
+		replica := ReplicaStatus{
+			Cluster:            status.ClusterName,
+			Status:             string(status.Status),
+			SourceVersion:      status.SourceVersion,
+			DestinationVersion: status.DestinationVersion,
+		}
+		if status.Error != nil {
+			replica.Error = status.Error.Error()
+		}
+		reported.Replicas = append(reported.Replicas, replica)
+
+		//nolint: exhaustive
+		switch status.Status {
+		case job.SyncJobStatusFailed, job.SyncJobStatusErrorDeleting:
+			failed = true
+		case job.SyncJobStatusUpdated, job.SyncJobStatusDeleted:
+			reported.Outcome = OutcomeSynced
+		}
+	}
+
+	switch {
+	case errors.Is(jobResult.Error, context.Canceled) || errors.Is(jobResult.Error, context.DeadlineExceeded):
+		reported.Outcome = OutcomeSkipped
+	case failed:
+		reported.Outcome = OutcomeFailed
+	}
+	return reported
+}
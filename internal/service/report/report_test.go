@@ -0,0 +1,234 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+)
+
+type ReportTestSuite struct {
+	suite.Suite
+	result *orchestrator.SyncResult
+}
+
+func TestReportSuite(t *testing.T) {
+	suite.Run(t, new(ReportTestSuite))
+}
+
+func (suite *ReportTestSuite) SetupSubTest() {
+	suite.result = &orchestrator.SyncResult{
+		TotalSecrets:      4,
+		SuccessfulSyncs:   1,
+		FailedSyncs:       1,
+		SkippedSecrets:    1,
+		NoOpSecrets:       1,
+		DiscoveryFailures: 1,
+		FailedMounts:      []string{"team-c"},
+		Duration:          1500 * time.Millisecond,
+		VaultRequests:     map[string]int64{"main": 12},
+		ReplicaProgress: []job.ReplicaProgress{
+			{ClusterName: "replica-a", Enqueued: 3, Completed: 2, Failed: 1, MaxBacklog: 2},
+		},
+		JobResults: []*job.SyncJobResult{
+			{Mount: "team-b", KeyPath: "app/api", Error: context.Canceled},
+			{Mount: "team-a", KeyPath: "app/db", Status: []*job.ClusterSyncStatus{
+				{ClusterName: "replica-a", Status: job.SyncJobStatusUpdated, SourceVersion: 3, DestinationVersion: 1},
+			}},
+			{Mount: "team-a", KeyPath: "app/cache", Status: []*job.ClusterSyncStatus{
+				{ClusterName: "replica-a", Status: job.SyncJobStatusUnModified, SourceVersion: 2, DestinationVersion: 2},
+			}},
+			{Mount: "team-a", KeyPath: "app/queue", Error: errors.New("replica-a: write failed"),
+				Status: []*job.ClusterSyncStatus{
+					{ClusterName: "replica-a", Status: job.SyncJobStatusFailed, SourceVersion: 4,
+						Error: errors.New("write failed | permission denied")},
+				}},
+		},
+	}
+}
+
+func (suite *ReportTestSuite) TestStatusOf() {
+	suite.Run("succeeds when every secret was synced or unchanged", func() {
+		result := &orchestrator.SyncResult{TotalSecrets: 2, SuccessfulSyncs: 1, NoOpSecrets: 1}
+
+		status := StatusOf(result, nil)
+
+		suite.Equal(StatusSuccess, status)
+		suite.Equal(ExitSuccess, status.ExitCode())
+	})
+
+	suite.Run("partially fails when some secrets failed or a mount could not be listed", func() {
+		suite.Equal(StatusPartialFailure, StatusOf(suite.result, nil))
+		suite.Equal(StatusPartialFailure, StatusOf(&orchestrator.SyncResult{
+			TotalSecrets: 3, NoOpSecrets: 3, DiscoveryFailures: 1,
+		}, nil))
+		suite.Equal(ExitPartialFailure, StatusPartialFailure.ExitCode())
+	})
+
+	suite.Run("fails when no secret was synced", func() {
+		suite.Equal(StatusFailure, StatusOf(&orchestrator.SyncResult{TotalSecrets: 2, FailedSyncs: 2}, nil))
+		suite.Equal(StatusFailure, StatusOf(&orchestrator.SyncResult{DiscoveryFailures: 2}, nil))
+	})
+
+	suite.Run("fails when the run failed or was interrupted", func() {
+		suite.Equal(StatusFailure, StatusOf(nil, errors.New("failed to load the sync state")))
+		suite.Equal(StatusFailure, StatusOf(suite.result, fmt.Errorf("sync interrupted: %w", context.Canceled)))
+		suite.Equal(ExitFailure, StatusFailure.ExitCode())
+	})
+
+	suite.Run("is aborted by the run budget or strict discovery", func() {
+		budgetErr := fmt.Errorf("sync %w: 5 jobs failed", orchestrator.ErrRunAborted)
+		discoveryErr := fmt.Errorf("sync aborted: %w", &pathmatching.DiscoveryError{
+			MountErrors: map[string]error{"team-c": errors.New("permission denied")},
+		})
+
+		suite.Equal(StatusAborted, StatusOf(suite.result, budgetErr))
+		suite.Equal(StatusAborted, StatusOf(nil, discoveryErr))
+		suite.Equal(ExitAborted, StatusAborted.ExitCode())
+	})
+}
+
+func (suite *ReportTestSuite) TestNew() {
+	suite.Run("reports the outcome of every job sorted by path", func() {
+		report := New(suite.result, nil)
+
+		suite.Equal(StatusPartialFailure, report.Status)
+		suite.Equal(ExitPartialFailure, report.ExitCode)
+		suite.InDelta(1.5, report.DurationSeconds, 0)
+		suite.Require().Len(report.Jobs, 4)
+		outcomes := make(map[string]string)
+		for _, reported := range report.Jobs {
+			outcomes[reported.Mount+"/"+reported.Path] = reported.Outcome
+		}
+		suite.Equal(map[string]string{
+			"team-a/app/cache": OutcomeUnchanged,
+			"team-a/app/db":    OutcomeSynced,
+			"team-a/app/queue": OutcomeFailed,
+			"team-b/app/api":   OutcomeSkipped,
+		}, outcomes)
+		suite.Equal("app/cache", report.Jobs[0].Path)
+		suite.Equal("team-b", report.Jobs[3].Mount)
+		suite.Equal("write failed | permission denied", report.Jobs[2].Replicas[0].Error)
+	})
+
+	suite.Run("reports a run that stopped before discovering every secret", func() {
+		result := &orchestrator.SyncResult{
+			TotalSecrets: 3, SkippedSecrets: 2, NoOpSecrets: 1, AbortReason: "5 jobs failed", Incomplete: true,
+		}
+
+		report := New(result, fmt.Errorf("sync %w: 5 jobs failed", orchestrator.ErrRunAborted))
+
+		suite.Equal(StatusAborted, report.Status)
+		suite.True(report.Incomplete)
+	})
+
+	suite.Run("reports a run that failed before it started", func() {
+		report := New(nil, errors.New("failed to load the sync state"))
+
+		suite.Equal(StatusFailure, report.Status)
+		suite.Equal("failed to load the sync state", report.Error)
+		suite.Empty(report.Jobs)
+	})
+}
+
+func (suite *ReportTestSuite) TestWrite() {
+	suite.Run("rejects an unsupported format", func() {
+		_, err := ParseFormat("yaml")
+
+		suite.ErrorContains(err, "unsupported report format: yaml")
+	})
+
+	suite.Run("writes json", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(Write(&buf, FormatJSON, New(suite.result, nil)))
+
+		var decoded map[string]any
+		suite.Require().NoError(json.Unmarshal(buf.Bytes(), &decoded))
+		suite.Equal("partial_failure", decoded["status"])
+		suite.InDelta(1, decoded["exit_code"], 0)
+		suite.Equal([]any{"team-c"}, decoded["failed_mounts"])
+		jobs, ok := decoded["jobs"].([]any)
+		suite.Require().True(ok)
+		suite.Len(jobs, 4)
+		suite.Equal("context canceled", jobs[3].(map[string]any)["error"])
+	})
+
+	suite.Run("writes junit with a test case per secret", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(Write(&buf, FormatJUnit, New(suite.result, nil)))
+
+		var decoded junitTestSuites
+		suite.Require().NoError(xml.Unmarshal(buf.Bytes(), &decoded))
+		suite.Equal(6, decoded.Tests)
+		suite.Equal(2, decoded.Failures)
+		suite.Equal(1, decoded.Skipped)
+		suite.Equal("1.500", decoded.Time)
+		suite.Require().Len(decoded.Suites, 3)
+		suite.Equal([]string{"run", "team-a", "team-b"},
+			[]string{decoded.Suites[0].Name, decoded.Suites[1].Name, decoded.Suites[2].Name})
+		suite.Equal("list team-c", decoded.Suites[0].Cases[1].Name)
+		failed := decoded.Suites[1].Cases[2]
+		suite.Equal("app/queue", failed.Name)
+		suite.Require().NotNil(failed.Failure)
+		suite.Equal("replica-a: write failed", failed.Failure.Message)
+		suite.Contains(failed.Failure.Text, "replica-a: failed: write failed")
+	})
+
+	suite.Run("writes junit with an error for an aborted run", func() {
+		suite.result.AbortReason = "5 jobs failed, the error budget allows 5"
+		var buf bytes.Buffer
+
+		err := fmt.Errorf("sync %w: %s", orchestrator.ErrRunAborted, suite.result.AbortReason)
+		suite.Require().NoError(Write(&buf, FormatJUnit, New(suite.result, err)))
+
+		var decoded junitTestSuites
+		suite.Require().NoError(xml.Unmarshal(buf.Bytes(), &decoded))
+		suite.Equal(1, decoded.Errors)
+		suite.Require().NotNil(decoded.Suites[0].Cases[0].Error)
+		suite.Equal("run aborted: 5 jobs failed, the error budget allows 5",
+			decoded.Suites[0].Cases[0].Error.Message)
+	})
+
+	suite.Run("writes a markdown summary", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(Write(&buf, FormatMarkdown, New(suite.result, nil)))
+
+		summary := buf.String()
+		suite.Contains(summary, "**Status:** partial_failure (exit code 1)")
+		suite.Contains(summary, "| 4 | 1 | 1 | 1 | 1 | 1.5s |")
+		suite.Contains(summary, "- `team-c`")
+		suite.Contains(summary, "| `team-a/app/queue` | replica-a: write failed |")
+		suite.Contains(summary, "| `team-b/app/api` | context canceled |")
+		suite.Contains(summary, "| replica-a | 3 | 2 | 1 | 0 | 2 |")
+		suite.NotContains(summary, "app/cache")
+	})
+
+	suite.Run("lists a bounded number of secrets in the markdown summary", func() {
+		suite.result.JobResults = nil
+		for i := range markdownMaxPaths + 5 {
+			suite.result.JobResults = append(suite.result.JobResults, &job.SyncJobResult{
+				Mount: "team-a", KeyPath: fmt.Sprintf("app/secret-%04d", i), Error: errors.New("read failed"),
+			})
+		}
+		var buf bytes.Buffer
+
+		suite.Require().NoError(Write(&buf, FormatMarkdown, New(suite.result, nil)))
+
+		suite.Equal(markdownMaxPaths, strings.Count(buf.String(), "read failed"))
+		suite.Contains(buf.String(), "and 5 more.")
+	})
+}
@@ -0,0 +1,255 @@
+// Package syncrequests works through the sync_requests table, the queue external systems insert into to have a
+// secret synced right away, e.g. after a credential rotation. The daemon wakes up on the notification of every
+// inserted request, polls as the backstop for notifications missed while it was not listening, and writes the
+// outcome of every request back to its row.
+package syncrequests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/metrics"
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/pkg/log"
+)
+
+const (
+	// batchSize is how many requests are claimed, and synced in one run, at a time.
+	batchSize = 100
+	// finishTimeout bounds writing the outcome of a batch once the daemon is stopping.
+	finishTimeout = 10 * time.Second
+)
+
+// Store is the queue of sync requests. It is implemented by the Postgres repository.
+type Store interface {
+	ClaimSyncRequests(
+		ctx context.Context, claimedBy string, limit int, staleAfter time.Duration,
+	) ([]*models.SyncRequest, error)
+	FinishSyncRequests(ctx context.Context, claimedBy string, requests []*models.SyncRequest) error
+	ListenForSyncRequests(ctx context.Context, notify func()) error
+}
+
+// Runner syncs the given paths and returns the result of the run. It is implemented by the daemon, which syncs
+// them next to the scheduled runs without syncing a path in two runs at once.
+type Runner interface {
+	SyncRequested(ctx context.Context, paths []pathmatching.SecretPath) (*orchestrator.SyncResult, error)
+}
+
+// Matcher decides whether a path is synced. It is implemented by the path matcher.
+type Matcher interface {
+	ShouldSync(mount, keyPath string) bool
+}
+
+// Processor claims the pending sync requests, syncs their paths and writes the outcome back.
+type Processor struct {
+	store        Store
+	runner       Runner
+	matcher      Matcher
+	instanceID   string
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	wake         chan struct{}
+	now          func() time.Time
+	logger       zerolog.Logger
+}
+
+// NewProcessor returns a processor that claims requests as instanceID. Requests are looked for every
+// pollInterval besides on notification, and requests claimed longer than claimTimeout ago, by an instance that
+// died before it finished them, are claimed again.
+func NewProcessor(
+	store Store,
+	runner Runner,
+	matcher Matcher,
+	instanceID string,
+	pollInterval, claimTimeout time.Duration,
+) *Processor {
+	return &Processor{
+		store:        store,
+		runner:       runner,
+		matcher:      matcher,
+		instanceID:   instanceID,
+		pollInterval: pollInterval,
+		claimTimeout: claimTimeout,
+		wake:         make(chan struct{}, 1),
+		now:          time.Now,
+		logger:       log.Logger.With().Str("component", "sync_requests").Logger(),
+	}
+}
+
+// Run processes the requests until ctx is done, starting with the ones inserted while the daemon was down.
+func (p *Processor) Run(ctx context.Context) {
+	p.logger.Info().Dur("poll_interval", p.pollInterval).Msg("Processing sync requests")
+	listenDone := make(chan struct{})
+	go func() {
+		defer close(listenDone)
+		p.listen(ctx)
+	}()
+	defer func() { <-listenDone }()
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		p.processPending(ctx)
+		select {
+		case <-ctx.Done():
+			p.logger.Info().Msg("Stopped processing sync requests")
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// listen wakes the processor on every notification. A lost connection is opened again after the poll interval;
+// requests inserted in the meantime are picked up by the poll.
+func (p *Processor) listen(ctx context.Context) {
+	for {
+		err := p.store.ListenForSyncRequests(ctx, p.notify)
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Warn().Err(err).Dur("retry_in", p.pollInterval).
+			Msg("Lost sync request notifications, relying on polling until listening again")
+
+		timer := time.NewTimer(p.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Processor) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// processPending processes batches until no request is left to claim.
+func (p *Processor) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		requests, err := p.store.ClaimSyncRequests(ctx, p.instanceID, batchSize, p.claimTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.Error().Err(err).Msg("Failed to claim sync requests")
+			}
+			return
+		}
+		if len(requests) == 0 {
+			return
+		}
+		p.process(ctx, requests)
+		if len(requests) < batchSize {
+			return
+		}
+	}
+}
+
+// process syncs the paths of the requests in one run and writes their outcome back. Requests interrupted by
+// the shutdown of the daemon are put back as pending for the next daemon to claim.
+func (p *Processor) process(ctx context.Context, requests []*models.SyncRequest) {
+	var paths []pathmatching.SecretPath
+	accepted := make(map[*models.SyncRequest]string)
+	seen := make(map[string]bool)
+	for _, request := range requests {
+		path, err := p.requestedPath(request)
+		if err != nil {
+			p.finish(request, models.SyncRequestRejected, err.Error())
+			continue
+		}
+		accepted[request] = path.String()
+		if !seen[path.String()] {
+			seen[path.String()] = true
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) > 0 {
+		p.logger.Info().Int("requests", len(accepted)).Int("paths", len(paths)).Msg("Syncing requested paths")
+		result, err := p.runner.SyncRequested(ctx, paths)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			for request := range accepted {
+				release(request)
+			}
+		case err != nil && !errors.Is(err, orchestrator.ErrRunAborted):
+			for request := range accepted {
+				p.finish(request, models.SyncRequestFailed, err.Error())
+			}
+		default:
+			// The requests a run aborted by its budget skipped are claimed again.
+			failures := result.PathFailures()
+			skipped := result.SkippedPaths()
+			for request, path := range accepted {
+				if skipped[path] {
+					release(request)
+					continue
+				}
+				if failure, failed := failures[path]; failed {
+					p.finish(request, models.SyncRequestFailed, failure)
+					continue
+				}
+				p.finish(request, models.SyncRequestCompleted, "")
+			}
+		}
+	}
+
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer cancel()
+	if err := p.store.FinishSyncRequests(finishCtx, p.instanceID, requests); err != nil {
+		p.logger.Error().Err(err).Int("requests", len(requests)).
+			Msg("Failed to write the outcome of sync requests; they are claimed again after the claim timeout")
+		return
+	}
+	for _, request := range requests {
+		if request.Status != models.SyncRequestPending {
+			metrics.ObserveSyncRequest(request.Status.String())
+		}
+	}
+	p.logger.Info().Int("requests", len(requests)).Msg("Processed sync requests")
+}
+
+// requestedPath returns the path of the request, or why it is rejected.
+func (p *Processor) requestedPath(request *models.SyncRequest) (pathmatching.SecretPath, error) {
+	mount := strings.Trim(request.SecretBackend, "/")
+	keyPath := strings.Trim(request.SecretPath, "/")
+	if mount == "" || keyPath == "" {
+		return pathmatching.SecretPath{}, errors.New("secret_backend and secret_path are required")
+	}
+	if !p.matcher.ShouldSync(mount, keyPath) {
+		return pathmatching.SecretPath{}, fmt.Errorf("%s/%s is not covered by the sync rule", mount, keyPath)
+	}
+	return pathmatching.SecretPath{Mount: mount, KeyPath: keyPath}, nil
+}
+
+func (p *Processor) finish(request *models.SyncRequest, status models.SyncRequestStatus, errorMessage string) {
+	finishedAt := p.now()
+	request.Status = status
+	request.FinishedAt = &finishedAt
+	request.ErrorMessage = nil
+	if errorMessage != "" {
+		request.ErrorMessage = &errorMessage
+	}
+	p.logger.Debug().
+		Int64("request_id", request.ID).
+		Str("status", status.String()).
+		Str("error", errorMessage).
+		Msg("Finished sync request")
+}
+
+// release makes the request pending again.
+func release(request *models.SyncRequest) {
+	request.Status = models.SyncRequestPending
+	request.ClaimedBy = nil
+	request.ClaimedAt = nil
+}
@@ -0,0 +1,277 @@
+package syncrequests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+)
+
+// fakeStore hands out the pending requests on claim and keeps the finished ones.
+type fakeStore struct {
+	mu         sync.Mutex
+	pending    []*models.SyncRequest
+	finished   []models.SyncRequest
+	claimedBy  string
+	finishedBy string
+	listening  chan func()
+}
+
+func (s *fakeStore) ClaimSyncRequests(
+	_ context.Context, claimedBy string, limit int, _ time.Duration,
+) ([]*models.SyncRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimedBy = claimedBy
+	claimed := s.pending[:min(limit, len(s.pending))]
+	s.pending = s.pending[len(claimed):]
+	for _, request := range claimed {
+		request.Status = models.SyncRequestRunning
+		request.ClaimedBy = &claimedBy
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) FinishSyncRequests(_ context.Context, claimedBy string, requests []*models.SyncRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finishedBy = claimedBy
+	for _, request := range requests {
+		s.finished = append(s.finished, *request)
+	}
+	return nil
+}
+
+func (s *fakeStore) ListenForSyncRequests(ctx context.Context, notify func()) error {
+	if s.listening != nil {
+		s.listening <- notify
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *fakeStore) insert(requests ...*models.SyncRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, requests...)
+}
+
+func (s *fakeStore) finishedRequests() []models.SyncRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.SyncRequest(nil), s.finished...)
+}
+
+type fakeRunner struct {
+	runs   [][]pathmatching.SecretPath
+	result *orchestrator.SyncResult
+	err    error
+}
+
+func (r *fakeRunner) SyncRequested(
+	_ context.Context,
+	paths []pathmatching.SecretPath,
+) (*orchestrator.SyncResult, error) {
+	r.runs = append(r.runs, paths)
+	if r.result == nil {
+		return &orchestrator.SyncResult{}, r.err
+	}
+	return r.result, r.err
+}
+
+// fakeMatcher syncs every path of the production mount except those under legacy/.
+type fakeMatcher struct{}
+
+func (fakeMatcher) ShouldSync(mount, keyPath string) bool {
+	return mount == "production" && !strings.HasPrefix(keyPath, "legacy/")
+}
+
+func newRequest(id int64, backend, path string) *models.SyncRequest {
+	return &models.SyncRequest{ID: id, SecretBackend: backend, SecretPath: path, Status: models.SyncRequestPending}
+}
+
+type ProcessorTestSuite struct {
+	suite.Suite
+	store     *fakeStore
+	runner    *fakeRunner
+	processor *Processor
+}
+
+func TestProcessorSuite(t *testing.T) {
+	suite.Run(t, new(ProcessorTestSuite))
+}
+
+func (suite *ProcessorTestSuite) SetupTest() {
+	suite.store = &fakeStore{}
+	suite.runner = &fakeRunner{}
+	suite.processor = NewProcessor(suite.store, suite.runner, fakeMatcher{}, "instance-a", time.Hour, time.Hour)
+}
+
+func (suite *ProcessorTestSuite) statuses() map[int64]models.SyncRequestStatus {
+	statuses := make(map[int64]models.SyncRequestStatus)
+	for _, request := range suite.store.finishedRequests() {
+		statuses[request.ID] = request.Status
+	}
+	return statuses
+}
+
+func (suite *ProcessorTestSuite) TestSyncsTheRequestedPathsInOneRun() {
+	suite.store.insert(
+		newRequest(1, "production", "app/database"),
+		newRequest(2, "/production/", "/app/cache/"),
+		newRequest(3, "production", "app/database"),
+	)
+
+	suite.processor.processPending(context.Background())
+
+	suite.Equal("instance-a", suite.store.claimedBy)
+	suite.Equal("instance-a", suite.store.finishedBy)
+	suite.Equal([][]pathmatching.SecretPath{{
+		{Mount: "production", KeyPath: "app/database"},
+		{Mount: "production", KeyPath: "app/cache"},
+	}}, suite.runner.runs)
+	suite.Equal(map[int64]models.SyncRequestStatus{
+		1: models.SyncRequestCompleted,
+		2: models.SyncRequestCompleted,
+		3: models.SyncRequestCompleted,
+	}, suite.statuses())
+	for _, request := range suite.store.finishedRequests() {
+		suite.NotNil(request.FinishedAt)
+		suite.Nil(request.ErrorMessage)
+	}
+}
+
+func (suite *ProcessorTestSuite) TestRejectsPathsTheSyncRuleDoesNotCover() {
+	suite.store.insert(
+		newRequest(1, "production", "legacy/ftp"),
+		newRequest(2, "staging", "app/database"),
+		newRequest(3, "production", ""),
+	)
+
+	suite.processor.processPending(context.Background())
+
+	suite.Empty(suite.runner.runs)
+	finished := suite.store.finishedRequests()
+	suite.Require().Len(finished, 3)
+	for _, request := range finished {
+		suite.Equal(models.SyncRequestRejected, request.Status)
+		suite.Require().NotNil(request.ErrorMessage)
+	}
+	suite.Contains(*finished[0].ErrorMessage, "production/legacy/ftp is not covered by the sync rule")
+	suite.Contains(*finished[2].ErrorMessage, "secret_path are required")
+}
+
+func (suite *ProcessorTestSuite) TestRecordsThePathsThatWereNotSynced() {
+	suite.runner.result = &orchestrator.SyncResult{JobResults: []*job.SyncJobResult{
+		{Mount: "production", KeyPath: "app/database", Error: errors.New("replica-1: permission denied")},
+		{Mount: "production", KeyPath: "app/cache", Status: []*job.ClusterSyncStatus{
+			{ClusterName: "replica-1", Status: job.SyncJobStatusUpdated},
+			{ClusterName: "replica-2", Status: job.SyncJobStatusQuarantined},
+		}},
+		{Mount: "production", KeyPath: "app/queue", Status: []*job.ClusterSyncStatus{
+			{ClusterName: "replica-1", Status: job.SyncJobStatusUpdated},
+		}},
+	}}
+	suite.store.insert(
+		newRequest(1, "production", "app/database"),
+		newRequest(2, "production", "app/cache"),
+		newRequest(3, "production", "app/queue"),
+	)
+
+	suite.processor.processPending(context.Background())
+
+	finished := suite.store.finishedRequests()
+	suite.Require().Len(finished, 3)
+	suite.Equal(models.SyncRequestFailed, finished[0].Status)
+	suite.Equal("replica-1: permission denied", *finished[0].ErrorMessage)
+	suite.Equal(models.SyncRequestFailed, finished[1].Status)
+	suite.Equal("not synced, replica replica-2 is quarantined", *finished[1].ErrorMessage)
+	suite.Equal(models.SyncRequestCompleted, finished[2].Status)
+}
+
+func (suite *ProcessorTestSuite) TestFailsTheRequestsOfAFailedRun() {
+	suite.runner.err = errors.New("discovery failed")
+	suite.store.insert(newRequest(1, "production", "app/database"), newRequest(2, "staging", "app"))
+
+	suite.processor.processPending(context.Background())
+
+	suite.Equal(map[int64]models.SyncRequestStatus{
+		1: models.SyncRequestFailed,
+		2: models.SyncRequestRejected,
+	}, suite.statuses())
+}
+
+func (suite *ProcessorTestSuite) TestReleasesTheRequestsAnAbortedRunSkipped() {
+	suite.runner.result = &orchestrator.SyncResult{AbortReason: "3 jobs failed", JobResults: []*job.SyncJobResult{
+		{Mount: "production", KeyPath: "app/database", Error: errors.New("replica-1: permission denied")},
+		{Mount: "production", KeyPath: "app/cache", Error: context.Canceled},
+	}}
+	suite.runner.err = orchestrator.ErrRunAborted
+	suite.store.insert(
+		newRequest(1, "production", "app/database"),
+		newRequest(2, "production", "app/cache"),
+		newRequest(3, "production", "app/queue"),
+	)
+
+	suite.processor.processPending(context.Background())
+
+	suite.Equal(map[int64]models.SyncRequestStatus{
+		1: models.SyncRequestFailed,
+		2: models.SyncRequestPending,
+		3: models.SyncRequestCompleted,
+	}, suite.statuses())
+}
+
+func (suite *ProcessorTestSuite) TestReleasesTheRequestsOfAnInterruptedRun() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.runner.err = context.Canceled
+
+	suite.processor.process(ctx, []*models.SyncRequest{{
+		ID: 1, SecretBackend: "production", SecretPath: "app/database", Status: models.SyncRequestRunning,
+	}})
+
+	finished := suite.store.finishedRequests()
+	suite.Require().Len(finished, 1)
+	suite.Equal(models.SyncRequestPending, finished[0].Status)
+	suite.Nil(finished[0].ClaimedBy)
+	suite.Nil(finished[0].FinishedAt)
+}
+
+func (suite *ProcessorTestSuite) TestClaimsBatchesUntilNoneIsLeft() {
+	for id := range int64(batchSize + 1) {
+		suite.store.insert(newRequest(id, "production", "app/database"))
+	}
+
+	suite.processor.processPending(context.Background())
+
+	suite.Len(suite.runner.runs, 2)
+	suite.Len(suite.store.finishedRequests(), batchSize+1)
+}
+
+func (suite *ProcessorTestSuite) TestRunWakesUpOnNotification() {
+	suite.store.listening = make(chan func(), 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		suite.processor.Run(ctx)
+	}()
+	notify := <-suite.store.listening
+
+	suite.store.insert(newRequest(1, "production", "app/database"))
+	notify()
+
+	suite.Eventually(func() bool { return len(suite.store.finishedRequests()) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,137 @@
+package syncstate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Format is the output format of Write.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case FormatTable, FormatJSON, FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s (use 'table', 'json' or 'csv')", value)
+	}
+}
+
+// entry is a row of a report; fields returns its columns in the order of the header.
+type entry interface {
+	StatusEntry | HistoryEntry
+	fields() []string
+}
+
+//nolint:gochecknoglobals
+var (
+	statusHeader = []string{
+		"MOUNT", "PATH", "CLUSTER", "STATUS", "SOURCE_VERSION", "DESTINATION_VERSION",
+		"LAST_SYNC_ATTEMPT", "LAST_SYNC_SUCCESS", "ATTEMPTS", "NEXT_RETRY_AT", "QUARANTINED_AT", "ERROR_CATEGORY", "ERROR",
+	}
+	historyHeader = []string{
+		"RUN_ID", "OCCURRED_AT", "CLUSTER", "ACTION", "SOURCE_VERSION", "DESTINATION_VERSION", "ERROR",
+	}
+)
+
+func (e StatusEntry) fields() []string {
+	return []string{
+		e.Mount,
+		e.Path,
+		e.Cluster,
+		e.Status,
+		strconv.FormatInt(e.SourceVersion, 10),
+		strconv.FormatInt(e.DestinationVersion, 10),
+		formatTime(&e.LastSyncAttempt),
+		formatTime(e.LastSyncSuccess),
+		strconv.Itoa(e.AttemptCount),
+		formatTime(e.NextRetryAt),
+		formatTime(e.QuarantinedAt),
+		e.ErrorCategory,
+		e.Error,
+	}
+}
+
+func (e HistoryEntry) fields() []string {
+	return []string{
+		strconv.FormatInt(e.RunID, 10),
+		formatTime(&e.OccurredAt),
+		e.Cluster,
+		e.Action,
+		formatVersion(e.SourceVersion),
+		formatVersion(e.DestinationVersion),
+		e.Error,
+	}
+}
+
+// WriteStatus writes the entries to w in the given format.
+func WriteStatus(w io.Writer, format Format, entries []StatusEntry) error {
+	return write(w, format, statusHeader, entries)
+}
+
+// WriteHistory writes the entries to w in the given format.
+func WriteHistory(w io.Writer, format Format, entries []HistoryEntry) error {
+	return write(w, format, historyHeader, entries)
+}
+
+func write[T entry](w io.Writer, format Format, header []string, entries []T) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := writer.Write(e.fields()); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatTable:
+		//nolint:mnd
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, e := range entries {
+			fields := e.fields()
+			for i, field := range fields {
+				// Keep every row on a single line; multi-line errors would break the alignment.
+				fields[i] = strings.Join(strings.Fields(field), " ")
+			}
+			fmt.Fprintln(writer, strings.Join(fields, "\t"))
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatVersion(version *int64) string {
+	if version == nil {
+		return ""
+	}
+	return strconv.FormatInt(*version, 10)
+}
@@ -0,0 +1,216 @@
+// Package syncstate reads the stored sync state of secrets and their history for inspection.
+package syncstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+	"vault-sync/internal/service/pathmatching"
+)
+
+// FilterableStatuses are the statuses Status can filter on.
+//
+//nolint:gochecknoglobals
+var FilterableStatuses = []models.SyncStatus{
+	models.StatusSuccess,
+	models.StatusFailed,
+	models.StatusErrorDeleting,
+	models.StatusPending,
+}
+
+// FilterableErrorCategories are the error categories Status can filter on.
+//
+//nolint:gochecknoglobals
+var FilterableErrorCategories = []models.ErrorCategory{
+	models.ErrorCategoryNotFound,
+	models.ErrorCategoryPermissionDenied,
+	models.ErrorCategoryAuthExpired,
+	models.ErrorCategoryUnavailable,
+	models.ErrorCategoryRateLimited,
+	models.ErrorCategoryCASConflict,
+	models.ErrorCategoryNetwork,
+	models.ErrorCategoryUnknown,
+}
+
+// StatusFilter selects the records returned by Status. Zero fields match every record.
+type StatusFilter struct {
+	Mount    string
+	PathGlob string
+	Cluster  string
+	Statuses []models.SyncStatus
+	// ErrorCategories matches failed records whose last error is of one of these categories.
+	ErrorCategories []models.ErrorCategory
+	// LastSuccessOlderThan matches records that never synced successfully or last did longer ago than this.
+	LastSuccessOlderThan time.Duration
+}
+
+func (f *StatusFilter) Validate() error {
+	if f.PathGlob != "" && !doublestar.ValidatePattern(f.PathGlob) {
+		return fmt.Errorf("invalid path glob: %s", f.PathGlob)
+	}
+	for _, status := range f.Statuses {
+		if !slices.Contains(FilterableStatuses, status) {
+			return fmt.Errorf("invalid status: %s (valid: %s)", status, join(FilterableStatuses))
+		}
+	}
+	for _, category := range f.ErrorCategories {
+		if !slices.Contains(FilterableErrorCategories, category) {
+			return fmt.Errorf("invalid error category: %s (valid: %s)", category, join(FilterableErrorCategories))
+		}
+	}
+	if f.LastSuccessOlderThan < 0 {
+		return errors.New("last success age must not be negative")
+	}
+	return nil
+}
+
+func (f *StatusFilter) matches(secret *models.SyncedSecret, now time.Time) bool {
+	if f.Mount != "" && secret.SecretBackend != f.Mount {
+		return false
+	}
+	if f.Cluster != "" && secret.DestinationCluster != f.Cluster {
+		return false
+	}
+	if f.PathGlob != "" {
+		if matched, _ := doublestar.Match(f.PathGlob, secret.SecretPath); !matched {
+			return false
+		}
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, secret.ReportedStatus()) {
+		return false
+	}
+	if len(f.ErrorCategories) > 0 &&
+		(secret.ErrorCategory == nil || !slices.Contains(f.ErrorCategories, *secret.ErrorCategory)) {
+		return false
+	}
+	if f.LastSuccessOlderThan > 0 && secret.LastSyncSuccess != nil &&
+		!secret.LastSyncSuccess.Before(now.Add(-f.LastSuccessOlderThan)) {
+		return false
+	}
+	return true
+}
+
+// StatusEntry is the state of one secret on one replica.
+type StatusEntry struct {
+	Mount              string     `json:"mount"`
+	Path               string     `json:"path"`
+	Cluster            string     `json:"cluster"`
+	Status             string     `json:"status"`
+	SourceVersion      int64      `json:"source_version"`
+	DestinationVersion int64      `json:"destination_version"`
+	LastSyncAttempt    time.Time  `json:"last_sync_attempt"`
+	LastSyncSuccess    *time.Time `json:"last_sync_success"`
+	AttemptCount       int        `json:"attempt_count"`
+	NextRetryAt        *time.Time `json:"next_retry_at"`
+	QuarantinedAt      *time.Time `json:"quarantined_at"`
+	ErrorCategory      string     `json:"error_category,omitempty"`
+	Error              string     `json:"error,omitempty"`
+}
+
+// HistoryEntry is one recorded change or failure of a secret on a replica.
+type HistoryEntry struct {
+	RunID              int64     `json:"run_id"`
+	OccurredAt         time.Time `json:"occurred_at"`
+	Cluster            string    `json:"cluster"`
+	Action             string    `json:"action"`
+	SourceVersion      *int64    `json:"source_version"`
+	DestinationVersion *int64    `json:"destination_version"`
+	Error              string    `json:"error,omitempty"`
+}
+
+type Inspector struct {
+	dbClient repository.SyncedSecretRepository
+}
+
+func NewInspector(dbClient repository.SyncedSecretRepository) *Inspector {
+	return &Inspector{dbClient: dbClient}
+}
+
+// Status returns the records matching the filter, ordered by mount, path and cluster.
+func (i *Inspector) Status(ctx context.Context, filter StatusFilter, now time.Time) ([]StatusEntry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	secrets, err := i.dbClient.GetSyncedSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read synced secrets: %w", err)
+	}
+
+	entries := make([]StatusEntry, 0)
+	for _, secret := range secrets {
+		if !filter.matches(secret, now) {
+			continue
+		}
+		entry := StatusEntry{
+			Mount:              secret.SecretBackend,
+			Path:               secret.SecretPath,
+			Cluster:            secret.DestinationCluster,
+			Status:             secret.ReportedStatus().String(),
+			SourceVersion:      secret.SourceVersion,
+			DestinationVersion: secret.DestinationVersion,
+			LastSyncAttempt:    secret.LastSyncAttempt,
+			LastSyncSuccess:    secret.LastSyncSuccess,
+			AttemptCount:       secret.AttemptCount,
+			NextRetryAt:        secret.NextRetryAt,
+			QuarantinedAt:      secret.QuarantinedAt,
+		}
+		if secret.ErrorMessage != nil {
+			entry.Error = *secret.ErrorMessage
+		}
+		if secret.ErrorCategory != nil {
+			entry.ErrorCategory = secret.ErrorCategory.String()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// History returns up to limit recorded events of the secret at mount/path, newest first.
+func (i *Inspector) History(ctx context.Context, secretPath string, limit int) ([]HistoryEntry, error) {
+	path, err := pathmatching.ParseSecretPath(secretPath)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit must be positive")
+	}
+
+	events, err := i.dbClient.GetSyncEvents(ctx, path.Mount, path.KeyPath, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync history: %w", err)
+	}
+
+	entries := make([]HistoryEntry, 0, len(events))
+	for _, event := range events {
+		entry := HistoryEntry{
+			RunID:              event.RunID,
+			OccurredAt:         event.OccurredAt,
+			Cluster:            event.DestinationCluster,
+			Action:             event.Action.String(),
+			SourceVersion:      event.SourceVersion,
+			DestinationVersion: event.DestinationVersion,
+		}
+		if event.ErrorMessage != nil {
+			entry.Error = *event.ErrorMessage
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func join[T fmt.Stringer](values []T) string {
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		names = append(names, value.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,275 @@
+package syncstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/repository"
+)
+
+// stubRepository serves fixed records and events. Only the read methods used by the Inspector are implemented.
+type stubRepository struct {
+	repository.SyncedSecretRepository
+	secrets []*models.SyncedSecret
+	events  []*models.SyncEvent
+	err     error
+
+	requestedLimit int
+}
+
+func (r *stubRepository) GetSyncedSecrets(_ context.Context) ([]*models.SyncedSecret, error) {
+	return r.secrets, r.err
+}
+
+func (r *stubRepository) GetSyncEvents(
+	_ context.Context, backend, path string, limit int,
+) ([]*models.SyncEvent, error) {
+	r.requestedLimit = limit
+	var events []*models.SyncEvent
+	for _, event := range r.events {
+		if event.SecretBackend == backend && event.SecretPath == path {
+			events = append(events, event)
+		}
+	}
+	return events, r.err
+}
+
+type SyncStateTestSuite struct {
+	suite.Suite
+	ctx  context.Context
+	now  time.Time
+	repo *stubRepository
+}
+
+func TestSyncStateSuite(t *testing.T) {
+	suite.Run(t, new(SyncStateTestSuite))
+}
+
+func (suite *SyncStateTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	recent := suite.now.Add(-time.Hour)
+	old := suite.now.Add(-72 * time.Hour)
+	errorMsg := "permission denied"
+	permissionDenied := models.ErrorCategoryPermissionDenied
+	suite.repo = &stubRepository{
+		secrets: []*models.SyncedSecret{
+			{SecretBackend: "team-a", SecretPath: "app/db", DestinationCluster: "replica-a",
+				SourceVersion: 3, DestinationVersion: 3, Status: models.StatusSuccess, LastSyncSuccess: &recent},
+			{SecretBackend: "team-a", SecretPath: "app/db", DestinationCluster: "replica-b",
+				SourceVersion: 3, DestinationVersion: 2, Status: models.StatusFailed, LastSyncSuccess: &old,
+				ErrorMessage: &errorMsg, ErrorCategory: &permissionDenied},
+			{SecretBackend: "team-a", SecretPath: "infra/tls", DestinationCluster: "replica-a",
+				SourceVersion: models.DeleteFailedVersion, DestinationVersion: models.DeleteFailedVersion,
+				Status: models.StatusFailed, LastSyncSuccess: &recent},
+			{SecretBackend: "team-b", SecretPath: "app/api", DestinationCluster: "replica-a",
+				SourceVersion: 1, Status: models.StatusPending},
+		},
+	}
+}
+
+func (suite *SyncStateTestSuite) keys(entries []StatusEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Mount+"/"+entry.Path+"@"+entry.Cluster)
+	}
+	return keys
+}
+
+func (suite *SyncStateTestSuite) TestStatus() {
+	testCases := []struct {
+		name     string
+		filter   StatusFilter
+		expected []string
+	}{
+		{
+			name:   "returns every record without a filter",
+			filter: StatusFilter{},
+			expected: []string{
+				"team-a/app/db@replica-a", "team-a/app/db@replica-b", "team-a/infra/tls@replica-a",
+				"team-b/app/api@replica-a",
+			},
+		},
+		{
+			name:     "filters by mount and cluster",
+			filter:   StatusFilter{Mount: "team-a", Cluster: "replica-a"},
+			expected: []string{"team-a/app/db@replica-a", "team-a/infra/tls@replica-a"},
+		},
+		{
+			name:     "filters by path glob",
+			filter:   StatusFilter{PathGlob: "app/**"},
+			expected: []string{"team-a/app/db@replica-a", "team-a/app/db@replica-b", "team-b/app/api@replica-a"},
+		},
+		{
+			name:     "tells failed deletes apart from failed writes",
+			filter:   StatusFilter{Statuses: []models.SyncStatus{models.StatusErrorDeleting}},
+			expected: []string{"team-a/infra/tls@replica-a"},
+		},
+		{
+			name:     "matches any of several statuses",
+			filter:   StatusFilter{Statuses: []models.SyncStatus{models.StatusFailed, models.StatusPending}},
+			expected: []string{"team-a/app/db@replica-b", "team-b/app/api@replica-a"},
+		},
+		{
+			name: "filters by error category",
+			filter: StatusFilter{
+				ErrorCategories: []models.ErrorCategory{models.ErrorCategoryPermissionDenied},
+			},
+			expected: []string{"team-a/app/db@replica-b"},
+		},
+		{
+			name:     "matches old and missing last successes",
+			filter:   StatusFilter{LastSuccessOlderThan: 24 * time.Hour},
+			expected: []string{"team-a/app/db@replica-b", "team-b/app/api@replica-a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			entries, err := NewInspector(suite.repo).Status(suite.ctx, tc.filter, suite.now)
+
+			suite.Require().NoError(err)
+			suite.Equal(tc.expected, suite.keys(entries))
+		})
+	}
+
+	suite.Run("reports the status and error of a record", func() {
+		entries, err := NewInspector(suite.repo).Status(suite.ctx, StatusFilter{Cluster: "replica-b"}, suite.now)
+
+		suite.Require().NoError(err)
+		suite.Require().Len(entries, 1)
+		suite.Equal("failed", entries[0].Status)
+		suite.Equal("permission denied", entries[0].Error)
+		suite.Equal("permission_denied", entries[0].ErrorCategory)
+	})
+
+	suite.Run("rejects an invalid filter", func() {
+		_, err := NewInspector(suite.repo).Status(suite.ctx, StatusFilter{
+			Statuses: []models.SyncStatus{"deleted"},
+		}, suite.now)
+
+		suite.ErrorContains(err, "invalid status: deleted")
+
+		_, err = NewInspector(suite.repo).Status(suite.ctx, StatusFilter{
+			ErrorCategories: []models.ErrorCategory{"timeout"},
+		}, suite.now)
+
+		suite.ErrorContains(err, "invalid error category: timeout")
+	})
+
+	suite.Run("returns the repository error", func() {
+		suite.repo.err = errors.New("connection refused")
+
+		_, err := NewInspector(suite.repo).Status(suite.ctx, StatusFilter{}, suite.now)
+
+		suite.ErrorContains(err, "connection refused")
+	})
+}
+
+func (suite *SyncStateTestSuite) TestHistory() {
+	version := int64(4)
+	errorMsg := "permission denied"
+	suite.repo.events = []*models.SyncEvent{
+		{RunID: 2, SecretBackend: "team-a", SecretPath: "app/db", DestinationCluster: "replica-b",
+			Action: models.SyncActionFailed, SourceVersion: &version, ErrorMessage: &errorMsg, OccurredAt: suite.now},
+		{RunID: 1, SecretBackend: "team-a", SecretPath: "app/db", DestinationCluster: "replica-a",
+			Action: models.SyncActionUpdated, SourceVersion: &version, DestinationVersion: &version,
+			OccurredAt: suite.now.Add(-time.Hour)},
+		{RunID: 1, SecretBackend: "team-a", SecretPath: "app/api", DestinationCluster: "replica-a",
+			Action: models.SyncActionDeleted, OccurredAt: suite.now.Add(-time.Hour)},
+	}
+
+	suite.Run("returns the events of the secret", func() {
+		entries, err := NewInspector(suite.repo).History(suite.ctx, "team-a/app/db", 10)
+
+		suite.Require().NoError(err)
+		suite.Equal(10, suite.repo.requestedLimit)
+		suite.Require().Len(entries, 2)
+		suite.Equal("failed", entries[0].Action)
+		suite.Equal("permission denied", entries[0].Error)
+		suite.Equal("replica-a", entries[1].Cluster)
+	})
+
+	suite.Run("rejects a path without a mount", func() {
+		_, err := NewInspector(suite.repo).History(suite.ctx, "team-a", 10)
+
+		suite.ErrorContains(err, "format should be mount/path")
+	})
+}
+
+func (suite *SyncStateTestSuite) TestWrite() {
+	success := suite.now.Add(-time.Hour)
+	entries := []StatusEntry{
+		{Mount: "team-a", Path: "app/db", Cluster: "replica-a", Status: "success", SourceVersion: 3,
+			DestinationVersion: 3, LastSyncAttempt: success, LastSyncSuccess: &success},
+		{Mount: "team-a", Path: "app/db", Cluster: "replica-b", Status: "failed", SourceVersion: 3,
+			LastSyncAttempt: success, AttemptCount: 2, NextRetryAt: &success, ErrorCategory: "permission_denied",
+			Error: "write failed:\npermission denied"},
+	}
+
+	suite.Run("writes a table with one line per entry", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(WriteStatus(&buf, FormatTable, entries))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		suite.Require().Len(lines, 3)
+		suite.True(strings.HasPrefix(lines[0], "MOUNT"))
+		suite.Contains(lines[1], "2026-10-18T11:00:00Z")
+		suite.Contains(lines[2], "write failed: permission denied")
+	})
+
+	suite.Run("writes csv with a header", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(WriteStatus(&buf, FormatCSV, entries))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		suite.Require().NoError(err)
+		suite.Require().Len(records, 3)
+		suite.Equal(statusHeader, records[0])
+		suite.Equal("", records[2][7])
+		suite.Equal("2", records[2][8])
+		suite.Equal("2026-10-18T11:00:00Z", records[2][9])
+		suite.Equal("", records[2][10])
+		suite.Equal("permission_denied", records[2][11])
+		suite.Equal("write failed:\npermission denied", records[2][12])
+	})
+
+	suite.Run("writes json", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(WriteStatus(&buf, FormatJSON, entries))
+
+		var decoded []map[string]any
+		suite.Require().NoError(json.Unmarshal(buf.Bytes(), &decoded))
+		suite.Require().Len(decoded, 2)
+		suite.Equal("replica-b", decoded[1]["cluster"])
+		suite.Nil(decoded[1]["last_sync_success"])
+		suite.InDelta(2, decoded[1]["attempt_count"], 0)
+		suite.Nil(decoded[1]["quarantined_at"])
+	})
+
+	suite.Run("writes an empty json array without entries", func() {
+		var buf bytes.Buffer
+
+		suite.Require().NoError(WriteHistory(&buf, FormatJSON, []HistoryEntry{}))
+
+		suite.JSONEq("[]", buf.String())
+	})
+
+	suite.Run("rejects an unknown format", func() {
+		_, err := ParseFormat("xml")
+
+		suite.ErrorContains(err, "unsupported format: xml")
+	})
+}
@@ -0,0 +1,179 @@
+// Package webhook serves the webhook of the daemon, which syncs the paths named in a request right away instead of
+// waiting for the next full sync, e.g. after a credential rotation. Requests come from CI or from a forwarder of
+// Vault event notifications and are signed with a shared secret.
+//
+// A request carries the Unix time it was sent in the X-Vault-Sync-Timestamp header and the HMAC-SHA256 of
+// "<timestamp>.<body>" in the X-Vault-Sync-Signature header as "sha256=<hex>". Requests older than five minutes
+// are rejected, so a captured request cannot be replayed later.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/pkg/log"
+)
+
+const (
+	TimestampHeader = "X-Vault-Sync-Timestamp"
+	SignatureHeader = "X-Vault-Sync-Signature"
+
+	signaturePrefix    = "sha256="
+	maxClockSkew       = 5 * time.Minute
+	maxRequestBodySize = 1 << 20
+)
+
+// Queue collects the paths to sync. It is implemented by the daemon, which debounces them.
+type Queue interface {
+	Enqueue(paths []pathmatching.SecretPath) int
+}
+
+// Matcher decides whether a path is synced. It is implemented by the path matcher.
+type Matcher interface {
+	ShouldSync(mount, keyPath string) bool
+}
+
+// Request is the body of a webhook request.
+type Request struct {
+	Paths []string `json:"paths"`
+}
+
+// Response reports the paths that are synced and the paths the sync rule does not cover. Queued counts the paths
+// that were not already waiting to be synced.
+type Response struct {
+	Paths   []string `json:"paths"`
+	Ignored []string `json:"ignored,omitempty"`
+	Queued  int      `json:"queued"`
+}
+
+// ErrorResponse is the body of a failed request.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	queue   Queue
+	matcher Matcher
+	secret  []byte
+	now     func() time.Time
+	logger  zerolog.Logger
+}
+
+// NewHandler returns the webhook handler. Paths of a correctly signed request that the sync rule covers are
+// enqueued on queue.
+func NewHandler(queue Queue, matcher Matcher, secret string) http.Handler {
+	return &handler{
+		queue:   queue,
+		matcher: matcher,
+		secret:  []byte(secret),
+		now:     time.Now,
+		logger:  log.Logger.With().Str("component", "webhook").Logger(),
+	}
+}
+
+// Sign returns the signature header value of body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(sign([]byte(secret), strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+		return
+	}
+	if err = h.verify(r.Header, body); err != nil {
+		h.logger.Warn().Err(err).Str("remote_addr", r.RemoteAddr).Msg("Rejected webhook request")
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var request Request
+	if err = json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if len(request.Paths) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no paths given"))
+		return
+	}
+	paths, err := pathmatching.ParseSecretPaths(request.Paths)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := Response{Paths: []string{}}
+	var accepted []pathmatching.SecretPath
+	for _, path := range paths {
+		if !h.matcher.ShouldSync(path.Mount, path.KeyPath) {
+			response.Ignored = append(response.Ignored, path.String())
+			continue
+		}
+		accepted = append(accepted, path)
+		response.Paths = append(response.Paths, path.String())
+	}
+	if len(accepted) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, errors.New("no path is covered by the sync rule"))
+		return
+	}
+	response.Queued = h.queue.Enqueue(accepted)
+	h.logger.Info().
+		Str("remote_addr", r.RemoteAddr).
+		Strs("paths", response.Paths).
+		Strs("ignored", response.Ignored).
+		Int("queued", response.Queued).
+		Msg("Webhook received")
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// verify checks that the request was signed with the secret within maxClockSkew of now.
+func (h *handler) verify(header http.Header, body []byte) error {
+	timestamp := header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid %s header", TimestampHeader)
+	}
+	if skew := h.now().Sub(time.Unix(seconds, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("request timestamp is more than %s away from the time of the daemon", maxClockSkew)
+	}
+
+	given, found := strings.CutPrefix(header.Get(SignatureHeader), signaturePrefix)
+	signature, err := hex.DecodeString(given)
+	if !found || err != nil || !hmac.Equal(signature, sign(h.secret, timestamp, body)) {
+		return fmt.Errorf("missing or invalid %s header", SignatureHeader)
+	}
+	return nil
+}
+
+func sign(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Logger.Warn().Err(err).Str("component", "webhook").Msg("Failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, ErrorResponse{Error: err.Error()})
+}
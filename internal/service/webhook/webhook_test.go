@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/service/pathmatching"
+)
+
+type fakeQueue struct {
+	enqueued [][]pathmatching.SecretPath
+}
+
+func (q *fakeQueue) Enqueue(paths []pathmatching.SecretPath) int {
+	q.enqueued = append(q.enqueued, paths)
+	return len(paths)
+}
+
+// fakeMatcher syncs every path of the production mount except those under legacy/.
+type fakeMatcher struct{}
+
+func (fakeMatcher) ShouldSync(mount, keyPath string) bool {
+	return mount == "production" && !strings.HasPrefix(keyPath, "legacy/")
+}
+
+type WebhookTestSuite struct {
+	suite.Suite
+	now     time.Time
+	queue   *fakeQueue
+	handler *handler
+}
+
+func TestWebhookSuite(t *testing.T) {
+	suite.Run(t, new(WebhookTestSuite))
+}
+
+func (suite *WebhookTestSuite) SetupTest() {
+	suite.now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	suite.queue = &fakeQueue{}
+	handler, ok := NewHandler(suite.queue, fakeMatcher{}, "s3cret").(*handler)
+	suite.Require().True(ok)
+	handler.now = func() time.Time { return suite.now }
+	suite.handler = handler
+}
+
+func (suite *WebhookTestSuite) post(body string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	suite.handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *WebhookTestSuite) signed(body string, timestamp time.Time) http.Header {
+	header := http.Header{}
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set(SignatureHeader, Sign("s3cret", timestamp, []byte(body)))
+	return header
+}
+
+func (suite *WebhookTestSuite) TestEnqueuesThePathsCoveredByTheSyncRule() {
+	body := `{"paths": ["production/app/database", "/production/app/cache/", "production/legacy/ftp", "staging/app"]}`
+
+	recorder := suite.post(body, suite.signed(body, suite.now))
+
+	suite.Equal(http.StatusAccepted, recorder.Code)
+	var response Response
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(Response{
+		Paths:   []string{"production/app/database", "production/app/cache"},
+		Ignored: []string{"production/legacy/ftp", "staging/app"},
+		Queued:  2,
+	}, response)
+	suite.Equal([][]pathmatching.SecretPath{{
+		{Mount: "production", KeyPath: "app/database"},
+		{Mount: "production", KeyPath: "app/cache"},
+	}}, suite.queue.enqueued)
+}
+
+func (suite *WebhookTestSuite) TestRejectsUnsignedRequests() {
+	body := `{"paths": ["production/app/database"]}`
+	testCases := []struct {
+		name   string
+		header http.Header
+	}{
+		{name: "no headers", header: http.Header{}},
+		{name: "wrong secret", header: func() http.Header {
+			header := suite.signed(body, suite.now)
+			header.Set(SignatureHeader, Sign("wrong", suite.now, []byte(body)))
+			return header
+		}()},
+		{name: "signature of another body", header: suite.signed(`{"paths": ["production/app"]}`, suite.now)},
+		{name: "signature without prefix", header: func() http.Header {
+			header := suite.signed(body, suite.now)
+			header.Set(SignatureHeader, strings.TrimPrefix(header.Get(SignatureHeader), "sha256="))
+			return header
+		}()},
+		{name: "replayed request", header: suite.signed(body, suite.now.Add(-6*time.Minute))},
+		{name: "timestamp in the future", header: suite.signed(body, suite.now.Add(6*time.Minute))},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			recorder := suite.post(body, tc.header)
+
+			suite.Equal(http.StatusUnauthorized, recorder.Code)
+			suite.Empty(suite.queue.enqueued)
+		})
+	}
+}
+
+func (suite *WebhookTestSuite) TestRejectsInvalidRequests() {
+	testCases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{name: "invalid json", body: `{"paths":`, code: http.StatusBadRequest},
+		{name: "no paths", body: `{}`, code: http.StatusBadRequest},
+		{name: "invalid path", body: `{"paths": ["production"]}`, code: http.StatusBadRequest},
+		{name: "no path covered", body: `{"paths": ["staging/app"]}`, code: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			recorder := suite.post(tc.body, suite.signed(tc.body, suite.now.Add(-time.Minute)))
+
+			suite.Equal(tc.code, recorder.Code)
+			suite.Empty(suite.queue.enqueued)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Package workqueue runs the sync jobs a coordinator enqueued in the sync_job_queue table. A worker leases a batch
+// of jobs, runs them through the daemon after its scheduled or triggered run in progress, and writes the outcome of
+// every job back. The leases are extended while the jobs run; the jobs of a worker that died are leased again by
+// another worker once their leases expired.
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"vault-sync/internal/metrics"
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+	"vault-sync/pkg/log"
+)
+
+const (
+	// finishTimeout bounds writing the outcome of a batch once the daemon is stopping.
+	finishTimeout = 10 * time.Second
+	// leaseExtensions is how many times a lease is extended within its duration while the jobs run.
+	leaseExtensions = 3
+)
+
+// Store is the work queue. It is implemented by the Postgres repository.
+type Store interface {
+	LeaseSyncJobs(
+		ctx context.Context, workerID string, limit int, leaseDuration time.Duration, maxAttempts int,
+	) ([]*models.QueuedJob, error)
+	ExtendSyncJobLeases(ctx context.Context, workerID string, jobIDs []int64, leaseDuration time.Duration) error
+	FinishSyncJobs(ctx context.Context, workerID string, jobs []*models.QueuedJob) error
+}
+
+// Runner runs the jobs of the leased paths and returns the result of the run. It is implemented by the daemon.
+type Runner interface {
+	SyncLeased(
+		ctx context.Context,
+		paths []pathmatching.SecretPath,
+		incremental bool,
+	) (*orchestrator.SyncResult, error)
+}
+
+// Worker leases the jobs of the work queue, runs them and writes their outcome back.
+type Worker struct {
+	store         Store
+	runner        Runner
+	workerID      string
+	batchSize     int
+	maxAttempts   int
+	leaseDuration time.Duration
+	pollInterval  time.Duration
+	now           func() time.Time
+	logger        zerolog.Logger
+}
+
+// NewWorker returns a worker that leases up to batchSize jobs at a time as workerID, for leaseDuration. A job is
+// leased up to maxAttempts times. When the queue is empty, the worker looks again after pollInterval.
+func NewWorker(
+	store Store,
+	runner Runner,
+	workerID string,
+	batchSize, maxAttempts int,
+	leaseDuration, pollInterval time.Duration,
+) *Worker {
+	return &Worker{
+		store:         store,
+		runner:        runner,
+		workerID:      workerID,
+		batchSize:     batchSize,
+		maxAttempts:   maxAttempts,
+		leaseDuration: leaseDuration,
+		pollInterval:  pollInterval,
+		now:           time.Now,
+		logger:        log.Logger.With().Str("component", "work_queue").Str("worker_id", workerID).Logger(),
+	}
+}
+
+// Run works through the queue until ctx is done. Full batches are followed by the next one right away.
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.Info().
+		Int("batch_size", w.batchSize).
+		Int("max_attempts", w.maxAttempts).
+		Dur("lease_duration", w.leaseDuration).
+		Dur("poll_interval", w.pollInterval).
+		Msg("Processing the work queue")
+
+	for ctx.Err() == nil {
+		if w.runBatch(ctx) == w.batchSize {
+			continue
+		}
+
+		timer := time.NewTimer(w.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+	w.logger.Info().Msg("Stopped processing the work queue")
+}
+
+// runBatch leases a batch of jobs, runs it and returns how many jobs were leased.
+func (w *Worker) runBatch(ctx context.Context) int {
+	jobs, err := w.store.LeaseSyncJobs(ctx, w.workerID, w.batchSize, w.leaseDuration, w.maxAttempts)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error().Err(err).Msg("Failed to lease sync jobs")
+		}
+		return 0
+	}
+	if len(jobs) == 0 {
+		return 0
+	}
+
+	w.logger.Info().Int("jobs", len(jobs)).Msg("Leased sync jobs")
+	stopExtending := w.extendLeases(ctx, jobs)
+	for _, incremental := range []bool{false, true} {
+		var group []*models.QueuedJob
+		for _, queuedJob := range jobs {
+			if queuedJob.Incremental == incremental {
+				group = append(group, queuedJob)
+			}
+		}
+		if len(group) > 0 {
+			w.run(ctx, group, incremental)
+		}
+	}
+	stopExtending()
+	w.finish(ctx, jobs)
+	return len(jobs)
+}
+
+// run runs the jobs in one run and sets their outcome. Jobs interrupted by the shutdown of the daemon are released
+// for the next worker to lease.
+func (w *Worker) run(ctx context.Context, jobs []*models.QueuedJob, incremental bool) {
+	var paths []pathmatching.SecretPath
+	jobPaths := make(map[*models.QueuedJob]string, len(jobs))
+	seen := make(map[string]bool)
+	for _, queuedJob := range jobs {
+		path := pathmatching.SecretPath{Mount: queuedJob.SecretBackend, KeyPath: queuedJob.SecretPath}
+		jobPaths[queuedJob] = path.String()
+		if !seen[path.String()] {
+			seen[path.String()] = true
+			paths = append(paths, path)
+		}
+	}
+
+	result, err := w.runner.SyncLeased(ctx, paths, incremental)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		for _, queuedJob := range jobs {
+			release(queuedJob)
+		}
+	case err != nil && !errors.Is(err, orchestrator.ErrRunAborted):
+		for _, queuedJob := range jobs {
+			w.setOutcome(queuedJob, models.QueuedJobFailed, err.Error())
+		}
+	default:
+		// The jobs a run aborted by its budget skipped are leased again.
+		failures := result.PathFailures()
+		skipped := result.SkippedPaths()
+		for _, queuedJob := range jobs {
+			if skipped[jobPaths[queuedJob]] {
+				release(queuedJob)
+				continue
+			}
+			if failure, failed := failures[jobPaths[queuedJob]]; failed {
+				w.setOutcome(queuedJob, models.QueuedJobFailed, failure)
+				continue
+			}
+			w.setOutcome(queuedJob, models.QueuedJobCompleted, "")
+		}
+	}
+}
+
+// extendLeases extends the leases of jobs a few times per lease duration until the returned function is called.
+func (w *Worker) extendLeases(ctx context.Context, jobs []*models.QueuedJob) func() {
+	jobIDs := make([]int64, 0, len(jobs))
+	for _, queuedJob := range jobs {
+		jobIDs = append(jobIDs, queuedJob.ID)
+	}
+
+	extendCtx, cancel := context.WithCancel(ctx)
+	var extending sync.WaitGroup
+	extending.Add(1)
+	go func() {
+		defer extending.Done()
+		ticker := time.NewTicker(w.leaseDuration / leaseExtensions)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-extendCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := w.store.ExtendSyncJobLeases(extendCtx, w.workerID, jobIDs, w.leaseDuration)
+			if err != nil && extendCtx.Err() == nil {
+				w.logger.Warn().Err(err).Int("jobs", len(jobIDs)).
+					Msg("Failed to extend the leases of sync jobs; they may be leased again by another worker")
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		extending.Wait()
+	}
+}
+
+// finish writes the outcome of the jobs back.
+func (w *Worker) finish(ctx context.Context, jobs []*models.QueuedJob) {
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer cancel()
+	if err := w.store.FinishSyncJobs(finishCtx, w.workerID, jobs); err != nil {
+		w.logger.Error().Err(err).Int("jobs", len(jobs)).
+			Msg("Failed to write the outcome of sync jobs; they are leased again once their leases expired")
+		return
+	}
+	for _, queuedJob := range jobs {
+		if queuedJob.FinishedAt != nil {
+			metrics.ObserveQueuedJob(queuedJob.Status.String())
+		}
+	}
+	w.logger.Info().Int("jobs", len(jobs)).Msg("Processed sync jobs")
+}
+
+func (w *Worker) setOutcome(queuedJob *models.QueuedJob, status models.QueuedJobStatus, errorMessage string) {
+	finishedAt := w.now()
+	queuedJob.Status = status
+	queuedJob.LeaseExpiresAt = nil
+	queuedJob.FinishedAt = &finishedAt
+	queuedJob.ErrorMessage = nil
+	if errorMessage != "" {
+		queuedJob.ErrorMessage = &errorMessage
+	}
+	w.logger.Debug().
+		Int64("job_id", queuedJob.ID).
+		Str("status", status.String()).
+		Str("error", errorMessage).
+		Msg("Finished sync job")
+}
+
+// release leaves the job leased; FinishSyncJobs lets its lease expire, so that any worker leases it again.
+func release(queuedJob *models.QueuedJob) {
+	queuedJob.Status = models.QueuedJobLeased
+	queuedJob.LeaseExpiresAt = nil
+	queuedJob.FinishedAt = nil
+	queuedJob.ErrorMessage = nil
+}
@@ -0,0 +1,263 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"vault-sync/internal/models"
+	"vault-sync/internal/service/job"
+	"vault-sync/internal/service/orchestrator"
+	"vault-sync/internal/service/pathmatching"
+)
+
+// fakeStore hands out the pending jobs on lease and keeps the finished ones.
+type fakeStore struct {
+	mu          sync.Mutex
+	pending     []*models.QueuedJob
+	finished    []models.QueuedJob
+	leasedBy    string
+	maxAttempts int
+	extensions  int
+}
+
+func (s *fakeStore) LeaseSyncJobs(
+	_ context.Context, workerID string, limit int, _ time.Duration, maxAttempts int,
+) ([]*models.QueuedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leasedBy = workerID
+	s.maxAttempts = maxAttempts
+	leased := s.pending[:min(limit, len(s.pending))]
+	s.pending = s.pending[len(leased):]
+	for _, queuedJob := range leased {
+		queuedJob.Status = models.QueuedJobLeased
+		queuedJob.LeasedBy = &workerID
+	}
+	return leased, nil
+}
+
+func (s *fakeStore) ExtendSyncJobLeases(context.Context, string, []int64, time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extensions++
+	return nil
+}
+
+func (s *fakeStore) FinishSyncJobs(_ context.Context, _ string, jobs []*models.QueuedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, queuedJob := range jobs {
+		s.finished = append(s.finished, *queuedJob)
+	}
+	return nil
+}
+
+func (s *fakeStore) enqueue(jobs ...*models.QueuedJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, jobs...)
+}
+
+func (s *fakeStore) finishedJobs() []models.QueuedJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.QueuedJob(nil), s.finished...)
+}
+
+type leasedRun struct {
+	paths       []pathmatching.SecretPath
+	incremental bool
+}
+
+type fakeRunner struct {
+	mu      sync.Mutex
+	runs    []leasedRun
+	result  *orchestrator.SyncResult
+	err     error
+	runTime time.Duration
+}
+
+func (r *fakeRunner) SyncLeased(
+	_ context.Context,
+	paths []pathmatching.SecretPath,
+	incremental bool,
+) (*orchestrator.SyncResult, error) {
+	time.Sleep(r.runTime)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, leasedRun{paths: paths, incremental: incremental})
+	if r.result == nil {
+		return &orchestrator.SyncResult{}, r.err
+	}
+	return r.result, r.err
+}
+
+func newJob(id int64, keyPath string, incremental bool) *models.QueuedJob {
+	return &models.QueuedJob{
+		ID:            id,
+		SecretBackend: "production",
+		SecretPath:    keyPath,
+		Incremental:   incremental,
+		Status:        models.QueuedJobPending,
+	}
+}
+
+type WorkerTestSuite struct {
+	suite.Suite
+	store  *fakeStore
+	runner *fakeRunner
+	worker *Worker
+}
+
+func TestWorkerSuite(t *testing.T) {
+	suite.Run(t, new(WorkerTestSuite))
+}
+
+func (suite *WorkerTestSuite) SetupTest() {
+	suite.store = &fakeStore{}
+	suite.runner = &fakeRunner{}
+	suite.worker = NewWorker(suite.store, suite.runner, "worker-a", 10, 5, time.Hour, time.Hour)
+}
+
+func (suite *WorkerTestSuite) statuses() map[int64]models.QueuedJobStatus {
+	statuses := make(map[int64]models.QueuedJobStatus)
+	for _, queuedJob := range suite.store.finishedJobs() {
+		statuses[queuedJob.ID] = queuedJob.Status
+	}
+	return statuses
+}
+
+func (suite *WorkerTestSuite) TestRunsTheLeasedJobsByKind() {
+	suite.store.enqueue(
+		newJob(1, "app/database", false),
+		newJob(2, "app/cache", true),
+		newJob(3, "app/queue", false),
+		newJob(4, "app/database", false),
+	)
+
+	leased := suite.worker.runBatch(context.Background())
+
+	suite.Equal(4, leased)
+	suite.Equal("worker-a", suite.store.leasedBy)
+	suite.Equal(5, suite.store.maxAttempts)
+	suite.Equal([]leasedRun{
+		{paths: []pathmatching.SecretPath{
+			{Mount: "production", KeyPath: "app/database"},
+			{Mount: "production", KeyPath: "app/queue"},
+		}},
+		{paths: []pathmatching.SecretPath{{Mount: "production", KeyPath: "app/cache"}}, incremental: true},
+	}, suite.runner.runs)
+	suite.Equal(map[int64]models.QueuedJobStatus{
+		1: models.QueuedJobCompleted,
+		2: models.QueuedJobCompleted,
+		3: models.QueuedJobCompleted,
+		4: models.QueuedJobCompleted,
+	}, suite.statuses())
+	for _, queuedJob := range suite.store.finishedJobs() {
+		suite.NotNil(queuedJob.FinishedAt)
+		suite.Nil(queuedJob.LeaseExpiresAt)
+		suite.Equal("worker-a", *queuedJob.LeasedBy)
+	}
+}
+
+func (suite *WorkerTestSuite) TestRecordsThePathsThatWereNotSynced() {
+	suite.runner.result = &orchestrator.SyncResult{JobResults: []*job.SyncJobResult{
+		{Mount: "production", KeyPath: "app/database", Error: errors.New("replica-1: permission denied")},
+		{Mount: "production", KeyPath: "app/cache", Status: []*job.ClusterSyncStatus{
+			{ClusterName: "replica-1", Status: job.SyncJobStatusBackingOff},
+		}},
+	}}
+	suite.store.enqueue(newJob(1, "app/database", false), newJob(2, "app/cache", false), newJob(3, "app/queue", false))
+
+	suite.worker.runBatch(context.Background())
+
+	finished := suite.store.finishedJobs()
+	suite.Require().Len(finished, 3)
+	suite.Equal(models.QueuedJobFailed, finished[0].Status)
+	suite.Equal("replica-1: permission denied", *finished[0].ErrorMessage)
+	suite.Equal(models.QueuedJobFailed, finished[1].Status)
+	suite.Equal("not synced, replica replica-1 is backing_off", *finished[1].ErrorMessage)
+	suite.Equal(models.QueuedJobCompleted, finished[2].Status)
+}
+
+func (suite *WorkerTestSuite) TestFailsTheJobsOfAFailedRun() {
+	suite.runner.err = errors.New("database unavailable")
+	suite.store.enqueue(newJob(1, "app/database", false))
+
+	suite.worker.runBatch(context.Background())
+
+	finished := suite.store.finishedJobs()
+	suite.Require().Len(finished, 1)
+	suite.Equal(models.QueuedJobFailed, finished[0].Status)
+	suite.Equal("database unavailable", *finished[0].ErrorMessage)
+}
+
+func (suite *WorkerTestSuite) TestReleasesTheJobsAnAbortedRunSkipped() {
+	suite.runner.result = &orchestrator.SyncResult{AbortReason: "3 jobs failed", JobResults: []*job.SyncJobResult{
+		{Mount: "production", KeyPath: "app/database", Error: errors.New("replica-1: permission denied")},
+		{Mount: "production", KeyPath: "app/cache", Error: context.Canceled},
+	}}
+	suite.runner.err = orchestrator.ErrRunAborted
+	suite.store.enqueue(newJob(1, "app/database", false), newJob(2, "app/cache", false), newJob(3, "app/queue", false))
+
+	suite.worker.runBatch(context.Background())
+
+	suite.Equal(map[int64]models.QueuedJobStatus{
+		1: models.QueuedJobFailed,
+		2: models.QueuedJobLeased,
+		3: models.QueuedJobCompleted,
+	}, suite.statuses())
+}
+
+func (suite *WorkerTestSuite) TestReleasesTheJobsOfAnInterruptedRun() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.runner.err = context.Canceled
+	leasedBy := "worker-a"
+	queuedJob := &models.QueuedJob{
+		ID: 1, SecretBackend: "production", SecretPath: "app/database", Status: models.QueuedJobLeased,
+		LeasedBy: &leasedBy,
+	}
+
+	suite.worker.run(ctx, []*models.QueuedJob{queuedJob}, false)
+
+	suite.Equal(models.QueuedJobLeased, queuedJob.Status)
+	suite.Nil(queuedJob.LeaseExpiresAt)
+	suite.Nil(queuedJob.FinishedAt)
+}
+
+func (suite *WorkerTestSuite) TestExtendsTheLeasesWhileTheJobsRun() {
+	suite.worker = NewWorker(suite.store, suite.runner, "worker-a", 10, 5, 30*time.Millisecond, time.Hour)
+	suite.runner.runTime = 50 * time.Millisecond
+	suite.store.enqueue(newJob(1, "app/database", false))
+
+	suite.worker.runBatch(context.Background())
+
+	suite.store.mu.Lock()
+	defer suite.store.mu.Unlock()
+	suite.GreaterOrEqual(suite.store.extensions, 1)
+}
+
+func (suite *WorkerTestSuite) TestRunLeasesBatchesUntilTheQueueIsEmpty() {
+	for id := range int64(25) {
+		suite.store.enqueue(newJob(id, "app/database", false))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		suite.worker.Run(ctx)
+	}()
+
+	suite.Eventually(func() bool { return len(suite.store.finishedJobs()) == 25 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	suite.runner.mu.Lock()
+	defer suite.runner.mu.Unlock()
+	suite.Len(suite.runner.runs, 3)
+}
@@ -0,0 +1,155 @@
+// Package tracing sets up the OpenTelemetry traces of vault-sync. Runs, sync jobs, Vault operations and database
+// queries are spans; their attributes name clusters, mounts and paths but never secret values.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"vault-sync/internal/config"
+	"vault-sync/pkg/log"
+)
+
+const (
+	serviceName = "vault-sync"
+	tracerName  = "vault-sync"
+
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Attribute keys of the spans.
+const (
+	ClusterKey       = attribute.Key("vault.cluster")
+	MountKey         = attribute.Key("vault.mount")
+	PathKey          = attribute.Key("vault.path")
+	OperationKey     = attribute.Key("vault_sync.operation")
+	RunKindKey       = attribute.Key("vault_sync.run_kind")
+	ErrorCategoryKey = attribute.Key("vault_sync.error_category")
+	AttemptsKey      = attribute.Key("vault_sync.attempts")
+)
+
+// ShutdownFunc flushes the pending spans and stops the exporter.
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs the global tracer provider for cfg. Without an exporter, the default no-op provider is kept and
+// spans cost next to nothing. The returned ShutdownFunc has to be called before the process exits, otherwise the
+// last spans are lost.
+func Setup(ctx context.Context, cfg *config.Tracing, instanceID string) (ShutdownFunc, error) {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceInstanceID(instanceID)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetSampleRatio()))),
+	)
+	logger := log.Logger.With().Str("component", "tracing").Str("exporter", cfg.Exporter).Logger()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn().Err(err).Msg("Failed to export spans")
+	}))
+	otel.SetTracerProvider(provider)
+	logger.Info().Float64("sample_ratio", cfg.GetSampleRatio()).Msg("Tracing enabled")
+
+	return provider.Shutdown, nil
+}
+
+// newExporter returns the span exporter of cfg, or nil when tracing is disabled.
+//
+//nolint:nilnil
+func newExporter(ctx context.Context, cfg *config.Tracing) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout span exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP span exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+}
+
+// Start starts a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// SecretAttributes returns the attributes naming a secret on a cluster. Empty values are left out.
+func SecretAttributes(cluster, mount, keyPath string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if cluster != "" {
+		attrs = append(attrs, ClusterKey.String(cluster))
+	}
+	if mount != "" {
+		attrs = append(attrs, MountKey.String(mount))
+	}
+	if keyPath != "" {
+		attrs = append(attrs, PathKey.String(keyPath))
+	}
+	return attrs
+}
+
+// RecordError marks span as failed with err. A nil err leaves the span untouched.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// WithTraceContext returns logger with the trace and span ID of the span in ctx, so log lines can be looked up
+// from a trace and the other way around. logger is returned as is when ctx has no span.
+func WithTraceContext(ctx context.Context, logger zerolog.Logger) zerolog.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger
+	}
+	return logger.With().
+		Str("trace_id", spanContext.TraceID().String()).
+		Str("span_id", spanContext.SpanID().String()).
+		Logger()
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"vault-sync/internal/config"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("keeps the no-op provider without an exporter", func(t *testing.T) {
+		for _, exporter := range []string{"", ExporterNone} {
+			provider := otel.GetTracerProvider()
+
+			shutdown, err := Setup(ctx, &config.Tracing{Exporter: exporter}, "test")
+
+			require.NoError(t, err)
+			require.NoError(t, shutdown(ctx))
+			assert.Same(t, provider, otel.GetTracerProvider())
+		}
+	})
+
+	t.Run("installs a provider for the stdout exporter", func(t *testing.T) {
+		provider := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(provider) })
+
+		shutdown, err := Setup(ctx, &config.Tracing{Exporter: ExporterStdout}, "test")
+
+		require.NoError(t, err)
+		assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+		require.NoError(t, shutdown(ctx))
+	})
+
+	t.Run("rejects unknown exporters", func(t *testing.T) {
+		_, err := Setup(ctx, &config.Tracing{Exporter: "jaeger"}, "test")
+
+		require.ErrorContains(t, err, `unknown tracing exporter "jaeger"`)
+	})
+}
+
+func TestSecretAttributes(t *testing.T) {
+	assert.Equal(t,
+		[]attribute.KeyValue{ClusterKey.String("replica"), MountKey.String("kv"), PathKey.String("app/db")},
+		SecretAttributes("replica", "kv", "app/db"),
+	)
+	assert.Equal(t, []attribute.KeyValue{MountKey.String("kv")}, SecretAttributes("", "kv", ""))
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	RecordError(failed, errors.New("boom"))
+	failed.End()
+	_, succeeded := tracer.Start(context.Background(), "succeeded")
+	RecordError(succeeded, nil)
+	succeeded.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Empty(t, spans[1].Events())
+}
+
+func TestWithTraceContext(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	t.Run("adds the trace and span ID of the span in ctx", func(t *testing.T) {
+		buf.Reset()
+		tracer := sdktrace.NewTracerProvider().Tracer("test")
+		ctx, span := tracer.Start(context.Background(), "test")
+		defer span.End()
+
+		traced := WithTraceContext(ctx, logger)
+		traced.Info().Msg("hello")
+
+		assert.Contains(t, buf.String(), `"trace_id":"`+span.SpanContext().TraceID().String()+`"`)
+		assert.Contains(t, buf.String(), `"span_id":"`+span.SpanContext().SpanID().String()+`"`)
+	})
+
+	t.Run("leaves the logger alone without a span", func(t *testing.T) {
+		buf.Reset()
+
+		traced := WithTraceContext(context.Background(), logger)
+		traced.Info().Msg("hello")
+
+		assert.NotContains(t, buf.String(), "trace_id")
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"vault-sync/internal/config"
 	"vault-sync/internal/models"
@@ -123,6 +126,31 @@ func (mc *MultiClusterVaultClient) GetSecretMetadata(
 	return metadata, nil
 }
 
+// GetSourceState reads the existence and metadata of a secret in the main cluster with a single request.
+// A secret that does not exist is not an error; SecretExists and GetSecretMetadata would take two requests.
+func (mc *MultiClusterVaultClient) GetSourceState(
+	ctx context.Context, mount, keyPath string,
+) (*SourceState, error) {
+	logger := mc.createOperationLogger("get_source_state", mount, keyPath)
+
+	if err := validateMountAndKeyPath(mount, keyPath); err != nil {
+		logger.Error().Err(err).Msg("Invalid mount or key path")
+		return nil, err
+	}
+
+	metadata, err := mc.mainCluster.fetchSecretMetadata(ctx, mount, keyPath)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			logger.Debug().Msg("Secret does not exist in main cluster")
+			return &SourceState{Exists: false}, nil
+		}
+		return nil, fmt.Errorf("failed to get source state for %s/%s: %w", mount, keyPath, err)
+	}
+
+	logger.Debug().Int64("current_version", metadata.CurrentVersion).Msg("Retrieved source state")
+	return &SourceState{Exists: true, Metadata: metadata}, nil
+}
+
 // GetKeysUnderMount retrieves all available keys (using path format) under a given mount from the main cluster.
 // This operation is only performed on the main cluster as it's used for discovery purposes.
 func (mc *MultiClusterVaultClient) GetKeysUnderMount(
@@ -147,6 +175,38 @@ func (mc *MultiClusterVaultClient) GetKeysUnderMount(
 	return keys, nil
 }
 
+// StreamKeysUnderMount walks a mount on the main cluster and calls emit for every included key as soon as
+// its folder is listed, without holding the whole listing in memory. Keys arrive in no particular order and
+// emit may be called concurrently. An error returned by emit stops the walk and is returned wrapped.
+func (mc *MultiClusterVaultClient) StreamKeysUnderMount(
+	ctx context.Context,
+	mount string,
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+	emit func(keyPath string) error,
+) error {
+	logger := mc.createOperationLogger("stream_keys_under_mount", mount, "")
+	if mount == "" {
+		logger.Error().Msg("mount cannot be empty")
+		return errors.New("mount cannot be empty")
+	}
+
+	var emitted atomic.Int64
+	err := mc.mainCluster.streamKeysUnderMount(ctx, mount, shouldIncludeKeyPath, func(keyPath string) error {
+		if err := emit(keyPath); err != nil {
+			return err
+		}
+		emitted.Add(1)
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to stream keys under mount")
+		return fmt.Errorf("failed to get keys under mount %s: %w", mount, err)
+	}
+
+	logger.Info().Int64("key_count", emitted.Load()).Msg("Successfully streamed keys from main cluster")
+	return nil
+}
+
 // SecretExists checks if a secret exists at the given mount and key path in the main cluster.
 // It returns true if the secret exists, false if it doesn't exist, and an error for other failures.
 // This operation is only performed on the main cluster for discovery purposes.
@@ -235,6 +295,93 @@ func (mc *MultiClusterVaultClient) DeleteSecretFromReplicas(
 	return results, nil
 }
 
+// ReadSecret reads the secret data and its version from the main cluster.
+// The response can be handed to SyncSecretToReplica for every replica, so the source is read only once.
+func (mc *MultiClusterVaultClient) ReadSecret(
+	ctx context.Context, mount, keyPath string,
+) (*SecretResponse, error) {
+	logger := mc.createOperationLogger("read_secret", mount, keyPath)
+
+	if err := validateMountAndKeyPath(mount, keyPath); err != nil {
+		logger.Error().Err(err).Msg("Invalid mount or key path")
+		return nil, err
+	}
+
+	return mc.readSecretFromMainCluster(ctx, mount, keyPath)
+}
+
+// SyncSecretToReplica writes a secret previously read from the main cluster to a single replica cluster.
+// Write failures are reported in the returned SyncedSecret; an error is only returned for invalid input.
+func (mc *MultiClusterVaultClient) SyncSecretToReplica(
+	ctx context.Context, clusterName, mount, keyPath string, sourceSecret *SecretResponse,
+) (*models.SyncedSecret, error) {
+	logger := mc.createOperationLogger("sync_secret_to_replica", mount, keyPath).
+		With().
+		Str("cluster", clusterName).
+		Logger()
+
+	if err := mc.validateReplicaOperation(clusterName, mount, keyPath); err != nil {
+		logger.Error().Err(err).Msg("Invalid replica sync request")
+		return nil, err
+	}
+	if sourceSecret == nil {
+		logger.Error().Msg("Source secret cannot be nil")
+		return nil, errors.New("source secret cannot be nil")
+	}
+
+	replicaHandler := replicaSyncHandler[*models.SyncedSecret]{
+		operationType: operationTypeSync,
+		ctx:           ctx,
+		logger:        &logger,
+		sourceVersion: sourceSecret.Metadata.Version,
+		clusters:      []string{clusterName},
+		mount:         mount,
+		keyPath:       keyPath,
+		operationFunc: mc.syncSecretFuncFactory(sourceSecret.Data),
+	}
+
+	results, err := replicaHandler.executeSync()
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
+// DeleteSecretFromReplica deletes a secret from a single replica cluster.
+// Like DeleteSecretFromReplicas, a secret that does not exist in the replica is treated as deleted.
+func (mc *MultiClusterVaultClient) DeleteSecretFromReplica(
+	ctx context.Context, clusterName, mount, keyPath string,
+) (*models.SyncSecretDeletionResult, error) {
+	logger := mc.createOperationLogger("delete_secret_from_replica", mount, keyPath).
+		With().
+		Str("cluster", clusterName).
+		Logger()
+
+	if err := mc.validateReplicaOperation(clusterName, mount, keyPath); err != nil {
+		logger.Error().Err(err).Msg("Invalid replica delete request")
+		return nil, err
+	}
+
+	replicaHandler := replicaSyncHandler[*models.SyncSecretDeletionResult]{
+		operationType: operationTypeDelete,
+		ctx:           ctx,
+		logger:        &logger,
+		sourceVersion: 0,
+		clusters:      []string{clusterName},
+		mount:         mount,
+		keyPath:       keyPath,
+		operationFunc: mc.deleteSecretFuncFactory(),
+	}
+
+	results, err := replicaHandler.executeSync()
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
 func (mc *MultiClusterVaultClient) GetReplicaNames() []string {
 	names := make([]string, 0, len(mc.replicaClusters))
 	for name := range mc.replicaClusters {
@@ -243,6 +390,42 @@ func (mc *MultiClusterVaultClient) GetReplicaNames() []string {
 	return names
 }
 
+// RequestCounts returns the number of requests sent to each cluster since the client was created, keyed by
+// cluster name. Retries and token checks are counted, requests rejected by the circuit breaker are not.
+func (mc *MultiClusterVaultClient) RequestCounts() map[string]int64 {
+	counts := make(map[string]int64, len(mc.replicaClusters)+1)
+	counts[mc.mainCluster.config.Name] = mc.mainCluster.resilience.requests.Load()
+	for name, cm := range mc.replicaClusters {
+		counts[name] = cm.resilience.requests.Load()
+	}
+	return counts
+}
+
+// ClusterStatuses checks every cluster concurrently and returns their statuses, the main cluster first and the
+// replicas by name.
+func (mc *MultiClusterVaultClient) ClusterStatuses(ctx context.Context) []ClusterStatus {
+	names := mc.GetReplicaNames()
+	slices.Sort(names)
+	clusters := make([]*clusterManager, 0, len(names)+1)
+	clusters = append(clusters, mc.mainCluster)
+	for _, name := range names {
+		clusters = append(clusters, mc.replicaClusters[name])
+	}
+
+	statuses := make([]ClusterStatus, len(clusters))
+	var wg sync.WaitGroup
+	for i, cm := range clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = cm.status(ctx)
+		}()
+	}
+	wg.Wait()
+	statuses[0].Main = true
+	return statuses
+}
+
 // readSecretFromMainCluster reads both secret data and metadata from the main cluster.
 func (mc *MultiClusterVaultClient) readSecretFromMainCluster(
 	ctx context.Context, mount, keyPath string,
@@ -318,6 +501,13 @@ func (mc *MultiClusterVaultClient) checkSecretExists(
 	return exists, nil
 }
 
+func (mc *MultiClusterVaultClient) validateReplicaOperation(clusterName, mount, keyPath string) error {
+	if _, exists := mc.replicaClusters[clusterName]; !exists {
+		return fmt.Errorf("replica cluster not found: %s", clusterName)
+	}
+	return validateMountAndKeyPath(mount, keyPath)
+}
+
 func (mc *MultiClusterVaultClient) createOperationLogger(
 	operation, mount, keyPath string,
 ) zerolog.Logger {
@@ -514,6 +514,63 @@ func (suite *MultiClusterVaultClientTestSuite) TestSecretExists() {
 	})
 }
 
+func (suite *MultiClusterVaultClientTestSuite) TestGetSourceState() {
+	mount := "team-a"
+	keyPath := "app/database"
+
+	suite.Run("returns existence and metadata of an existing secret", func() {
+		suite.mainVault.WriteSecret(suite.ctx, mount, keyPath, map[string]string{"password": "v1"})
+		suite.mainVault.WriteSecret(suite.ctx, mount, keyPath, map[string]string{"password": "v2"})
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.Require().NoError(err)
+
+		state, err := client.GetSourceState(suite.ctx, mount, keyPath)
+
+		suite.Require().NoError(err)
+		suite.True(state.Exists)
+		suite.Require().NotNil(state.Metadata)
+		suite.Equal(int64(2), state.Metadata.CurrentVersion)
+		suite.NotZero(state.Metadata.UpdatedTime)
+	})
+
+	suite.Run("returns a missing secret without error", func() {
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.Require().NoError(err)
+
+		state, err := client.GetSourceState(suite.ctx, mount, "non/existent/secret")
+
+		suite.NoError(err)
+		suite.False(state.Exists)
+		suite.Nil(state.Metadata)
+	})
+
+	suite.Run("sends a single request to the main cluster", func() {
+		suite.mainVault.WriteSecret(suite.ctx, mount, keyPath, map[string]string{"password": "v1"})
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.Require().NoError(err)
+		before := client.RequestCounts()
+
+		_, err = client.GetSourceState(suite.ctx, mount, keyPath)
+
+		suite.Require().NoError(err)
+		after := client.RequestCounts()
+		suite.Equal(before[suite.mainConfig.Name]+1, after[suite.mainConfig.Name])
+		for _, replica := range suite.replicaConfig {
+			suite.Equal(before[replica.Name], after[replica.Name])
+		}
+	})
+
+	suite.Run("returns error for empty mount", func() {
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.Require().NoError(err)
+
+		state, err := client.GetSourceState(suite.ctx, "", keyPath)
+
+		suite.ErrorContains(err, "mount cannot be empty")
+		suite.Nil(state)
+	})
+}
+
 func (suite *MultiClusterVaultClientTestSuite) TestSyncSecretToReplicas() {
 	mount := "team-a"
 	keyPath := "app/database"
@@ -864,3 +921,63 @@ func (suite *MultiClusterVaultClientTestSuite) TestDeleteSecretFromReplicas() {
 		})
 	})
 }
+
+func (suite *MultiClusterVaultClientTestSuite) TestSingleReplicaOperations() {
+	mount := "team-a"
+	keyPath := "app/database"
+	secret := map[string]string{
+		"host":     "main-db.example.com",
+		"username": "admin",
+	}
+
+	suite.Run("syncs a secret read once from main to a single replica", func() {
+		suite.mainVault.WriteSecret(suite.ctx, mount, keyPath, secret)
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.NoError(err)
+		replica1 := suite.replica1Vault.Config.ClusterName
+
+		source, err := client.ReadSecret(suite.ctx, mount, keyPath)
+		suite.NoError(err)
+		result, err := client.SyncSecretToReplica(suite.ctx, replica1, mount, keyPath, source)
+
+		suite.NoError(err)
+		suite.Equal(replica1, result.DestinationCluster)
+		suite.Equal(models.StatusSuccess, result.Status)
+		suite.Equal(source.Metadata.Version, result.SourceVersion)
+
+		data, _, err := suite.replica1Vault.ReadSecretData(suite.ctx, mount, keyPath)
+		suite.NoError(err)
+		suite.Equal(secret, data)
+
+		_, _, err = suite.replica2Vault.ReadSecretData(suite.ctx, mount, keyPath)
+		suite.ErrorContains(err, "no secret found")
+	})
+
+	suite.Run("deletes a secret from a single replica", func() {
+		suite.replica1Vault.WriteSecret(suite.ctx, mount, keyPath, secret)
+		suite.replica2Vault.WriteSecret(suite.ctx, mount, keyPath, secret)
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.NoError(err)
+		replica2 := suite.replica2Vault.Config.ClusterName
+
+		result, err := client.DeleteSecretFromReplica(suite.ctx, replica2, mount, keyPath)
+
+		suite.NoError(err)
+		suite.Equal(models.StatusDeleted, result.Status)
+		_, _, err = suite.replica2Vault.ReadSecretData(suite.ctx, mount, keyPath)
+		suite.ErrorContains(err, "no secret found")
+		_, _, err = suite.replica1Vault.ReadSecretData(suite.ctx, mount, keyPath)
+		suite.NoError(err)
+	})
+
+	suite.Run("returns error for unknown replica cluster", func() {
+		client, err := NewMultiClusterVaultClient(suite.ctx, suite.mainConfig, suite.replicaConfig)
+		suite.NoError(err)
+
+		_, err = client.SyncSecretToReplica(suite.ctx, "unknown", mount, keyPath, &SecretResponse{})
+		suite.ErrorContains(err, "replica cluster not found: unknown")
+
+		_, err = client.DeleteSecretFromReplica(suite.ctx, "unknown", mount, keyPath)
+		suite.ErrorContains(err, "replica cluster not found: unknown")
+	})
+}
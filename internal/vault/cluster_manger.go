@@ -3,16 +3,22 @@ package vault
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"vault-sync/internal/config"
+	"vault-sync/internal/tracing"
 	"vault-sync/pkg/converter"
 	"vault-sync/pkg/log"
 
+	"github.com/cenkalti/backoff/v5"
 	"github.com/hashicorp/vault-client-go"
 	"github.com/hashicorp/vault-client-go/schema"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -20,9 +26,22 @@ const (
 )
 
 type clusterManager struct {
-	client *vault.Client
-	config *config.VaultClusterConfig
-	logger zerolog.Logger
+	client     *vault.Client
+	config     *config.VaultClusterConfig
+	resilience *resiliencePolicy
+	logger     zerolog.Logger
+
+	// tokenMu serializes token checks; tokenRenewAt is when the token TTL drops below five minutes.
+	// Until then the token is trusted without looking it up, saving a request per operation.
+	tokenMu      sync.Mutex
+	tokenRenewAt time.Time
+	// tokenRejected is set when a request was rejected for an expired or revoked token, so the next token
+	// check authenticates again instead of trusting tokenRenewAt.
+	tokenRejected atomic.Bool
+	// clientToken is the token set on the client, also sent when subscribing to events.
+	clientToken atomic.Pointer[string]
+	// eventsBackoff returns the wait between reconnects of the event subscription.
+	eventsBackoff func() backoff.BackOff
 }
 
 func newClusterManager(cfg *config.VaultClusterConfig) (*clusterManager, error) {
@@ -38,28 +57,13 @@ func newClusterManager(cfg *config.VaultClusterConfig) (*clusterManager, error)
 		}
 	}
 
-	// TODO add retry options to VaultClusterConfig
-	retryMax := cfg.RetryMax
-	retryWaitMin := time.Duration(cfg.RetryWaitMin)
-	retryWaitMax := time.Duration(cfg.RetryWaitMax)
-
-	if retryMax == 0 {
-		retryMax = vault.DefaultConfiguration().RetryConfiguration.RetryMax
-	}
-	if retryWaitMin == 0 {
-		retryWaitMin = vault.DefaultConfiguration().RetryConfiguration.RetryWaitMin
-	}
-	if retryWaitMax == 0 {
-		retryWaitMax = vault.DefaultConfiguration().RetryConfiguration.RetryWaitMax
-	}
-
+	// Retries are handled by the resilience policy of the cluster manager so that
+	// they are classified consistently and counted by the circuit breaker.
 	client, err := vault.New(
 		vault.WithAddress(cfg.Address),
 		vault.WithTLS(tlsConfig),
 		vault.WithRetryConfiguration(vault.RetryConfiguration{
-			RetryWaitMin: retryWaitMin,
-			RetryWaitMax: retryWaitMax,
-			RetryMax:     retryMax,
+			RetryMax: -1,
 		}),
 	)
 
@@ -67,9 +71,10 @@ func newClusterManager(cfg *config.VaultClusterConfig) (*clusterManager, error)
 		return nil, fmt.Errorf("failed to create Vault client: %w", err)
 	}
 
-	return &clusterManager{
-		client: client,
-		config: cfg,
+	cm := &clusterManager{
+		client:     client,
+		config:     cfg,
+		resilience: newResiliencePolicy(cfg),
 		logger: log.Logger.With().
 			Str("component", "cluster_manager").
 			Str("cluster", cfg.Name).
@@ -77,23 +82,30 @@ func newClusterManager(cfg *config.VaultClusterConfig) (*clusterManager, error)
 			Str("app_role_mount", cfg.AppRoleMount).
 			Str("vault_address", cfg.Address).
 			Logger(),
-	}, nil
+	}
+	cm.resilience.onAuthExpired = func() { cm.tokenRejected.Store(true) }
+	cm.eventsBackoff = newEventsBackoff
+	return cm, nil
 }
 
 // authenticate authenticates the cluster manager with Vault using AppRole
 // It sets the client token on success.
 func (cm *clusterManager) authenticate(ctx context.Context) error {
-	logger := cm.logger.With().Str("action", "authenticate").Logger()
+	ctx, span, logger := cm.startSpan(ctx, "authenticate", "", "")
+	defer span.End()
+	logger = logger.With().Str("action", "authenticate").Logger()
 
 	logger.Info().Msg("Authenticating with Vault")
-	res, err := cm.client.Auth.AppRoleLogin(
-		ctx,
-		schema.AppRoleLoginRequest{
-			RoleId:   cm.config.AppRoleID,
-			SecretId: cm.config.AppRoleSecret,
-		},
-		vault.WithMountPath(cm.config.AppRoleMount),
-	)
+	res, err := executeWithPolicy(ctx, cm.resilience, "login", func() (*vault.Response[map[string]interface{}], error) {
+		return cm.client.Auth.AppRoleLogin(
+			ctx,
+			schema.AppRoleLoginRequest{
+				RoleId:   cm.config.AppRoleID,
+				SecretId: cm.config.AppRoleSecret,
+			},
+			vault.WithMountPath(cm.config.AppRoleMount),
+		)
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to authenticate with Vault")
 		return fmt.Errorf(
@@ -103,24 +115,66 @@ func (cm *clusterManager) authenticate(ctx context.Context) error {
 			err,
 		)
 	}
-	if setTokenErr := cm.client.SetToken(res.Auth.ClientToken); setTokenErr != nil {
+	if setTokenErr := cm.setToken(res.Auth.ClientToken); setTokenErr != nil {
 		logger.Error().Err(setTokenErr).Msg("Failed to set client token")
 		return fmt.Errorf("failed to set client token: %w", setTokenErr)
 	}
+	cm.setTokenRenewAt(int64(res.Auth.LeaseDuration))
+	return nil
+}
+
+// setToken sets the token of the client.
+func (cm *clusterManager) setToken(token string) error {
+	if err := cm.client.SetToken(token); err != nil {
+		return err
+	}
+	cm.clientToken.Store(&token)
 	return nil
 }
 
+// setTokenRenewAt records when a token with the given TTL has to be checked again.
+func (cm *clusterManager) setTokenRenewAt(ttlSeconds int64) {
+	cm.tokenRenewAt = time.Now().Add(time.Duration(ttlSeconds)*time.Second - fiveMinutes)
+}
+
+// startSpan starts the span of an operation on the cluster and returns the logger of the operation with the
+// trace ID of the span. Empty mounts and key paths are left out of the span attributes.
+func (cm *clusterManager) startSpan(
+	ctx context.Context,
+	action, mount, keyPath string,
+) (context.Context, trace.Span, zerolog.Logger) {
+	ctx, span := tracing.Start(ctx, "vault."+action, tracing.SecretAttributes(cm.config.Name, mount, keyPath)...)
+	return ctx, span, tracing.WithTraceContext(ctx, cm.logger)
+}
+
 // ensureValidToken checks if the Vault token is valid and has sufficient TTL.
 // If the token is invalid or has low TTL, it re-authenticates.
+// The token is only looked up again once its last known TTL is about to run out.
 func (cm *clusterManager) ensureValidToken(ctx context.Context) error {
 	logger := cm.logger.With().Str("action", "ensure_valid_token").Logger()
+
+	cm.tokenMu.Lock()
+	defer cm.tokenMu.Unlock()
+	tokenRejected := cm.tokenRejected.Swap(false)
+	if !tokenRejected && time.Now().Before(cm.tokenRenewAt) {
+		return nil
+	}
 	reauthenticate := func(msg string, ttlSeconds int64, err error) error {
 		logger.Warn().Int64("ttl_seconds", ttlSeconds).Err(err).Msg(msg)
 		return cm.authenticate(ctx)
 	}
+	if tokenRejected {
+		return reauthenticate("Token was rejected by Vault, re-authenticating", 0, nil)
+	}
 
 	logger.Debug().Msg("Ensuring Vault token is valid")
-	resp, err := cm.client.Auth.TokenLookUpSelf(ctx)
+	lookUp := func() (*vault.Response[map[string]interface{}], error) {
+		return cm.client.Auth.TokenLookUpSelf(ctx)
+	}
+	resp, err := executeWithPolicy(ctx, cm.resilience, "token_lookup", lookUp)
+	if errors.Is(err, ErrClusterUnavailable) {
+		return err
+	}
 	if err != nil {
 		return reauthenticate("Failed to look up token, re-authenticating", 0, err)
 	}
@@ -139,16 +193,42 @@ func (cm *clusterManager) ensureValidToken(ctx context.Context) error {
 		}
 
 		logger.Debug().Int64("ttl_seconds", ttlSeconds).Msg("Token is valid")
+		cm.setTokenRenewAt(ttlSeconds)
 		return nil
 	}
 
 	return reauthenticate("Could not determine token TTL, re-authenticating", 0, nil)
 }
 
+// status checks the token and reads the seal status of the cluster. The seal status is read without the
+// circuit breaker and the retries, so a check neither waits for nor trips the breaker.
+func (cm *clusterManager) status(ctx context.Context) ClusterStatus {
+	status := ClusterStatus{
+		Name:           cm.config.Name,
+		CircuitBreaker: cm.resilience.circuitBreaker.State(),
+	}
+	var errs []error
+	if err := cm.ensureValidToken(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("token check failed: %w", err))
+	} else {
+		status.Authenticated = true
+	}
+	resp, err := cm.client.System.SealStatus(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("seal status check failed: %w", classifyError(err)))
+	} else {
+		status.Sealed = resp.Data.Sealed
+	}
+	status.Err = errors.Join(errs...)
+	return status
+}
+
 // checkMounts checks if the specified mounts exist in the Vault cluster.
 // It returns a slice of missing mounts if any are not found.
 func (cm *clusterManager) checkMounts(ctx context.Context, mounts []string) ([]string, error) {
-	logger := cm.logger.With().
+	ctx, span, logger := cm.startSpan(ctx, "check_mounts", "", "")
+	defer span.End()
+	logger = logger.With().
 		Str("action", "check_mounts").
 		Strs("mounts", mounts).
 		Logger()
@@ -185,7 +265,10 @@ func (cm *clusterManager) checkMounts(ctx context.Context, mounts []string) ([]s
 // The mount paths are cleaned to remove trailing slashes.
 func (cm *clusterManager) retrieveSecretEngineMounts(ctx context.Context) (map[string]bool, error) {
 	logger := cm.logger.With().Str("action", "retrieve_secret_engine_mounts").Logger()
-	resp, err := cm.client.System.MountsListSecretsEngines(ctx)
+	listMounts := func() (*vault.Response[map[string]interface{}], error) {
+		return cm.client.System.MountsListSecretsEngines(ctx)
+	}
+	resp, err := executeWithPolicy(ctx, cm.resilience, "list_mounts", listMounts)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to list secret engines")
 		return nil, fmt.Errorf("failed to list secret engines: %w", err)
@@ -207,7 +290,9 @@ func (cm *clusterManager) fetchKeysUnderMount(
 	mount string,
 	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
 ) ([]string, error) {
-	logger := cm.logger.With().
+	ctx, span, logger := cm.startSpan(ctx, "fetch_keys_under_mount", mount, "")
+	defer span.End()
+	logger = logger.With().
 		Str("action", "fetch_keys_under_mount").
 		Str("mount", mount).
 		Logger()
@@ -218,10 +303,7 @@ func (cm *clusterManager) fetchKeysUnderMount(
 	}
 
 	logger.Debug().Msg("Listing keys under mount")
-	// Pre-allocate with a reasonable initial capacity to reduce allocations
-	initialCapacity := 500
-	allKeys := make([]string, 0, initialCapacity)
-	err := cm.listKeysRecursively(ctx, mount, "", &allKeys, shouldIncludeKeyPath)
+	allKeys, err := cm.listKeysRecursively(ctx, mount, shouldIncludeKeyPath)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to list keys recursively")
 		return nil, err
@@ -235,53 +317,65 @@ func (cm *clusterManager) fetchKeysUnderMount(
 	return allKeys, nil
 }
 
-// listKeysRecursively recursively lists all keys under a path.
+// listKeysRecursively lists all keys under a mount. Folders are listed concurrently by up to
+// list_concurrency workers, each request going through the rate limit and retry policy of the cluster.
+// Keys are returned in the same depth-first order as a serial walk.
 func (cm *clusterManager) listKeysRecursively(
 	ctx context.Context,
-	mount, currentPath string,
-	allKeys *[]string,
+	mount string,
 	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+) ([]string, error) {
+	return cm.newMountKeyLister(mount, shouldIncludeKeyPath).list(ctx)
+}
+
+// streamKeysUnderMount walks a mount like listKeysRecursively, but hands every key to emit as soon
+// as its folder is listed instead of collecting them.
+func (cm *clusterManager) streamKeysUnderMount(
+	ctx context.Context,
+	mount string,
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+	emit func(keyPath string) error,
 ) error {
-	listPath := ""
-	if currentPath != "" {
-		listPath = currentPath
-	}
+	ctx, span, logger := cm.startSpan(ctx, "stream_keys_under_mount", mount, "")
+	defer span.End()
+	logger = logger.With().
+		Str("action", "stream_keys_under_mount").
+		Str("mount", mount).
+		Logger()
 
-	resp, err := cm.client.Secrets.KvV2List(ctx, listPath, vault.WithMountPath(mount))
-	if err != nil {
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "no such path") {
-			return nil
-		}
-		return fmt.Errorf("failed to list path %s: %w", listPath, err)
+	if err := cm.ensureValidToken(ctx); err != nil {
+		logger.Error().Err(err).Msg("Failed to ensure valid token")
+		return err
 	}
 
-	if resp.Data.Keys == nil {
-		return nil
+	logger.Debug().Msg("Streaming keys under mount")
+	if err := cm.newMountKeyLister(mount, shouldIncludeKeyPath).stream(ctx, emit); err != nil {
+		logger.Error().Err(err).Msg("Failed to stream keys")
+		return err
 	}
 
-	for _, key := range resp.Data.Keys {
-		keyPath := key
-		if currentPath != "" {
-			keyPath = fmt.Sprintf("%s/%s", currentPath, key)
-		}
+	return nil
+}
 
-		if !strings.HasSuffix(key, "/") {
-			if shouldIncludeKeyPath(keyPath, true) {
-				*allKeys = append(*allKeys, keyPath)
-			}
-			continue
+func (cm *clusterManager) newMountKeyLister(
+	mount string,
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+) *keyLister {
+	listFolder := func(ctx context.Context, folderPath string) ([]string, error) {
+		list := func() (*vault.Response[schema.StandardListResponse], error) {
+			return cm.client.Secrets.KvV2List(ctx, folderPath, vault.WithMountPath(mount))
 		}
-		// If key ends with '/', it's a directory - recurse into it.
-		dirPath := strings.TrimRight(keyPath, "/")
-		if shouldIncludeKeyPath(dirPath, false) {
-			recursiveErr := cm.listKeysRecursively(ctx, mount, dirPath, allKeys, shouldIncludeKeyPath)
-			if recursiveErr != nil {
-				return recursiveErr
+		resp, err := executeWithPolicy(ctx, cm.resilience, "list", list)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, nil
 			}
+			return nil, fmt.Errorf("failed to list path %s: %w", folderPath, err)
 		}
+		return resp.Data.Keys, nil
 	}
 
-	return nil
+	return newKeyLister(listFolder, shouldIncludeKeyPath, cm.config.ListConcurrency)
 }
 
 // fetchSecretMetadata retrieves metadata for a secret at the given mount and key path.
@@ -289,7 +383,9 @@ func (cm *clusterManager) fetchSecretMetadata(
 	ctx context.Context,
 	mount, keyPath string,
 ) (*SecretMetadataResponse, error) {
-	logger := cm.logger.With().
+	ctx, span, logger := cm.startSpan(ctx, "fetch_secret_metadata", mount, keyPath)
+	defer span.End()
+	logger = logger.With().
 		Str("action", "fetch_secret_metadata").
 		Str("mount", mount).
 		Str("key_path", keyPath).
@@ -302,7 +398,10 @@ func (cm *clusterManager) fetchSecretMetadata(
 
 	logger.Debug().Msg("Fetching secret metadata")
 
-	resp, err := cm.client.Secrets.KvV2ReadMetadata(ctx, keyPath, vault.WithMountPath(mount))
+	readMetadata := func() (*vault.Response[schema.KvV2ReadMetadataResponse], error) {
+		return cm.client.Secrets.KvV2ReadMetadata(ctx, keyPath, vault.WithMountPath(mount))
+	}
+	resp, err := executeWithPolicy(ctx, cm.resilience, "read_metadata", readMetadata)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to read secret metadata")
 		return nil, fmt.Errorf("failed to read metadata from %s: %w", keyPath, err)
@@ -324,7 +423,9 @@ func (cm *clusterManager) fetchSecretMetadata(
 
 // secretExists checks if a secret exists at the given mount and key path.
 func (cm *clusterManager) secretExists(ctx context.Context, mount, keyPath string) (bool, error) {
-	logger := cm.logger.With().
+	ctx, span, logger := cm.startSpan(ctx, "secret_exists", mount, keyPath)
+	defer span.End()
+	logger = logger.With().
 		Str("action", "secret_exists").
 		Str("mount", mount).
 		Str("key_path", keyPath).
@@ -337,9 +438,12 @@ func (cm *clusterManager) secretExists(ctx context.Context, mount, keyPath strin
 
 	logger.Debug().Msg("Checking secret existence in cluster")
 
-	_, err := cm.client.Secrets.KvV2ReadMetadata(ctx, keyPath, vault.WithMountPath(mount))
+	readMetadata := func() (*vault.Response[schema.KvV2ReadMetadataResponse], error) {
+		return cm.client.Secrets.KvV2ReadMetadata(ctx, keyPath, vault.WithMountPath(mount))
+	}
+	_, err := executeWithPolicy(ctx, cm.resilience, "read_metadata", readMetadata)
 	if err != nil {
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "no such path") {
+		if errors.Is(err, ErrNotFound) {
 			logger.Debug().Msg("Secret does not exist")
 			return false, nil
 		}
@@ -353,7 +457,9 @@ func (cm *clusterManager) secretExists(ctx context.Context, mount, keyPath strin
 
 // readSecret reads secret data from the cluster.
 func (cm *clusterManager) readSecret(ctx context.Context, mount, keyPath string) (*SecretResponse, error) {
-	logger := cm.logger.With().Str("action", "read_secret").
+	ctx, span, logger := cm.startSpan(ctx, "read_secret", mount, keyPath)
+	defer span.End()
+	logger = logger.With().Str("action", "read_secret").
 		Str("mount", mount).
 		Str("key_path", keyPath).
 		Logger()
@@ -364,7 +470,9 @@ func (cm *clusterManager) readSecret(ctx context.Context, mount, keyPath string)
 	}
 
 	logger.Debug().Msg("Reading secret from cluster")
-	res, err := cm.client.Secrets.KvV2Read(ctx, keyPath, vault.WithMountPath(mount))
+	res, err := executeWithPolicy(ctx, cm.resilience, "read", func() (*vault.Response[schema.KvV2ReadResponse], error) {
+		return cm.client.Secrets.KvV2Read(ctx, keyPath, vault.WithMountPath(mount))
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to read secret")
 		return nil, fmt.Errorf("failed to read secret from %s: %w", keyPath, err)
@@ -386,7 +494,9 @@ func (cm *clusterManager) writeSecret(
 	mount, keyPath string,
 	data map[string]interface{},
 ) (int64, error) {
-	logger := cm.logger.With().Str("action", "write_secret").
+	ctx, span, logger := cm.startSpan(ctx, "write_secret", mount, keyPath)
+	defer span.End()
+	logger = logger.With().Str("action", "write_secret").
 		Str("mount", mount).
 		Str("key_path", keyPath).
 		Logger()
@@ -398,7 +508,10 @@ func (cm *clusterManager) writeSecret(
 
 	logger.Debug().Msg("Writing secret to cluster")
 	writeRequest := schema.KvV2WriteRequest{Data: data}
-	res, err := cm.client.Secrets.KvV2Write(ctx, keyPath, writeRequest, vault.WithMountPath(mount))
+	write := func() (*vault.Response[schema.KvV2WriteResponse], error) {
+		return cm.client.Secrets.KvV2Write(ctx, keyPath, writeRequest, vault.WithMountPath(mount))
+	}
+	res, err := executeWithPolicy(ctx, cm.resilience, "write", write)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to write secret")
 		return -1, fmt.Errorf("failed to write secret to %s/%s: %w", mount, keyPath, err)
@@ -410,7 +523,9 @@ func (cm *clusterManager) writeSecret(
 
 // deleteSecret deletes a secret from the cluster.
 func (cm *clusterManager) deleteSecret(ctx context.Context, mount, keyPath string) error {
-	logger := cm.logger.With().
+	ctx, span, logger := cm.startSpan(ctx, "delete_secret", mount, keyPath)
+	defer span.End()
+	logger = logger.With().
 		Str("action", "delete_secret").
 		Str("mount", mount).
 		Str("key_path", keyPath).
@@ -422,7 +537,9 @@ func (cm *clusterManager) deleteSecret(ctx context.Context, mount, keyPath strin
 	}
 
 	logger.Debug().Msg("Deleting secret from cluster")
-	_, err := cm.client.Secrets.KvV2DeleteMetadataAndAllVersions(ctx, keyPath, vault.WithMountPath(mount))
+	_, err := executeWithPolicy(ctx, cm.resilience, "delete", func() (*vault.Response[map[string]interface{}], error) {
+		return cm.client.Secrets.KvV2DeleteMetadataAndAllVersions(ctx, keyPath, vault.WithMountPath(mount))
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to delete secret")
 		return err
@@ -3,10 +3,16 @@ package vault
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 	"vault-sync/internal/config"
 	"vault-sync/testutil"
 
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -77,6 +83,29 @@ func (suite *ClusterManagerTestSuite) TestEnsureValidToken() {
 		suite.NoError(err)
 	})
 
+	suite.Run("does not look up the token again while its TTL is sufficient", func() {
+		clusterManager, _ := newClusterManager(suite.cfg)
+		suite.Require().NoError(clusterManager.authenticate(suite.ctx))
+		requests := clusterManager.resilience.requests.Load()
+
+		err := clusterManager.ensureValidToken(suite.ctx)
+
+		suite.NoError(err)
+		suite.Equal(requests, clusterManager.resilience.requests.Load())
+	})
+
+	suite.Run("looks up the token again when it expires within 5 minutes", func() {
+		suite.vaultHelper.SetTokenTTL(suite.ctx, suite.approleName, "4m", "10m")
+		clusterManager, _ := newClusterManager(suite.cfg)
+		suite.Require().NoError(clusterManager.authenticate(suite.ctx))
+		requests := clusterManager.resilience.requests.Load()
+
+		err := clusterManager.ensureValidToken(suite.ctx)
+
+		suite.NoError(err)
+		suite.Greater(clusterManager.resilience.requests.Load(), requests)
+	})
+
 	suite.Run("all methods check token before use", func() {
 		type MethodsTestData struct {
 			name         string
@@ -150,3 +179,58 @@ func (suite *ClusterManagerTestSuite) TestEnsureValidToken() {
 		}
 	})
 }
+
+func TestClusterManagerStatus(t *testing.T) {
+	newManager := func(t *testing.T, handler http.HandlerFunc) *clusterManager {
+		t.Helper()
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		cfg := newTestClusterConfig()
+		cfg.Address = server.URL
+		cm, err := newClusterManager(cfg)
+		require.NoError(t, err)
+		cm.tokenRenewAt = time.Now().Add(time.Hour)
+		return cm
+	}
+
+	t.Run("reports an unsealed cluster with a valid token", func(t *testing.T) {
+		cm := newManager(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/sys/seal-status", r.URL.Path)
+			_, _ = w.Write([]byte(`{"sealed": false}`))
+		})
+
+		status := cm.status(context.Background())
+
+		require.NoError(t, status.Err)
+		assert.Equal(t, "test-cluster", status.Name)
+		assert.True(t, status.Authenticated)
+		assert.False(t, status.Sealed)
+		assert.Equal(t, gobreaker.StateClosed, status.CircuitBreaker)
+	})
+
+	t.Run("reports a sealed cluster", func(t *testing.T) {
+		cm := newManager(t, func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"sealed": true}`))
+		})
+
+		status := cm.status(context.Background())
+
+		require.NoError(t, status.Err)
+		assert.True(t, status.Sealed)
+	})
+
+	t.Run("reports the checks that failed", func(t *testing.T) {
+		cm := newManager(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		cm.tokenRejected.Store(true)
+
+		status := cm.status(context.Background())
+
+		require.Error(t, status.Err)
+		assert.Contains(t, status.Err.Error(), "token check failed")
+		assert.Contains(t, status.Err.Error(), "seal status check failed")
+		assert.False(t, status.Authenticated)
+		assert.False(t, status.Sealed)
+	})
+}
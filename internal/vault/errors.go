@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault-client-go"
+
+	"vault-sync/internal/models"
+)
+
+// Error is a failed Vault request classified by its response. It wraps the error of the request, so the
+// vault-client-go ResponseError stays reachable with errors.As.
+//
+// The sentinel errors below match any Error of the same category:
+//
+//	if errors.Is(err, vault.ErrNotFound) { ... }
+type Error struct {
+	Category   models.ErrorCategory
+	StatusCode int
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return "vault: " + e.Category.String()
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel errors by category.
+func (e *Error) Is(target error) bool {
+	sentinel, ok := target.(*Error)
+	return ok && sentinel.Err == nil && sentinel.Category == e.Category
+}
+
+//nolint:gochecknoglobals
+var (
+	ErrNotFound         error = &Error{Category: models.ErrorCategoryNotFound}
+	ErrPermissionDenied error = &Error{Category: models.ErrorCategoryPermissionDenied}
+	ErrAuthExpired      error = &Error{Category: models.ErrorCategoryAuthExpired}
+	ErrUnavailable      error = &Error{Category: models.ErrorCategoryUnavailable}
+	ErrRateLimited      error = &Error{Category: models.ErrorCategoryRateLimited}
+	ErrCASConflict      error = &Error{Category: models.ErrorCategoryCASConflict}
+	ErrNetwork          error = &Error{Category: models.ErrorCategoryNetwork}
+)
+
+// ErrClusterUnavailable is returned when the circuit breaker of a cluster is open
+// and requests are rejected without being sent to Vault. It matches ErrUnavailable.
+//
+//nolint:gochecknoglobals
+var ErrClusterUnavailable error = &Error{
+	Category: models.ErrorCategoryUnavailable,
+	Err:      errors.New("vault cluster is unavailable: circuit breaker is open"),
+}
+
+// ErrorCategoryOf returns the category of err: the category of a wrapped Error, or the one derived from a
+// wrapped ResponseError or network error. Any other error is ErrorCategoryUnknown.
+func ErrorCategoryOf(err error) models.ErrorCategory {
+	var vaultErr *Error
+	if errors.As(err, &vaultErr) {
+		return vaultErr.Category
+	}
+
+	var responseErr *vault.ResponseError
+	if errors.As(err, &responseErr) {
+		return categoryOfResponse(responseErr)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return models.ErrorCategoryNetwork
+	}
+	return models.ErrorCategoryUnknown
+}
+
+// categoryOfResponse maps the status code of a Vault response to its category. Vault answers both a missing
+// permission and an expired token with 403, so the messages tell them apart; a 400 is a CAS conflict when
+// its message says so.
+func categoryOfResponse(responseErr *vault.ResponseError) models.ErrorCategory {
+	switch {
+	case responseErr.StatusCode == http.StatusNotFound:
+		return models.ErrorCategoryNotFound
+	case responseErr.StatusCode == http.StatusUnauthorized:
+		return models.ErrorCategoryAuthExpired
+	case responseErr.StatusCode == http.StatusForbidden:
+		if responseMentions(responseErr, "invalid token", "token expired") {
+			return models.ErrorCategoryAuthExpired
+		}
+		return models.ErrorCategoryPermissionDenied
+	case responseErr.StatusCode == http.StatusBadRequest && responseMentions(responseErr, "check-and-set"):
+		return models.ErrorCategoryCASConflict
+	case responseErr.StatusCode == http.StatusTooManyRequests:
+		return models.ErrorCategoryRateLimited
+	case responseErr.StatusCode == http.StatusPreconditionFailed,
+		responseErr.StatusCode >= http.StatusInternalServerError:
+		return models.ErrorCategoryUnavailable
+	default:
+		return models.ErrorCategoryUnknown
+	}
+}
+
+func responseMentions(responseErr *vault.ResponseError, phrases ...string) bool {
+	for _, message := range responseErr.Errors {
+		for _, phrase := range phrases {
+			if strings.Contains(message, phrase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classifyError wraps the error of a request in an Error carrying its category. Cancellations, errors that
+// are already classified and errors of an unknown category are returned unchanged.
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var vaultErr *Error
+	if errors.As(err, &vaultErr) {
+		return err
+	}
+
+	category := ErrorCategoryOf(err)
+	if category == models.ErrorCategoryUnknown {
+		return err
+	}
+	classified := &Error{Category: category, Err: err}
+	var responseErr *vault.ResponseError
+	if errors.As(err, &responseErr) {
+		classified.StatusCode = responseErr.StatusCode
+	}
+	return classified
+}
@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vault-sync/internal/models"
+)
+
+func TestErrorCategoryOf(t *testing.T) {
+	responseErr := func(statusCode int, messages ...string) error {
+		return &vault.ResponseError{StatusCode: statusCode, Errors: messages}
+	}
+	testCases := []struct {
+		name     string
+		err      error
+		category models.ErrorCategory
+	}{
+		{name: "not found", err: responseErr(http.StatusNotFound), category: models.ErrorCategoryNotFound},
+		{
+			name:     "permission denied",
+			err:      responseErr(http.StatusForbidden, "permission denied"),
+			category: models.ErrorCategoryPermissionDenied,
+		},
+		{
+			name:     "invalid token",
+			err:      responseErr(http.StatusForbidden, "2 errors occurred:\n\t* permission denied\n\t* invalid token"),
+			category: models.ErrorCategoryAuthExpired,
+		},
+		{name: "unauthorized", err: responseErr(http.StatusUnauthorized), category: models.ErrorCategoryAuthExpired},
+		{
+			name:     "cas conflict",
+			err:      responseErr(http.StatusBadRequest, "check-and-set parameter did not match the current version"),
+			category: models.ErrorCategoryCASConflict,
+		},
+		{name: "bad request", err: responseErr(http.StatusBadRequest, "invalid"), category: models.ErrorCategoryUnknown},
+		{name: "rate limited", err: responseErr(http.StatusTooManyRequests), category: models.ErrorCategoryRateLimited},
+		{name: "sealed", err: responseErr(http.StatusServiceUnavailable), category: models.ErrorCategoryUnavailable},
+		{name: "internal error", err: responseErr(http.StatusInternalServerError), category: models.ErrorCategoryUnavailable},
+		{name: "index not ready", err: responseErr(http.StatusPreconditionFailed), category: models.ErrorCategoryUnavailable},
+		{
+			name:     "network error",
+			err:      &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			category: models.ErrorCategoryNetwork,
+		},
+		{
+			name:     "circuit breaker open",
+			err:      fmt.Errorf("%w (cluster replica)", ErrClusterUnavailable),
+			category: models.ErrorCategoryUnavailable,
+		},
+		{
+			name:     "wrapped response error",
+			err:      fmt.Errorf("failed to read: %w", responseErr(http.StatusNotFound)),
+			category: models.ErrorCategoryNotFound,
+		},
+		{name: "unknown error", err: errors.New("boom"), category: models.ErrorCategoryUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.category, ErrorCategoryOf(tc.err))
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Run("wraps response errors in a typed error", func(t *testing.T) {
+		responseErr := &vault.ResponseError{StatusCode: http.StatusNotFound}
+
+		err := fmt.Errorf("failed to read metadata: %w", classifyError(responseErr))
+
+		require.ErrorIs(t, err, ErrNotFound)
+		assert.NotErrorIs(t, err, ErrPermissionDenied)
+		var vaultErr *Error
+		require.ErrorAs(t, err, &vaultErr)
+		assert.Equal(t, http.StatusNotFound, vaultErr.StatusCode)
+		var unwrapped *vault.ResponseError
+		require.ErrorAs(t, err, &unwrapped)
+		assert.Same(t, responseErr, unwrapped)
+	})
+
+	t.Run("leaves unknown and cancelled errors unchanged", func(t *testing.T) {
+		unknownErr := errors.New("boom")
+
+		assert.Same(t, unknownErr, classifyError(unknownErr))
+		assert.Equal(t, context.Canceled, classifyError(context.Canceled))
+		assert.NoError(t, classifyError(nil))
+	})
+
+	t.Run("matches an open circuit breaker as unavailable", func(t *testing.T) {
+		err := fmt.Errorf("%w (cluster replica)", ErrClusterUnavailable)
+
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.ErrorIs(t, err, ErrClusterUnavailable)
+		assert.NotErrorIs(t, classifyError(&vault.ResponseError{StatusCode: http.StatusBadGateway}), ErrClusterUnavailable)
+	})
+}
+
+func TestErrorCategory_IsTransient(t *testing.T) {
+	transient := []models.ErrorCategory{
+		models.ErrorCategoryAuthExpired, models.ErrorCategoryUnavailable, models.ErrorCategoryRateLimited,
+		models.ErrorCategoryCASConflict, models.ErrorCategoryNetwork,
+	}
+	permanent := []models.ErrorCategory{
+		models.ErrorCategoryNotFound, models.ErrorCategoryPermissionDenied, models.ErrorCategoryUnknown,
+	}
+
+	for _, category := range transient {
+		assert.True(t, category.IsTransient(), category)
+	}
+	for _, category := range permanent {
+		assert.False(t, category.IsTransient(), category)
+	}
+	assert.Equal(t, models.ErrorCategoryUnavailable, failureCategory(context.DeadlineExceeded))
+}
@@ -0,0 +1,238 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/gorilla/websocket"
+
+	"vault-sync/internal/metrics"
+)
+
+const (
+	eventsInitialBackoff   = time.Second
+	eventsMaxBackoff       = 5 * time.Minute
+	eventsHandshakeTimeout = 30 * time.Second
+)
+
+// kvOperationPrefixes are the API path segments between the mount and the key path of KV v2 events.
+//
+//nolint:gochecknoglobals
+var kvOperationPrefixes = []string{"data/", "metadata/", "delete/", "undelete/", "destroy/"}
+
+// SecretEvent is a change of a secret on the main cluster, reported by a Vault event notification.
+type SecretEvent struct {
+	EventType string
+	Mount     string
+	KeyPath   string
+}
+
+// vaultEvent is the part of a Vault event notification, a CloudEvents envelope, that names the changed secret.
+type vaultEvent struct {
+	Data struct {
+		EventType string `json:"event_type"`
+		Event     struct {
+			Metadata struct {
+				Path string `json:"path"`
+			} `json:"metadata"`
+		} `json:"event"`
+		PluginInfo struct {
+			MountPath string `json:"mount_path"`
+		} `json:"plugin_info"`
+	} `json:"data"`
+}
+
+// SubscribeSecretEvents subscribes to the event notifications of eventType on the main cluster and hands every
+// secret change to handle until ctx is done. Vault 1.13 or later serves them on sys/events/subscribe. A lost or
+// refused subscription is retried with a backoff of up to five minutes; changes made in the meantime are only
+// picked up by the next interval sync.
+func (mc *MultiClusterVaultClient) SubscribeSecretEvents(
+	ctx context.Context,
+	eventType string,
+	handle func(SecretEvent),
+) {
+	mc.mainCluster.subscribeEvents(ctx, eventType, handle)
+}
+
+func (cm *clusterManager) subscribeEvents(ctx context.Context, eventType string, handle func(SecretEvent)) {
+	logger := cm.logger.With().Str("action", "subscribe_events").Str("event_type", eventType).Logger()
+	reconnectBackoff := cm.eventsBackoff()
+	for {
+		connected, err := cm.streamEvents(ctx, eventType, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			reconnectBackoff.Reset()
+		}
+		wait := reconnectBackoff.NextBackOff()
+		logger.Warn().Err(err).Dur("retry_in", wait).
+			Msg("Vault event subscription unavailable, relying on interval sync until it reconnects")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// streamEvents subscribes once and reads events until the connection is lost or ctx is done. It reports
+// whether the subscription was established.
+func (cm *clusterManager) streamEvents(
+	ctx context.Context,
+	eventType string,
+	handle func(SecretEvent),
+) (bool, error) {
+	logger := cm.logger.With().Str("action", "stream_events").Str("event_type", eventType).Logger()
+	if err := cm.ensureValidToken(ctx); err != nil {
+		return false, fmt.Errorf("token check failed: %w", err)
+	}
+	subscribeURL, err := eventsURL(cm.config.Address, eventType)
+	if err != nil {
+		return false, err
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: eventsHandshakeTimeout,
+		TLSClientConfig:  cm.tlsClientConfig(),
+	}
+	header := http.Header{}
+	if token := cm.clientToken.Load(); token != nil {
+		header.Set("X-Vault-Token", *token)
+	}
+	//nolint:bodyclose // the handshake response body is closed by the dialer
+	conn, response, err := dialer.DialContext(ctx, subscribeURL, header)
+	if err != nil {
+		return false, cm.subscribeError(eventType, response, err)
+	}
+	defer conn.Close()
+	stopClosing := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stopClosing()
+
+	metrics.SetEventSubscriptionConnected(true)
+	defer metrics.SetEventSubscriptionConnected(false)
+	logger.Info().Msg("Subscribed to Vault event notifications")
+
+	for {
+		_, message, readErr := conn.ReadMessage()
+		if readErr != nil {
+			return true, fmt.Errorf("event subscription closed: %w", readErr)
+		}
+		event, ok, parseErr := parseSecretEvent(message)
+		if parseErr != nil {
+			logger.Warn().Err(parseErr).Msg("Ignoring unreadable Vault event")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		metrics.ObserveSecretEvent(event.EventType)
+		logger.Debug().
+			Str("received_event_type", event.EventType).
+			Str("mount", event.Mount).
+			Str("key_path", event.KeyPath).
+			Msg("Received secret event")
+		handle(event)
+	}
+}
+
+// tlsClientConfig returns the TLS config of the Vault client, so the subscription trusts the same certificates.
+func (cm *clusterManager) tlsClientConfig() *tls.Config {
+	transport, ok := cm.client.Configuration().HTTPClient.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil {
+		return nil
+	}
+	return transport.TLSClientConfig.Clone()
+}
+
+// subscribeError describes a refused subscription. A 403 may be an expired token, so the next attempt checks
+// the token again.
+func (cm *clusterManager) subscribeError(eventType string, response *http.Response, err error) error {
+	if response == nil {
+		return fmt.Errorf("failed to subscribe to %s events: %w", eventType, err)
+	}
+	switch response.StatusCode {
+	case http.StatusForbidden:
+		cm.tokenRejected.Store(true)
+		return fmt.Errorf("failed to subscribe to %s events, permission denied: %w", eventType, ErrPermissionDenied)
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return fmt.Errorf("failed to subscribe to %s events: event notifications are not available on this "+
+			"Vault (%s)", eventType, response.Status)
+	default:
+		return fmt.Errorf("failed to subscribe to %s events: %s", eventType, response.Status)
+	}
+}
+
+// eventsURL returns the websocket URL of the event subscription on the cluster at address.
+func eventsURL(address, eventType string) (string, error) {
+	subscribeURL, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid Vault address %q: %w", address, err)
+	}
+	switch subscribeURL.Scheme {
+	case "https":
+		subscribeURL.Scheme = "wss"
+	case "http":
+		subscribeURL.Scheme = "ws"
+	default:
+		return "", fmt.Errorf("invalid Vault address %q: unsupported scheme", address)
+	}
+	subscribeURL = subscribeURL.JoinPath("v1/sys/events/subscribe", eventType)
+	subscribeURL.RawQuery = "json=true"
+	return subscribeURL.String(), nil
+}
+
+// parseSecretEvent reads the secret changed by a Vault event. It reports false for events that are not about a
+// KV v2 secret.
+func parseSecretEvent(message []byte) (SecretEvent, bool, error) {
+	var event vaultEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return SecretEvent{}, false, fmt.Errorf("invalid event: %w", err)
+	}
+	if !strings.HasPrefix(event.Data.EventType, "kv-v2/") {
+		return SecretEvent{}, false, nil
+	}
+
+	mountPath := event.Data.PluginInfo.MountPath
+	path := event.Data.Event.Metadata.Path
+	if mountPath == "" || path == "" {
+		return SecretEvent{}, false, errors.New("event without mount or path")
+	}
+	rest, found := strings.CutPrefix(path, mountPath)
+	if !found {
+		return SecretEvent{}, false, fmt.Errorf("event path %q is not under mount %q", path, mountPath)
+	}
+	for _, prefix := range kvOperationPrefixes {
+		if keyPath, ok := strings.CutPrefix(rest, prefix); ok && keyPath != "" {
+			return SecretEvent{
+				EventType: event.Data.EventType,
+				Mount:     strings.TrimSuffix(mountPath, "/"),
+				KeyPath:   strings.TrimSuffix(keyPath, "/"),
+			}, true, nil
+		}
+	}
+	return SecretEvent{}, false, nil
+}
+
+func newEventsBackoff() backoff.BackOff {
+	eventsBackoff := &backoff.ExponentialBackOff{
+		InitialInterval:     eventsInitialBackoff,
+		RandomizationFactor: backoff.DefaultRandomizationFactor,
+		Multiplier:          backoff.DefaultMultiplier,
+		MaxInterval:         eventsMaxBackoff,
+	}
+	eventsBackoff.Reset()
+	return eventsBackoff
+}
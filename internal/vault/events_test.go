@@ -0,0 +1,261 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// kvEvent returns a Vault event notification of a KV v2 operation, as sent with json=true.
+func kvEvent(eventType, mountPath, path string) string {
+	return fmt.Sprintf(`{
+		"id": "a3be9fb1-b514-519f-5b25-b6f144a8c1ce",
+		"source": "https://vaultproject.io/",
+		"specversion": "1.0",
+		"type": "*",
+		"data": {
+			"event": {
+				"id": "a3be9fb1-b514-519f-5b25-b6f144a8c1ce",
+				"metadata": {"current_version": "2", "modified": "true", "path": %q}
+			},
+			"event_type": %q,
+			"plugin_info": {"mount_class": "secret", "mount_path": %q, "plugin": "kv"}
+		},
+		"datacontentype": "application/cloudevents",
+		"time": "2026-10-18T12:00:00Z"
+	}`, path, eventType, mountPath)
+}
+
+func TestParseSecretEvent(t *testing.T) {
+	testCases := []struct {
+		name    string
+		message string
+		event   SecretEvent
+		ok      bool
+		err     string
+	}{
+		{
+			name:    "data write",
+			message: kvEvent("kv-v2/data-write", "secret/", "secret/data/app/database"),
+			event:   SecretEvent{EventType: "kv-v2/data-write", Mount: "secret", KeyPath: "app/database"},
+			ok:      true,
+		},
+		{
+			name:    "soft delete",
+			message: kvEvent("kv-v2/delete", "team-a/", "team-a/delete/app/cache"),
+			event:   SecretEvent{EventType: "kv-v2/delete", Mount: "team-a", KeyPath: "app/cache"},
+			ok:      true,
+		},
+		{
+			name:    "metadata delete",
+			message: kvEvent("kv-v2/metadata-delete", "secret/", "secret/metadata/app/database"),
+			event:   SecretEvent{EventType: "kv-v2/metadata-delete", Mount: "secret", KeyPath: "app/database"},
+			ok:      true,
+		},
+		{
+			name:    "nested mount",
+			message: kvEvent("kv-v2/destroy", "teams/platform/", "teams/platform/destroy/app/database"),
+			event:   SecretEvent{EventType: "kv-v2/destroy", Mount: "teams/platform", KeyPath: "app/database"},
+			ok:      true,
+		},
+		{name: "other plugin", message: kvEvent("database/rotate", "db/", "db/rotate-root/main")},
+		{name: "mount configuration", message: kvEvent("kv-v2/config-write", "secret/", "secret/config")},
+		{name: "invalid json", message: `{"data":`, err: "invalid event"},
+		{name: "no mount", message: kvEvent("kv-v2/data-write", "", "secret/data/app"), err: "without mount"},
+		{
+			name:    "path outside the mount",
+			message: kvEvent("kv-v2/data-write", "secret/", "other/data/app"),
+			err:     "is not under mount",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event, ok, err := parseSecretEvent([]byte(tc.message))
+
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.event, event)
+		})
+	}
+}
+
+func TestEventsURL(t *testing.T) {
+	subscribeURL, err := eventsURL("https://vault.example.com:8200", "kv-v2/*")
+	require.NoError(t, err)
+	assert.Equal(t, "wss://vault.example.com:8200/v1/sys/events/subscribe/kv-v2/*?json=true", subscribeURL)
+
+	subscribeURL, err = eventsURL("http://127.0.0.1:8200/", "kv-v2/data-write")
+	require.NoError(t, err)
+	assert.Equal(t, "ws://127.0.0.1:8200/v1/sys/events/subscribe/kv-v2/data-write?json=true", subscribeURL)
+
+	_, err = eventsURL("unix:///var/run/vault.sock", "kv-v2/*")
+	require.Error(t, err)
+}
+
+// eventStandIn stands in for the event subscription endpoint of Vault. Each connection is refused with the next
+// refusal status, if any, and otherwise sent the next batch of messages before it is closed. The last batch is
+// kept open until the test ends.
+type eventStandIn struct {
+	t           *testing.T
+	refusals    []int
+	batches     [][]string
+	connections atomic.Int32
+	mu          sync.Mutex
+	done        chan struct{}
+}
+
+func (s *eventStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(s.t, "/v1/sys/events/subscribe/kv-v2/*", r.URL.Path)
+	assert.Equal(s.t, "true", r.URL.Query().Get("json"))
+	assert.Equal(s.t, "test-token", r.Header.Get("X-Vault-Token"))
+
+	s.mu.Lock()
+	if len(s.refusals) > 0 {
+		status := s.refusals[0]
+		s.refusals = s.refusals[1:]
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		return
+	}
+	batch := s.batches[0]
+	last := len(s.batches) == 1
+	if !last {
+		s.batches = s.batches[1:]
+	}
+	s.mu.Unlock()
+
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if !assert.NoError(s.t, err) {
+		return
+	}
+	defer conn.Close()
+	s.connections.Add(1)
+	for _, message := range batch {
+		if !assert.NoError(s.t, conn.WriteMessage(websocket.TextMessage, []byte(message))) {
+			return
+		}
+	}
+	if last {
+		<-s.done
+	}
+}
+
+func newEventsManager(t *testing.T, standIn *eventStandIn) *clusterManager {
+	t.Helper()
+	standIn.t = t
+	standIn.done = make(chan struct{})
+	server := httptest.NewServer(standIn)
+	t.Cleanup(func() {
+		close(standIn.done)
+		server.Close()
+	})
+	cfg := newTestClusterConfig()
+	cfg.Address = server.URL
+	cm, err := newClusterManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, cm.setToken("test-token"))
+	cm.tokenRenewAt = time.Now().Add(time.Hour)
+	cm.eventsBackoff = func() backoff.BackOff { return backoff.NewConstantBackOff(time.Millisecond) }
+	return cm
+}
+
+// collect subscribes until want events were received and returns them.
+func collect(t *testing.T, cm *clusterManager, want int) []SecretEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var events []SecretEvent
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cm.subscribeEvents(ctx, "kv-v2/*", func(event SecretEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) >= want
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription did not stop when its context was cancelled")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return events
+}
+
+func TestSubscribeSecretEvents(t *testing.T) {
+	t.Run("hands the secret changes to the handler", func(t *testing.T) {
+		standIn := &eventStandIn{batches: [][]string{{
+			kvEvent("kv-v2/data-write", "secret/", "secret/data/app/database"),
+			kvEvent("database/rotate", "db/", "db/rotate-root/main"),
+			`not json`,
+			kvEvent("kv-v2/delete", "secret/", "secret/delete/app/cache"),
+		}}}
+		cm := newEventsManager(t, standIn)
+
+		events := collect(t, cm, 2)
+
+		assert.Equal(t, []SecretEvent{
+			{EventType: "kv-v2/data-write", Mount: "secret", KeyPath: "app/database"},
+			{EventType: "kv-v2/delete", Mount: "secret", KeyPath: "app/cache"},
+		}, events)
+	})
+
+	t.Run("reconnects after the subscription was closed", func(t *testing.T) {
+		standIn := &eventStandIn{batches: [][]string{
+			{kvEvent("kv-v2/data-write", "secret/", "secret/data/app/database")},
+			{kvEvent("kv-v2/data-write", "secret/", "secret/data/app/cache")},
+		}}
+		cm := newEventsManager(t, standIn)
+
+		events := collect(t, cm, 2)
+
+		assert.Equal(t, []string{"app/database", "app/cache"}, []string{events[0].KeyPath, events[1].KeyPath})
+		assert.Equal(t, int32(2), standIn.connections.Load())
+	})
+
+	t.Run("keeps retrying while events are unavailable", func(t *testing.T) {
+		standIn := &eventStandIn{
+			refusals: []int{http.StatusNotFound, http.StatusServiceUnavailable},
+			batches:  [][]string{{kvEvent("kv-v2/data-write", "secret/", "secret/data/app/database")}},
+		}
+		cm := newEventsManager(t, standIn)
+
+		events := collect(t, cm, 1)
+
+		assert.Equal(t, "app/database", events[0].KeyPath)
+	})
+
+	t.Run("checks the token again after a permission denied", func(t *testing.T) {
+		standIn := &eventStandIn{refusals: []int{http.StatusForbidden}}
+		cm := newEventsManager(t, standIn)
+
+		connected, err := cm.streamEvents(context.Background(), "kv-v2/*", func(SecretEvent) {})
+
+		assert.False(t, connected)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		assert.True(t, cm.tokenRejected.Load())
+	})
+}
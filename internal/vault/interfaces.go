@@ -10,16 +10,31 @@ import (
 type Syncer interface {
 	GetSecretMounts(ctx context.Context, secretPaths []string) ([]string, error)
 	GetSecretMetadata(ctx context.Context, mount, keyPath string) (*SecretMetadataResponse, error)
+	GetSourceState(ctx context.Context, mount, keyPath string) (*SourceState, error)
 	GetKeysUnderMount(
 		ctx context.Context,
 		mount string,
 		shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
 	) ([]string, error)
+	StreamKeysUnderMount(
+		ctx context.Context,
+		mount string,
+		shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+		emit func(keyPath string) error,
+	) error
 	SecretExists(ctx context.Context, mount, keyPath string) (bool, error)
 	SecretExistsInReplica(ctx context.Context, clusterName, mount, path string) (bool, error)
 	SyncSecretToReplicas(ctx context.Context, mount, keyPath string) ([]*models.SyncedSecret, error)
 	DeleteSecretFromReplicas(ctx context.Context, mount, keyPath string) ([]*models.SyncSecretDeletionResult, error)
+	ReadSecret(ctx context.Context, mount, keyPath string) (*SecretResponse, error)
+	SyncSecretToReplica(
+		ctx context.Context, clusterName, mount, keyPath string, sourceSecret *SecretResponse,
+	) (*models.SyncedSecret, error)
+	DeleteSecretFromReplica(
+		ctx context.Context, clusterName, mount, keyPath string,
+	) (*models.SyncSecretDeletionResult, error)
 	GetReplicaNames() []string
+	RequestCounts() map[string]int64
 }
 
 // replicaSyncOperationResult is an interface that defines the methods required for a result
@@ -28,6 +43,7 @@ type replicaSyncOperationResult interface {
 	*models.SyncedSecret | *models.SyncSecretDeletionResult
 	SetStatus(status models.SyncStatus)
 	SetErrorMessage(msg *string)
+	SetErrorCategory(category models.ErrorCategory)
 	SetLastSuccessAttempt(t *time.Time)
 	GetStatus() models.SyncStatus
 	GetDestinationCluster() string
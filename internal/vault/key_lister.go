@@ -0,0 +1,193 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//nolint:gochecknoglobals,mnd
+var defaultListConcurrency = 8
+
+// listFolderFunc lists the direct children of a folder. Folders end with '/'.
+// A missing folder is reported as an empty listing.
+type listFolderFunc func(ctx context.Context, folderPath string) ([]string, error)
+
+// keyLister walks a KV tree with a bounded pool of workers.
+//
+// Every folder is listed once by one of the workers; folder listings are kept in a tree that mirrors
+// the KV structure, so the flattened result has the same depth-first order as a serial walk no matter
+// in which order the folders were listed. The first error stops the walk.
+//
+// When keys are streamed instead, they are handed to emit as soon as their folder is listed and no tree
+// is kept, so memory stays bounded by the folders waiting to be listed rather than the size of the mount.
+type keyLister struct {
+	listFolder           listFolderFunc
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool
+	concurrency          int
+	emit                 func(keyPath string) error
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*folderNode
+	pending int
+	err     error
+
+	// includeMu serializes the pruning callback, which is not required to be safe for concurrent use.
+	includeMu sync.Mutex
+}
+
+// folderNode holds the listing of one folder in listing order. Each entry is either a key or a sub folder.
+type folderNode struct {
+	path    string
+	entries []folderEntry
+}
+
+type folderEntry struct {
+	keyPath string
+	folder  *folderNode
+}
+
+func newKeyLister(
+	listFolder listFolderFunc,
+	shouldIncludeKeyPath func(path string, isFinalPath bool) bool,
+	concurrency int,
+) *keyLister {
+	if concurrency <= 0 {
+		concurrency = defaultListConcurrency
+	}
+
+	lister := &keyLister{
+		listFolder:           listFolder,
+		shouldIncludeKeyPath: shouldIncludeKeyPath,
+		concurrency:          concurrency,
+	}
+	lister.cond = sync.NewCond(&lister.mu)
+	return lister
+}
+
+// list walks the tree below the root folder and returns all included keys in depth-first order.
+func (l *keyLister) list(ctx context.Context) ([]string, error) {
+	root := &folderNode{}
+	if err := l.walk(ctx, root); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	return flattenFolder(root, keys), nil
+}
+
+// stream walks the tree below the root folder and calls emit for every included key as soon as its
+// folder is listed. Keys are emitted in no particular order and emit may be called from several
+// goroutines at once. An error returned by emit stops the walk and is returned.
+func (l *keyLister) stream(ctx context.Context, emit func(keyPath string) error) error {
+	l.emit = emit
+	return l.walk(ctx, &folderNode{})
+}
+
+func (l *keyLister) walk(ctx context.Context, root *folderNode) error {
+	l.queue = []*folderNode{root}
+	l.pending = 1
+
+	var wg sync.WaitGroup
+	for range l.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return l.err
+}
+
+func (l *keyLister) work(ctx context.Context) {
+	for {
+		l.mu.Lock()
+		for len(l.queue) == 0 && l.pending > 0 && l.err == nil {
+			l.cond.Wait()
+		}
+		if l.err != nil || l.pending == 0 {
+			l.mu.Unlock()
+			return
+		}
+		// Take the most recently discovered folder first to keep the queue short on deep trees.
+		node := l.queue[len(l.queue)-1]
+		l.queue = l.queue[:len(l.queue)-1]
+		l.mu.Unlock()
+
+		subFolders, err := l.listNode(ctx, node)
+
+		l.mu.Lock()
+		if err != nil && l.err == nil {
+			l.err = err
+		}
+		l.queue = append(l.queue, subFolders...)
+		l.pending += len(subFolders) - 1
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	}
+}
+
+// listNode lists a single folder, records its included entries and returns the sub folders to walk.
+func (l *keyLister) listNode(ctx context.Context, node *folderNode) ([]*folderNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keys, err := l.listFolder(ctx, node.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var subFolders []*folderNode
+	for _, key := range keys {
+		keyPath := key
+		if node.path != "" {
+			keyPath = fmt.Sprintf("%s/%s", node.path, key)
+		}
+
+		if !strings.HasSuffix(key, "/") {
+			if !l.include(keyPath, true) {
+				continue
+			}
+			if l.emit == nil {
+				node.entries = append(node.entries, folderEntry{keyPath: keyPath})
+			} else if err := l.emit(keyPath); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// If key ends with '/', it's a directory - walk into it.
+		dirPath := strings.TrimRight(keyPath, "/")
+		if l.include(dirPath, false) {
+			subFolder := &folderNode{path: dirPath}
+			if l.emit == nil {
+				node.entries = append(node.entries, folderEntry{folder: subFolder})
+			}
+			subFolders = append(subFolders, subFolder)
+		}
+	}
+
+	return subFolders, nil
+}
+
+func (l *keyLister) include(path string, isFinalPath bool) bool {
+	l.includeMu.Lock()
+	defer l.includeMu.Unlock()
+	return l.shouldIncludeKeyPath(path, isFinalPath)
+}
+
+func flattenFolder(node *folderNode, keys []string) []string {
+	for _, entry := range node.entries {
+		if entry.folder != nil {
+			keys = flattenFolder(entry.folder, keys)
+			continue
+		}
+		keys = append(keys, entry.keyPath)
+	}
+	return keys
+}
@@ -0,0 +1,199 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKVTree lists folders from a static tree, with a random delay so folders complete out of order.
+type fakeKVTree struct {
+	folders  map[string][]string
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+	errPath  string
+}
+
+func (f *fakeKVTree) listFolder(ctx context.Context, folderPath string) ([]string, error) {
+	current := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		seen := f.maxSeen.Load()
+		if current <= seen || f.maxSeen.CompareAndSwap(seen, current) {
+			break
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Duration(rand.IntN(3)) * time.Millisecond): //nolint:gosec
+	}
+
+	if f.errPath != "" && folderPath == f.errPath {
+		return nil, fmt.Errorf("failed to list path %s: permission denied", folderPath)
+	}
+	return f.folders[folderPath], nil
+}
+
+func newFakeKVTree() *fakeKVTree {
+	folders := map[string][]string{
+		"": {"app/", "db-password", "team/"},
+	}
+	for i := range 20 {
+		appFolder := fmt.Sprintf("app/service-%02d", i)
+		folders["app"] = append(folders["app"], fmt.Sprintf("service-%02d/", i))
+		folders[appFolder] = []string{"config", "nested/", "token"}
+		folders[appFolder+"/nested"] = []string{"deep"}
+	}
+	folders["team"] = []string{"ignored/", "owner"}
+	folders["team/ignored"] = []string{"secret"}
+	return &fakeKVTree{folders: folders}
+}
+
+// serialWalk is the reference depth-first order.
+func serialWalk(tree *fakeKVTree, path string, include func(string, bool) bool) []string {
+	var keys []string
+	for _, key := range tree.folders[path] {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "/" + key
+		}
+		if !strings.HasSuffix(key, "/") {
+			if include(keyPath, true) {
+				keys = append(keys, keyPath)
+			}
+			continue
+		}
+		dirPath := strings.TrimRight(keyPath, "/")
+		if include(dirPath, false) {
+			keys = append(keys, serialWalk(tree, dirPath, include)...)
+		}
+	}
+	return keys
+}
+
+func TestKeyLister(t *testing.T) {
+	includeAll := func(string, bool) bool { return true }
+
+	t.Run("returns keys in depth-first order", func(t *testing.T) {
+		tree := newFakeKVTree()
+
+		for range 5 {
+			keys, err := newKeyLister(tree.listFolder, includeAll, 8).list(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, serialWalk(tree, "", includeAll), keys)
+		}
+		assert.Len(t, serialWalk(tree, "", includeAll), 63)
+	})
+
+	t.Run("prunes folders and keys with the callback", func(t *testing.T) {
+		tree := newFakeKVTree()
+		include := func(path string, isFinalPath bool) bool {
+			if !isFinalPath {
+				return path != "team/ignored" && !strings.HasSuffix(path, "/nested")
+			}
+			return !strings.HasSuffix(path, "/token")
+		}
+
+		keys, err := newKeyLister(tree.listFolder, include, 4).list(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, serialWalk(tree, "", include), keys)
+		assert.NotContains(t, keys, "team/ignored/secret")
+		assert.NotContains(t, keys, "app/service-00/nested/deep")
+		assert.NotContains(t, keys, "app/service-00/token")
+		assert.Contains(t, keys, "team/owner")
+	})
+
+	t.Run("never lists more folders in parallel than the concurrency", func(t *testing.T) {
+		tree := newFakeKVTree()
+
+		_, err := newKeyLister(tree.listFolder, includeAll, 3).list(context.Background())
+
+		require.NoError(t, err)
+		assert.LessOrEqual(t, tree.maxSeen.Load(), int32(3))
+	})
+
+	t.Run("returns the first listing error", func(t *testing.T) {
+		tree := newFakeKVTree()
+		tree.errPath = "app/service-07"
+
+		keys, err := newKeyLister(tree.listFolder, includeAll, 4).list(context.Background())
+
+		require.ErrorContains(t, err, "failed to list path app/service-07")
+		assert.Nil(t, keys)
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		tree := newFakeKVTree()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		keys, err := newKeyLister(tree.listFolder, includeAll, 4).list(ctx)
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, keys)
+	})
+
+	t.Run("returns an empty list for an empty mount", func(t *testing.T) {
+		tree := &fakeKVTree{folders: map[string][]string{}}
+
+		keys, err := newKeyLister(tree.listFolder, includeAll, 4).list(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("streams every included key without keeping the tree", func(t *testing.T) {
+		tree := newFakeKVTree()
+		include := func(path string, isFinalPath bool) bool {
+			return isFinalPath || path != "team/ignored"
+		}
+		var mu sync.Mutex
+		var keys []string
+
+		err := newKeyLister(tree.listFolder, include, 4).stream(context.Background(), func(keyPath string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			keys = append(keys, keyPath)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.ElementsMatch(t, serialWalk(tree, "", include), keys)
+		assert.NotContains(t, keys, "team/ignored/secret")
+	})
+
+	t.Run("stops streaming when emit fails", func(t *testing.T) {
+		tree := newFakeKVTree()
+		emitErr := errors.New("consumer stopped")
+		var emitted atomic.Int32
+
+		err := newKeyLister(tree.listFolder, includeAll, 4).stream(context.Background(), func(string) error {
+			if emitted.Add(1) == 5 {
+				return emitErr
+			}
+			return nil
+		})
+
+		require.ErrorIs(t, err, emitErr)
+		assert.Less(t, emitted.Load(), int32(len(serialWalk(tree, "", includeAll))))
+	})
+
+	t.Run("default concurrency is used when not configured", func(t *testing.T) {
+		lister := newKeyLister(newFakeKVTree().listFolder, includeAll, 0)
+
+		assert.Equal(t, defaultListConcurrency, lister.concurrency)
+	})
+}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"time"
@@ -95,6 +96,7 @@ func (o *replicaSyncHandler[T]) execute(destinationCluster string, syncResult T)
 	errorMsg, hasError := o.checkSyncError(destinationCluster, err)
 	if hasError {
 		syncResult.SetErrorMessage(&errorMsg)
+		syncResult.SetErrorCategory(failureCategory(err))
 		syncResult.SetStatus(models.StatusFailed)
 		o.logger.Error().
 			Err(err).
@@ -116,7 +118,7 @@ func (o *replicaSyncHandler[T]) processResults(ctx context.Context, syncResult T
 }
 
 func (o *replicaSyncHandler[T]) checkSyncError(destinationCluster string, err error) (string, bool) {
-	if err != nil && o.operationType == operationTypeDelete && isNotFoundError(err) {
+	if err != nil && o.operationType == operationTypeDelete && errors.Is(err, ErrNotFound) {
 		return "Secret not found - treated as successful deletion", false
 	}
 
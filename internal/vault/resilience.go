@@ -0,0 +1,293 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"vault-sync/internal/config"
+	"vault-sync/internal/metrics"
+	"vault-sync/internal/models"
+	"vault-sync/internal/tracing"
+	"vault-sync/pkg/log"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//nolint:gochecknoglobals,mnd
+var (
+	defaultRetryMax            = 3
+	defaultRetryWaitMin        = 250 * time.Millisecond
+	defaultRetryWaitMax        = 5 * time.Second
+	defaultRetryJitter         = 0.5
+	defaultBreakerFailureRatio = 0.5
+	defaultBreakerMinRequests  = uint32(10)
+	defaultBreakerMaxRequests  = uint32(3)
+	defaultBreakerInterval     = 60 * time.Second
+	defaultBreakerTimeout      = 5 * time.Minute
+)
+
+// resiliencePolicy wraps every request sent to a single Vault cluster with
+// a retry policy and a circuit breaker.
+//
+// Requests are retried with exponential backoff and jitter as long as the error is
+// classified as retryable (see isRetryableError). Once the failure ratio reaches the
+// configured threshold the breaker opens and every request fails fast with
+// ErrClusterUnavailable until the breaker timeout elapses.
+// Every attempt, including retries, goes through the throttle of the cluster and is counted in requests.
+type resiliencePolicy struct {
+	clusterName    string
+	circuitBreaker *gobreaker.CircuitBreaker
+	retryOptFunc   func() []backoff.RetryOption
+	throttle       *requestThrottle
+	requests       atomic.Int64
+	// onAuthExpired is called when a request was rejected for an expired or revoked token.
+	onAuthExpired func()
+}
+
+func newResiliencePolicy(cfg *config.VaultClusterConfig) *resiliencePolicy {
+	circuitBreaker := gobreaker.NewCircuitBreaker(newCircuitBreakerSettings(cfg))
+	metrics.SetCircuitBreakerState(circuitBreaker.Name(), circuitBreaker.State())
+	return &resiliencePolicy{
+		clusterName:    cfg.Name,
+		circuitBreaker: circuitBreaker,
+		retryOptFunc:   newRetryStrategy(cfg),
+		throttle:       newRequestThrottle(cfg),
+	}
+}
+
+// executeWithPolicy runs operation through the circuit breaker and the retry policy of the cluster. Every attempt
+// is recorded in the request metrics under operationName, failed attempts also as events of the span in ctx.
+func executeWithPolicy[T any](
+	ctx context.Context,
+	policy *resiliencePolicy,
+	operationName string,
+	operation func() (T, error),
+) (T, error) {
+	result, err := executeRequest(ctx, policy, operationName, operation)
+	// A missing secret is an answer, not a failure of the operation.
+	if !errors.Is(err, ErrNotFound) {
+		tracing.RecordError(trace.SpanFromContext(ctx), err)
+	}
+	return result, err
+}
+
+// executeRequest runs operation for executeWithPolicy.
+func executeRequest[T any](
+	ctx context.Context,
+	policy *resiliencePolicy,
+	operationName string,
+	operation func() (T, error),
+) (T, error) {
+	var zero T
+
+	retryableOperation := func() (T, error) {
+		release, err := policy.throttle.acquire(ctx)
+		if err != nil {
+			return zero, backoff.Permanent(err)
+		}
+		policy.requests.Add(1)
+		startTime := time.Now()
+		result, err := operation()
+		err = classifyError(err)
+		release(err)
+		policy.observeRequest(ctx, operationName, time.Since(startTime), err)
+		if policy.onAuthExpired != nil && errors.Is(err, ErrAuthExpired) {
+			policy.onAuthExpired()
+		}
+		if err != nil && !isRetryableError(err) {
+			return result, backoff.Permanent(err)
+		}
+		return result, err
+	}
+
+	result, err := policy.circuitBreaker.Execute(func() (any, error) {
+		result, err := backoff.Retry(ctx, retryableOperation, policy.retryOptFunc()...)
+		if err != nil && ctx.Err() != nil {
+			return result, &callerDoneError{err: err}
+		}
+		return result, err
+	})
+	if err != nil {
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			return zero, fmt.Errorf("%w (cluster %s)", ErrClusterUnavailable, policy.clusterName)
+		}
+		var callerDone *callerDoneError
+		if errors.As(err, &callerDone) {
+			return zero, callerDone.err
+		}
+		return zero, err
+	}
+
+	typedResult, ok := result.(T)
+	if !ok {
+		return zero, nil
+	}
+	return typedResult, nil
+}
+
+// observeRequest records the latency of a request and, unless it succeeded or was cancelled, its error category.
+// A failed request, other than a missing secret, is added as an event to the span in ctx, so retries show up
+// in the trace.
+func (p *resiliencePolicy) observeRequest(
+	ctx context.Context,
+	operationName string,
+	duration time.Duration,
+	err error,
+) {
+	category := ""
+	if err != nil && !errors.Is(err, context.Canceled) {
+		category = ErrorCategoryOf(err).String()
+	}
+	if category != "" && !errors.Is(err, ErrNotFound) {
+		trace.SpanFromContext(ctx).AddEvent("vault request failed", trace.WithAttributes(
+			tracing.OperationKey.String(operationName),
+			tracing.ErrorCategoryKey.String(category),
+		))
+	}
+	metrics.ObserveVaultRequest(p.clusterName, operationName, duration, category)
+}
+
+// isRetryableError reports whether the error is transient and the request may succeed when retried.
+// Unavailable and rate limited clusters and network errors are retryable; other errors are permanent.
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrClusterUnavailable) {
+		return false
+	}
+
+	switch ErrorCategoryOf(err) {
+	case models.ErrorCategoryUnavailable, models.ErrorCategoryRateLimited, models.ErrorCategoryNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// failureCategory returns the category stored for a failed replica operation.
+func failureCategory(err error) models.ErrorCategory {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return models.ErrorCategoryUnavailable
+	}
+	return ErrorCategoryOf(err)
+}
+
+// callerDoneError is the error of a request whose context was done: the caller gave up, whether it was
+// cancelled, ran out of its job timeout or run deadline or waited too long in the throttle. It is only passed to
+// the circuit breaker, which does not count it against the cluster.
+type callerDoneError struct {
+	err error
+}
+
+func (e *callerDoneError) Error() string { return e.err.Error() }
+
+func (e *callerDoneError) Unwrap() error { return e.err }
+
+// countsAsClusterFailure reports whether the error says something about the health of the cluster: Vault
+// answered that it is unavailable or rate limited, or the request failed in transport. Missing secrets,
+// permission errors and requests whose caller gave up do not trip the circuit breaker.
+func countsAsClusterFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var callerDone *callerDoneError
+	if errors.As(err, &callerDone) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return isRetryableError(err) || errors.As(err, &netErr)
+}
+
+func newRetryStrategy(cfg *config.VaultClusterConfig) func() []backoff.RetryOption {
+	retryMax := cfg.RetryMax
+	retryWaitMin := cfg.RetryWaitMin
+	retryWaitMax := cfg.RetryWaitMax
+
+	if retryMax == 0 {
+		retryMax = defaultRetryMax
+	}
+	if retryMax < 0 {
+		retryMax = 0
+	}
+	if retryWaitMin == 0 {
+		retryWaitMin = defaultRetryWaitMin
+	}
+	if retryWaitMax == 0 {
+		retryWaitMax = max(defaultRetryWaitMax, retryWaitMin)
+	}
+
+	return func() []backoff.RetryOption {
+		strategy := &backoff.ExponentialBackOff{
+			InitialInterval:     retryWaitMin,
+			RandomizationFactor: defaultRetryJitter,
+			Multiplier:          backoff.DefaultMultiplier,
+			MaxInterval:         retryWaitMax,
+		}
+		//nolint:gosec
+		return []backoff.RetryOption{
+			backoff.WithBackOff(strategy),
+			backoff.WithMaxTries(uint(retryMax) + 1),
+			backoff.WithMaxElapsedTime(0),
+		}
+	}
+}
+
+func newCircuitBreakerSettings(cfg *config.VaultClusterConfig) gobreaker.Settings {
+	breakerCfg := cfg.CircuitBreaker
+
+	failureRatio := breakerCfg.FailureRatio
+	if failureRatio == 0 {
+		failureRatio = defaultBreakerFailureRatio
+	}
+	minRequests := breakerCfg.MinRequests
+	if minRequests == 0 {
+		minRequests = defaultBreakerMinRequests
+	}
+	maxRequests := breakerCfg.MaxRequests
+	if maxRequests == 0 {
+		maxRequests = defaultBreakerMaxRequests
+	}
+	interval := breakerCfg.Interval
+	if interval == 0 {
+		interval = defaultBreakerInterval
+	}
+	timeout := breakerCfg.Timeout
+	if timeout == 0 {
+		timeout = defaultBreakerTimeout
+	}
+
+	return gobreaker.Settings{
+		Name:        "vault_" + cfg.Name,
+		MaxRequests: maxRequests,
+		Interval:    interval,
+		Timeout:     timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			ratio := float64(counts.TotalFailures) / float64(counts.Requests)
+			return counts.Requests >= minRequests && ratio >= failureRatio
+		},
+		IsSuccessful: func(err error) bool {
+			return !countsAsClusterFailure(err)
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			metrics.SetCircuitBreakerState(name, to)
+			log.Logger.Warn().
+				Str("component", "cluster_manager").
+				Str("event", "circuit_breaker_state_change").
+				Str("cluster", cfg.Name).
+				Str("circuit_breaker", name).
+				Str("from_state", from.String()).
+				Str("to_state", to.String()).
+				Msg("Circuit breaker state changed")
+		},
+	}
+}
@@ -0,0 +1,226 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"vault-sync/internal/config"
+	"vault-sync/internal/tracing"
+)
+
+func newTestClusterConfig() *config.VaultClusterConfig {
+	return &config.VaultClusterConfig{
+		Name:         "test-cluster",
+		RetryMax:     2,
+		RetryWaitMin: time.Millisecond,
+		RetryWaitMax: 2 * time.Millisecond,
+		CircuitBreaker: config.CircuitBreaker{
+			FailureRatio: 0.5,
+			MinRequests:  2,
+			Timeout:      time.Minute,
+		},
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "nil error", err: nil, retryable: false},
+		{name: "not found", err: &vault.ResponseError{StatusCode: http.StatusNotFound}, retryable: false},
+		{name: "permission denied", err: &vault.ResponseError{StatusCode: http.StatusForbidden}, retryable: false},
+		{name: "rate limited", err: &vault.ResponseError{StatusCode: http.StatusTooManyRequests}, retryable: true},
+		{name: "internal error", err: &vault.ResponseError{StatusCode: http.StatusInternalServerError}, retryable: true},
+		{name: "sealed", err: &vault.ResponseError{StatusCode: http.StatusServiceUnavailable}, retryable: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, retryable: true},
+		{name: "context canceled", err: context.Canceled, retryable: false},
+		{name: "unknown error", err: errors.New("boom"), retryable: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.retryable, isRetryableError(tc.err))
+		})
+	}
+}
+
+func TestExecuteWithPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retries retryable errors until success", func(t *testing.T) {
+		policy := newResiliencePolicy(newTestClusterConfig())
+		attempts := 0
+
+		result, err := executeWithPolicy(ctx, policy, "test", func() (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "", &vault.ResponseError{StatusCode: http.StatusBadGateway}
+			}
+			return "ok", nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", result)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		policy := newResiliencePolicy(newTestClusterConfig())
+		attempts := 0
+		notFound := &vault.ResponseError{StatusCode: http.StatusNotFound}
+
+		_, err := executeWithPolicy(ctx, policy, "test", func() (string, error) {
+			attempts++
+			return "", notFound
+		})
+
+		require.ErrorIs(t, err, notFound)
+		require.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("reports rejected tokens", func(t *testing.T) {
+		policy := newResiliencePolicy(newTestClusterConfig())
+		rejected := 0
+		policy.onAuthExpired = func() { rejected++ }
+
+		_, err := executeWithPolicy(ctx, policy, "test", func() (string, error) {
+			return "", &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"invalid token"}}
+		})
+
+		require.ErrorIs(t, err, ErrAuthExpired)
+		assert.Equal(t, 1, rejected)
+	})
+
+	t.Run("records failed requests on the span in ctx", func(t *testing.T) {
+		policy := newResiliencePolicy(newTestClusterConfig())
+		recorder := tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+		attempts := 0
+
+		spanCtx, span := tracer.Start(ctx, "operation")
+		_, err := executeWithPolicy(spanCtx, policy, "test", func() (string, error) {
+			attempts++
+			if attempts == 1 {
+				return "", &vault.ResponseError{StatusCode: http.StatusBadGateway}
+			}
+			return "", &vault.ResponseError{StatusCode: http.StatusForbidden}
+		})
+		span.End()
+		notFoundCtx, notFoundSpan := tracer.Start(ctx, "not found")
+		_, _ = executeWithPolicy(notFoundCtx, policy, "test", func() (string, error) {
+			return "", &vault.ResponseError{StatusCode: http.StatusNotFound}
+		})
+		notFoundSpan.End()
+
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		var failedRequests []string
+		for _, event := range spans[0].Events() {
+			if event.Name == "vault request failed" {
+				attrs := attribute.NewSet(event.Attributes...)
+				category, _ := attrs.Value(tracing.ErrorCategoryKey)
+				failedRequests = append(failedRequests, category.AsString())
+			}
+		}
+		assert.Equal(t, []string{"unavailable", "permission_denied"}, failedRequests)
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+		assert.Empty(t, spans[1].Events())
+	})
+
+	t.Run("stops after retry_max retries", func(t *testing.T) {
+		policy := newResiliencePolicy(newTestClusterConfig())
+		attempts := 0
+
+		_, err := executeWithPolicy(ctx, policy, "test", func() (string, error) {
+			attempts++
+			return "", &vault.ResponseError{StatusCode: http.StatusServiceUnavailable}
+		})
+
+		require.Error(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("opens the circuit breaker and fails fast", func(t *testing.T) {
+		cfg := newTestClusterConfig()
+		cfg.RetryMax = -1
+		policy := newResiliencePolicy(cfg)
+		attempts := 0
+		failingOperation := func() (string, error) {
+			attempts++
+			return "", &vault.ResponseError{StatusCode: http.StatusInternalServerError}
+		}
+
+		for range 2 {
+			_, _ = executeWithPolicy(ctx, policy, "test", failingOperation)
+		}
+		_, err := executeWithPolicy(ctx, policy, "test", failingOperation)
+
+		require.ErrorIs(t, err, ErrClusterUnavailable)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, gobreaker.StateOpen, policy.circuitBreaker.State())
+	})
+
+	t.Run("requests whose caller ran out of time do not open the circuit breaker", func(t *testing.T) {
+		cfg := newTestClusterConfig()
+		cfg.RetryMax = -1
+		policy := newResiliencePolicy(cfg)
+
+		for range 5 {
+			jobCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+			_, err := executeWithPolicy(jobCtx, policy, "test", func() (string, error) {
+				<-jobCtx.Done()
+				return "", &net.OpError{Op: "read", Err: jobCtx.Err()}
+			})
+			cancel()
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrClusterUnavailable)
+		}
+
+		assert.Equal(t, gobreaker.StateClosed, policy.circuitBreaker.State())
+	})
+
+	t.Run("transport errors open the circuit breaker", func(t *testing.T) {
+		cfg := newTestClusterConfig()
+		cfg.RetryMax = -1
+		policy := newResiliencePolicy(cfg)
+
+		for range 2 {
+			_, _ = executeWithPolicy(ctx, policy, "test", func() (string, error) {
+				return "", &net.OpError{Op: "dial", Err: context.DeadlineExceeded}
+			})
+		}
+
+		assert.Equal(t, gobreaker.StateOpen, policy.circuitBreaker.State())
+	})
+
+	t.Run("not found errors do not open the circuit breaker", func(t *testing.T) {
+		cfg := newTestClusterConfig()
+		policy := newResiliencePolicy(cfg)
+
+		for range 5 {
+			_, _ = executeWithPolicy(ctx, policy, "test", func() (string, error) {
+				return "", &vault.ResponseError{StatusCode: http.StatusNotFound}
+			})
+		}
+
+		assert.Equal(t, gobreaker.StateClosed, policy.circuitBreaker.State())
+	})
+}
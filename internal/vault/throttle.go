@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+
+	"vault-sync/internal/config"
+	"vault-sync/pkg/log"
+
+	"github.com/hashicorp/vault-client-go"
+	"golang.org/x/time/rate"
+)
+
+//nolint:gochecknoglobals,mnd
+var (
+	defaultMaxConcurrency      = 32
+	minConcurrency             = 1.0
+	concurrencyBackoffFactor   = 0.5
+	concurrencyRecoveryPerCall = 1.0
+)
+
+// requestThrottle limits the requests sent to a single Vault cluster.
+//
+// The token bucket caps the request rate, while the adaptive concurrency limiter caps the number of
+// in-flight requests. The concurrency limit is halved whenever Vault answers with 429 or 503, and grows
+// back by roughly one slot per window of successful requests (AIMD), up to the configured maximum.
+type requestThrottle struct {
+	clusterName string
+	rateLimiter *rate.Limiter
+	concurrency *adaptiveConcurrencyLimiter
+}
+
+func newRequestThrottle(cfg *config.VaultClusterConfig) *requestThrottle {
+	throttle := &requestThrottle{
+		clusterName: cfg.Name,
+		concurrency: newAdaptiveConcurrencyLimiter(cfg.Name, cfg.RateLimit.MaxConcurrency),
+	}
+
+	if cfg.RateLimit.RequestsPerSecond > 0 {
+		burst := cfg.RateLimit.Burst
+		if burst == 0 {
+			burst = max(1, int(math.Ceil(cfg.RateLimit.RequestsPerSecond)))
+		}
+		throttle.rateLimiter = rate.NewLimiter(rate.Limit(cfg.RateLimit.RequestsPerSecond), burst)
+	}
+
+	return throttle
+}
+
+// acquire blocks until the request is allowed by both the token bucket and the concurrency limiter.
+// The returned release function must be called with the result of the request.
+func (t *requestThrottle) acquire(ctx context.Context) (func(err error), error) {
+	if err := t.concurrency.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	if t.rateLimiter != nil {
+		if err := t.rateLimiter.Wait(ctx); err != nil {
+			t.concurrency.release(false)
+			return nil, err
+		}
+	}
+
+	return func(err error) {
+		t.concurrency.release(isOverloadError(err))
+	}, nil
+}
+
+// isOverloadError reports whether Vault asked the client to slow down.
+func isOverloadError(err error) bool {
+	var responseErr *vault.ResponseError
+	if !errors.As(err, &responseErr) {
+		return false
+	}
+	return responseErr.StatusCode == http.StatusTooManyRequests ||
+		responseErr.StatusCode == http.StatusServiceUnavailable
+}
+
+type adaptiveConcurrencyLimiter struct {
+	clusterName string
+	maxLimit    float64
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	changed  chan struct{}
+}
+
+func newAdaptiveConcurrencyLimiter(clusterName string, maxConcurrency int) *adaptiveConcurrencyLimiter {
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+
+	return &adaptiveConcurrencyLimiter{
+		clusterName: clusterName,
+		maxLimit:    float64(maxConcurrency),
+		limit:       float64(maxConcurrency),
+		changed:     make(chan struct{}),
+	}
+}
+
+func (l *adaptiveConcurrencyLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (l *adaptiveConcurrencyLimiter) release(overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	previousLimit := int(l.limit)
+	if overloaded {
+		l.limit = max(minConcurrency, l.limit*concurrencyBackoffFactor)
+	} else {
+		l.limit = min(l.maxLimit, l.limit+concurrencyRecoveryPerCall/l.limit)
+	}
+
+	if currentLimit := int(l.limit); currentLimit != previousLimit {
+		logEvent := log.Logger.Debug()
+		if overloaded {
+			logEvent = log.Logger.Warn()
+		}
+		logEvent.
+			Str("component", "cluster_manager").
+			Str("event", "concurrency_limit_change").
+			Str("cluster", l.clusterName).
+			Int("from_limit", previousLimit).
+			Int("to_limit", currentLimit).
+			Msg("Adaptive concurrency limit changed")
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *adaptiveConcurrencyLimiter) currentLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vault-sync/internal/config"
+)
+
+func TestRequestThrottle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("token bucket limits the request rate", func(t *testing.T) {
+		throttle := newRequestThrottle(&config.VaultClusterConfig{
+			Name:      "test-cluster",
+			RateLimit: config.RateLimit{RequestsPerSecond: 50, Burst: 1},
+		})
+
+		start := time.Now()
+		for range 5 {
+			release, err := throttle.acquire(ctx)
+			require.NoError(t, err)
+			release(nil)
+		}
+
+		assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond)
+	})
+
+	t.Run("no rate limit when requests_per_second is not set", func(t *testing.T) {
+		throttle := newRequestThrottle(&config.VaultClusterConfig{Name: "test-cluster"})
+
+		assert.Nil(t, throttle.rateLimiter)
+		assert.Equal(t, defaultMaxConcurrency, throttle.concurrency.currentLimit())
+	})
+
+	t.Run("concurrency backs off on 429 and 503 and recovers on success", func(t *testing.T) {
+		throttle := newRequestThrottle(&config.VaultClusterConfig{
+			Name:      "test-cluster",
+			RateLimit: config.RateLimit{MaxConcurrency: 8},
+		})
+		throttled := func(status int) {
+			release, err := throttle.acquire(ctx)
+			require.NoError(t, err)
+			release(&vault.ResponseError{StatusCode: status})
+		}
+
+		throttled(http.StatusTooManyRequests)
+		assert.Equal(t, 4, throttle.concurrency.currentLimit())
+		throttled(http.StatusServiceUnavailable)
+		assert.Equal(t, 2, throttle.concurrency.currentLimit())
+
+		for range 100 {
+			release, err := throttle.acquire(ctx)
+			require.NoError(t, err)
+			release(nil)
+		}
+		assert.Equal(t, 8, throttle.concurrency.currentLimit())
+	})
+
+	t.Run("other errors do not change the concurrency limit", func(t *testing.T) {
+		throttle := newRequestThrottle(&config.VaultClusterConfig{
+			Name:      "test-cluster",
+			RateLimit: config.RateLimit{MaxConcurrency: 4},
+		})
+
+		release, err := throttle.acquire(ctx)
+		require.NoError(t, err)
+		release(&vault.ResponseError{StatusCode: http.StatusInternalServerError})
+
+		assert.Equal(t, 4, throttle.concurrency.currentLimit())
+	})
+
+	t.Run("acquire blocks at the limit and respects context cancellation", func(t *testing.T) {
+		throttle := newRequestThrottle(&config.VaultClusterConfig{
+			Name:      "test-cluster",
+			RateLimit: config.RateLimit{MaxConcurrency: 1},
+		})
+
+		release, err := throttle.acquire(ctx)
+		require.NoError(t, err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err = throttle.acquire(timeoutCtx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		acquired := make(chan struct{})
+		go func() {
+			secondRelease, acquireErr := throttle.acquire(ctx)
+			if acquireErr == nil {
+				secondRelease(nil)
+			}
+			close(acquired)
+		}()
+		release(nil)
+
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("waiting request was not released")
+		}
+	})
+}
+
+func TestExecuteWithPolicyThrottling(t *testing.T) {
+	cfg := newTestClusterConfig()
+	cfg.RateLimit = config.RateLimit{MaxConcurrency: 4}
+	policy := newResiliencePolicy(cfg)
+	attempts := 0
+
+	result, err := executeWithPolicy(context.Background(), policy, "test", func() (string, error) {
+		attempts++
+		if attempts == 1 {
+			return "", &vault.ResponseError{StatusCode: http.StatusTooManyRequests}
+		}
+		return "ok", nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, 2, attempts)
+	assert.Less(t, policy.throttle.concurrency.currentLimit(), 4)
+}
@@ -5,12 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/sony/gobreaker"
 )
 
 const (
-	ErrorNotFound404 = "404"
-	ErrorNoSuchPath  = "no such path"
-
 	LogSecretNotFound  = "Secret does not exist in replica cluster"
 	LogSyncStarted     = "Starting secret synchronization from main cluster to replicas"
 	LogDeletionStarted = "Starting secret deletion from replica clusters"
@@ -47,6 +46,24 @@ type SecretMetadataResponse struct {
 	Versions       map[string]SecretEmbededMetadata `json:"versions"`
 }
 
+// SourceState is the state of a secret in the main cluster, read with a single metadata request.
+// Metadata is nil when the secret does not exist.
+type SourceState struct {
+	Exists   bool
+	Metadata *SecretMetadataResponse
+}
+
+// ClusterStatus is the health of a Vault cluster. Err holds what could not be checked, in which case the
+// fields depending on it are false.
+type ClusterStatus struct {
+	Name           string
+	Main           bool
+	Authenticated  bool
+	Sealed         bool
+	CircuitBreaker gobreaker.State
+	Err            error
+}
+
 // NullableTime is a custom type that can be used to represent a time.Time value that may be null.
 type NullableTime struct {
 	*time.Time
@@ -55,13 +55,6 @@ func validateMountAndKeyPath(mount, keyPath string) error {
 	return nil
 }
 
-// isNotFoundError checks if the error is a "not found" error.
-func isNotFoundError(err error) bool {
-	errStr := err.Error()
-	return strings.Contains(errStr, ErrorNotFound404) ||
-		strings.Contains(errStr, ErrorNoSuchPath)
-}
-
 // logOperationSummary logs a summary of the synchronization operation
 // It counts the number of successful, failed, and pending operations
 // and logs them using the provided logger.
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Listen subscribes to the notification channel on a dedicated connection, outside the pool, and calls notify
+// with the payload of every notification until ctx is done or the connection is lost.
+func (p *PostgresDatastore) Listen(ctx context.Context, channel string, notify func(payload string)) error {
+	conn, err := pgx.Connect(ctx, p.connectionString)
+	if err != nil {
+		return fmt.Errorf("failed to open listen connection: %w", err)
+	}
+	defer func() {
+		_ = conn.Close(context.WithoutCancel(ctx))
+	}()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+	p.logger.Debug().Str("channel", channel).Msg("Listening for notifications")
+
+	for {
+		notification, waitErr := conn.WaitForNotification(ctx)
+		if waitErr != nil {
+			return fmt.Errorf("stopped listening on %s: %w", channel, waitErr)
+		}
+		notify(notification.Payload)
+	}
+}
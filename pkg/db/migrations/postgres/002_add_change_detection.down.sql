@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS full_reconciliations;
+
+ALTER TABLE synced_secrets DROP COLUMN IF EXISTS source_updated_time;
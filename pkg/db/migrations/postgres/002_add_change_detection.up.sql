@@ -0,0 +1,6 @@
+ALTER TABLE synced_secrets ADD COLUMN IF NOT EXISTS source_updated_time TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS full_reconciliations (
+    instance_id TEXT PRIMARY KEY,
+    last_full_run TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sync_events;
+DROP TABLE IF EXISTS sync_runs;
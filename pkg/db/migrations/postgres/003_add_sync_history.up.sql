@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS sync_runs (
+    run_id BIGSERIAL PRIMARY KEY,
+    instance_id TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    status TEXT NOT NULL,
+    incremental BOOLEAN NOT NULL DEFAULT FALSE,
+    total_secrets INTEGER NOT NULL DEFAULT 0,
+    successful_syncs INTEGER NOT NULL DEFAULT 0,
+    failed_syncs INTEGER NOT NULL DEFAULT 0,
+    skipped_secrets INTEGER NOT NULL DEFAULT 0,
+    no_op_secrets INTEGER NOT NULL DEFAULT 0,
+    discovery_failures INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS sync_runs_instance_started_idx ON sync_runs (instance_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS sync_runs_started_idx ON sync_runs (started_at);
+
+CREATE TABLE IF NOT EXISTS sync_events (
+    event_id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES sync_runs (run_id) ON DELETE CASCADE,
+    secret_backend TEXT NOT NULL,
+    secret_path TEXT NOT NULL,
+    destination_cluster TEXT NOT NULL,
+    action TEXT NOT NULL,
+    source_version BIGINT,
+    destination_version BIGINT,
+    error_message TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sync_events_path_idx
+    ON sync_events (secret_backend, secret_path, destination_cluster, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS sync_events_run_idx ON sync_events (run_id);
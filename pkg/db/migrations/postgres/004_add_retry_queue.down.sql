@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS synced_secrets_failed_idx;
+
+ALTER TABLE synced_secrets DROP COLUMN IF EXISTS quarantined_at;
+ALTER TABLE synced_secrets DROP COLUMN IF EXISTS error_category;
+ALTER TABLE synced_secrets DROP COLUMN IF EXISTS next_retry_at;
+ALTER TABLE synced_secrets DROP COLUMN IF EXISTS attempt_count;
@@ -0,0 +1,7 @@
+ALTER TABLE synced_secrets ADD COLUMN IF NOT EXISTS attempt_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE synced_secrets ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMPTZ;
+-- 'transient' or 'permanent'; 005 replaces them with the categories of the vault package.
+ALTER TABLE synced_secrets ADD COLUMN IF NOT EXISTS error_category TEXT;
+ALTER TABLE synced_secrets ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS synced_secrets_failed_idx ON synced_secrets (next_retry_at) WHERE status = 'failed';
@@ -0,0 +1,5 @@
+-- Maps the categories of the vault package back to the 'transient' and 'permanent' values of 004.
+UPDATE synced_secrets SET error_category = 'transient'
+WHERE error_category IN ('auth_expired', 'unavailable', 'rate_limited', 'cas_conflict', 'network');
+UPDATE synced_secrets SET error_category = 'permanent'
+WHERE error_category IN ('not_found', 'permission_denied', 'unknown');
@@ -0,0 +1,4 @@
+-- error_category is added by 004. This migration adds no column: it only remaps the values written before errors
+-- were classified by response status, 'transient' and 'permanent', to the categories of the vault package.
+UPDATE synced_secrets SET error_category = 'unavailable' WHERE error_category = 'transient';
+UPDATE synced_secrets SET error_category = 'unknown' WHERE error_category = 'permanent';
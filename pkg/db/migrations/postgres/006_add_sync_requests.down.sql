@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS sync_requests_notify ON sync_requests;
+DROP FUNCTION IF EXISTS notify_sync_request();
+DROP TABLE IF EXISTS sync_requests;
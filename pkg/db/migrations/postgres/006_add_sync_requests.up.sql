@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS sync_requests (
+    request_id BIGSERIAL PRIMARY KEY,
+    secret_backend TEXT NOT NULL,
+    secret_path TEXT NOT NULL,
+    requested_by TEXT,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status TEXT NOT NULL DEFAULT 'pending',
+    claimed_by TEXT,
+    claimed_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS sync_requests_open_idx ON sync_requests (request_id) WHERE status IN ('pending', 'running');
+
+CREATE OR REPLACE FUNCTION notify_sync_request() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('vault_sync_requests', NEW.request_id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sync_requests_notify ON sync_requests;
+CREATE TRIGGER sync_requests_notify AFTER INSERT ON sync_requests
+    FOR EACH ROW EXECUTE FUNCTION notify_sync_request();
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS sync_job_queue (
+    job_id BIGSERIAL PRIMARY KEY,
+    secret_backend TEXT NOT NULL,
+    secret_path TEXT NOT NULL,
+    incremental BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    enqueued_by TEXT NOT NULL,
+    enqueued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    leased_by TEXT,
+    lease_expires_at TIMESTAMPTZ,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    finished_at TIMESTAMPTZ,
+    error_message TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS sync_job_queue_pending_path_idx
+    ON sync_job_queue (secret_backend, secret_path) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS sync_job_queue_open_idx ON sync_job_queue (job_id) WHERE status IN ('pending', 'leased');
+CREATE INDEX IF NOT EXISTS sync_job_queue_finished_idx ON sync_job_queue (finished_at) WHERE finished_at IS NOT NULL;
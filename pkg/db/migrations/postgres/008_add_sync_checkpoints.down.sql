@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sync_checkpoint_paths;
+DROP TABLE IF EXISTS sync_checkpoints;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sync_checkpoints (
+    instance_id TEXT PRIMARY KEY,
+    run_id BIGINT,
+    started_at TIMESTAMPTZ NOT NULL,
+    incremental BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS sync_checkpoint_paths (
+    instance_id TEXT NOT NULL REFERENCES sync_checkpoints (instance_id) ON DELETE CASCADE,
+    secret_backend TEXT NOT NULL,
+    secret_path TEXT NOT NULL,
+    PRIMARY KEY (instance_id, secret_backend, secret_path)
+);
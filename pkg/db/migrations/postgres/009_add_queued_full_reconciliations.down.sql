@@ -0,0 +1,4 @@
+ALTER TABLE full_reconciliations DROP COLUMN IF EXISTS queued_enqueued_at;
+ALTER TABLE full_reconciliations DROP COLUMN IF EXISTS queued_started_at;
+DELETE FROM full_reconciliations WHERE last_full_run IS NULL;
+ALTER TABLE full_reconciliations ALTER COLUMN last_full_run SET NOT NULL;
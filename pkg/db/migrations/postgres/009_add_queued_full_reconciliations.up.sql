@@ -0,0 +1,6 @@
+-- A coordinator only enqueues the jobs of a full reconciliation; it is recorded once the workers finished them.
+-- The queued reconciliation is kept with the instance, so it survives a restart of the coordinator. An instance
+-- whose first full reconciliation is still queued has no last full run yet.
+ALTER TABLE full_reconciliations ALTER COLUMN last_full_run DROP NOT NULL;
+ALTER TABLE full_reconciliations ADD COLUMN IF NOT EXISTS queued_started_at TIMESTAMPTZ;
+ALTER TABLE full_reconciliations ADD COLUMN IF NOT EXISTS queued_enqueued_at TIMESTAMPTZ;
@@ -25,6 +25,7 @@ var defaultHealthCheckPeriod = 1 * time.Minute
 
 type PostgresDatastore struct {
 	DB                  *sqlx.DB
+	connectionString    string
 	migrationSource     migrations.MigrationSource
 	healthCheckInterval *time.Ticker
 	stopHealthCheckCh   chan struct{}
@@ -68,6 +69,7 @@ func NewPostgresDatastore(
 
 	psqlDB := &PostgresDatastore{
 		DB:                  db,
+		connectionString:    connectionString,
 		migrationSource:     migrationSource,
 		healthCheckInterval: time.NewTicker(defaultHealthCheckPeriod),
 		stopHealthCheckCh:   make(chan struct{}),
@@ -97,6 +99,11 @@ func (p *PostgresDatastore) Close() error {
 	return nil
 }
 
+// Ping checks that the database can be reached, opening a connection when the pool has none.
+func (p *PostgresDatastore) Ping(ctx context.Context) error {
+	return p.DB.PingContext(ctx)
+}
+
 func redactDSN(dsnStr string) string {
 	parsedDSN, _ := url.Parse(dsnStr)
 
@@ -194,7 +201,7 @@ func (p *PostgresDatastore) startHealthCheck() {
 			select {
 			case <-p.healthCheckInterval.C:
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				err := p.DB.PingContext(ctx)
+				err := p.Ping(ctx)
 				if err != nil {
 					p.logger.Warn().Err(err).Msg("Database health check failed")
 				}
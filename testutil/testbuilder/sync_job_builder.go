@@ -2,6 +2,7 @@ package testbuilder
 
 import (
 	"context"
+	"time"
 	"vault-sync/internal/models"
 	"vault-sync/internal/repository"
 
@@ -24,6 +25,7 @@ type (
 
 	MockDatabaseSecretVersionStage interface {
 		WithGetSyncedSecret(clusters ...string) MockDatabaseStage
+		WithGetQuarantinedSyncedSecret(clusters ...string) MockDatabaseStage
 	}
 
 	MockDatabaseStage interface {
@@ -146,6 +148,22 @@ func (b *syncJobMockBuilder) WithGetSyncedSecret(clusters ...string) MockDatabas
 	return b
 }
 
+func (b *syncJobMockBuilder) WithGetQuarantinedSyncedSecret(clusters ...string) MockDatabaseStage {
+	quarantinedAt := time.Now()
+	for _, cluster := range clusters {
+		secret := &models.SyncedSecret{
+			SecretBackend:      b.mount,
+			SecretPath:         b.keyPath,
+			SourceVersion:      b.secretVersion,
+			DestinationCluster: cluster,
+			Status:             models.StatusFailed,
+			QuarantinedAt:      &quarantinedAt,
+		}
+		b.dbGetSecretsResult[cluster] = secret
+	}
+	return b
+}
+
 // MockDatabaseStage interface implementation
 func (b *syncJobMockBuilder) WithGetSyncedSecretError(err error, clusters ...string) MockDatabaseStage {
 	for _, cluster := range clusters {
@@ -266,15 +284,15 @@ func (b *syncJobMockBuilder) Build() (*mockRepository, *mockVaultClient) {
 	// Setup database mocks for GetSyncedSecret
 	for _, cluster := range b.clusters {
 		if dbError, hasError := b.dbErrors[cluster][DBGetSyncedSecret]; hasError {
-			b.mockRepo.On("GetSyncedSecret", b.mount, b.keyPath, cluster).Return(nil, dbError)
+			b.mockRepo.On("GetSyncedSecret", mock.Anything, b.mount, b.keyPath, cluster).Return(nil, dbError)
 		} else if secret, hasSecret := b.dbGetSecretsResult[cluster]; hasSecret {
-			b.mockRepo.On("GetSyncedSecret", b.mount, b.keyPath, cluster).Return(secret, nil)
+			b.mockRepo.On("GetSyncedSecret", mock.Anything, b.mount, b.keyPath, cluster).Return(secret, nil)
 		} else {
-			b.mockRepo.On("GetSyncedSecret", b.mount, b.keyPath, cluster).Return(nil, repository.ErrSecretNotFound)
+			b.mockRepo.On("GetSyncedSecret", mock.Anything, b.mount, b.keyPath, cluster).Return(nil, repository.ErrSecretNotFound)
 		}
 
 		if updateErr, hasError := b.dbErrors[cluster][DBUpdateSyncedSecretStatus]; hasError {
-			b.mockRepo.On("UpdateSyncedSecretStatus", mock.MatchedBy(func(methodArg *models.SyncedSecret) bool {
+			b.mockRepo.On("UpdateSyncedSecretStatus", mock.Anything, mock.MatchedBy(func(methodArg *models.SyncedSecret) bool {
 				return methodArg.DestinationCluster == cluster
 			})).Return(updateErr)
 		} else if secret, hasSecret := b.dbUpdateSecretsResult[cluster]; hasSecret {
@@ -285,12 +303,12 @@ func (b *syncJobMockBuilder) Build() (*mockRepository, *mockVaultClient) {
 					methodArg.Status == secret.Status &&
 					methodArg.SourceVersion == secret.SourceVersion
 			})
-			b.mockRepo.On("UpdateSyncedSecretStatus", updateMatcher).Return(nil)
+			b.mockRepo.On("UpdateSyncedSecretStatus", mock.Anything, updateMatcher).Return(nil)
 		}
 
 		if deleteErr, hasError := b.dbErrors[cluster][DBDeleteSyncedSecret]; hasError {
 			// error can be nil when WithDeleteSyncedSecret is used
-			b.mockRepo.On("DeleteSyncedSecret", b.mount, b.keyPath, cluster).Return(deleteErr)
+			b.mockRepo.On("DeleteSyncedSecret", mock.Anything, b.mount, b.keyPath, cluster).Return(deleteErr)
 		}
 	}
 
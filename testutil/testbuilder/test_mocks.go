@@ -2,6 +2,7 @@ package testbuilder
 
 import (
 	"context"
+	"time"
 	"vault-sync/internal/models"
 	"vault-sync/internal/vault"
 
@@ -35,11 +36,32 @@ func (m *mockVaultClient) GetSecretMetadata(ctx context.Context, mount, keyPath
 	return args.Get(0).(*vault.SecretMetadataResponse), args.Error(1)
 }
 
+func (m *mockVaultClient) GetSourceState(ctx context.Context, mount, keyPath string) (*vault.SourceState, error) {
+	args := m.Called(ctx, mount, keyPath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*vault.SourceState), args.Error(1)
+}
+
+func (m *mockVaultClient) RequestCounts() map[string]int64 {
+	args := m.Called()
+	return args.Get(0).(map[string]int64)
+}
+
 func (m *mockVaultClient) GetKeysUnderMount(ctx context.Context, mount string, shouldIncludeKeyPath func(path string, isFinalPath bool) bool) ([]string, error) {
 	args := m.Called(ctx, mount, shouldIncludeKeyPath)
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (m *mockVaultClient) StreamKeysUnderMount(ctx context.Context, mount string, shouldIncludeKeyPath func(path string, isFinalPath bool) bool, emit func(keyPath string) error) error {
+	args := m.Called(ctx, mount, shouldIncludeKeyPath, emit)
+	if streamFn, ok := args.Get(0).(func(context.Context, string, func(string, bool) bool, func(string) error) error); ok {
+		return streamFn(ctx, mount, shouldIncludeKeyPath, emit)
+	}
+	return args.Error(0)
+}
+
 func (m *mockVaultClient) SecretExists(ctx context.Context, mount, keyPath string) (bool, error) {
 	args := m.Called(ctx, mount, keyPath)
 	return args.Bool(0), args.Error(1)
@@ -66,6 +88,30 @@ func (m *mockVaultClient) DeleteSecretFromReplicas(ctx context.Context, mount, k
 	return args.Get(0).([]*models.SyncSecretDeletionResult), args.Error(1)
 }
 
+func (m *mockVaultClient) ReadSecret(ctx context.Context, mount, keyPath string) (*vault.SecretResponse, error) {
+	args := m.Called(ctx, mount, keyPath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*vault.SecretResponse), args.Error(1)
+}
+
+func (m *mockVaultClient) SyncSecretToReplica(ctx context.Context, cluster, mount, keyPath string, sourceSecret *vault.SecretResponse) (*models.SyncedSecret, error) {
+	args := m.Called(ctx, cluster, mount, keyPath, sourceSecret)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SyncedSecret), args.Error(1)
+}
+
+func (m *mockVaultClient) DeleteSecretFromReplica(ctx context.Context, cluster, mount, keyPath string) (*models.SyncSecretDeletionResult, error) {
+	args := m.Called(ctx, cluster, mount, keyPath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SyncSecretDeletionResult), args.Error(1)
+}
+
 // ********
 //
 // mockRepository is a mock implementation of the SyncedSecretRepository interface
@@ -75,30 +121,109 @@ type mockRepository struct {
 	mock.Mock
 }
 
-func (m *mockRepository) GetSyncedSecret(mount, keyPath, clusterName string) (*models.SyncedSecret, error) {
-	args := m.Called(mount, keyPath, clusterName)
+func (m *mockRepository) GetSyncedSecret(ctx context.Context, mount, keyPath, clusterName string) (*models.SyncedSecret, error) {
+	args := m.Called(ctx, mount, keyPath, clusterName)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.SyncedSecret), args.Error(1)
 }
 
-func (m *mockRepository) UpdateSyncedSecretStatus(secret *models.SyncedSecret) error {
-	args := m.Called(secret)
+func (m *mockRepository) UpdateSyncedSecretStatus(ctx context.Context, secret *models.SyncedSecret) error {
+	args := m.Called(ctx, secret)
 	return args.Error(0)
 }
-func (m *mockRepository) GetSyncedSecrets() ([]*models.SyncedSecret, error) {
-	args := m.Called()
+
+// UpdateSyncedSecretStatuses records every secret like UpdateSyncedSecretStatus, so the same expectations apply,
+// and fails the whole batch with the first error.
+func (m *mockRepository) UpdateSyncedSecretStatuses(ctx context.Context, secrets []*models.SyncedSecret) error {
+	var batchErr error
+	for _, secret := range secrets {
+		if err := m.UpdateSyncedSecretStatus(ctx, secret); err != nil && batchErr == nil {
+			batchErr = err
+		}
+	}
+	return batchErr
+}
+
+func (m *mockRepository) GetSyncedSecrets(ctx context.Context) ([]*models.SyncedSecret, error) {
+	args := m.Called(ctx)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*models.SyncedSecret), args.Error(1)
 }
-func (m *mockRepository) DeleteSyncedSecret(backend, path, destinationCluster string) error {
-	args := m.Called(backend, path, destinationCluster)
+func (m *mockRepository) GetFailedSyncedSecrets(ctx context.Context) ([]*models.SyncedSecret, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SyncedSecret), args.Error(1)
+}
+func (m *mockRepository) DeleteSyncedSecret(ctx context.Context, backend, path, destinationCluster string) error {
+	args := m.Called(ctx, backend, path, destinationCluster)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetLastFullReconciliation(ctx context.Context, instanceID string) (time.Time, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *mockRepository) RecordFullReconciliation(ctx context.Context, instanceID string, completedAt time.Time) error {
+	args := m.Called(ctx, instanceID, completedAt)
+	return args.Error(0)
+}
+
+func (m *mockRepository) QueueFullReconciliation(
+	ctx context.Context, instanceID string, startedAt, enqueuedAt time.Time,
+) error {
+	args := m.Called(ctx, instanceID, startedAt, enqueuedAt)
 	return args.Error(0)
 }
 
+func (m *mockRepository) GetQueuedFullReconciliation(
+	ctx context.Context, instanceID string,
+) (time.Time, time.Time, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).(time.Time), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *mockRepository) FinishQueuedFullReconciliation(ctx context.Context, instanceID string, completed bool) error {
+	args := m.Called(ctx, instanceID, completed)
+	return args.Error(0)
+}
+
+func (m *mockRepository) StartSyncRun(ctx context.Context, run *models.SyncRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *mockRepository) FinishSyncRun(ctx context.Context, run *models.SyncRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *mockRepository) InsertSyncEvents(ctx context.Context, events []*models.SyncEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (m *mockRepository) PruneSyncHistory(ctx context.Context, cutoff time.Time) (int64, error) {
+	args := m.Called(ctx, cutoff)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepository) GetSyncEvents(
+	ctx context.Context, backend, path string, limit int,
+) ([]*models.SyncEvent, error) {
+	args := m.Called(ctx, backend, path, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SyncEvent), args.Error(1)
+}
+
 func (m *mockRepository) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -171,6 +171,7 @@ func (b *VaultMockBuilder) Build() *mockVaultClient {
 	b.mockVault = new(mockVaultClient)
 
 	b.mockVault.On("GetReplicaNames").Return(b.clusters)
+	b.mockVault.On("RequestCounts").Return(map[string]int64{})
 
 	// Setup vault SecretExists mock
 	if vaultError, hasError := b.vaultErrors[VaultSecretExists]; hasError {
@@ -201,6 +202,7 @@ func (b *VaultMockBuilder) Build() *mockVaultClient {
 		} else {
 			b.mockVault.On("GetKeysUnderMount", mock.Anything, mount, mock.Anything).Return(keys, nil)
 		}
+		b.setupStreamKeysUnderMount(mount, keys)
 	}
 
 	// Setup vault GetSecretMetadata mock
@@ -217,12 +219,23 @@ func (b *VaultMockBuilder) Build() *mockVaultClient {
 		}
 	}
 
+	// Setup vault GetSourceState mock, failing like either of the two calls it replaces
+	b.setupGetSourceState()
+
 	// setup vault SyncSecretToReplicas mock if secret exists
 	if len(b.vaultSyncResults) > 0 || b.vaultErrors[VaultSyncSecretToReplicas] != nil {
 		if vaultError, hasError := b.vaultErrors[VaultSyncSecretToReplicas]; hasError {
 			b.mockVault.On("SyncSecretToReplicas", mock.Anything, b.mount, b.keyPath).Return(nil, vaultError)
+			b.mockVault.On("ReadSecret", mock.Anything, b.mount, b.keyPath).Return(nil, vaultError)
 		} else {
 			b.mockVault.On("SyncSecretToReplicas", mock.Anything, b.mount, b.keyPath).Return(b.vaultSyncResults, nil)
+
+			// replica pipeline: the source is read once and written to each replica separately
+			sourceSecret := &vault.SecretResponse{Metadata: vault.SecretEmbededMetadata{Version: b.sourceSecretVersion}}
+			b.mockVault.On("ReadSecret", mock.Anything, b.mount, b.keyPath).Return(sourceSecret, nil)
+			for _, result := range b.vaultSyncResults {
+				b.mockVault.On("SyncSecretToReplica", mock.Anything, result.DestinationCluster, b.mount, b.keyPath, mock.Anything).Return(result, nil)
+			}
 		}
 
 	}
@@ -233,8 +246,52 @@ func (b *VaultMockBuilder) Build() *mockVaultClient {
 			b.mockVault.On("DeleteSecretFromReplicas", mock.Anything, b.mount, b.keyPath).Return(nil, vaultError)
 		} else {
 			b.mockVault.On("DeleteSecretFromReplicas", mock.Anything, b.mount, b.keyPath).Return(b.vaultDeleteResults, nil)
+			for _, result := range b.vaultDeleteResults {
+				b.mockVault.On("DeleteSecretFromReplica", mock.Anything, result.DestinationCluster, b.mount, b.keyPath).Return(result, nil)
+			}
 		}
 	}
 
 	return b.mockVault
 }
+
+func (b *VaultMockBuilder) setupGetSourceState() {
+	call := b.mockVault.On("GetSourceState", mock.Anything, b.mount, b.keyPath)
+	for _, method := range []string{VaultSecretExists, VaultGetSecretMetadata} {
+		if vaultError, hasError := b.vaultErrors[method]; hasError {
+			call.Return(nil, vaultError)
+			return
+		}
+	}
+
+	if b.sourceSecretExists == nil || !*b.sourceSecretExists {
+		call.Return(&vault.SourceState{Exists: false}, nil)
+		return
+	}
+	metadata := &vault.SecretMetadataResponse{CurrentVersion: b.sourceSecretVersion}
+	call.Return(&vault.SourceState{Exists: true, Metadata: metadata}, nil)
+}
+
+// setupStreamKeysUnderMount streams the configured keys of a mount through the emit callback,
+// applying the include callback like the real client.
+func (b *VaultMockBuilder) setupStreamKeysUnderMount(mount string, keys []string) {
+	call := b.mockVault.On("StreamKeysUnderMount", mock.Anything, mount, mock.Anything, mock.Anything)
+	if vaultError, hasError := b.vaultErrors[VaultGetKeysUnderMount]; hasError {
+		call.Return(vaultError)
+		return
+	}
+
+	call.Return(func(
+		_ context.Context, _ string, shouldIncludeKeyPath func(string, bool) bool, emit func(string) error,
+	) error {
+		for _, key := range keys {
+			if !shouldIncludeKeyPath(key, true) {
+				continue
+			}
+			if err := emit(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -660,8 +660,8 @@ func setupSingleCluster(vaultHelper *VaultHelper, results chan clusterSetupResul
 				AppRoleSecret: secret,
 				// the following improve test speed by reducing retry wait times
 				RetryMax:     1,
-				RetryWaitMin: 100,
-				RetryWaitMax: 150,
+				RetryWaitMin: 100 * time.Millisecond,
+				RetryWaitMax: 150 * time.Millisecond,
 			},
 			err: nil,
 		}